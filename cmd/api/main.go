@@ -4,17 +4,31 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"ultra-bis/internal/account"
+	"ultra-bis/internal/apitoken"
 	"ultra-bis/internal/auth"
 	"ultra-bis/internal/barcode"
+	"ultra-bis/internal/coaching"
 	"ultra-bis/internal/database"
 	"ultra-bis/internal/diary"
+	"ultra-bis/internal/exercise"
+	"ultra-bis/internal/fasting"
 	"ultra-bis/internal/food"
 	"ultra-bis/internal/goal"
+	"ultra-bis/internal/hydration"
+	"ultra-bis/internal/i18n"
+	"ultra-bis/internal/insights"
+	"ultra-bis/internal/live"
 	"ultra-bis/internal/metrics"
 	"ultra-bis/internal/middleware"
 	"ultra-bis/internal/recipe"
+	"ultra-bis/internal/streaks"
 	"ultra-bis/internal/user"
+	"ultra-bis/internal/webhook"
 )
 
 func main() {
@@ -63,8 +77,25 @@ func main() {
 		&recipe.Recipe{},
 		&recipe.RecipeIngredient{},
 		&goal.NutritionGoal{},
+		&goal.DayTypeAssignment{},
+		&goal.CustomDiet{},
 		&diary.DiaryEntry{},
 		&metrics.BodyMetric{},
+		&metrics.BodyMeasurement{},
+		&hydration.WaterLog{},
+		&hydration.DrinkPreset{},
+		&fasting.FastingSession{},
+		&exercise.ExerciseEntry{},
+		&coaching.CoachLink{},
+		&apitoken.PersonalAccessToken{},
+		&account.DeletionRequest{},
+		&streaks.DayStatus{},
+		&streaks.Streak{},
+		&streaks.CustomBadge{},
+		&streaks.Achievement{},
+		&webhook.Endpoint{},
+		&webhook.Event{},
+		&webhook.Delivery{},
 	); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
@@ -78,6 +109,39 @@ func main() {
 	goalRepo := goal.NewRepository(db)
 	diaryRepo := diary.NewRepository(db)
 	metricsRepo := metrics.NewRepository(db)
+	hydrationRepo := hydration.NewRepository(db)
+	fastingRepo := fasting.NewRepository(db)
+	exerciseRepo := exercise.NewRepository(db)
+	coachingRepo := coaching.NewRepository(db)
+	tokenRepo := apitoken.NewRepository(db)
+	accountRepo := account.NewRepository(db)
+	streaksRepo := streaks.NewRepository(db)
+	webhookRepo := webhook.NewRepository(db)
+
+	// Promote configured administrators (comma-separated emails)
+	if adminEmails := getEnv("ADMIN_EMAILS", ""); adminEmails != "" {
+		var emails []string
+		for _, email := range strings.Split(adminEmails, ",") {
+			if email = strings.TrimSpace(email); email != "" {
+				emails = append(emails, email)
+			}
+		}
+		if err := userRepo.PromoteAdmins(emails); err != nil {
+			log.Fatal("Failed to promote admins:", err)
+		}
+	}
+
+	// Reject tokens of disabled accounts
+	auth.SetAccountStatusChecker(userRepo)
+
+	// Apply each user's locale, units and time zone to their requests
+	auth.SetPreferenceLookup(userRepo)
+
+	// Let coaches act on their clients' data within the scopes the client granted
+	auth.SetDelegationAuthorizer(coachingRepo)
+
+	// Accept personal access tokens ("Bearer pat_...") alongside login tokens
+	auth.SetPersonalTokenAuthenticator(tokenRepo)
 
 	// Initialize services
 	barcodeService := barcode.NewService()
@@ -88,6 +152,60 @@ func main() {
 	// Create recipe service with dependencies
 	recipeService := recipe.NewService(recipeRepo, foodAdapter, db)
 
+	// Confirmed account deletions run once the grace period (days) has passed
+	graceDays, err := strconv.Atoi(getEnv("ACCOUNT_DELETION_GRACE_DAYS", "30"))
+	if err != nil || graceDays < 0 {
+		log.Fatal("ACCOUNT_DELETION_GRACE_DAYS must be a non-negative number of days")
+	}
+	accountService := account.NewService(accountRepo, userRepo, graceDays)
+	go accountService.RunPurger(time.Hour)
+
+	// Webhook events are written to an outbox; due deliveries are sent on this interval
+	webhookInterval, err := time.ParseDuration(getEnv("WEBHOOK_DELIVERY_INTERVAL", "30s"))
+	if err != nil || webhookInterval <= 0 {
+		log.Fatal("WEBHOOK_DELIVERY_INTERVAL must be a positive duration (e.g. 30s, 1m)")
+	}
+	webhookPublisher := webhook.NewPublisher(webhookRepo)
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo)
+	go webhookDispatcher.Run(webhookInterval)
+	go webhookDispatcher.RunRetention(time.Hour)
+
+	// Diary and metric changes are pushed to the user's event streams; with LIVE_POSTGRES_FANOUT
+	// they go through Postgres LISTEN/NOTIFY so that every instance reaches its own streams
+	liveFanout, err := strconv.ParseBool(getEnv("LIVE_POSTGRES_FANOUT", "false"))
+	if err != nil {
+		log.Fatal("LIVE_POSTGRES_FANOUT must be true or false")
+	}
+	liveHub := live.NewHub()
+	if liveFanout {
+		pgFanout := live.NewPostgresFanout(db, liveHub)
+		liveHub.SetFanout(pgFanout)
+		go pgFanout.Run()
+	}
+	// Event streams are closed when their user's access changes
+	auth.SetSessionCloser(liveHub)
+
+	// A day counts towards the calorie goal streak within this percentage of the goal
+	calorieTolerance, err := strconv.ParseFloat(getEnv("CALORIE_STREAK_TOLERANCE", "10"), 64)
+	if err != nil || calorieTolerance < 0 || calorieTolerance > 100 {
+		log.Fatal("CALORIE_STREAK_TOLERANCE must be a percentage between 0 and 100")
+	}
+	streakTracker := streaks.NewTracker(streaksRepo, diaryRepo, goalRepo, calorieTolerance)
+	streakTracker.SetExerciseRepo(exerciseRepo)
+
+	// Expired protocol phases are ended and the next phase started on this interval
+	phaseInterval, err := time.ParseDuration(getEnv("GOAL_PHASE_CHECK_INTERVAL", "1h"))
+	if err != nil || phaseInterval <= 0 {
+		log.Fatal("GOAL_PHASE_CHECK_INTERVAL must be a positive duration (e.g. 1h, 30m)")
+	}
+	phaseScheduler := goal.NewPhaseScheduler(goalRepo, userRepo, metricsRepo)
+	phaseScheduler.SetWebhookPublisher(webhookPublisher)
+	phaseScheduler.SetDayObserver(streakTracker)
+	go phaseScheduler.Run(phaseInterval)
+
+	// Days that missed their calorie goal are reported as goal.adherence.missed events
+	go streaks.NewAdherenceReporter(streaksRepo, webhookPublisher).Run(time.Hour)
+
 	// Initialize handlers
 	authHandler := auth.NewHandler(userRepo)
 	barcodeHandler := barcode.NewHandler(barcodeService)
@@ -96,22 +214,59 @@ func main() {
 	goalHandler := goal.NewHandler(goalRepo, userRepo)
 	diaryHandler := diary.NewHandler(diaryRepo, foodRepo, goalRepo)
 	metricsHandler := metrics.NewHandler(metricsRepo)
+	hydrationHandler := hydration.NewHandler(hydrationRepo)
+	fastingHandler := fasting.NewHandler(fastingRepo)
+	exerciseHandler := exercise.NewHandler(exerciseRepo, userRepo, metricsRepo)
+	tokenHandler := apitoken.NewHandler(tokenRepo)
+	accountHandler := account.NewHandler(accountService)
+	coachingHandler := coaching.NewHandler(coachingRepo, userRepo, diaryRepo, goalRepo, metricsRepo)
+	insightsHandler := insights.NewHandler(diaryRepo, goalRepo, metricsRepo)
+	streaksHandler := streaks.NewHandler(streaksRepo, streakTracker)
+	webhookHandler := webhook.NewHandler(webhookRepo)
+	liveHandler := live.NewHandler(liveHub)
+	liveHandler.SetConverters(diary.LiveConverters)
+	liveHandler.SetConverters(metrics.LiveConverters)
 
 	// Set recipe repository in diary handler (to avoid circular dependency)
 	recipeAdapter := recipe.NewDiaryRecipeAdapter(recipeRepo, recipeService)
 	diaryHandler.SetRecipeRepo(recipeAdapter)
+	diaryHandler.SetMetricsRepo(metricsRepo)
+	diaryHandler.SetHydrationRepo(hydrationRepo)
+	diaryHandler.SetFastingRepo(fastingRepo)
+	diaryHandler.SetExerciseRepo(exerciseRepo)
+	diaryHandler.SetDayObserver(streakTracker)
+	diaryHandler.SetWebhookPublisher(webhookPublisher)
+	diaryHandler.SetLiveHub(liveHub)
+	goalHandler.SetMetricsRepo(metricsRepo)
+	goalHandler.SetWebhookPublisher(webhookPublisher)
+	goalHandler.SetDayObserver(streakTracker)
+	exerciseHandler.SetDayObserver(streakTracker)
+	metricsHandler.SetUserRepo(userRepo)
+	metricsHandler.SetWebhookPublisher(webhookPublisher)
+	metricsHandler.SetLiveHub(liveHub)
 
 	// Setup routes
 	mux := http.NewServeMux()
 
 	// Register all routes
 	auth.RegisterRoutes(mux, authHandler)
+	apitoken.RegisterRoutes(mux, tokenHandler)
+	account.RegisterRoutes(mux, accountHandler)
 	barcode.RegisterRoutes(mux, barcodeHandler)
 	food.RegisterRoutes(mux, foodHandler)
 	recipe.RegisterRoutes(mux, recipeHandler)
 	goal.RegisterRoutes(mux, goalHandler)
 	diary.RegisterRoutes(mux, diaryHandler)
 	metrics.RegisterRoutes(mux, metricsHandler)
+	hydration.RegisterRoutes(mux, hydrationHandler)
+	fasting.RegisterRoutes(mux, fastingHandler)
+	exercise.RegisterRoutes(mux, exerciseHandler)
+	coaching.RegisterRoutes(mux, coachingHandler)
+	insights.RegisterRoutes(mux, insightsHandler)
+	streaks.RegisterRoutes(mux, streaksHandler)
+	webhook.RegisterRoutes(mux, webhookHandler)
+	live.RegisterRoutes(mux, liveHandler)
+	i18n.RegisterRoutes(mux)
 
 	// Health check endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -130,6 +285,14 @@ func main() {
 	log.Println("  POST   /auth/login             - Login")
 	log.Println("  GET    /auth/me                - Get current user (protected)")
 	log.Println("  PUT    /users/profile          - Update profile (protected)")
+	log.Println("  POST   /auth/tokens            - Create personal access token (protected)")
+	log.Println("  GET    /auth/tokens            - List personal access tokens (protected)")
+	log.Println("  DELETE /auth/tokens/{id}       - Revoke personal access token (protected)")
+	log.Println("  GET    /users/export           - Download all my data as zip (protected)")
+	log.Println("  DELETE /users/me               - Request account deletion (protected)")
+	log.Println("  GET    /users/me/deletion      - Account deletion status (protected)")
+	log.Println("  POST   /users/me/deletion/confirm - Confirm account deletion (protected)")
+	log.Println("  POST   /users/me/deletion/cancel  - Cancel account deletion (protected)")
 	log.Println("-------------------------------------------")
 	log.Println("FOODS:")
 	log.Println("  POST   /foods                  - Create food")
@@ -159,6 +322,10 @@ func main() {
 	log.Println("  GET    /goals                  - Get active goal (protected)")
 	log.Println("  GET    /goals/all              - Get all goals (protected)")
 	log.Println("  POST   /goals/recommended      - Calculate recommended goals (protected)")
+	log.Println("  GET    /goals/timeline         - Protocol phase timeline (protected)")
+	log.Println("  GET    /goals/diets            - Diet models and protocols (protected)")
+	log.Println("  POST   /goals/diets            - Add a diet definition (coach)")
+	log.Println("  DELETE /goals/diets/{model}    - Remove a diet definition (coach)")
 	log.Println("  PUT    /goals/{id}             - Update goal (protected)")
 	log.Println("  DELETE /goals/{id}             - Delete goal (protected)")
 	log.Println("-------------------------------------------")
@@ -168,22 +335,96 @@ func main() {
 	log.Println("                                 - Log from Open Food Facts product (protected)")
 	log.Println("  GET    /diary/entries?date=... - Get entries by date (protected)")
 	log.Println("  GET    /diary/summary/{date}   - Get daily summary (protected)")
+	log.Println("  GET    /diary/summary?from=&to=&group_by=day|week|month - Range summary (protected)")
+	log.Println("  GET/PUT/DELETE /diary/days/{date} - Tag a training, rest or refeed day (protected)")
 	log.Println("  PUT    /diary/entries/{id}     - Update entry (protected)")
 	log.Println("  DELETE /diary/entries/{id}     - Delete entry (protected)")
+	log.Println("  POST   /diary/import?mode=preview|commit - Import MyFitnessPal/Cronometer CSV (protected)")
+	log.Println("  GET    /diary/export?from=&to=&format=csv|json|pdf - Export diary with daily totals (protected)")
 	log.Println("-------------------------------------------")
 	log.Println("BODY METRICS:")
 	log.Println("  POST   /metrics                - Log body metrics (protected)")
 	log.Println("  GET    /metrics                - Get all metrics (protected)")
 	log.Println("  GET    /metrics/latest         - Get latest metrics (protected)")
-	log.Println("  GET    /metrics/trends?period=7d|30d|90d - Get trends (protected)")
+	log.Println("  GET    /metrics/trends?period=<N>d&target_weight=X - Get trends and forecast (protected)")
 	log.Println("  DELETE /metrics/{id}           - Delete metric (protected)")
+	log.Println("  GET/POST /metrics/measurements - Body fat, circumferences, resting heart rate (protected)")
+	log.Println("  DELETE /metrics/measurements/{id} - Delete measurements (protected)")
+	log.Println("  POST   /metrics/import?mode=preview|commit - Import Apple Health/Google Fit/Withings/Garmin/CSV (protected)")
+	log.Println("-------------------------------------------")
+	log.Println("HYDRATION:")
+	log.Println("  POST   /water                  - Log a drink or quick-add a preset (protected)")
+	log.Println("  GET    /water?date=...         - Drinks logged on a day (protected)")
+	log.Println("  DELETE /water/{id}             - Delete drink (protected)")
+	log.Println("  GET/POST /water/presets        - Quick-add presets (protected)")
+	log.Println("  PUT/DELETE /water/presets/{id} - Update or delete preset (protected)")
+	log.Println("-------------------------------------------")
+	log.Println("FASTING:")
+	log.Println("  GET/PUT /fasting/protocol      - Fasting protocol: 16:8, 5:2 or omad (protected)")
+	log.Println("  POST   /fasting/start          - Start a fast (protected)")
+	log.Println("  POST   /fasting/stop           - Stop the fast in progress (protected)")
+	log.Println("  GET    /fasting?from=&to=      - Current fast, history and stats (protected)")
+	log.Println("  DELETE /fasting/{id}           - Delete fast (protected)")
+	log.Println("-------------------------------------------")
+	log.Println("EXERCISE:")
+	log.Println("  GET    /exercise/activities    - Activities and their MET values (protected)")
+	log.Println("  POST   /exercise               - Log an exercise, kcal from MET or entered (protected)")
+	log.Println("  GET    /exercise?date=...      - Exercises logged on a day (protected)")
+	log.Println("  DELETE /exercise/{id}          - Delete exercise (protected)")
+	log.Println("-------------------------------------------")
+	log.Println("INSIGHTS:")
+	log.Println("  GET    /insights?from=&to=     - Intake vs weight change, tag share, weekday adherence (protected)")
+	log.Println("-------------------------------------------")
+	log.Println("STREAKS & ACHIEVEMENTS:")
+	log.Println("  GET    /streaks                - Logging, calorie goal and protein goal streaks (protected)")
+	log.Println("  GET    /achievements           - Badges with progress (protected)")
+	log.Println("-------------------------------------------")
+	log.Println("LIVE UPDATES:")
+	log.Println("  GET    /events                 - Server-Sent Events stream of diary and metric changes (protected)")
+	log.Println("-------------------------------------------")
+	log.Println("WEBHOOKS:")
+	log.Println("  GET    /webhooks/events        - Available event types (protected)")
+	log.Println("  POST   /webhooks               - Subscribe an endpoint, returns its secret (protected)")
+	log.Println("  GET    /webhooks               - List my endpoints (protected)")
+	log.Println("  GET    /webhooks/{id}          - Get endpoint (protected)")
+	log.Println("  PUT    /webhooks/{id}          - Update endpoint (protected)")
+	log.Println("  DELETE /webhooks/{id}          - Delete endpoint (protected)")
+	log.Println("  GET    /webhooks/{id}/deliveries - Delivery log (protected, query: status, limit)")
+	log.Println("  POST   /webhooks/{id}/deliveries/{did}/replay - Send an event again (protected)")
+	log.Println("-------------------------------------------")
+	log.Println("COACHING:")
+	log.Println("  POST   /coaching/coaches       - Invite a coach by email (protected)")
+	log.Println("  GET    /coaching/coaches       - List my coaches (protected)")
+	log.Println("  PUT    /coaching/coaches/{id}  - Update coach permissions (protected)")
+	log.Println("  DELETE /coaching/coaches/{id}  - Revoke coach access (protected)")
+	log.Println("  GET    /coaching/invitations   - Pending invitations (coach)")
+	log.Println("  POST   /coaching/invitations/{id}/accept|decline - Answer invitation (coach)")
+	log.Println("  GET    /coaching/clients       - List active clients (coach)")
+	log.Println("  DELETE /coaching/clients/{id}  - Drop a client (coach)")
+	log.Println("  GET    /coaching/dashboard?week_start=YYYY-MM-DD - Weekly client adherence (coach)")
+	log.Println("  Header X-Client-ID: {id} on /diary, /goals, /metrics - Act on a client's data (coach)")
+	log.Println("-------------------------------------------")
+	log.Println("ADMIN (admin role required):")
+	log.Println("  GET    /admin/users            - List users (query: q, page, page_size)")
+	log.Println("  PUT    /admin/users/{id}/role  - Change user role (user, coach, admin)")
+	log.Println("  POST   /admin/users/{id}/disable - Disable account")
+	log.Println("  POST   /admin/users/{id}/enable  - Re-enable account")
+	log.Println("  GET    /admin/recipes          - List global recipes")
+	log.Println("  POST   /admin/recipes          - Create global recipe")
+	log.Println("  POST   /admin/general-foods    - Create general food")
+	log.Println("  PUT    /admin/general-foods/{id} - Update general food")
+	log.Println("  DELETE /admin/general-foods/{id} - Delete general food")
+	log.Println("  GET/POST /admin/badges         - List, add or replace badges")
+	log.Println("  DELETE /admin/badges/{code}    - Remove a custom badge")
 	log.Println("-------------------------------------------")
 	log.Println("HEALTH:")
 	log.Println("  GET    /health                 - Health check")
+	log.Println("  GET    /i18n                   - Negotiated locale and labels (Accept-Language or ?lang=)")
 	log.Println("===========================================")
 
-	// Wrap the mux with logging middleware
-	loggedHandler := middleware.LoggingMiddleware(mux)
+	// Wrap the mux with logging middleware, and locale negotiation so that
+	// every response helper sees the request locale
+	loggedHandler := middleware.LoggingMiddleware(i18n.Middleware(mux))
 
 	if err := http.ListenAndServe(":"+port, loggedHandler); err != nil {
 		log.Fatal("Server failed to start:", err)
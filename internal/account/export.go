@@ -0,0 +1,211 @@
+package account
+
+import (
+	"archive/zip"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"ultra-bis/internal/user"
+)
+
+// WriteArchive writes the export as a zip with a JSON and a CSV file per dataset
+func WriteArchive(w io.Writer, data *ExportData) error {
+	zw := zip.NewWriter(w)
+
+	// The profile is a single object in JSON and a single row in CSV
+	if err := writeJSONFile(zw, "profile.json", data.Profile); err != nil {
+		return err
+	}
+	if err := writeCSVFile(zw, "profile.csv", []user.User{data.Profile}); err != nil {
+		return err
+	}
+
+	datasets := []struct {
+		name    string
+		records interface{}
+	}{
+		{"foods", data.Foods},
+		{"recipes", data.Recipes},
+		{"goals", data.Goals},
+		{"diary_entries", data.DiaryEntries},
+		{"day_types", data.DayTypes},
+		{"custom_diets", data.CustomDiets},
+		{"body_metrics", data.BodyMetrics},
+		{"body_measurements", data.BodyMeasurements},
+		{"water_logs", data.WaterLogs},
+		{"drink_presets", data.DrinkPresets},
+		{"fasting_sessions", data.FastingSessions},
+		{"exercise_entries", data.ExerciseEntries},
+		{"achievements", data.Achievements},
+		{"coach_links", data.CoachLinks},
+		{"api_tokens", data.APITokens},
+		{"webhook_endpoints", data.WebhookEndpoints},
+	}
+
+	for _, dataset := range datasets {
+		if err := writeJSONFile(zw, dataset.name+".json", dataset.records); err != nil {
+			return err
+		}
+		if err := writeCSVFile(zw, dataset.name+".csv", dataset.records); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish export archive: %w", err)
+	}
+	return nil
+}
+
+// writeJSONFile adds an indented JSON file to the archive
+func writeJSONFile(zw *zip.Writer, name string, records interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(records); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeCSVFile adds a CSV file to the archive, one row per record
+// Columns follow the JSON field names of the record type
+func writeCSVFile(zw *zip.Writer, name string, records interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+
+	rows := reflect.ValueOf(records)
+	writer := csv.NewWriter(f)
+
+	columns := csvColumns(rows.Type().Elem())
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+
+		record := make([]string, len(columns))
+		for j, column := range columns {
+			record[j] = formatCSVValue(row.FieldByIndex(column.index))
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// csvColumn maps a CSV column to a struct field
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+// csvColumns lists the JSON-visible fields of a struct type, flattening embedded structs
+func csvColumns(t reflect.Type) []csvColumn {
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && tag == "" {
+			for _, nested := range csvColumns(field.Type) {
+				nested.index = append([]int{i}, nested.index...)
+				columns = append(columns, nested)
+			}
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, csvColumn{name: name, index: []int{i}})
+	}
+	return columns
+}
+
+// formatCSVValue renders a field as a CSV cell
+// Times use RFC 3339, nil pointers are empty and nested values are JSON encoded
+func formatCSVValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil || value == nil {
+			return ""
+		}
+		switch typed := value.(type) {
+		case time.Time:
+			return typed.Format(time.RFC3339)
+		case []byte:
+			return string(typed)
+		default:
+			return fmt.Sprint(typed)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Slice, reflect.Map, reflect.Struct:
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0 {
+			return ""
+		}
+		encoded, err := json.Marshal(v.Interface())
+		if err != nil {
+			return ""
+		}
+		return string(encoded)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
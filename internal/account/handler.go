@@ -0,0 +1,177 @@
+package account
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"ultra-bis/internal/httputil"
+)
+
+// Handler handles data export and account deletion requests
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new account handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// ExportData handles GET /users/export
+// Returns a zip with a JSON and a CSV file for each kind of data
+func (h *Handler) ExportData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	data, err := h.service.Export(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Build the archive in memory so a failure can still be reported as JSON
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, data); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("ultra-export-%s.zip", data.ExportedAt.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// RequestDeletion handles DELETE /users/me
+// The deletion only takes effect once confirmed and after the grace period
+func (h *Handler) RequestDeletion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req RequestDeletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	response, err := h.service.RequestDeletion(userID, req)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusAccepted, response)
+}
+
+// GetDeletion handles GET /users/me/deletion
+func (h *Handler) GetDeletion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	response, err := h.service.GetDeletion(userID)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, response)
+}
+
+// ConfirmDeletion handles POST /users/me/deletion/confirm
+func (h *Handler) ConfirmDeletion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req ConfirmDeletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.ConfirmationToken == "" {
+		httputil.WriteError(w, http.StatusBadRequest, "confirmation_token is required")
+		return
+	}
+
+	response, err := h.service.ConfirmDeletion(userID, req.ConfirmationToken)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, response)
+}
+
+// CancelDeletion handles POST /users/me/deletion/cancel
+func (h *Handler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	response, err := h.service.CancelDeletion(userID)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, response)
+}
+
+// handleError maps service errors to HTTP responses
+func (h *Handler) handleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidMode):
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrInvalidPassword), errors.Is(err, ErrInvalidConfirmation):
+		httputil.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrNoPendingDeletion):
+		httputil.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrDeletionAlreadyScheduled):
+		httputil.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,98 @@
+package account
+
+import (
+	"time"
+
+	"ultra-bis/internal/apitoken"
+	"ultra-bis/internal/coaching"
+	"ultra-bis/internal/diary"
+	"ultra-bis/internal/exercise"
+	"ultra-bis/internal/fasting"
+	"ultra-bis/internal/food"
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/hydration"
+	"ultra-bis/internal/metrics"
+	"ultra-bis/internal/recipe"
+	"ultra-bis/internal/streaks"
+	"ultra-bis/internal/user"
+	"ultra-bis/internal/webhook"
+)
+
+// DeletionMode controls what happens to a user's data once the grace period ends
+type DeletionMode string
+
+const (
+	ModeDelete    DeletionMode = "delete"    // Hard-delete every row belonging to the user
+	ModeAnonymize DeletionMode = "anonymize" // Strip personal data, keep anonymous nutrition history
+)
+
+// ValidateMode checks if mode is a known deletion mode
+func ValidateMode(mode DeletionMode) bool {
+	return mode == ModeDelete || mode == ModeAnonymize
+}
+
+// DeletionStatus represents the state of an account deletion request
+type DeletionStatus string
+
+const (
+	StatusAwaitingConfirmation DeletionStatus = "awaiting_confirmation" // Requested, confirmation token issued
+	StatusScheduled            DeletionStatus = "scheduled"             // Confirmed, waiting for the grace period to end
+	StatusCancelled            DeletionStatus = "cancelled"             // Cancelled by the user
+	StatusCompleted            DeletionStatus = "completed"             // Data deleted or anonymised
+)
+
+// DeletionRequest tracks a user's request to delete their account
+// Rows are kept after completion as a record that the deletion happened
+type DeletionRequest struct {
+	ID                uint           `json:"id" gorm:"primarykey"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	UserID            uint           `json:"user_id" gorm:"not null;index"`
+	Mode              DeletionMode   `json:"mode" gorm:"type:varchar(20);not null"`
+	Status            DeletionStatus `json:"status" gorm:"type:varchar(30);not null;index"`
+	ConfirmationHash  string         `json:"-" gorm:"type:char(64)"`
+	ConfirmationUntil *time.Time     `json:"confirmation_expires_at,omitempty"`
+	ScheduledFor      *time.Time     `json:"scheduled_for,omitempty" gorm:"index"`
+	ConfirmedAt       *time.Time     `json:"confirmed_at,omitempty"`
+	CompletedAt       *time.Time     `json:"completed_at,omitempty"`
+}
+
+// RequestDeletionRequest represents the request body for DELETE /users/me
+type RequestDeletionRequest struct {
+	Password string       `json:"password"`
+	Mode     DeletionMode `json:"mode"` // "delete" (default) or "anonymize"
+}
+
+// ConfirmDeletionRequest represents the request body to confirm a deletion
+type ConfirmDeletionRequest struct {
+	ConfirmationToken string `json:"confirmation_token"`
+}
+
+// DeletionResponse represents a deletion request in API responses
+type DeletionResponse struct {
+	DeletionRequest
+	ConfirmationToken string `json:"confirmation_token,omitempty"` // Only returned when the deletion is requested
+	GracePeriodDays   int    `json:"grace_period_days"`
+}
+
+// ExportData holds everything exported for a user
+type ExportData struct {
+	ExportedAt       time.Time                 `json:"exported_at"`
+	Profile          user.User                 `json:"profile"`
+	Foods            []food.Food               `json:"foods"` // Foods referenced by the user's diary and recipes
+	Recipes          []recipe.Recipe           `json:"recipes"`
+	Goals            []goal.NutritionGoal      `json:"goals"`
+	DiaryEntries     []diary.DiaryEntry        `json:"diary_entries"`
+	DayTypes         []goal.DayTypeAssignment  `json:"day_types"`
+	CustomDiets      []goal.CustomDiet         `json:"custom_diets"` // Diet definitions added as a coach
+	BodyMetrics      []metrics.BodyMetric      `json:"body_metrics"`
+	BodyMeasurements []metrics.BodyMeasurement `json:"body_measurements"`
+	WaterLogs        []hydration.WaterLog      `json:"water_logs"`
+	DrinkPresets     []hydration.DrinkPreset   `json:"drink_presets"`
+	FastingSessions  []fasting.FastingSession  `json:"fasting_sessions"`
+	ExerciseEntries  []exercise.ExerciseEntry  `json:"exercise_entries"`
+	Achievements     []streaks.Achievement     `json:"achievements"`
+	CoachLinks       []coaching.CoachLink      `json:"coach_links"`
+	APITokens        []apitoken.TokenResponse  `json:"api_tokens"`
+	WebhookEndpoints []webhook.Endpoint        `json:"webhook_endpoints"` // Without their signing secrets
+}
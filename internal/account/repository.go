@@ -0,0 +1,255 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"ultra-bis/internal/apitoken"
+	"ultra-bis/internal/diary"
+	"ultra-bis/internal/recipe"
+	"ultra-bis/internal/user"
+)
+
+// userTable describes a table holding rows that belong to a user
+type userTable struct {
+	Name     string // Table name
+	Column   string // Column referencing users.id
+	Personal bool   // Also deleted when anonymising (identifying or body data)
+}
+
+// userTables lists every table with user-owned rows; new per-user tables must be added here
+// so that account deletion reaches them. Recipes are handled separately (nullable owner).
+var userTables = []userTable{
+	{Name: "diary_entries", Column: "user_id"},
+	{Name: "nutrition_goals", Column: "user_id"},
+	{Name: "day_type_assignments", Column: "user_id"},
+	{Name: "water_logs", Column: "user_id"},
+	{Name: "drink_presets", Column: "user_id"},
+	{Name: "fasting_sessions", Column: "user_id"},
+	{Name: "exercise_entries", Column: "user_id"},
+	{Name: "custom_diets", Column: "coach_id"},
+	{Name: "day_statuses", Column: "user_id"},
+	{Name: "streaks", Column: "user_id"},
+	{Name: "achievements", Column: "user_id"},
+	{Name: "body_metrics", Column: "user_id", Personal: true},
+	{Name: "body_measurements", Column: "user_id", Personal: true},
+	{Name: "coach_links", Column: "client_id", Personal: true},
+	{Name: "coach_links", Column: "coach_id", Personal: true},
+	{Name: "personal_access_tokens", Column: "user_id", Personal: true},
+	{Name: "webhook_deliveries", Column: "user_id", Personal: true},
+	{Name: "webhook_endpoints", Column: "user_id", Personal: true},
+	{Name: "webhook_events", Column: "user_id", Personal: true},
+}
+
+// Repository handles database operations for data export and account deletion
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new account repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// LoadExport gathers all data belonging to a user
+func (r *Repository) LoadExport(userID uint) (*ExportData, error) {
+	data := &ExportData{ExportedAt: time.Now()}
+
+	if err := r.db.First(&data.Profile, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := r.db.Where("user_id = ?", userID).Preload("Ingredients").Order("id").Find(&data.Recipes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get recipes: %w", err)
+	}
+	if err := r.db.Where("user_id = ?", userID).Order("start_date, id").Find(&data.Goals).Error; err != nil {
+		return nil, fmt.Errorf("failed to get goals: %w", err)
+	}
+	if err := r.db.Where("user_id = ?", userID).Order("date, id").Find(&data.DiaryEntries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get diary entries: %w", err)
+	}
+	if err := r.db.Where("user_id = ?", userID).Order("date").Find(&data.DayTypes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get day types: %w", err)
+	}
+	if err := r.db.Where("coach_id = ?", userID).Order("id").Find(&data.CustomDiets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get custom diets: %w", err)
+	}
+	if err := r.db.Where("user_id = ?", userID).Order("date, id").Find(&data.BodyMetrics).Error; err != nil {
+		return nil, fmt.Errorf("failed to get body metrics: %w", err)
+	}
+	if err := r.db.Where("user_id = ?", userID).Order("date, id").Find(&data.BodyMeasurements).Error; err != nil {
+		return nil, fmt.Errorf("failed to get body measurements: %w", err)
+	}
+	if err := r.db.Where("user_id = ?", userID).Order("logged_at, id").Find(&data.WaterLogs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get water logs: %w", err)
+	}
+	if err := r.db.Where("user_id = ?", userID).Order("id").Find(&data.DrinkPresets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get drink presets: %w", err)
+	}
+	if err := r.db.Where("user_id = ?", userID).Order("started_at, id").Find(&data.FastingSessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get fasting sessions: %w", err)
+	}
+	if err := r.db.Where("user_id = ?", userID).Order("performed_at, id").Find(&data.ExerciseEntries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get exercise entries: %w", err)
+	}
+	if err := r.db.Where("user_id = ?", userID).Order("earned_on, id").Find(&data.Achievements).Error; err != nil {
+		return nil, fmt.Errorf("failed to get achievements: %w", err)
+	}
+	if err := r.db.Where("client_id = ? OR coach_id = ?", userID, userID).Order("id").Find(&data.CoachLinks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get coach links: %w", err)
+	}
+
+	if err := r.db.Where("user_id = ?", userID).Order("id").Find(&data.WebhookEndpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+
+	var tokens []apitoken.PersonalAccessToken
+	if err := r.db.Where("user_id = ?", userID).Order("id").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to get api tokens: %w", err)
+	}
+	for i := range tokens {
+		data.APITokens = append(data.APITokens, tokens[i].ToResponse())
+	}
+
+	foodIDs := referencedFoodIDs(data.DiaryEntries, data.Recipes)
+	if len(foodIDs) > 0 {
+		if err := r.db.Where("id IN ?", foodIDs).Order("id").Find(&data.Foods).Error; err != nil {
+			return nil, fmt.Errorf("failed to get foods: %w", err)
+		}
+	}
+
+	return data, nil
+}
+
+// GetLatestRequest retrieves the most recent deletion request of a user, or nil if none
+func (r *Repository) GetLatestRequest(userID uint) (*DeletionRequest, error) {
+	var req DeletionRequest
+	result := r.db.Where("user_id = ?", userID).Order("id DESC").First(&req)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get deletion request: %w", result.Error)
+	}
+
+	return &req, nil
+}
+
+// SaveRequest creates or updates a deletion request
+func (r *Repository) SaveRequest(req *DeletionRequest) error {
+	if err := r.db.Save(req).Error; err != nil {
+		return fmt.Errorf("failed to save deletion request: %w", err)
+	}
+	return nil
+}
+
+// ListDue retrieves scheduled deletion requests whose grace period has ended
+func (r *Repository) ListDue(now time.Time) ([]DeletionRequest, error) {
+	var requests []DeletionRequest
+	result := r.db.Where("status = ? AND scheduled_for <= ?", StatusScheduled, now).
+		Order("scheduled_for").
+		Find(&requests)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list due deletion requests: %w", result.Error)
+	}
+	return requests, nil
+}
+
+// Purge deletes or anonymises a user's data and marks the request completed, in one transaction
+// Rows are removed with real DELETE statements, not soft deletes
+func (r *Repository) Purge(req *DeletionRequest, now time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range userTables {
+			if req.Mode == ModeAnonymize && !table.Personal {
+				continue
+			}
+			if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", table.Name, table.Column), req.UserID).Error; err != nil {
+				return fmt.Errorf("failed to delete %s: %w", table.Name, err)
+			}
+		}
+
+		if req.Mode == ModeAnonymize {
+			if err := anonymizeUser(tx, req.UserID); err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Exec("DELETE FROM recipe_ingredients WHERE recipe_id IN (SELECT id FROM recipes WHERE user_id = ?)", req.UserID).Error; err != nil {
+				return fmt.Errorf("failed to delete recipe ingredients: %w", err)
+			}
+			if err := tx.Exec("DELETE FROM recipes WHERE user_id = ?", req.UserID).Error; err != nil {
+				return fmt.Errorf("failed to delete recipes: %w", err)
+			}
+			if err := tx.Unscoped().Delete(&user.User{}, req.UserID).Error; err != nil {
+				return fmt.Errorf("failed to delete user: %w", err)
+			}
+		}
+
+		req.Status = StatusCompleted
+		req.CompletedAt = &now
+		if err := tx.Save(req).Error; err != nil {
+			return fmt.Errorf("failed to complete deletion request: %w", err)
+		}
+		return nil
+	})
+}
+
+// anonymizeUser strips identifying data from a user row and clears free-text diary notes
+// The row is kept (disabled, with an unusable password) so anonymous history still has an owner
+func anonymizeUser(tx *gorm.DB, userID uint) error {
+	updates := map[string]interface{}{
+		"email":         fmt.Sprintf("deleted-user-%d@anonymized.invalid", userID),
+		"password_hash": "!",
+		"name":          "",
+		"age":           0,
+		"gender":        "",
+		"height":        0,
+		"weight":        0,
+		"body_fat":      0,
+		"disabled":      true,
+		"disabled_at":   time.Now(),
+	}
+	if err := tx.Model(&user.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+
+	if err := tx.Model(&diary.DiaryEntry{}).Unscoped().Where("user_id = ?", userID).Update("notes", "").Error; err != nil {
+		return fmt.Errorf("failed to clear diary notes: %w", err)
+	}
+
+	return nil
+}
+
+// referencedFoodIDs collects the IDs of foods used by diary entries and recipes
+func referencedFoodIDs(entries []diary.DiaryEntry, recipes []recipe.Recipe) []uint {
+	seen := make(map[uint]bool)
+	var ids []uint
+	add := func(id uint) {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.FoodID != nil {
+			add(*entry.FoodID)
+		}
+		for _, ingredient := range entry.CustomIngredients {
+			add(ingredient.FoodID)
+		}
+	}
+	for _, r := range recipes {
+		for _, ingredient := range r.Ingredients {
+			add(ingredient.FoodID)
+		}
+	}
+
+	return ids
+}
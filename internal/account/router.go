@@ -0,0 +1,16 @@
+package account
+
+import (
+	"net/http"
+
+	"ultra-bis/internal/auth"
+)
+
+// RegisterRoutes registers data export and account deletion routes to the provided mux
+func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("/users/export", auth.JWTMiddleware(handler.ExportData))
+	mux.HandleFunc("/users/me", auth.JWTMiddleware(handler.RequestDeletion))
+	mux.HandleFunc("/users/me/deletion", auth.JWTMiddleware(handler.GetDeletion))
+	mux.HandleFunc("/users/me/deletion/confirm", auth.JWTMiddleware(handler.ConfirmDeletion))
+	mux.HandleFunc("/users/me/deletion/cancel", auth.JWTMiddleware(handler.CancelDeletion))
+}
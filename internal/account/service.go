@@ -0,0 +1,226 @@
+package account
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ultra-bis/internal/user"
+)
+
+// ConfirmationWindow is how long a deletion confirmation token stays valid
+const ConfirmationWindow = 15 * time.Minute
+
+var (
+	// ErrInvalidPassword is returned when the password given to request a deletion is wrong
+	ErrInvalidPassword = errors.New("invalid password")
+
+	// ErrInvalidMode is returned for an unknown deletion mode
+	ErrInvalidMode = errors.New("mode must be 'delete' or 'anonymize'")
+
+	// ErrNoPendingDeletion is returned when there is no deletion to confirm or cancel
+	ErrNoPendingDeletion = errors.New("no pending account deletion")
+
+	// ErrDeletionAlreadyScheduled is returned when a deletion is requested while one is scheduled
+	ErrDeletionAlreadyScheduled = errors.New("account deletion is already scheduled")
+
+	// ErrInvalidConfirmation is returned for a wrong or expired confirmation token
+	ErrInvalidConfirmation = errors.New("invalid or expired confirmation token")
+)
+
+// Service handles data export and account deletion
+type Service struct {
+	repo        *Repository
+	userRepo    *user.Repository
+	gracePeriod int // days between confirmation and deletion
+}
+
+// NewService creates a new account service
+// gracePeriodDays is how long a confirmed deletion can still be cancelled
+func NewService(repo *Repository, userRepo *user.Repository, gracePeriodDays int) *Service {
+	return &Service{
+		repo:        repo,
+		userRepo:    userRepo,
+		gracePeriod: gracePeriodDays,
+	}
+}
+
+// Export gathers all data belonging to a user
+func (s *Service) Export(userID uint) (*ExportData, error) {
+	return s.repo.LoadExport(userID)
+}
+
+// RequestDeletion starts an account deletion after re-checking the password
+// It returns a confirmation token that must be sent back within ConfirmationWindow
+func (s *Service) RequestDeletion(userID uint, req RequestDeletionRequest) (*DeletionResponse, error) {
+	if req.Mode == "" {
+		req.Mode = ModeDelete
+	}
+	if !ValidateMode(req.Mode) {
+		return nil, ErrInvalidMode
+	}
+
+	u, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.CheckPassword(req.Password) {
+		return nil, ErrInvalidPassword
+	}
+
+	latest, err := s.repo.GetLatestRequest(userID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.Status == StatusScheduled {
+		return nil, ErrDeletionAlreadyScheduled
+	}
+
+	token, err := generateConfirmationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// Reuse an unconfirmed request rather than piling them up
+	deletion := latest
+	if deletion == nil || deletion.Status != StatusAwaitingConfirmation {
+		deletion = &DeletionRequest{UserID: userID}
+	}
+	until := time.Now().Add(ConfirmationWindow)
+	deletion.Mode = req.Mode
+	deletion.Status = StatusAwaitingConfirmation
+	deletion.ConfirmationHash = hashConfirmationToken(token)
+	deletion.ConfirmationUntil = &until
+
+	if err := s.repo.SaveRequest(deletion); err != nil {
+		return nil, err
+	}
+
+	return &DeletionResponse{
+		DeletionRequest:   *deletion,
+		ConfirmationToken: token,
+		GracePeriodDays:   s.gracePeriod,
+	}, nil
+}
+
+// ConfirmDeletion schedules the deletion at the end of the grace period
+func (s *Service) ConfirmDeletion(userID uint, token string) (*DeletionResponse, error) {
+	deletion, err := s.repo.GetLatestRequest(userID)
+	if err != nil {
+		return nil, err
+	}
+	if deletion == nil || deletion.Status != StatusAwaitingConfirmation {
+		return nil, ErrNoPendingDeletion
+	}
+
+	now := time.Now()
+	if deletion.ConfirmationUntil == nil || now.After(*deletion.ConfirmationUntil) ||
+		hashConfirmationToken(token) != deletion.ConfirmationHash {
+		return nil, ErrInvalidConfirmation
+	}
+
+	scheduledFor := now.AddDate(0, 0, s.gracePeriod)
+	deletion.Status = StatusScheduled
+	deletion.ConfirmedAt = &now
+	deletion.ScheduledFor = &scheduledFor
+	deletion.ConfirmationHash = ""
+	deletion.ConfirmationUntil = nil
+
+	if err := s.repo.SaveRequest(deletion); err != nil {
+		return nil, err
+	}
+
+	return &DeletionResponse{DeletionRequest: *deletion, GracePeriodDays: s.gracePeriod}, nil
+}
+
+// CancelDeletion cancels a deletion that is awaiting confirmation or still in its grace period
+func (s *Service) CancelDeletion(userID uint) (*DeletionResponse, error) {
+	deletion, err := s.repo.GetLatestRequest(userID)
+	if err != nil {
+		return nil, err
+	}
+	if deletion == nil || (deletion.Status != StatusAwaitingConfirmation && deletion.Status != StatusScheduled) {
+		return nil, ErrNoPendingDeletion
+	}
+
+	deletion.Status = StatusCancelled
+	deletion.ConfirmationHash = ""
+	deletion.ConfirmationUntil = nil
+	deletion.ScheduledFor = nil
+
+	if err := s.repo.SaveRequest(deletion); err != nil {
+		return nil, err
+	}
+
+	return &DeletionResponse{DeletionRequest: *deletion, GracePeriodDays: s.gracePeriod}, nil
+}
+
+// GetDeletion returns the user's latest deletion request
+func (s *Service) GetDeletion(userID uint) (*DeletionResponse, error) {
+	deletion, err := s.repo.GetLatestRequest(userID)
+	if err != nil {
+		return nil, err
+	}
+	if deletion == nil {
+		return nil, ErrNoPendingDeletion
+	}
+
+	return &DeletionResponse{DeletionRequest: *deletion, GracePeriodDays: s.gracePeriod}, nil
+}
+
+// PurgeDue deletes or anonymises every account whose grace period has ended
+// An account that fails is retried on the next run without holding back the others; it returns
+// the number of accounts processed and the failures joined
+func (s *Service) PurgeDue(now time.Time) (int, error) {
+	due, err := s.repo.ListDue(now)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	var errs []error
+	for i := range due {
+		if err := s.repo.Purge(&due[i], now); err != nil {
+			errs = append(errs, fmt.Errorf("failed to purge user %d: %w", due[i].UserID, err))
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(errs...)
+}
+
+// RunPurger calls PurgeDue every interval; it blocks and is meant to run in its own goroutine
+func (s *Service) RunPurger(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.PurgeDue(time.Now())
+		if err != nil {
+			log.Printf("Account purge failed: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("Purged %d account(s) after their deletion grace period", purged)
+		}
+		<-ticker.C
+	}
+}
+
+// generateConfirmationToken returns a random token for confirming a deletion
+func generateConfirmationToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashConfirmationToken returns the hex SHA-256 digest stored for a confirmation token
+func hashConfirmationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
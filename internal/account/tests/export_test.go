@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ultra-bis/internal/account"
+	"ultra-bis/internal/diary"
+	"ultra-bis/internal/metrics"
+	"ultra-bis/internal/user"
+)
+
+func TestWriteArchive(t *testing.T) {
+	date := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	foodID := uint(3)
+	data := &account.ExportData{
+		ExportedAt: date,
+		Profile:    user.User{ID: 1, Email: "user@example.com", Name: "Test User", PasswordHash: "secret-hash"},
+		DiaryEntries: []diary.DiaryEntry{
+			{ID: 10, UserID: 1, FoodID: &foodID, Date: date, MealType: diary.Lunch, QuantityGrams: 150, Calories: 320.5, Notes: "with sauce, extra"},
+		},
+		BodyMetrics: []metrics.BodyMetric{
+			{ID: 20, UserID: 1, Date: date, Weight: 80.4},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, account.WriteArchive(&buf, data))
+
+	files := readZip(t, buf.Bytes())
+
+	for _, name := range []string{"profile", "foods", "recipes", "goals", "diary_entries", "body_metrics", "body_measurements", "water_logs", "drink_presets", "fasting_sessions", "exercise_entries", "achievements", "coach_links", "api_tokens", "webhook_endpoints"} {
+		assert.Contains(t, files, name+".json")
+		assert.Contains(t, files, name+".csv")
+	}
+
+	// Profile JSON is a single object without the password hash
+	var profile map[string]interface{}
+	require.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+	assert.Equal(t, "user@example.com", profile["email"])
+	assert.NotContains(t, string(files["profile.json"]), "secret-hash")
+
+	// Diary CSV uses JSON field names as header and quotes free text
+	rows, err := csv.NewReader(bytes.NewReader(files["diary_entries.csv"])).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	record := csvRecord(rows[0], rows[1])
+	assert.Equal(t, "10", record["id"])
+	assert.Equal(t, "3", record["food_id"])
+	assert.Equal(t, "", record["recipe_id"])
+	assert.Equal(t, "2025-01-06T00:00:00Z", record["date"])
+	assert.Equal(t, "lunch", record["meal_type"])
+	assert.Equal(t, "320.5", record["calories"])
+	assert.Equal(t, "with sauce, extra", record["notes"])
+	assert.Equal(t, "", record["deleted_at"])
+
+	// Empty datasets still get a header row
+	rows, err = csv.NewReader(bytes.NewReader(files["goals.csv"])).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Contains(t, rows[0], "calories")
+}
+
+func TestValidateMode(t *testing.T) {
+	assert.True(t, account.ValidateMode(account.ModeDelete))
+	assert.True(t, account.ValidateMode(account.ModeAnonymize))
+	assert.False(t, account.ValidateMode("archive"))
+}
+
+// readZip returns the content of every file in a zip archive
+func readZip(t *testing.T, content []byte) map[string][]byte {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		body, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = body
+	}
+	return files
+}
+
+// csvRecord pairs a CSV header with a row
+func csvRecord(header, row []string) map[string]string {
+	record := make(map[string]string)
+	for i, name := range header {
+		record[name] = row[i]
+	}
+	return record
+}
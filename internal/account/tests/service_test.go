@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"ultra-bis/internal/account"
+	"ultra-bis/internal/apitoken"
+	"ultra-bis/internal/coaching"
+	"ultra-bis/internal/diary"
+	"ultra-bis/internal/exercise"
+	"ultra-bis/internal/fasting"
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/hydration"
+	"ultra-bis/internal/metrics"
+	"ultra-bis/internal/recipe"
+	"ultra-bis/internal/streaks"
+	"ultra-bis/internal/user"
+	"ultra-bis/internal/webhook"
+	"ultra-bis/test/testutil"
+)
+
+func setupAccountTest(t *testing.T) *gorm.DB {
+	db := testutil.SetupTestDB(t)
+	require.NoError(t, db.AutoMigrate(
+		&user.User{},
+		&recipe.Recipe{},
+		&recipe.RecipeIngredient{},
+		&goal.NutritionGoal{},
+		&goal.DayTypeAssignment{},
+		&goal.CustomDiet{},
+		&diary.DiaryEntry{},
+		&metrics.BodyMetric{},
+		&metrics.BodyMeasurement{},
+		&hydration.WaterLog{},
+		&hydration.DrinkPreset{},
+		&fasting.FastingSession{},
+		&exercise.ExerciseEntry{},
+		&coaching.CoachLink{},
+		&apitoken.PersonalAccessToken{},
+		&account.DeletionRequest{},
+		&streaks.DayStatus{},
+		&streaks.Streak{},
+		&streaks.Achievement{},
+		&webhook.Endpoint{},
+		&webhook.Event{},
+		&webhook.Delivery{},
+	))
+	return db
+}
+
+func TestPurgeDue_ContinuesAfterAFailedAccount(t *testing.T) {
+	db := setupAccountTest(t)
+
+	failing := testutil.CreateTestUser(t, db, "failing@example.com")
+	purgeable := testutil.CreateTestUser(t, db, "purgeable@example.com")
+	scheduled := time.Now().Add(-time.Hour)
+	for _, userID := range []uint{failing.ID, purgeable.ID} {
+		require.NoError(t, db.Create(&account.DeletionRequest{
+			UserID:       userID,
+			Mode:         account.ModeDelete,
+			Status:       account.StatusScheduled,
+			ScheduledFor: &scheduled,
+		}).Error)
+	}
+
+	// Deleting the first user fails, as it would on a lock timeout
+	require.NoError(t, db.Exec(`CREATE FUNCTION block_delete() RETURNS trigger AS $$
+		BEGIN RAISE EXCEPTION 'user is locked'; END $$ LANGUAGE plpgsql`).Error)
+	require.NoError(t, db.Exec(fmt.Sprintf(`CREATE TRIGGER block_delete BEFORE DELETE ON users
+		FOR EACH ROW WHEN (OLD.id = %d) EXECUTE FUNCTION block_delete()`, failing.ID)).Error)
+
+	service := account.NewService(account.NewRepository(db), user.NewRepository(db), 30)
+	purged, err := service.PurgeDue(time.Now())
+	assert.Equal(t, 1, purged)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("failed to purge user %d", failing.ID))
+
+	var remaining int64
+	require.NoError(t, db.Model(&user.User{}).Unscoped().Where("id = ?", purgeable.ID).Count(&remaining).Error)
+	assert.Zero(t, remaining)
+
+	// The failed account is still due, so the next run retries it
+	due, err := account.NewRepository(db).ListDue(time.Now())
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, failing.ID, due[0].UserID)
+}
@@ -0,0 +1,137 @@
+package apitoken
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"ultra-bis/internal/auth"
+	"ultra-bis/internal/httputil"
+)
+
+// Handler handles personal access token requests
+type Handler struct {
+	repo *Repository
+}
+
+// NewHandler creates a new token handler
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// CreateToken handles POST /auth/tokens
+// The plain token is only returned in this response
+func (h *Handler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		httputil.WriteError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if len(req.Name) > 100 {
+		httputil.WriteError(w, http.StatusBadRequest, "name must be at most 100 characters")
+		return
+	}
+
+	scopes, err := ValidateScopes(req.Scopes)
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.ExpiresInDays == 0 {
+		req.ExpiresInDays = DefaultExpiryDays
+	}
+	if req.ExpiresInDays < 1 || req.ExpiresInDays > MaxExpiryDays {
+		httputil.WriteError(w, http.StatusBadRequest, "expires_in_days must be between 1 and 365")
+		return
+	}
+
+	expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+	token, plain, err := h.repo.Create(userID, req.Name, scopes, expiresAt)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := token.ToResponse()
+	response.Token = plain
+	httputil.WriteJSON(w, http.StatusCreated, response)
+}
+
+// ListTokens handles GET /auth/tokens
+func (h *Handler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	tokens, err := h.repo.ListByUser(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]TokenResponse, len(tokens))
+	for i := range tokens {
+		responses[i] = tokens[i].ToResponse()
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, responses)
+}
+
+// RevokeToken handles DELETE /auth/tokens/{id}
+func (h *Handler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, ok := httputil.GetPathID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusBadRequest, "Token ID required")
+		return
+	}
+
+	if err := h.repo.Revoke(uint(id), userID); err != nil {
+		if err.Error() == "token not found" {
+			httputil.WriteError(w, http.StatusNotFound, "Token not found")
+			return
+		}
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// Streams opened with the token must not outlive it
+	auth.CloseSessions(userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,118 @@
+package apitoken
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"ultra-bis/internal/auth"
+	"ultra-bis/internal/httputil"
+)
+
+const (
+	DefaultExpiryDays = 90  // Expiry used when none is requested
+	MaxExpiryDays     = 365 // Longest allowed token lifetime
+)
+
+// AvailableScopes lists every scope a personal access token can be granted
+var AvailableScopes = []string{
+	auth.Scope(httputil.ResourceDiary, false),
+	auth.Scope(httputil.ResourceDiary, true),
+	auth.Scope(httputil.ResourceGoals, false),
+	auth.Scope(httputil.ResourceGoals, true),
+	auth.Scope(httputil.ResourceMetrics, false),
+	auth.Scope(httputil.ResourceMetrics, true),
+}
+
+// PersonalAccessToken is a named, scoped credential for scripts and integrations
+// Only a SHA-256 hash of the token is stored; the plain value is shown once at creation
+type PersonalAccessToken struct {
+	ID         uint           `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+	UserID     uint           `json:"user_id" gorm:"not null;index"`
+	Name       string         `json:"name" gorm:"type:varchar(100);not null"`
+	TokenHash  string         `json:"-" gorm:"type:char(64);not null;uniqueIndex"`
+	Prefix     string         `json:"prefix" gorm:"type:varchar(16);not null"` // First characters, to recognise the token
+	Scopes     string         `json:"-" gorm:"type:varchar(255);not null"`     // Space-separated scope list
+	ExpiresAt  time.Time      `json:"expires_at"`
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time     `json:"revoked_at,omitempty"`
+}
+
+// ScopeList returns the token scopes as a slice
+func (t *PersonalAccessToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+// IsExpired reports whether the token is past its expiry
+func (t *PersonalAccessToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// CreateTokenRequest represents the request to create a personal access token
+type CreateTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"` // Defaults to 90, at most 365
+}
+
+// TokenResponse represents a personal access token in API responses
+type TokenResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Expired    bool       `json:"expired"`
+	Token      string     `json:"token,omitempty"` // Plain token, only returned on creation
+}
+
+// ToResponse converts a token to its API representation
+func (t *PersonalAccessToken) ToResponse() TokenResponse {
+	return TokenResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     t.ScopeList(),
+		CreatedAt:  t.CreatedAt,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		Expired:    t.IsExpired(time.Now()),
+	}
+}
+
+// ValidateScopes checks that scopes are non-empty and known, and returns them de-duplicated
+func ValidateScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, scope := range scopes {
+		if !isAvailableScope(scope) {
+			return nil, fmt.Errorf("unknown scope: %s (available: %s)", scope, strings.Join(AvailableScopes, ", "))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+// isAvailableScope reports whether scope is one of AvailableScopes
+func isAvailableScope(scope string) bool {
+	for _, available := range AvailableScopes {
+		if scope == available {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"ultra-bis/internal/auth"
+)
+
+// lastUsedResolution limits how often last_used_at is written for a busy token
+const lastUsedResolution = time.Minute
+
+// Repository handles database operations for personal access tokens
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new token repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Create generates a new token for a user and returns it with its plain value
+func (r *Repository) Create(userID uint, name string, scopes []string, expiresAt time.Time) (*PersonalAccessToken, string, error) {
+	plain, err := GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	token := &PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: HashToken(plain),
+		Prefix:    plain[:len(auth.PersonalTokenPrefix)+6],
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: expiresAt,
+	}
+
+	if err := r.db.Create(token).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create token: %w", err)
+	}
+
+	return token, plain, nil
+}
+
+// ListByUser retrieves all non-revoked tokens of a user, newest first
+func (r *Repository) ListByUser(userID uint) ([]PersonalAccessToken, error) {
+	var tokens []PersonalAccessToken
+	result := r.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&tokens)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", result.Error)
+	}
+	return tokens, nil
+}
+
+// Revoke revokes a user's token so it is rejected from now on
+func (r *Repository) Revoke(id, userID uint) error {
+	result := r.db.Model(&PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("token not found")
+	}
+	return nil
+}
+
+// AuthenticateToken implements auth.PersonalTokenAuthenticator
+// It resolves a plain token to its owner and scopes and records when it was last used
+func (r *Repository) AuthenticateToken(plain string) (*auth.TokenIdentity, error) {
+	var token PersonalAccessToken
+	result := r.db.Where("token_hash = ? AND revoked_at IS NULL", HashToken(plain)).First(&token)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("token not found")
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get token: %w", result.Error)
+	}
+
+	now := time.Now()
+	if token.IsExpired(now) {
+		return nil, fmt.Errorf("token expired")
+	}
+
+	var owner struct {
+		Email string
+		Role  string
+	}
+	result = r.db.Table("users").Select("email, role").
+		Where("id = ? AND deleted_at IS NULL", token.UserID).
+		Scan(&owner)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get token owner: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("token owner not found")
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		if err := r.db.Model(&token).UpdateColumn("last_used_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to record token use: %w", err)
+		}
+	}
+
+	return &auth.TokenIdentity{
+		UserID: token.UserID,
+		Email:  owner.Email,
+		Role:   owner.Role,
+		Scopes: token.ScopeList(),
+	}, nil
+}
+
+// GenerateToken returns a new random token value ("pat_" followed by 40 hex characters)
+func GenerateToken() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return auth.PersonalTokenPrefix + hex.EncodeToString(buf), nil
+}
+
+// HashToken returns the hex SHA-256 digest under which a token is stored
+func HashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
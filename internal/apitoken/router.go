@@ -0,0 +1,31 @@
+package apitoken
+
+import (
+	"net/http"
+
+	"ultra-bis/internal/auth"
+	"ultra-bis/internal/httputil"
+)
+
+// RegisterRoutes registers all personal access token routes to the provided mux
+// Tokens are managed with a regular login token: personal access tokens have no
+// scope covering these endpoints, so a leaked token cannot mint new ones
+func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("/auth/tokens", auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handler.ListTokens(w, r)
+		case http.MethodPost:
+			handler.CreateToken(w, r)
+		default:
+			httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}))
+
+	// Pattern: /auth/tokens/{id}
+	mux.HandleFunc("/auth/tokens/", httputil.ChainMiddleware(
+		handler.RevokeToken,
+		httputil.ExtractPathID(2),
+		auth.JWTMiddleware,
+	))
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ultra-bis/internal/apitoken"
+)
+
+func TestGenerateToken(t *testing.T) {
+	first, err := apitoken.GenerateToken()
+	require.NoError(t, err)
+	second, err := apitoken.GenerateToken()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(first, "pat_"))
+	assert.Len(t, first, len("pat_")+40)
+	assert.NotEqual(t, first, second)
+}
+
+func TestHashToken(t *testing.T) {
+	hash := apitoken.HashToken("pat_example")
+
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, apitoken.HashToken("pat_example"))
+	assert.NotEqual(t, hash, apitoken.HashToken("pat_other"))
+}
+
+func TestValidateScopes(t *testing.T) {
+	scopes, err := apitoken.ValidateScopes([]string{"diary:read", "metrics:write", "diary:read"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"diary:read", "metrics:write"}, scopes)
+
+	_, err = apitoken.ValidateScopes(nil)
+	assert.Error(t, err)
+
+	_, err = apitoken.ValidateScopes([]string{"recipes:read"})
+	assert.Error(t, err)
+}
+
+func TestPersonalAccessToken_ToResponse(t *testing.T) {
+	now := time.Now()
+	token := apitoken.PersonalAccessToken{
+		ID:        4,
+		Name:      "Scale sync",
+		Prefix:    "pat_1a2b3c",
+		Scopes:    "metrics:read metrics:write",
+		ExpiresAt: now.Add(-time.Hour),
+	}
+
+	response := token.ToResponse()
+	assert.Equal(t, []string{"metrics:read", "metrics:write"}, response.Scopes)
+	assert.True(t, response.Expired)
+	assert.Empty(t, response.Token)
+
+	token.ExpiresAt = now.AddDate(0, 0, 30)
+	assert.False(t, token.ToResponse().Expired)
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/user"
+)
+
+// ListUsers handles GET /admin/users?q=&page=&page_size= (admin only)
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	page := 1
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		p, err := strconv.Atoi(pageStr)
+		if err != nil || p <= 0 {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid page parameter")
+			return
+		}
+		page = p
+	}
+
+	pageSize := 20
+	if pageSizeStr := r.URL.Query().Get("page_size"); pageSizeStr != "" {
+		ps, err := strconv.Atoi(pageSizeStr)
+		if err != nil || ps <= 0 {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid page_size parameter")
+			return
+		}
+		if ps > 100 {
+			ps = 100 // Cap at 100
+		}
+		pageSize = ps
+	}
+
+	users, count, err := h.userRepo.List(r.URL.Query().Get("q"), page, pageSize)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, user.UserListResponse{
+		Count:    count,
+		Page:     page,
+		PageSize: pageSize,
+		Users:    users,
+	})
+}
+
+// UpdateUserRole handles PUT /admin/users/{id}/role (admin only)
+func (h *Handler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	adminID, _ := httputil.GetUserID(r)
+	targetID, ok := httputil.GetPathID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusBadRequest, "User ID required")
+		return
+	}
+
+	var req user.UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !user.ValidateRole(req.Role) {
+		httputil.WriteError(w, http.StatusBadRequest, "Role must be 'user', 'coach', or 'admin'")
+		return
+	}
+
+	// Prevent admins from locking themselves out
+	if uint(targetID) == adminID && req.Role != user.RoleAdmin {
+		httputil.WriteError(w, http.StatusBadRequest, "You cannot remove your own admin role")
+		return
+	}
+
+	if err := h.userRepo.SetRole(uint(targetID), req.Role); err != nil {
+		httputil.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	// Open streams were authorized with the previous role
+	CloseSessions(uint(targetID))
+
+	h.writeUser(w, uint(targetID))
+}
+
+// DisableUser handles POST /admin/users/{id}/disable (admin only)
+func (h *Handler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, true)
+}
+
+// EnableUser handles POST /admin/users/{id}/enable (admin only)
+func (h *Handler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, false)
+}
+
+// setUserDisabled toggles the disabled flag on the user referenced by the path
+func (h *Handler) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	adminID, _ := httputil.GetUserID(r)
+	targetID, ok := httputil.GetPathID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusBadRequest, "User ID required")
+		return
+	}
+
+	if disabled && uint(targetID) == adminID {
+		httputil.WriteError(w, http.StatusBadRequest, "You cannot disable your own account")
+		return
+	}
+
+	if err := h.userRepo.SetDisabled(uint(targetID), disabled); err != nil {
+		httputil.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if disabled {
+		CloseSessions(uint(targetID))
+	}
+
+	h.writeUser(w, uint(targetID))
+}
+
+// writeUser reloads a user and writes it as the response
+func (h *Handler) writeUser(w http.ResponseWriter, id uint) {
+	foundUser, err := h.userRepo.GetByID(id)
+	if err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, foundUser)
+}
@@ -5,7 +5,10 @@ import (
 	"net/http"
 	"strings"
 
+	"ultra-bis/internal/calendar"
 	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/i18n"
+	"ultra-bis/internal/units"
 	"ultra-bis/internal/user"
 )
 
@@ -58,6 +61,7 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	newUser := &user.User{
 		Email: req.Email,
 		Name:  req.Name,
+		Role:  user.RoleUser,
 	}
 
 	if err := newUser.HashPassword(req.Password); err != nil {
@@ -71,7 +75,7 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate token
-	token, err := GenerateToken(newUser.ID, newUser.Email)
+	token, err := GenerateTokenWithRole(newUser.ID, newUser.Email, string(newUser.Role))
 	if err != nil {
 		httputil.WriteError(w, http.StatusInternalServerError, "Failed to generate token")
 		return
@@ -109,8 +113,13 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if foundUser.Disabled {
+		httputil.WriteError(w, http.StatusForbidden, "Account is disabled")
+		return
+	}
+
 	// Generate token
-	token, err := GenerateToken(foundUser.ID, foundUser.Email)
+	token, err := GenerateTokenWithRole(foundUser.ID, foundUser.Email, string(foundUser.Role))
 	if err != nil {
 		httputil.WriteError(w, http.StatusInternalServerError, "Failed to generate token")
 		return
@@ -142,7 +151,7 @@ func (h *Handler) GetMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	httputil.WriteJSON(w, http.StatusOK, foundUser)
+	httputil.WriteJSON(w, http.StatusOK, foundUser.Profile(units.FromRequest(r)))
 }
 
 // UpdateProfile updates the user's profile
@@ -172,6 +181,37 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Apply unit preferences first: height and weight are given in the new units
+	if req.UnitSystem != "" {
+		if !units.ValidSystem(req.UnitSystem) {
+			httputil.WriteError(w, http.StatusBadRequest, "unit_system must be 'metric' or 'imperial'")
+			return
+		}
+		foundUser.UnitSystem = req.UnitSystem
+	}
+	if req.WeightUnit != "" {
+		if !units.ValidWeight(req.WeightUnit) {
+			httputil.WriteError(w, http.StatusBadRequest, "weight_unit must be 'kg', 'lb' or 'st'")
+			return
+		}
+		foundUser.WeightUnit = req.WeightUnit
+	}
+	if req.QuantityUnit != "" {
+		if !units.ValidQuantity(req.QuantityUnit) {
+			httputil.WriteError(w, http.StatusBadRequest, "quantity_unit must be 'g' or 'oz'")
+			return
+		}
+		foundUser.QuantityUnit = req.QuantityUnit
+	}
+	if req.EnergyUnit != "" {
+		if !units.ValidEnergy(req.EnergyUnit) {
+			httputil.WriteError(w, http.StatusBadRequest, "energy_unit must be 'kcal' or 'kJ'")
+			return
+		}
+		foundUser.EnergyUnit = req.EnergyUnit
+	}
+	prefs := units.Override(r, foundUser.Units())
+
 	// Update fields
 	if req.Name != "" {
 		foundUser.Name = req.Name
@@ -183,10 +223,10 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		foundUser.Gender = req.Gender
 	}
 	if req.Height > 0 {
-		foundUser.Height = req.Height
+		foundUser.Height = prefs.HeightToCm(req.Height)
 	}
 	if req.Weight > 0 {
-		foundUser.Weight = req.Weight
+		foundUser.Weight = prefs.WeightToKg(req.Weight)
 	}
 	if req.BodyFat > 0 {
 		foundUser.BodyFat = req.BodyFat
@@ -197,13 +237,34 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	if req.GoalType != "" {
 		foundUser.GoalType = req.GoalType
 	}
+	if req.TimeZone != "" {
+		if !calendar.ValidTimeZone(req.TimeZone) {
+			httputil.WriteError(w, http.StatusBadRequest, "time_zone must be an IANA time zone such as 'Europe/Paris'")
+			return
+		}
+		foundUser.TimeZone = req.TimeZone
+	}
+	if req.WeekStart != "" {
+		if _, ok := calendar.ParseWeekday(req.WeekStart); !ok {
+			httputil.WriteError(w, http.StatusBadRequest, "week_start must be a lowercase weekday such as 'monday' or 'sunday'")
+			return
+		}
+		foundUser.WeekStart = req.WeekStart
+	}
+	if req.Locale != "" {
+		if !i18n.IsSupported(req.Locale) {
+			httputil.WriteError(w, http.StatusBadRequest, "locale must be one of the supported locales")
+			return
+		}
+		foundUser.Locale = req.Locale
+	}
 
 	if err := h.userRepo.Update(foundUser); err != nil {
 		httputil.WriteError(w, http.StatusInternalServerError, "Failed to update profile")
 		return
 	}
 
-	httputil.WriteJSON(w, http.StatusOK, foundUser)
+	httputil.WriteJSON(w, http.StatusOK, foundUser.Profile(prefs))
 }
 
 // ExtractTokenFromHeader extracts the token from Authorization header
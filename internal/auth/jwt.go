@@ -14,16 +14,23 @@ var jwtSecret = []byte(getEnv("JWT_SECRET", "your-secret-key-change-in-productio
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token for a user
+// GenerateToken generates a JWT token for a user with the default "user" role
 func GenerateToken(userID uint, email string) (string, error) {
+	return GenerateTokenWithRole(userID, email, "user")
+}
+
+// GenerateTokenWithRole generates a JWT token carrying the user's role
+func GenerateTokenWithRole(userID uint, email, role string) (string, error) {
 	expirationTime := time.Now().Add(30 * 24 * time.Hour)
 
 	claims := &Claims{
 		UserID: userID,
 		Email:  email,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
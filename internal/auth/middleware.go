@@ -2,9 +2,16 @@ package auth
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"ultra-bis/internal/calendar"
 	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/i18n"
+	"ultra-bis/internal/units"
+	"ultra-bis/internal/user"
 )
 
 // contextKey is a custom type for context keys to avoid collisions
@@ -15,26 +22,274 @@ const (
 	EmailKey contextKey = "email"
 )
 
-// JWTMiddleware is a middleware that validates JWT tokens
-func JWTMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		tokenString := ExtractTokenFromHeader(r)
-		if tokenString == "" {
-			httputil.WriteError(w, http.StatusUnauthorized, "Missing authorization token")
-			return
+// AccountStatusChecker reports whether a user account may still authenticate, and its current role
+type AccountStatusChecker interface {
+	AccountStatus(userID uint) (disabled bool, role string, err error)
+}
+
+// accountChecker is consulted on every authenticated request when set
+var accountChecker AccountStatusChecker
+
+// SetAccountStatusChecker sets the checker used to reject tokens of disabled accounts
+// and to apply role changes to tokens issued before them
+func SetAccountStatusChecker(checker AccountStatusChecker) {
+	accountChecker = checker
+}
+
+// DelegationAuthorizer decides whether an actor may access another user's data
+// resource is one of the httputil.Resource* values; write is true for mutating requests
+type DelegationAuthorizer interface {
+	AuthorizeDelegation(actorID, ownerID uint, resource string, write bool) error
+}
+
+// delegationAuthorizer enables the X-Client-ID header / client_id query parameter when set
+var delegationAuthorizer DelegationAuthorizer
+
+// SetDelegationAuthorizer sets the authorizer used for coach access to client data
+func SetDelegationAuthorizer(authorizer DelegationAuthorizer) {
+	delegationAuthorizer = authorizer
+}
+
+// PreferenceLookup returns the locale, units and calendar a user chose in their profile
+type PreferenceLookup interface {
+	Preferences(userID uint) (user.Preferences, error)
+}
+
+// preferenceLookup applies each user's profile preferences to their requests when set
+var preferenceLookup PreferenceLookup
+
+// SetPreferenceLookup sets the lookup used to apply each user's profile preferences
+func SetPreferenceLookup(lookup PreferenceLookup) {
+	preferenceLookup = lookup
+}
+
+// PersonalTokenPrefix marks personal access tokens in the Authorization header
+const PersonalTokenPrefix = "pat_"
+
+// TokenIdentity is the user and scopes a personal access token resolves to
+type TokenIdentity struct {
+	UserID uint
+	Email  string
+	Role   string
+	Scopes []string
+}
+
+// PersonalTokenAuthenticator resolves personal access tokens ("pat_...")
+type PersonalTokenAuthenticator interface {
+	AuthenticateToken(token string) (*TokenIdentity, error)
+}
+
+// tokenAuthenticator is required for personal access tokens to be accepted
+var tokenAuthenticator PersonalTokenAuthenticator
+
+// SetPersonalTokenAuthenticator sets the authenticator used for personal access tokens
+func SetPersonalTokenAuthenticator(authenticator PersonalTokenAuthenticator) {
+	tokenAuthenticator = authenticator
+}
+
+// Scope returns the scope needed to read or write a resource, e.g. "diary:read"
+func Scope(resource string, write bool) string {
+	if write {
+		return resource + ":write"
+	}
+	return resource + ":read"
+}
+
+// HasScope reports whether scopes grant access to a resource; write scopes also grant reads
+func HasScope(scopes []string, resource string, write bool) bool {
+	if resource == "" {
+		return false
+	}
+
+	for _, scope := range scopes {
+		if scope == Scope(resource, true) || (!write && scope == Scope(resource, false)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionCloser ends the long-lived connections of a user, such as event streams, so that
+// they authenticate again
+type SessionCloser interface {
+	Disconnect(userID uint)
+}
+
+// sessionCloser is told when a user's access changes, when set
+var sessionCloser SessionCloser
+
+// SetSessionCloser sets the closer of long-lived connections used by CloseSessions
+func SetSessionCloser(closer SessionCloser) {
+	sessionCloser = closer
+}
+
+// CloseSessions ends the long-lived connections of a user after their access changed,
+// e.g. a disabled account or a revoked token
+func CloseSessions(userID uint) {
+	if sessionCloser != nil {
+		sessionCloser.Disconnect(userID)
+	}
+}
+
+// authenticate resolves the token of a request to its claims and the account's current role
+// A refused request gets the response status and the reason, a catalog message
+func authenticate(r *http.Request) (claims *Claims, role string, status int, err error) {
+	tokenString := ExtractTokenFromHeader(r)
+	if tokenString == "" && acceptsEventStream(r) {
+		// Browsers cannot set headers on an EventSource, so event streams may pass the token in the URL
+		tokenString = r.URL.Query().Get("access_token")
+	}
+	if tokenString == "" {
+		return nil, "", http.StatusUnauthorized, i18n.Errorf("Missing authorization token")
+	}
+
+	if strings.HasPrefix(tokenString, PersonalTokenPrefix) {
+		// Personal access tokens only reach the resources their scopes grant
+		if tokenAuthenticator == nil {
+			return nil, "", http.StatusUnauthorized, i18n.Errorf("Invalid or expired token")
+		}
+
+		identity, authErr := tokenAuthenticator.AuthenticateToken(tokenString)
+		if authErr != nil {
+			return nil, "", http.StatusUnauthorized, i18n.Errorf("Invalid or expired token")
+		}
+
+		write := !httputil.IsReadOnlyMethod(r.Method)
+		if !HasScope(identity.Scopes, httputil.ResourceForPath(r.URL.Path), write) {
+			return nil, "", http.StatusForbidden, i18n.Errorf("Token does not have the required scope")
+		}
+
+		claims = &Claims{UserID: identity.UserID, Email: identity.Email, Role: identity.Role}
+	} else {
+		claims, err = ValidateToken(tokenString)
+		if err != nil {
+			return nil, "", http.StatusUnauthorized, i18n.Errorf("Invalid or expired token")
+		}
+	}
+
+	// Tokens issued before roles existed carry no role claim
+	role = claims.Role
+
+	// Reject tokens belonging to disabled accounts; the current role replaces the one
+	// the token was issued with, so that a demotion takes effect at once
+	if accountChecker != nil {
+		disabled, currentRole, checkErr := accountChecker.AccountStatus(claims.UserID)
+		if checkErr != nil {
+			return nil, "", http.StatusInternalServerError, i18n.Errorf("Failed to check account status")
+		}
+		if disabled {
+			return nil, "", http.StatusForbidden, i18n.Errorf("Account is disabled")
 		}
+		role = currentRole
+	}
+	if role == "" {
+		role = "user"
+	}
+	return claims, role, http.StatusOK, nil
+}
+
+// Revalidate checks again the credentials of a request that JWTMiddleware let through, for
+// connections that stay open such as event streams: it fails once the token expired or was
+// revoked, the account was disabled, or a coach acting for a client lost access
+func Revalidate(r *http.Request) error {
+	_, role, _, err := authenticate(r)
+	if err != nil {
+		return err
+	}
+	if !httputil.IsDelegated(r) {
+		return nil
+	}
+
+	actorID, _ := httputil.GetActorID(r)
+	clientID, _ := httputil.GetUserID(r)
+	return authorizeDelegation(r, role, actorID, clientID)
+}
 
-		claims, err := ValidateToken(tokenString)
+// authorizeDelegation checks that an actor with the given current role may act on a client's data
+func authorizeDelegation(r *http.Request, role string, actorID, clientID uint) error {
+	resource := httputil.ResourceForPath(r.URL.Path)
+	if resource == "" || delegationAuthorizer == nil {
+		return i18n.Errorf("Client access is not available for this endpoint")
+	}
+	// Links outlive a change of role: a coach who loses the role loses access to every client at once
+	if role != string(user.RoleCoach) {
+		return i18n.Errorf("Only coaches can access client data")
+	}
+
+	write := !httputil.IsReadOnlyMethod(r.Method)
+	return delegationAuthorizer.AuthorizeDelegation(actorID, clientID, resource, write)
+}
+
+// JWTMiddleware is a middleware that validates JWT tokens and personal access tokens
+func JWTMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, role, status, err := authenticate(r)
 		if err != nil {
-			httputil.WriteError(w, http.StatusUnauthorized, "Invalid or expired token")
+			httputil.WriteErrorFrom(w, status, err)
 			return
 		}
 
 		// Add user info to context using typed keys
 		ctx := httputil.SetUserID(r.Context(), claims.UserID)
+		ctx = httputil.SetUserRole(ctx, role)
 		ctx = context.WithValue(ctx, EmailKey, claims.Email)
 
+		// The caller's profile preferences: the locale replaces Accept-Language, values are
+		// read and written in their units, and "today" and weeks follow their calendar
+		if preferenceLookup != nil {
+			if prefs, err := preferenceLookup.Preferences(claims.UserID); err == nil {
+				if prefs.Locale != "" {
+					i18n.SetProfileLocale(r, prefs.Locale)
+				}
+				ctx = units.WithPreferences(ctx, prefs.Units)
+				ctx = calendar.WithSettings(ctx, prefs.Calendar)
+			}
+		}
+
+		// Acting on a client's data: the client becomes the data owner (GetUserID)
+		// and the authenticated caller is kept as the actor (GetActorID)
+		if clientID, requested, err := requestedClientID(r); requested {
+			if err != nil {
+				httputil.WriteError(w, http.StatusBadRequest, "Invalid client ID")
+				return
+			}
+
+			if clientID != claims.UserID {
+				if err := authorizeDelegation(r, role, claims.UserID, clientID); err != nil {
+					httputil.WriteErrorFrom(w, http.StatusForbidden, err)
+					return
+				}
+
+				ctx = httputil.SetActorID(ctx, claims.UserID)
+				ctx = httputil.SetUserID(ctx, clientID)
+			}
+		}
+
 		// Call next handler with updated context
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
+
+// acceptsEventStream reports whether the request asks for a Server-Sent Events stream
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// requestedClientID reads the client a coach wants to act on from the
+// X-Client-ID header or the client_id query parameter
+func requestedClientID(r *http.Request) (uint, bool, error) {
+	value := r.Header.Get("X-Client-ID")
+	if value == "" {
+		value = r.URL.Query().Get("client_id")
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || id == 0 {
+		return 0, true, fmt.Errorf("invalid client ID")
+	}
+
+	return uint(id), true, nil
+}
@@ -1,6 +1,12 @@
 package auth
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/user"
+)
 
 // RegisterRoutes registers all auth-related routes to the provided mux
 func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
@@ -11,4 +17,36 @@ func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
 	// Protected routes
 	mux.HandleFunc("/auth/me", JWTMiddleware(handler.GetMe))
 	mux.HandleFunc("/users/profile", JWTMiddleware(handler.UpdateProfile))
+
+	// Admin routes
+	requireAdmin := httputil.RequireRole(string(user.RoleAdmin))
+
+	mux.HandleFunc("/admin/users", httputil.ChainMiddleware(
+		handler.ListUsers,
+		JWTMiddleware,
+		requireAdmin,
+	))
+
+	// Pattern: /admin/users/{id}/role, /admin/users/{id}/disable, /admin/users/{id}/enable
+	mux.HandleFunc("/admin/users/", func(w http.ResponseWriter, r *http.Request) {
+		var action http.HandlerFunc
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/role"):
+			action = handler.UpdateUserRole
+		case strings.HasSuffix(r.URL.Path, "/disable"):
+			action = handler.DisableUser
+		case strings.HasSuffix(r.URL.Path, "/enable"):
+			action = handler.EnableUser
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		httputil.ChainMiddleware(
+			action,
+			httputil.ExtractPathID(2),
+			JWTMiddleware,
+			requireAdmin,
+		)(w, r)
+	})
 }
@@ -1,7 +1,9 @@
 package tests
 
 import (
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -9,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"ultra-bis/internal/auth"
+	"ultra-bis/internal/httputil"
 )
 
 func TestGenerateToken(t *testing.T) {
@@ -280,3 +283,319 @@ func newTestRequest(authHeader string) (*http.Request, error) {
 	}
 	return req, nil
 }
+
+func TestGenerateTokenWithRole(t *testing.T) {
+	token, err := auth.GenerateTokenWithRole(7, "admin@example.com", "admin")
+	require.NoError(t, err)
+
+	claims, err := auth.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), claims.UserID)
+	assert.Equal(t, "admin", claims.Role)
+
+	// GenerateToken defaults to the regular user role
+	token, err = auth.GenerateToken(8, "user@example.com")
+	require.NoError(t, err)
+
+	claims, err = auth.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user", claims.Role)
+}
+
+// stubAccountChecker reports a fixed set of disabled user IDs and current roles
+type stubAccountChecker struct {
+	disabled map[uint]bool
+	roles    map[uint]string
+}
+
+func (s stubAccountChecker) AccountStatus(userID uint) (bool, string, error) {
+	return s.disabled[userID], s.roles[userID], nil
+}
+
+func TestJWTMiddleware_RoleAndDisabledAccounts(t *testing.T) {
+	auth.SetAccountStatusChecker(stubAccountChecker{
+		disabled: map[uint]bool{2: true},
+		roles:    map[uint]string{1: "coach", 3: "user"},
+	})
+	t.Cleanup(func() { auth.SetAccountStatusChecker(nil) })
+
+	var gotRole string
+	handler := auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		gotRole, _ = httputil.GetUserRole(r)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Active coach account: role is propagated to the context
+	token, err := auth.GenerateTokenWithRole(1, "coach@example.com", "coach")
+	require.NoError(t, err)
+	req, err := newTestRequest("Bearer " + token)
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "coach", gotRole)
+
+	// Disabled account: token is rejected
+	token, err = auth.GenerateToken(2, "disabled@example.com")
+	require.NoError(t, err)
+	req, err = newTestRequest("Bearer " + token)
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Demoted admin: the current role replaces the token's
+	token, err = auth.GenerateTokenWithRole(3, "former-admin@example.com", "admin")
+	require.NoError(t, err)
+	req, err = newTestRequest("Bearer " + token)
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "user", gotRole)
+}
+
+// stubDelegationAuthorizer lets coach 1 read client 2's diary only, until the client revoked it
+type stubDelegationAuthorizer struct {
+	revoked bool
+}
+
+func (s stubDelegationAuthorizer) AuthorizeDelegation(actorID, ownerID uint, resource string, write bool) error {
+	if !s.revoked && actorID == 1 && ownerID == 2 && resource == httputil.ResourceDiary && !write {
+		return nil
+	}
+	return fmt.Errorf("You do not have access to this client's %s", resource)
+}
+
+func TestRevalidate(t *testing.T) {
+	checker := stubAccountChecker{disabled: map[uint]bool{}, roles: map[uint]string{1: "coach"}}
+	auth.SetAccountStatusChecker(checker)
+	auth.SetDelegationAuthorizer(stubDelegationAuthorizer{})
+	t.Cleanup(func() {
+		auth.SetAccountStatusChecker(nil)
+		auth.SetDelegationAuthorizer(nil)
+	})
+
+	// Keep the request as the handler of a long-lived stream sees it
+	var streamRequest *http.Request
+	handler := auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		streamRequest = r
+	})
+	open := func(t *testing.T, userID uint, clientID string) *http.Request {
+		token, err := auth.GenerateToken(userID, "user@example.com")
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/diary/entries", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if clientID != "" {
+			req.Header.Set("X-Client-ID", clientID)
+		}
+		streamRequest = nil
+		handler(httptest.NewRecorder(), req)
+		require.NotNil(t, streamRequest)
+		return streamRequest
+	}
+
+	own := open(t, 1, "")
+	assert.NoError(t, auth.Revalidate(own))
+
+	// Disabling the account ends the stream on its next check
+	checker.disabled[1] = true
+	assert.EqualError(t, auth.Revalidate(own), "Account is disabled")
+	checker.disabled[1] = false
+
+	// A coach streaming a client's data is checked again against the client's sharing
+	delegated := open(t, 1, "2")
+	assert.NoError(t, auth.Revalidate(delegated))
+	checker.roles[1] = "user"
+	assert.EqualError(t, auth.Revalidate(delegated), "Only coaches can access client data")
+	checker.roles[1] = "coach"
+	auth.SetDelegationAuthorizer(stubDelegationAuthorizer{revoked: true})
+	assert.Error(t, auth.Revalidate(delegated))
+}
+
+// stubSessionCloser records the users whose sessions were closed
+type stubSessionCloser struct {
+	closed []uint
+}
+
+func (s *stubSessionCloser) Disconnect(userID uint) {
+	s.closed = append(s.closed, userID)
+}
+
+func TestCloseSessions(t *testing.T) {
+	auth.CloseSessions(1) // Without a closer it does nothing
+
+	closer := &stubSessionCloser{}
+	auth.SetSessionCloser(closer)
+	t.Cleanup(func() { auth.SetSessionCloser(nil) })
+
+	auth.CloseSessions(4)
+	assert.Equal(t, []uint{4}, closer.closed)
+}
+
+func TestJWTMiddleware_EventStreamToken(t *testing.T) {
+	handler := auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	token, err := auth.GenerateToken(1, "user@example.com")
+	require.NoError(t, err)
+
+	// EventSource clients pass the token in the URL
+	req := httptest.NewRequest(http.MethodGet, "/events?access_token="+token, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Other requests must use the Authorization header
+	req = httptest.NewRequest(http.MethodGet, "/diary/entries?access_token="+token, nil)
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestJWTMiddleware_Delegation(t *testing.T) {
+	auth.SetDelegationAuthorizer(stubDelegationAuthorizer{})
+	t.Cleanup(func() { auth.SetDelegationAuthorizer(nil) })
+
+	var gotUserID, gotActorID uint
+	handler := auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID, _ = httputil.GetUserID(r)
+		gotActorID, _ = httputil.GetActorID(r)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	token, err := auth.GenerateTokenWithRole(1, "coach@example.com", "coach")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		clientHeader   string
+		expectedStatus int
+		expectedUserID uint
+	}{
+		{"no client requested", http.MethodGet, "/diary/entries", "", http.StatusOK, 1},
+		{"own ID as client", http.MethodGet, "/diary/entries", "1", http.StatusOK, 1},
+		{"allowed read", http.MethodGet, "/diary/entries", "2", http.StatusOK, 2},
+		{"query parameter", http.MethodGet, "/diary/entries?client_id=2", "", http.StatusOK, 2},
+		{"write refused", http.MethodPost, "/diary/entries", "2", http.StatusForbidden, 0},
+		{"resource not granted", http.MethodGet, "/metrics", "2", http.StatusForbidden, 0},
+		{"not a shareable endpoint", http.MethodGet, "/recipes", "2", http.StatusForbidden, 0},
+		{"invalid client ID", http.MethodGet, "/diary/entries", "abc", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUserID, gotActorID = 0, 0
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			if tt.clientHeader != "" {
+				req.Header.Set("X-Client-ID", tt.clientHeader)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.expectedUserID, gotUserID)
+				assert.Equal(t, uint(1), gotActorID)
+			}
+		})
+	}
+
+	// The link still exists, but the account is no longer a coach
+	auth.SetAccountStatusChecker(stubAccountChecker{roles: map[uint]string{1: "user"}})
+	t.Cleanup(func() { auth.SetAccountStatusChecker(nil) })
+	req := httptest.NewRequest(http.MethodGet, "/diary/entries", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Client-ID", "2")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestHasScope(t *testing.T) {
+	scopes := []string{"diary:read", "metrics:write"}
+
+	tests := []struct {
+		name     string
+		resource string
+		write    bool
+		expected bool
+	}{
+		{"read scope allows read", httputil.ResourceDiary, false, true},
+		{"read scope refuses write", httputil.ResourceDiary, true, false},
+		{"write scope allows write", httputil.ResourceMetrics, true, true},
+		{"write scope allows read", httputil.ResourceMetrics, false, true},
+		{"resource not granted", httputil.ResourceGoals, false, false},
+		{"endpoint without resource", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, auth.HasScope(scopes, tt.resource, tt.write))
+		})
+	}
+}
+
+// stubTokenAuthenticator accepts a single personal access token
+type stubTokenAuthenticator struct{}
+
+func (stubTokenAuthenticator) AuthenticateToken(token string) (*auth.TokenIdentity, error) {
+	if token != "pat_valid" {
+		return nil, fmt.Errorf("token not found")
+	}
+	return &auth.TokenIdentity{UserID: 3, Email: "script@example.com", Role: "user", Scopes: []string{"metrics:write"}}, nil
+}
+
+func TestJWTMiddleware_PersonalAccessToken(t *testing.T) {
+	var gotUserID uint
+	handler := auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID, _ = httputil.GetUserID(r)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Without an authenticator personal access tokens are rejected
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer pat_valid")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	auth.SetPersonalTokenAuthenticator(stubTokenAuthenticator{})
+	t.Cleanup(func() { auth.SetPersonalTokenAuthenticator(nil) })
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{"scoped write", http.MethodPost, "/metrics", "pat_valid", http.StatusOK},
+		{"scoped read", http.MethodGet, "/metrics/latest", "pat_valid", http.StatusOK},
+		{"outside scopes", http.MethodGet, "/diary/entries", "pat_valid", http.StatusForbidden},
+		{"token management", http.MethodPost, "/auth/tokens", "pat_valid", http.StatusForbidden},
+		{"unknown token", http.MethodGet, "/metrics", "pat_unknown", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUserID = 0
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, uint(3), gotUserID)
+			}
+		})
+	}
+}
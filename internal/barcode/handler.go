@@ -49,7 +49,7 @@ func (h *Handler) SearchProducts(w http.ResponseWriter, r *http.Request) {
 	// Search Open Food Facts
 	results, err := h.service.SearchByName(query, page, pageSize)
 	if err != nil {
-		httputil.WriteError(w, http.StatusInternalServerError, "Failed to search products: "+err.Error())
+		httputil.WriteErrorf(w, http.StatusInternalServerError, "Failed to search products: %v", err)
 		return
 	}
 
@@ -76,10 +76,10 @@ func (h *Handler) ScanBarcode(w http.ResponseWriter, r *http.Request) {
 	productData, err := h.service.ScanBarcode(code)
 	if err != nil {
 		if strings.Contains(err.Error(), "product not found") {
-			httputil.WriteError(w, http.StatusNotFound, "Product not found for barcode: "+code)
+			httputil.WriteErrorf(w, http.StatusNotFound, "Product not found for barcode: %s", code)
 			return
 		}
-		httputil.WriteError(w, http.StatusInternalServerError, "Failed to scan barcode: "+err.Error())
+		httputil.WriteErrorf(w, http.StatusInternalServerError, "Failed to scan barcode: %v", err)
 		return
 	}
 
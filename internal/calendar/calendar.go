@@ -0,0 +1,97 @@
+package calendar
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	// Embedded time zone database: the alpine runtime image ships without tzdata
+	_ "time/tzdata"
+)
+
+// Calendar days are stored as midnight UTC of the date (what time.Parse("2006-01-02")
+// returns), so "today" and week boundaries are computed in the user's time zone and
+// then expressed as UTC dates.
+
+// Settings are the time zone and first day of the week of a user
+type Settings struct {
+	Location  *time.Location
+	WeekStart time.Weekday
+}
+
+// Default is used for users without settings: UTC, weeks starting on Monday
+var Default = Settings{Location: time.UTC, WeekStart: time.Monday}
+
+// New returns the settings for an IANA time zone and a weekday name, falling back to Default
+func New(timeZone, weekStart string) Settings {
+	settings := Default
+	if location, err := time.LoadLocation(timeZone); err == nil && timeZone != "" {
+		settings.Location = location
+	}
+	if day, ok := ParseWeekday(weekStart); ok {
+		settings.WeekStart = day
+	}
+	return settings
+}
+
+// ValidTimeZone checks if name is a known IANA time zone, e.g. "America/Los_Angeles"
+func ValidTimeZone(name string) bool {
+	if name == "" || strings.EqualFold(name, "local") {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// ParseWeekday parses a lowercase weekday name such as "monday"
+func ParseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if name == strings.ToLower(day.String()) {
+			return day, true
+		}
+	}
+	return time.Sunday, false
+}
+
+// DateOf returns the calendar date of instant t in the user's time zone, as midnight UTC
+func (s Settings) DateOf(t time.Time) time.Time {
+	local := t.In(s.Location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Today returns the user's current date, as midnight UTC
+func (s Settings) Today() time.Time {
+	return s.DateOf(time.Now())
+}
+
+// StartOfWeek returns the first day of the week containing date, following WeekStart
+func (s Settings) StartOfWeek(date time.Time) time.Time {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	offset := (int(day.Weekday()) - int(s.WeekStart) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// contextKey is a custom type for context keys to avoid collisions
+type contextKey string
+
+// settingsKey is the context key for the authenticated user's calendar settings
+const settingsKey contextKey = "calendar"
+
+// WithSettings returns a context carrying the user's calendar settings
+func WithSettings(ctx context.Context, settings Settings) context.Context {
+	return context.WithValue(ctx, settingsKey, settings)
+}
+
+// FromContext returns the calendar settings carried by a context, or Default
+func FromContext(ctx context.Context) Settings {
+	if settings, ok := ctx.Value(settingsKey).(Settings); ok {
+		return settings
+	}
+	return Default
+}
+
+// FromRequest returns the calendar settings of a request's user
+func FromRequest(r *http.Request) Settings {
+	return FromContext(r.Context())
+}
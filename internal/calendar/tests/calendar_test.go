@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/user"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDateOf_UsesUserTimeZone(t *testing.T) {
+	// 9pm in Los Angeles on Jan 15 is already Jan 16 in UTC
+	dinner := time.Date(2025, 1, 16, 5, 0, 0, 0, time.UTC)
+
+	losAngeles := calendar.New("America/Los_Angeles", "")
+	assert.Equal(t, "2025-01-15", losAngeles.DateOf(dinner).Format("2006-01-02"))
+	assert.Equal(t, "2025-01-16", calendar.Default.DateOf(dinner).Format("2006-01-02"))
+
+	// Dates are midnight UTC, like the dates parsed from requests
+	parsed, err := time.Parse("2006-01-02", "2025-01-15")
+	require.NoError(t, err)
+	assert.Equal(t, parsed, losAngeles.DateOf(dinner))
+}
+
+func TestStartOfWeek(t *testing.T) {
+	sunday := time.Date(2025, 1, 12, 0, 0, 0, 0, time.UTC)
+	wednesday := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	monday := calendar.New("", "monday")
+	assert.Equal(t, "2025-01-06", monday.StartOfWeek(sunday).Format("2006-01-02"))
+	assert.Equal(t, "2025-01-13", monday.StartOfWeek(wednesday).Format("2006-01-02"))
+
+	sundayStart := calendar.New("", "sunday")
+	assert.Equal(t, "2025-01-12", sundayStart.StartOfWeek(sunday).Format("2006-01-02"))
+	assert.Equal(t, "2025-01-12", sundayStart.StartOfWeek(wednesday).Format("2006-01-02"))
+
+	saturday := calendar.New("", "saturday")
+	assert.Equal(t, "2025-01-11", saturday.StartOfWeek(wednesday).Format("2006-01-02"))
+}
+
+func TestNew_FallsBackToDefault(t *testing.T) {
+	settings := calendar.New("Mars/Olympus_Mons", "funday")
+	assert.Equal(t, time.UTC, settings.Location)
+	assert.Equal(t, time.Monday, settings.WeekStart)
+
+	assert.True(t, calendar.ValidTimeZone("Europe/Paris"))
+	assert.False(t, calendar.ValidTimeZone("Mars/Olympus_Mons"))
+	assert.False(t, calendar.ValidTimeZone(""))
+	assert.False(t, calendar.ValidTimeZone("Local"))
+
+	day, ok := calendar.ParseWeekday("sunday")
+	assert.True(t, ok)
+	assert.Equal(t, time.Sunday, day)
+	_, ok = calendar.ParseWeekday("Sunday")
+	assert.False(t, ok)
+}
+
+func TestFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/diary/weekly", nil)
+	assert.Equal(t, calendar.Default, calendar.FromRequest(r))
+
+	u := user.User{TimeZone: "Asia/Tokyo", WeekStart: "sunday"}
+	r = r.WithContext(calendar.WithSettings(r.Context(), u.Calendar()))
+	settings := calendar.FromRequest(r)
+	assert.Equal(t, "Asia/Tokyo", settings.Location.String())
+	assert.Equal(t, time.Sunday, settings.WeekStart)
+}
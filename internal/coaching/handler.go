@@ -0,0 +1,477 @@
+package coaching
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/diary"
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/metrics"
+	"ultra-bis/internal/user"
+)
+
+// Handler handles coach/client sharing requests
+type Handler struct {
+	repo        *Repository
+	userRepo    *user.Repository
+	diaryRepo   *diary.Repository
+	goalRepo    *goal.Repository
+	metricsRepo *metrics.Repository
+}
+
+// NewHandler creates a new coaching handler
+func NewHandler(repo *Repository, userRepo *user.Repository, diaryRepo *diary.Repository, goalRepo *goal.Repository, metricsRepo *metrics.Repository) *Handler {
+	return &Handler{
+		repo:        repo,
+		userRepo:    userRepo,
+		diaryRepo:   diaryRepo,
+		goalRepo:    goalRepo,
+		metricsRepo: metricsRepo,
+	}
+}
+
+// InviteCoach handles POST /coaching/coaches (client invites a coach by email)
+func (h *Handler) InviteCoach(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	clientID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req InviteCoachRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.CoachEmail == "" {
+		httputil.WriteError(w, http.StatusBadRequest, "coach_email is required")
+		return
+	}
+
+	coach, err := h.userRepo.GetByEmail(req.CoachEmail)
+	if err != nil || coach.Disabled {
+		httputil.WriteError(w, http.StatusNotFound, "Coach not found")
+		return
+	}
+
+	if coach.Role != user.RoleCoach {
+		httputil.WriteError(w, http.StatusBadRequest, "This user is not a coach")
+		return
+	}
+
+	if coach.ID == clientID {
+		httputil.WriteError(w, http.StatusBadRequest, "You cannot coach yourself")
+		return
+	}
+
+	existing, err := h.repo.GetOpen(clientID, coach.ID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if existing != nil {
+		httputil.WriteError(w, http.StatusConflict, "This coach has already been invited")
+		return
+	}
+
+	link := &CoachLink{
+		ClientID:       clientID,
+		CoachID:        coach.ID,
+		Status:         StatusPending,
+		CanViewDiary:   boolOrDefault(req.CanViewDiary, true),
+		CanViewGoals:   boolOrDefault(req.CanViewGoals, true),
+		CanViewMetrics: boolOrDefault(req.CanViewMetrics, true),
+		CanSetGoals:    req.CanSetGoals,
+	}
+
+	if err := h.repo.Create(link); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	link.CoachName = coach.Name
+	link.CoachEmail = coach.Email
+	httputil.WriteJSON(w, http.StatusCreated, link)
+}
+
+// GetCoaches handles GET /coaching/coaches (client lists their coaches)
+func (h *Handler) GetCoaches(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	clientID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	links, err := h.repo.ListForClient(clientID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, links)
+}
+
+// UpdatePermissions handles PUT /coaching/coaches/{id} (client changes a coach's scopes)
+func (h *Handler) UpdatePermissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	link, ok := h.clientLink(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdatePermissionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	link.CanViewDiary = boolOrDefault(req.CanViewDiary, link.CanViewDiary)
+	link.CanViewGoals = boolOrDefault(req.CanViewGoals, link.CanViewGoals)
+	link.CanViewMetrics = boolOrDefault(req.CanViewMetrics, link.CanViewMetrics)
+	link.CanSetGoals = boolOrDefault(req.CanSetGoals, link.CanSetGoals)
+
+	if err := h.repo.Update(link); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, link)
+}
+
+// RevokeCoach handles DELETE /coaching/coaches/{id} (client revokes access)
+func (h *Handler) RevokeCoach(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	link, ok := h.clientLink(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.End(link, StatusRevoked); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetInvitations handles GET /coaching/invitations (coach lists pending invitations)
+func (h *Handler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	coachID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	links, err := h.repo.ListForCoach(coachID, StatusPending)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, links)
+}
+
+// RespondToInvitation handles POST /coaching/invitations/{id}/accept|decline
+func (h *Handler) RespondToInvitation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	coachID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, ok := httputil.GetPathID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusBadRequest, "Invitation ID required")
+		return
+	}
+
+	link, err := h.repo.GetByID(uint(id))
+	if err != nil || link.CoachID != coachID {
+		httputil.WriteError(w, http.StatusNotFound, "Invitation not found")
+		return
+	}
+
+	if link.Status != StatusPending {
+		httputil.WriteError(w, http.StatusConflict, "Invitation is no longer pending")
+		return
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/decline") {
+		err = h.repo.End(link, StatusDeclined)
+	} else {
+		now := time.Now()
+		link.Status = StatusActive
+		link.AcceptedAt = &now
+		err = h.repo.Update(link)
+	}
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, link)
+}
+
+// GetClients handles GET /coaching/clients (coach lists active clients)
+func (h *Handler) GetClients(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	coachID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	links, err := h.repo.ListForCoach(coachID, StatusActive)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, links)
+}
+
+// DropClient handles DELETE /coaching/clients/{clientId} (coach ends the relationship)
+func (h *Handler) DropClient(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	coachID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	clientID, ok := httputil.GetPathID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusBadRequest, "Client ID required")
+		return
+	}
+
+	link, err := h.repo.GetActive(coachID, uint(clientID))
+	if err != nil {
+		httputil.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if err := h.repo.End(link, StatusRevoked); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDashboard handles GET /coaching/dashboard?week_start=YYYY-MM-DD
+// Lists every active client's adherence for the week (defaults to the coach's current week)
+func (h *Handler) GetDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	coachID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var startDate time.Time
+	if weekStartStr := r.URL.Query().Get("week_start"); weekStartStr != "" {
+		var err error
+		startDate, err = time.Parse("2006-01-02", weekStartStr)
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
+			return
+		}
+	} else {
+		settings := calendar.FromRequest(r)
+		startDate = settings.StartOfWeek(settings.Today())
+	}
+	startDate = time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
+	endDate := startDate.AddDate(0, 0, 7)
+
+	links, err := h.repo.ListForCoach(coachID, StatusActive)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	clients := make([]ClientAdherence, 0, len(links))
+	for _, link := range links {
+		var entries []diary.DiaryEntry
+		if link.CanViewDiary {
+			entries, err = h.diaryRepo.GetByDateRange(link.ClientID, startDate, endDate)
+			if err != nil {
+				httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+
+		var activeGoal *goal.NutritionGoal
+		if link.CanViewGoals {
+			activeGoal, _ = h.goalRepo.GetActive(link.ClientID)
+		}
+
+		var weights []metrics.BodyMetric
+		if link.CanViewMetrics {
+			weights, err = h.metricsRepo.GetByDateRange(link.ClientID, startDate, endDate)
+			if err != nil {
+				httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+
+		adherence := SummarizeWeek(entries, activeGoal, weights)
+		adherence.ClientID = link.ClientID
+		adherence.ClientName = link.ClientName
+		adherence.ClientEmail = link.ClientEmail
+		adherence.CanViewDiary = link.CanViewDiary
+		adherence.CanViewGoals = link.CanViewGoals
+		adherence.CanViewMetrics = link.CanViewMetrics
+		clients = append(clients, adherence)
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, DashboardResponse{
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.AddDate(0, 0, -1).Format("2006-01-02"),
+		Clients:   clients,
+	})
+}
+
+// SummarizeWeek computes a client's adherence from a week of diary entries,
+// their active goal (may be nil) and weigh-ins sorted by date
+func SummarizeWeek(entries []diary.DiaryEntry, activeGoal *goal.NutritionGoal, weights []metrics.BodyMetric) ClientAdherence {
+	var result ClientAdherence
+
+	// Aggregate calories and protein per day
+	type dayTotals struct{ calories, protein float64 }
+	days := make(map[string]*dayTotals)
+	for _, entry := range entries {
+		key := entry.Date.Format("2006-01-02")
+		if days[key] == nil {
+			days[key] = &dayTotals{}
+		}
+		days[key].calories += entry.Calories
+		days[key].protein += entry.Protein
+	}
+
+	if activeGoal != nil {
+		result.GoalCalories = activeGoal.Calories
+		result.GoalProtein = activeGoal.Protein
+	}
+
+	var totalCalories, totalProtein float64
+	for _, day := range days {
+		if day.calories == 0 {
+			continue
+		}
+		result.DaysLogged++
+		totalCalories += day.calories
+		totalProtein += day.protein
+
+		if result.GoalCalories > 0 && math.Abs(day.calories-result.GoalCalories) <= result.GoalCalories*0.10 {
+			result.DaysOnTarget++
+		}
+	}
+
+	if result.DaysLogged > 0 {
+		result.AverageCalories = roundToTwo(totalCalories / float64(result.DaysLogged))
+		result.AverageProtein = roundToTwo(totalProtein / float64(result.DaysLogged))
+	}
+	if result.GoalCalories > 0 {
+		result.CalorieAdherence = roundToTwo(result.AverageCalories / result.GoalCalories * 100)
+	}
+	if result.GoalProtein > 0 {
+		result.ProteinAdherence = roundToTwo(result.AverageProtein / result.GoalProtein * 100)
+	}
+
+	if len(weights) > 0 {
+		latest := weights[len(weights)-1].Weight
+		delta := roundToTwo(latest - weights[0].Weight)
+		result.LatestWeight = &latest
+		result.WeeklyWeightDelta = &delta
+	}
+
+	return result
+}
+
+// clientLink loads the link referenced by the path and checks it belongs to the caller as client
+func (h *Handler) clientLink(w http.ResponseWriter, r *http.Request) (*CoachLink, bool) {
+	clientID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	id, ok := httputil.GetPathID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusBadRequest, "Coach link ID required")
+		return nil, false
+	}
+
+	link, err := h.repo.GetByID(uint(id))
+	if err != nil || link.ClientID != clientID {
+		httputil.WriteError(w, http.StatusNotFound, "Coach link not found")
+		return nil, false
+	}
+
+	if link.Status != StatusPending && link.Status != StatusActive {
+		httputil.WriteError(w, http.StatusConflict, "Coach link has already ended")
+		return nil, false
+	}
+
+	return link, true
+}
+
+// boolOrDefault returns *value when set, otherwise the default
+func boolOrDefault(value *bool, def bool) bool {
+	if value == nil {
+		return def
+	}
+	return *value
+}
+
+// roundToTwo rounds a float to 2 decimal places
+func roundToTwo(val float64) float64 {
+	return math.Round(val*100) / 100
+}
@@ -0,0 +1,109 @@
+package coaching
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"ultra-bis/internal/httputil"
+)
+
+// LinkStatus represents the state of a coach/client relationship
+type LinkStatus string
+
+const (
+	StatusPending  LinkStatus = "pending"  // Invited by the client, waiting for the coach
+	StatusActive   LinkStatus = "active"   // Accepted by the coach, access granted
+	StatusDeclined LinkStatus = "declined" // Declined by the coach
+	StatusRevoked  LinkStatus = "revoked"  // Ended by either party
+)
+
+// CoachLink grants a coach scoped, revocable access to a client's data
+type CoachLink struct {
+	ID             uint           `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	ClientID       uint           `json:"client_id" gorm:"not null;index"`
+	CoachID        uint           `json:"coach_id" gorm:"not null;index"`
+	Status         LinkStatus     `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	CanViewDiary   bool           `json:"can_view_diary" gorm:"not null;default:true"`
+	CanViewGoals   bool           `json:"can_view_goals" gorm:"not null;default:true"`
+	CanViewMetrics bool           `json:"can_view_metrics" gorm:"not null;default:true"`
+	CanSetGoals    bool           `json:"can_set_goals" gorm:"not null;default:false"`
+	AcceptedAt     *time.Time     `json:"accepted_at,omitempty"`
+	EndedAt        *time.Time     `json:"ended_at,omitempty"`
+
+	// Additional fields for display (not persisted)
+	CoachName   string `json:"coach_name,omitempty" gorm:"-"`
+	CoachEmail  string `json:"coach_email,omitempty" gorm:"-"`
+	ClientName  string `json:"client_name,omitempty" gorm:"-"`
+	ClientEmail string `json:"client_email,omitempty" gorm:"-"`
+}
+
+// InviteCoachRequest represents a client's invitation to a coach
+// View permissions default to true when omitted; goal setting defaults to false
+type InviteCoachRequest struct {
+	CoachEmail     string `json:"coach_email"`
+	CanViewDiary   *bool  `json:"can_view_diary,omitempty"`
+	CanViewGoals   *bool  `json:"can_view_goals,omitempty"`
+	CanViewMetrics *bool  `json:"can_view_metrics,omitempty"`
+	CanSetGoals    bool   `json:"can_set_goals"`
+}
+
+// UpdatePermissionsRequest represents a client's change to a coach's permissions
+type UpdatePermissionsRequest struct {
+	CanViewDiary   *bool `json:"can_view_diary,omitempty"`
+	CanViewGoals   *bool `json:"can_view_goals,omitempty"`
+	CanViewMetrics *bool `json:"can_view_metrics,omitempty"`
+	CanSetGoals    *bool `json:"can_set_goals,omitempty"`
+}
+
+// ClientAdherence summarizes one client's week for the coach dashboard
+type ClientAdherence struct {
+	ClientID          uint     `json:"client_id"`
+	ClientName        string   `json:"client_name"`
+	ClientEmail       string   `json:"client_email"`
+	DaysLogged        int      `json:"days_logged"`
+	DaysOnTarget      int      `json:"days_on_target"`   // Days within ±10% of the calorie goal
+	AverageCalories   float64  `json:"average_calories"` // Average over logged days
+	AverageProtein    float64  `json:"average_protein"`  // Average over logged days
+	GoalCalories      float64  `json:"goal_calories"`
+	GoalProtein       float64  `json:"goal_protein"`
+	CalorieAdherence  float64  `json:"calorie_adherence"` // Average calories as % of goal
+	ProteinAdherence  float64  `json:"protein_adherence"` // Average protein as % of goal
+	LatestWeight      *float64 `json:"latest_weight,omitempty"`
+	WeeklyWeightDelta *float64 `json:"weekly_weight_change,omitempty"` // Last minus first weigh-in of the week (kg)
+	CanViewDiary      bool     `json:"can_view_diary"`
+	CanViewGoals      bool     `json:"can_view_goals"`
+	CanViewMetrics    bool     `json:"can_view_metrics"`
+}
+
+// DashboardResponse represents the coach dashboard for a week
+type DashboardResponse struct {
+	StartDate string            `json:"start_date"`
+	EndDate   string            `json:"end_date"`
+	Clients   []ClientAdherence `json:"clients"`
+}
+
+// Allows reports whether the link grants access to a resource
+// Reads follow the view permissions; the only write allowed is setting goals
+func (l *CoachLink) Allows(resource string, write bool) bool {
+	if l.Status != StatusActive {
+		return false
+	}
+
+	switch resource {
+	case httputil.ResourceDiary:
+		return !write && l.CanViewDiary
+	case httputil.ResourceGoals:
+		if write {
+			return l.CanSetGoals
+		}
+		return l.CanViewGoals
+	case httputil.ResourceMetrics:
+		return !write && l.CanViewMetrics
+	default:
+		return false
+	}
+}
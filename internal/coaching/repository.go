@@ -0,0 +1,160 @@
+package coaching
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"ultra-bis/internal/i18n"
+)
+
+// Repository handles database operations for coach/client links
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new coaching repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Create creates a new coach link
+func (r *Repository) Create(link *CoachLink) error {
+	result := r.db.Create(link)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create coach link: %w", result.Error)
+	}
+	return nil
+}
+
+// GetByID retrieves a coach link by ID
+func (r *Repository) GetByID(id uint) (*CoachLink, error) {
+	var link CoachLink
+	result := r.db.First(&link, id)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("coach link not found")
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get coach link: %w", result.Error)
+	}
+
+	return &link, nil
+}
+
+// GetOpen retrieves the pending or active link between a client and a coach, if any
+func (r *Repository) GetOpen(clientID, coachID uint) (*CoachLink, error) {
+	var link CoachLink
+	result := r.db.Where("client_id = ? AND coach_id = ? AND status IN ?", clientID, coachID,
+		[]LinkStatus{StatusPending, StatusActive}).First(&link)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get coach link: %w", result.Error)
+	}
+
+	return &link, nil
+}
+
+// GetActive retrieves the active link between a coach and a client
+func (r *Repository) GetActive(coachID, clientID uint) (*CoachLink, error) {
+	var link CoachLink
+	result := r.db.Where("client_id = ? AND coach_id = ? AND status = ?", clientID, coachID, StatusActive).First(&link)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("no active coaching relationship with this client")
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get coach link: %w", result.Error)
+	}
+
+	return &link, nil
+}
+
+// ListForClient retrieves the pending and active coaches of a client
+func (r *Repository) ListForClient(clientID uint) ([]CoachLink, error) {
+	var links []CoachLink
+	result := r.db.Where("client_id = ? AND status IN ?", clientID, []LinkStatus{StatusPending, StatusActive}).
+		Order("created_at DESC").
+		Find(&links)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get coaches: %w", result.Error)
+	}
+
+	r.populateNames(links)
+	return links, nil
+}
+
+// ListForCoach retrieves a coach's links with the given status
+func (r *Repository) ListForCoach(coachID uint, status LinkStatus) ([]CoachLink, error) {
+	var links []CoachLink
+	result := r.db.Where("coach_id = ? AND status = ?", coachID, status).
+		Order("created_at DESC").
+		Find(&links)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get clients: %w", result.Error)
+	}
+
+	r.populateNames(links)
+	return links, nil
+}
+
+// Update updates a coach link
+func (r *Repository) Update(link *CoachLink) error {
+	result := r.db.Save(link)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update coach link: %w", result.Error)
+	}
+	return nil
+}
+
+// End marks a link as declined or revoked
+func (r *Repository) End(link *CoachLink, status LinkStatus) error {
+	now := time.Now()
+	link.Status = status
+	link.EndedAt = &now
+	return r.Update(link)
+}
+
+// AuthorizeDelegation implements auth.DelegationAuthorizer
+// Coaches may read the resources their client shared, and write goals only when allowed
+func (r *Repository) AuthorizeDelegation(coachID, clientID uint, resource string, write bool) error {
+	link, err := r.GetActive(coachID, clientID)
+	if err != nil {
+		return err
+	}
+
+	if !link.Allows(resource, write) {
+		if write {
+			return i18n.Errorf("client has not allowed you to modify %s", resource)
+		}
+		return i18n.Errorf("client has not shared %s with you", resource)
+	}
+
+	return nil
+}
+
+// populateNames populates coach and client names and emails for display
+func (r *Repository) populateNames(links []CoachLink) {
+	for i := range links {
+		link := &links[i]
+
+		var coach struct{ Name, Email string }
+		if err := r.db.Table("users").Select("name, email").Where("id = ?", link.CoachID).Scan(&coach).Error; err == nil {
+			link.CoachName = coach.Name
+			link.CoachEmail = coach.Email
+		}
+
+		var client struct{ Name, Email string }
+		if err := r.db.Table("users").Select("name, email").Where("id = ?", link.ClientID).Scan(&client).Error; err == nil {
+			link.ClientName = client.Name
+			link.ClientEmail = client.Email
+		}
+	}
+}
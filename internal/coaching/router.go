@@ -0,0 +1,81 @@
+package coaching
+
+import (
+	"net/http"
+
+	"ultra-bis/internal/auth"
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/user"
+)
+
+// RegisterRoutes registers all coaching routes to the provided mux
+func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
+	// Client side: invite, list, update and revoke coaches
+	mux.HandleFunc("/coaching/coaches", auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handler.GetCoaches(w, r)
+		case http.MethodPost:
+			handler.InviteCoach(w, r)
+		default:
+			httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}))
+
+	// Pattern: /coaching/coaches/{id}
+	mux.HandleFunc("/coaching/coaches/", func(w http.ResponseWriter, r *http.Request) {
+		var action http.HandlerFunc
+		switch r.Method {
+		case http.MethodPut:
+			action = handler.UpdatePermissions
+		case http.MethodDelete:
+			action = handler.RevokeCoach
+		default:
+			httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		httputil.ChainMiddleware(
+			action,
+			httputil.ExtractPathID(2),
+			auth.JWTMiddleware,
+		)(w, r)
+	})
+
+	// Coach side (coach role required)
+	requireCoach := httputil.RequireRole(string(user.RoleCoach))
+
+	mux.HandleFunc("/coaching/invitations", httputil.ChainMiddleware(
+		handler.GetInvitations,
+		auth.JWTMiddleware,
+		requireCoach,
+	))
+
+	// Pattern: /coaching/invitations/{id}/accept, /coaching/invitations/{id}/decline
+	mux.HandleFunc("/coaching/invitations/", httputil.ChainMiddleware(
+		handler.RespondToInvitation,
+		httputil.ExtractPathID(2),
+		auth.JWTMiddleware,
+		requireCoach,
+	))
+
+	mux.HandleFunc("/coaching/clients", httputil.ChainMiddleware(
+		handler.GetClients,
+		auth.JWTMiddleware,
+		requireCoach,
+	))
+
+	// Pattern: /coaching/clients/{clientId}
+	mux.HandleFunc("/coaching/clients/", httputil.ChainMiddleware(
+		handler.DropClient,
+		httputil.ExtractPathID(2),
+		auth.JWTMiddleware,
+		requireCoach,
+	))
+
+	mux.HandleFunc("/coaching/dashboard", httputil.ChainMiddleware(
+		handler.GetDashboard,
+		auth.JWTMiddleware,
+		requireCoach,
+	))
+}
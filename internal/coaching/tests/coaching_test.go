@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ultra-bis/internal/coaching"
+	"ultra-bis/internal/diary"
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/metrics"
+)
+
+func TestCoachLink_Allows(t *testing.T) {
+	link := coaching.CoachLink{
+		Status:         coaching.StatusActive,
+		CanViewDiary:   true,
+		CanViewGoals:   true,
+		CanViewMetrics: false,
+		CanSetGoals:    true,
+	}
+
+	tests := []struct {
+		name     string
+		resource string
+		write    bool
+		expected bool
+	}{
+		{"read diary", httputil.ResourceDiary, false, true},
+		{"write diary", httputil.ResourceDiary, true, false},
+		{"read goals", httputil.ResourceGoals, false, true},
+		{"write goals with can_set_goals", httputil.ResourceGoals, true, true},
+		{"read metrics without permission", httputil.ResourceMetrics, false, false},
+		{"write metrics", httputil.ResourceMetrics, true, false},
+		{"unknown resource", "recipes", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, link.Allows(tt.resource, tt.write))
+		})
+	}
+
+	// Goal writes are refused once the permission is withdrawn
+	link.CanSetGoals = false
+	assert.False(t, link.Allows(httputil.ResourceGoals, true))
+}
+
+func TestSummarizeWeek(t *testing.T) {
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	entries := []diary.DiaryEntry{
+		{Date: monday, Calories: 1200, Protein: 80},
+		{Date: monday, Calories: 900, Protein: 70},                    // Monday: 2100 kcal (on target)
+		{Date: monday.AddDate(0, 0, 1), Calories: 2600, Protein: 130}, // Tuesday: 2600 kcal (over)
+		{Date: monday.AddDate(0, 0, 2), Calories: 1900, Protein: 170}, // Wednesday: 1900 kcal (on target)
+	}
+	activeGoal := &goal.NutritionGoal{Calories: 2000, Protein: 150}
+	weights := []metrics.BodyMetric{
+		{Date: monday, Weight: 80.4},
+		{Date: monday.AddDate(0, 0, 6), Weight: 79.9},
+	}
+
+	result := coaching.SummarizeWeek(entries, activeGoal, weights)
+
+	assert.Equal(t, 3, result.DaysLogged)
+	assert.Equal(t, 2, result.DaysOnTarget)
+	assert.Equal(t, 2200.0, result.AverageCalories)
+	assert.Equal(t, 150.0, result.AverageProtein)
+	assert.Equal(t, 110.0, result.CalorieAdherence)
+	assert.Equal(t, 100.0, result.ProteinAdherence)
+	require.NotNil(t, result.LatestWeight)
+	require.NotNil(t, result.WeeklyWeightDelta)
+	assert.Equal(t, 79.9, *result.LatestWeight)
+	assert.Equal(t, -0.5, *result.WeeklyWeightDelta)
+}
+
+func TestSummarizeWeek_NoData(t *testing.T) {
+	result := coaching.SummarizeWeek(nil, nil, nil)
+
+	assert.Equal(t, 0, result.DaysLogged)
+	assert.Equal(t, 0.0, result.CalorieAdherence)
+	assert.Nil(t, result.LatestWeight)
+	assert.Nil(t, result.WeeklyWeightDelta)
+}
@@ -0,0 +1,97 @@
+package csvimport
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Row gives access to a CSV record by column name
+type Row struct {
+	columns map[string]int
+	record  []string
+}
+
+// NewRow wraps a record read after a header indexed with IndexColumns
+func NewRow(columns map[string]int, record []string) Row {
+	return Row{columns: columns, record: record}
+}
+
+// Has reports whether the header has a column
+func (r Row) Has(column string) bool {
+	_, ok := r.columns[column]
+	return ok
+}
+
+// Text returns the trimmed value of a column, or "" when absent
+func (r Row) Text(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+// Number returns the numeric value of a column (see ParseNumber), or 0 when absent, empty or invalid
+func (r Row) Number(column string) float64 {
+	value, _ := ParseNumber(r.Text(column))
+	return value
+}
+
+// Date parses a YYYY-MM-DD column
+func (r Row) Date(column string) (string, error) {
+	value := r.Text(column)
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", value)
+	}
+	return date.Format("2006-01-02"), nil
+}
+
+// IndexColumns maps lower-cased header names to their position
+func IndexColumns(header []string) map[string]int {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, exists := columns[name]; !exists {
+			columns[name] = i
+		}
+	}
+	return columns
+}
+
+// IsBlankRecord reports whether every field of a record is empty
+func IsBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseNumber parses a number as exported by spreadsheets and apps of any locale:
+// commas group thousands when the number also has a decimal point ("1,210.5"), when
+// there are several of them or when three digits follow the only one ("1,210");
+// otherwise a comma is the decimal separator ("79,6")
+func ParseNumber(text string) (float64, bool) {
+	text = strings.TrimSpace(text)
+	if comma := strings.Index(text, ","); comma >= 0 {
+		grouped := strings.Contains(text, ".") || strings.Count(text, ",") > 1 || len(text)-comma-1 == 3
+		if grouped {
+			text = strings.ReplaceAll(text, ",", "")
+		} else {
+			text = strings.Replace(text, ",", ".", 1)
+		}
+	}
+	if text == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"testing"
+
+	"ultra-bis/internal/csvimport"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		text  string
+		value float64
+		ok    bool
+	}{
+		{"80.4", 80.4, true},
+		{" 79,6 ", 79.6, true},
+		{"79,65", 79.65, true},
+		{"1,210", 1210, true},
+		{"1,210.5", 1210.5, true},
+		{"1,234,567", 1234567, true},
+		{"", 0, false},
+		{"--", 0, false},
+		{"NaN", 0, false},
+	}
+
+	for _, tt := range tests {
+		value, ok := csvimport.ParseNumber(tt.text)
+		assert.Equal(t, tt.ok, ok, tt.text)
+		assert.InDelta(t, tt.value, value, 0.0001, tt.text)
+	}
+}
+
+func TestRow(t *testing.T) {
+	columns := csvimport.IndexColumns([]string{"\ufeffDate", " Calories ", "Note", "date"})
+	assert.Equal(t, map[string]int{"date": 0, "calories": 1, "note": 2}, columns)
+
+	row := csvimport.NewRow(columns, []string{"2025-01-06", "1,210", " oats "})
+	assert.True(t, row.Has("note"))
+	assert.False(t, row.Has("fiber"))
+	assert.Equal(t, "oats", row.Text("note"))
+	assert.Equal(t, "", row.Text("fiber"))
+	assert.Equal(t, 1210.0, row.Number("calories"))
+	assert.Equal(t, 0.0, row.Number("fiber"))
+
+	date, err := row.Date("date")
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-01-06", date)
+	_, err = csvimport.NewRow(columns, []string{"06/01/2025"}).Date("date")
+	assert.Error(t, err)
+
+	assert.True(t, csvimport.IsBlankRecord([]string{"", "  "}))
+	assert.False(t, csvimport.IsBlankRecord([]string{"", "x"}))
+}
@@ -0,0 +1,21 @@
+package diary
+
+import (
+	"ultra-bis/internal/exercise"
+	"ultra-bis/internal/goal"
+)
+
+// NewExerciseSummary starts the summary of the exercises logged on a day
+func NewExerciseSummary(burned exercise.Totals) ExerciseSummary {
+	return ExerciseSummary{CaloriesBurned: roundToTwo(burned.Calories), Minutes: roundToTwo(burned.Minutes)}
+}
+
+// AdjustGoal returns the day's goal with the calories burned added following its exercise policy,
+// and records the policy and the adjustment in the summary
+func (s *ExerciseSummary) AdjustGoal(dayGoal *goal.NutritionGoal) *goal.NutritionGoal {
+	adjusted := dayGoal.AdjustForExercise(s.CaloriesBurned)
+	s.Policy = dayGoal.ExercisePolicy
+	s.BaseGoalCalories = dayGoal.Calories
+	s.CaloriesAdded = adjusted.ExerciseCalories
+	return adjusted
+}
@@ -0,0 +1,357 @@
+package diary
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/pdf"
+)
+
+// Export formats and PDF report periods
+const (
+	ExportCSV  = "csv"
+	ExportJSON = "json"
+	ExportPDF  = "pdf"
+
+	ReportWeekly  = "weekly"
+	ReportMonthly = "monthly"
+)
+
+// MaxExportDays limits the range of a single export
+const MaxExportDays = 366
+
+// BuildDaySummary computes a day's totals and adherence from its entries and the goal in effect
+// goal may be nil when no goal covered that day
+func BuildDaySummary(date string, entries []DiaryEntry, dayGoal *goal.NutritionGoal) DailySummary {
+	summary := DailySummary{Date: date, Entries: entries}
+	if summary.Entries == nil {
+		summary.Entries = []DiaryEntry{}
+	}
+
+	for _, entry := range entries {
+		summary.TotalCalories += entry.Calories
+		summary.TotalProtein += entry.Protein
+		summary.TotalCarbs += entry.Carbs
+		summary.TotalFat += entry.Fat
+		summary.TotalFiber += entry.Fiber
+	}
+	summary.TotalCalories = roundToTwo(summary.TotalCalories)
+	summary.TotalProtein = roundToTwo(summary.TotalProtein)
+	summary.TotalCarbs = roundToTwo(summary.TotalCarbs)
+	summary.TotalFat = roundToTwo(summary.TotalFat)
+	summary.TotalFiber = roundToTwo(summary.TotalFiber)
+
+	if dayGoal != nil {
+		summary.GoalCalories = dayGoal.Calories
+		summary.GoalProtein = dayGoal.Protein
+		summary.GoalCarbs = dayGoal.Carbs
+		summary.GoalFat = dayGoal.Fat
+		summary.GoalFiber = dayGoal.Fiber
+	}
+
+	summary.Adherence = AdherencePercent{
+		Calories: roundToTwo(calculateAdherence(summary.TotalCalories, summary.GoalCalories)),
+		Protein:  roundToTwo(calculateAdherence(summary.TotalProtein, summary.GoalProtein)),
+		Carbs:    roundToTwo(calculateAdherence(summary.TotalCarbs, summary.GoalCarbs)),
+		Fat:      roundToTwo(calculateAdherence(summary.TotalFat, summary.GoalFat)),
+		Fiber:    roundToTwo(calculateAdherence(summary.TotalFiber, summary.GoalFiber)),
+	}
+
+	summary.RoutineCalories, summary.ContextualCalories, summary.RoutinePercent, summary.ContextualPercent =
+		calculateCaloriesByTag(entries)
+	summary.Meals = BuildMealAdherence(entries, dayGoal)
+
+	return summary
+}
+
+// EffectiveGoal returns the goal covering a date with its weekday or day type override applied
+// dayTypes maps YYYY-MM-DD to the day type the user tagged that date with
+func EffectiveGoal(goals []goal.NutritionGoal, dayTypes map[string]string, date time.Time) *goal.NutritionGoal {
+	dayGoal := GoalForDate(goals, date)
+	if dayGoal == nil {
+		return nil
+	}
+	return dayGoal.ResolveForDate(date, dayTypes[date.Format("2006-01-02")])
+}
+
+// GoalForDate returns the goal covering a date, preferring the most recently started one
+func GoalForDate(goals []goal.NutritionGoal, date time.Time) *goal.NutritionGoal {
+	dayEnd := date.AddDate(0, 0, 1)
+
+	var match *goal.NutritionGoal
+	for i := range goals {
+		g := &goals[i]
+		if !g.StartDate.Before(dayEnd) {
+			continue
+		}
+		if g.EndDate != nil && g.EndDate.Before(date) {
+			continue
+		}
+		if match == nil || g.StartDate.After(match.StartDate) {
+			match = g
+		}
+	}
+	return match
+}
+
+// ExportWriter receives an export one day at a time, in date order
+type ExportWriter interface {
+	WriteDay(day DailySummary) error
+	Close() error
+}
+
+// NewExportWriter creates a writer for the given format
+// report selects weekly or monthly sections and weekStart the first day of weekly ones; both are only used by PDF
+func NewExportWriter(w io.Writer, format, report string, from, to time.Time, weekStart time.Weekday) (ExportWriter, error) {
+	switch format {
+	case ExportCSV:
+		return newCSVExportWriter(w)
+	case ExportJSON:
+		return newJSONExportWriter(w, from, to)
+	case ExportPDF:
+		return newPDFExportWriter(w, report, from, to, weekStart), nil
+	default:
+		return nil, fmt.Errorf("format must be 'csv', 'json' or 'pdf'")
+	}
+}
+
+// csvExportWriter writes one row per entry followed by a daily total row
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+var csvExportHeader = []string{
+	"row_type", "date", "meal_type", "food_name", "recipe_name", "quantity_grams",
+	"calories", "protein", "carbs", "fat", "fiber",
+	"goal_calories", "goal_protein", "goal_carbs", "goal_fat", "goal_fiber",
+	"adherence_calories", "adherence_protein", "adherence_carbs", "adherence_fat", "adherence_fiber",
+	"notes",
+}
+
+func newCSVExportWriter(w io.Writer) (*csvExportWriter, error) {
+	writer := &csvExportWriter{w: csv.NewWriter(w)}
+	if err := writer.w.Write(csvExportHeader); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (c *csvExportWriter) WriteDay(day DailySummary) error {
+	for _, entry := range day.Entries {
+		row := []string{
+			"entry", day.Date, string(entry.MealType), entry.FoodName, entry.RecipeName, formatNumber(entry.QuantityGrams),
+			formatNumber(entry.Calories), formatNumber(entry.Protein), formatNumber(entry.Carbs), formatNumber(entry.Fat), formatNumber(entry.Fiber),
+			"", "", "", "", "",
+			"", "", "", "", "",
+			entry.Notes,
+		}
+		if err := c.w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	total := []string{
+		"daily_total", day.Date, "", "", "", "",
+		formatNumber(day.TotalCalories), formatNumber(day.TotalProtein), formatNumber(day.TotalCarbs), formatNumber(day.TotalFat), formatNumber(day.TotalFiber),
+		formatNumber(day.GoalCalories), formatNumber(day.GoalProtein), formatNumber(day.GoalCarbs), formatNumber(day.GoalFat), formatNumber(day.GoalFiber),
+		formatNumber(day.Adherence.Calories), formatNumber(day.Adherence.Protein), formatNumber(day.Adherence.Carbs), formatNumber(day.Adherence.Fat), formatNumber(day.Adherence.Fiber),
+		"",
+	}
+	if err := c.w.Write(total); err != nil {
+		return err
+	}
+
+	// Flush each day so the response streams
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvExportWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonExportWriter writes {"from","to","days":[...]} one day at a time
+type jsonExportWriter struct {
+	w     io.Writer
+	first bool
+}
+
+func newJSONExportWriter(w io.Writer, from, to time.Time) (*jsonExportWriter, error) {
+	_, err := fmt.Fprintf(w, `{"from":%q,"to":%q,"days":[`, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	return &jsonExportWriter{w: w, first: true}, nil
+}
+
+func (j *jsonExportWriter) WriteDay(day DailySummary) error {
+	if !j.first {
+		if _, err := io.WriteString(j.w, ","); err != nil {
+			return err
+		}
+	}
+	j.first = false
+
+	encoded, err := json.Marshal(day)
+	if err != nil {
+		return err
+	}
+	_, err = j.w.Write(encoded)
+	return err
+}
+
+func (j *jsonExportWriter) Close() error {
+	_, err := io.WriteString(j.w, "]}\n")
+	return err
+}
+
+// pdfExportWriter lays out a printable report with a table per week or month
+// followed by the entries of each day of that period
+type pdfExportWriter struct {
+	w         io.Writer
+	doc       *pdf.Document
+	report    string
+	weekStart time.Weekday // First day of weekly sections
+	period    string       // Key of the current section
+	days      []DailySummary
+	sections  int
+}
+
+func newPDFExportWriter(w io.Writer, report string, from, to time.Time, weekStart time.Weekday) *pdfExportWriter {
+	doc := pdf.NewDocument(fmt.Sprintf("Nutrition report %s to %s", from.Format("2006-01-02"), to.Format("2006-01-02")))
+	doc.Heading("Nutrition diary report", 16)
+	doc.Text(fmt.Sprintf("Period: %s to %s", from.Format("Mon 02 Jan 2006"), to.Format("Mon 02 Jan 2006")))
+	doc.Text(fmt.Sprintf("Generated: %s", time.Now().Format("02 Jan 2006 15:04")))
+	doc.Text("Adherence is intake as a percentage of the goal in effect that day.")
+
+	if report != ReportMonthly {
+		report = ReportWeekly
+	}
+	return &pdfExportWriter{w: w, doc: doc, report: report, weekStart: weekStart}
+}
+
+// periodOf returns the section key and title of a date
+func (p *pdfExportWriter) periodOf(date time.Time) (string, string) {
+	if p.report == ReportMonthly {
+		return date.Format("2006-01"), date.Format("January 2006")
+	}
+	start := PeriodStart(date, GroupByWeek, p.weekStart)
+	return start.Format("2006-01-02"), "Week of " + start.Format("Mon 02 Jan 2006")
+}
+
+func (p *pdfExportWriter) WriteDay(day DailySummary) error {
+	date, err := time.Parse("2006-01-02", day.Date)
+	if err != nil {
+		return err
+	}
+
+	key, _ := p.periodOf(date)
+	if key != p.period {
+		p.flushSection()
+		p.period = key
+	}
+	p.days = append(p.days, day)
+	return nil
+}
+
+func (p *pdfExportWriter) Close() error {
+	p.flushSection()
+	_, err := p.doc.WriteTo(p.w)
+	return err
+}
+
+// flushSection writes the buffered days of the current week or month
+func (p *pdfExportWriter) flushSection() {
+	if len(p.days) == 0 {
+		return
+	}
+	date, _ := time.Parse("2006-01-02", p.days[0].Date)
+	_, title := p.periodOf(date)
+
+	if p.report == ReportMonthly && p.sections > 0 {
+		p.doc.PageBreak()
+	}
+	p.sections++
+
+	p.doc.Heading(title, 12)
+	p.doc.MonoBold(fmt.Sprintf("%-15s %8s %8s %6s %8s %8s %8s %7s", "Date", "Calories", "Goal", "Adh %", "Protein", "Carbs", "Fat", "Fiber"))
+
+	var logged int
+	var calories, protein, carbs, fat, fiber, adherence float64
+	for _, day := range p.days {
+		date, _ := time.Parse("2006-01-02", day.Date)
+		if len(day.Entries) == 0 {
+			p.doc.Mono(fmt.Sprintf("%-15s %8s", date.Format("Mon 02 Jan"), "-"))
+			continue
+		}
+
+		logged++
+		calories += day.TotalCalories
+		protein += day.TotalProtein
+		carbs += day.TotalCarbs
+		fat += day.TotalFat
+		fiber += day.TotalFiber
+		adherence += day.Adherence.Calories
+
+		p.doc.Mono(fmt.Sprintf("%-15s %8.0f %8s %6s %8.1f %8.1f %8.1f %7.1f",
+			date.Format("Mon 02 Jan"), day.TotalCalories, optionalNumber(day.GoalCalories, "%.0f"),
+			optionalNumber(day.Adherence.Calories, "%.0f"), day.TotalProtein, day.TotalCarbs, day.TotalFat, day.TotalFiber))
+	}
+
+	if logged > 0 {
+		n := float64(logged)
+		p.doc.Rule()
+		p.doc.Mono(fmt.Sprintf("%-15s %8.0f %8s %6s %8.1f %8.1f %8.1f %7.1f",
+			fmt.Sprintf("Average (%d d)", logged), calories/n, "", optionalNumber(adherence/n, "%.0f"),
+			protein/n, carbs/n, fat/n, fiber/n))
+	}
+
+	// Entry details per day
+	for _, day := range p.days {
+		if len(day.Entries) == 0 {
+			continue
+		}
+		date, _ := time.Parse("2006-01-02", day.Date)
+		p.doc.Space(4)
+		p.doc.Text(date.Format("Monday 02 January 2006"))
+		for _, entry := range day.Entries {
+			name := entry.FoodName
+			if name == "" {
+				name = entry.RecipeName
+			}
+			p.doc.Mono(fmt.Sprintf("  %-10s %-34s %7.0fg %6.0f kcal  P %5.1f  C %5.1f  F %5.1f",
+				entry.MealType, truncate(name, 34), entry.QuantityGrams, entry.Calories, entry.Protein, entry.Carbs, entry.Fat))
+		}
+	}
+
+	p.doc.Space(10)
+	p.days = nil
+}
+
+// formatNumber formats a value for CSV without trailing zeros
+func formatNumber(val float64) string {
+	return strconv.FormatFloat(val, 'f', -1, 64)
+}
+
+// optionalNumber formats a value, or "-" when it is zero (no goal)
+func optionalNumber(val float64, format string) string {
+	if val == 0 {
+		return "-"
+	}
+	return fmt.Sprintf(format, val)
+}
+
+// truncate shortens text to at most n characters
+func truncate(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-1]) + "."
+}
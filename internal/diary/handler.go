@@ -4,21 +4,43 @@ import (
 	"ultra-bis/internal/httputil"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/exercise"
+	"ultra-bis/internal/fasting"
 	"ultra-bis/internal/food"
 	"ultra-bis/internal/goal"
+	"ultra-bis/internal/hydration"
+	"ultra-bis/internal/live"
+	"ultra-bis/internal/metrics"
+	"ultra-bis/internal/units"
+	"ultra-bis/internal/webhook"
 )
 
 // Handler handles diary entry requests
 type Handler struct {
-	repo       *Repository
-	foodRepo   *food.Repository
-	goalRepo   *goal.Repository
-	recipeRepo RecipeRepository
+	repo          *Repository
+	foodRepo      *food.Repository
+	goalRepo      *goal.Repository
+	recipeRepo    RecipeRepository
+	metricsRepo   *metrics.Repository
+	hydrationRepo *hydration.Repository
+	fastingRepo   *fasting.Repository
+	exerciseRepo  *exercise.Repository
+	dayObserver   DayObserver
+	webhooks      *webhook.Publisher
+	live          *live.Hub
+}
+
+// DayObserver is notified of the days whose entries or day type changed, e.g. to update streaks
+type DayObserver interface {
+	DayChanged(userID uint, dates ...time.Time) error
 }
 
 // RecipeRepository interface for recipe operations needed by diary
@@ -74,6 +96,47 @@ func (h *Handler) SetRecipeRepo(recipeRepo RecipeRepository) {
 	h.recipeRepo = recipeRepo
 }
 
+// SetMetricsRepo sets the metrics repository used to import weight history
+func (h *Handler) SetMetricsRepo(metricsRepo *metrics.Repository) {
+	h.metricsRepo = metricsRepo
+}
+
+// SetHydrationRepo sets the hydration repository used to add the water log to daily summaries
+func (h *Handler) SetHydrationRepo(hydrationRepo *hydration.Repository) {
+	h.hydrationRepo = hydrationRepo
+}
+
+// SetFastingRepo sets the fasting repository used to compare eating windows with the user's protocol
+func (h *Handler) SetFastingRepo(fastingRepo *fasting.Repository) {
+	h.fastingRepo = fastingRepo
+}
+
+// SetExerciseRepo sets the exercise repository used to adjust daily summaries for the calories burned
+func (h *Handler) SetExerciseRepo(exerciseRepo *exercise.Repository) {
+	h.exerciseRepo = exerciseRepo
+}
+
+// SetDayObserver sets the observer notified when a day's entries change (to avoid circular dependency)
+func (h *Handler) SetDayObserver(observer DayObserver) {
+	h.dayObserver = observer
+}
+
+// SetWebhookPublisher sets the publisher of diary.entry.created events
+func (h *Handler) SetWebhookPublisher(publisher *webhook.Publisher) {
+	h.webhooks = publisher
+}
+
+// notifyDayChanged tells the day observer about changed days; a failure is logged
+// rather than failing a request whose entries were already saved
+func (h *Handler) notifyDayChanged(userID uint, dates ...time.Time) {
+	if h.dayObserver == nil || len(dates) == 0 {
+		return
+	}
+	if err := h.dayObserver.DayChanged(userID, dates...); err != nil {
+		log.Printf("Failed to update streaks for user %d: %v", userID, err)
+	}
+}
+
 
 // CreateEntry handles POST /diary/entries
 func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
@@ -93,6 +156,8 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	prefs := units.FromRequest(r)
+	req.NormalizeUnits(prefs)
 
 	// Validation: Count which entry type is being used
 	entryTypes := 0
@@ -124,7 +189,8 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 	if req.InlineFoodName != "" {
 		// Validate nutrition values (must be non-negative)
 		if req.InlineFoodCalories < 0 || req.InlineFoodProtein < 0 || req.InlineFoodCarbs < 0 ||
-			req.InlineFoodFat < 0 || req.InlineFoodFiber < 0 {
+			req.InlineFoodFat < 0 || req.InlineFoodFiber < 0 || req.InlineFoodWater < 0 ||
+			req.InlineFoodCaffeine < 0 || req.InlineFoodAlcohol < 0 {
 			httputil.WriteError(w, http.StatusBadRequest, "Inline food nutrition values must be non-negative")
 			return
 		}
@@ -167,17 +233,11 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Parse date
-	var entryDate time.Time
-	if req.Date == "" {
-		entryDate = time.Now()
-	} else {
-		var err error
-		entryDate, err = time.Parse("2006-01-02", req.Date)
-		if err != nil {
-			httputil.WriteError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
-			return
-		}
+	// Parse date, defaulting to the day the food was consumed
+	entryDate, err := ResolveEntryDate(req.Date, req.ConsumedAt, calendar.FromRequest(r), time.Now())
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	// Create entry
@@ -186,6 +246,7 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 		FoodID:        req.FoodID,
 		RecipeID:      req.RecipeID,
 		Date:          entryDate,
+		ConsumedAt:    req.ConsumedAt,
 		MealType:      req.MealType,
 		QuantityGrams: req.QuantityGrams,
 		Notes:         req.Notes,
@@ -206,6 +267,7 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 		entry.Fat = foodItem.Fat * multiplier
 		entry.Fiber = foodItem.Fiber * multiplier
 		entry.FoodTag = foodItem.Tag
+		entry.setBeverages(foodItem.Water, foodItem.Caffeine, foodItem.Alcohol, req.QuantityGrams)
 	}
 
 	// Calculate nutrition if recipe_id is provided
@@ -239,7 +301,7 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 			var calcErr error
 			customIngredients, totalCalories, totalProtein, totalCarbs, totalFat, totalFiber, totalWeight, calcErr = h.calculateCustomIngredientsNutrition(req.CustomIngredients)
 			if calcErr != nil {
-				httputil.WriteError(w, http.StatusInternalServerError, "Failed to calculate nutrition: "+calcErr.Error())
+				httputil.WriteErrorf(w, http.StatusInternalServerError, "Failed to calculate nutrition: %v", calcErr)
 				return
 			}
 		} else {
@@ -247,7 +309,7 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 			var calcErr error
 			customIngredients, totalCalories, totalProtein, totalCarbs, totalFat, totalFiber, calcErr = h.convertProportionalToCustomIngredients(int(*req.RecipeID), req.QuantityGrams)
 			if calcErr != nil {
-				httputil.WriteError(w, http.StatusInternalServerError, "Failed to calculate nutrition: "+calcErr.Error())
+				httputil.WriteErrorf(w, http.StatusInternalServerError, "Failed to calculate nutrition: %v", calcErr)
 				return
 			}
 			totalWeight = req.QuantityGrams
@@ -261,6 +323,7 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 		entry.Fiber = roundToTwo(totalFiber)
 		entry.QuantityGrams = roundToTwo(totalWeight)
 		entry.CustomIngredients = customIngredients
+		entry.sumIngredientBeverages()
 	}
 
 	// Handle inline recipes
@@ -268,7 +331,7 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 		// Calculate nutrition with custom quantities
 		customIngredients, totalCalories, totalProtein, totalCarbs, totalFat, totalFiber, totalWeight, calcErr := h.calculateCustomIngredientsNutrition(req.CustomIngredients)
 		if calcErr != nil {
-			httputil.WriteError(w, http.StatusInternalServerError, "Failed to calculate nutrition: "+calcErr.Error())
+			httputil.WriteErrorf(w, http.StatusInternalServerError, "Failed to calculate nutrition: %v", calcErr)
 			return
 		}
 
@@ -280,6 +343,7 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 		entry.Fiber = roundToTwo(totalFiber)
 		entry.QuantityGrams = roundToTwo(totalWeight)
 		entry.CustomIngredients = customIngredients
+		entry.sumIngredientBeverages()
 		entry.InlineRecipeName = &req.InlineRecipeName
 
 		// Determine tag from ingredients
@@ -304,6 +368,11 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 		entry.InlineFoodFat = &req.InlineFoodFat
 		entry.InlineFoodFiber = &req.InlineFoodFiber
 		entry.InlineFoodTag = &tag
+		if req.InlineFoodWater > 0 || req.InlineFoodCaffeine > 0 || req.InlineFoodAlcohol > 0 {
+			entry.InlineFoodWater = &req.InlineFoodWater
+			entry.InlineFoodCaffeine = &req.InlineFoodCaffeine
+			entry.InlineFoodAlcohol = &req.InlineFoodAlcohol
+		}
 
 		if req.InlineFoodDescription != "" {
 			entry.InlineFoodDescription = &req.InlineFoodDescription
@@ -315,6 +384,7 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 		entry.Carbs = roundToTwo(req.InlineFoodCarbs * multiplier)
 		entry.Fat = roundToTwo(req.InlineFoodFat * multiplier)
 		entry.Fiber = roundToTwo(req.InlineFoodFiber * multiplier)
+		entry.setBeverages(req.InlineFoodWater, req.InlineFoodCaffeine, req.InlineFoodAlcohol, req.QuantityGrams)
 		entry.FoodTag = tag // Cache tag for calorie breakdown
 	}
 
@@ -322,7 +392,11 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.notifyDayChanged(userID, entry.Date)
+	h.webhooks.Notify(userID, webhook.EventDiaryEntryCreated, entry)
 
+	h.publishLive(userID, live.EventEntryCreated, entry, entry.Date)
+	entry.ConvertUnits(prefs)
 	httputil.WriteJSON(w, http.StatusCreated, entry)
 }
 
@@ -342,7 +416,7 @@ func (h *Handler) GetEntries(w http.ResponseWriter, r *http.Request) {
 	dateStr := r.URL.Query().Get("date")
 	var entryDate time.Time
 	if dateStr == "" {
-		entryDate = time.Now()
+		entryDate = calendar.FromRequest(r).Today()
 	} else {
 		var err error
 		entryDate, err = time.Parse("2006-01-02", dateStr)
@@ -358,6 +432,7 @@ func (h *Handler) GetEntries(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ConvertEntryUnits(entries, units.FromRequest(r))
 	httputil.WriteJSON(w, http.StatusOK, entries)
 }
 
@@ -376,7 +451,7 @@ func (h *Handler) GetDailySummary(w http.ResponseWriter, r *http.Request) {
 
 	dateStr := strings.TrimPrefix(r.URL.Path, "/diary/summary/")
 	if dateStr == "" {
-		dateStr = time.Now().Format("2006-01-02")
+		dateStr = calendar.FromRequest(r).Today().Format("2006-01-02")
 	}
 
 	entryDate, err := time.Parse("2006-01-02", dateStr)
@@ -402,10 +477,35 @@ func (h *Handler) GetDailySummary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get active goal
-	activeGoal, err := h.goalRepo.GetActive(userID)
+	// Get the calories burned exercising that day
+	var burned exercise.Totals
+	if h.exerciseRepo != nil {
+		burned, err = h.exerciseRepo.GetDayTotals(userID, entryDate)
+		if err != nil {
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	exerciseSummary := NewExerciseSummary(burned)
+
+	// Get the goal in effect that day, with its weekday or day type override,
+	// falling back to the active goal for dates no goal covers
+	activeGoal, err := h.goalRepo.GetForDate(userID, entryDate)
+	if err != nil {
+		activeGoal, err = h.goalRepo.GetActive(userID)
+		if err == nil {
+			dayType, _ := h.goalRepo.GetDayType(userID, entryDate)
+			activeGoal = activeGoal.ResolveForDate(entryDate, dayType)
+		}
+	}
 	var goalCalories, goalProtein, goalCarbs, goalFat, goalFiber float64
+	var meals []MealAdherence
+	var dayGoal *goal.NutritionGoal
 	if err == nil {
+		// Targets grow with the calories burned as the goal's exercise policy allows
+		activeGoal = exerciseSummary.AdjustGoal(activeGoal)
+		dayGoal = activeGoal
+		meals = BuildMealAdherence(entries, activeGoal)
 		goalCalories = activeGoal.Calories
 		goalProtein = activeGoal.Protein
 		goalCarbs = activeGoal.Carbs
@@ -422,6 +522,26 @@ func (h *Handler) GetDailySummary(w http.ResponseWriter, r *http.Request) {
 		Fiber:    calculateAdherence(summary["fiber"], goalFiber),
 	}
 
+	// Add the drinks logged that day to the water of the foods eaten
+	var logged hydration.Totals
+	if h.hydrationRepo != nil {
+		logged, err = h.hydrationRepo.GetDayTotals(userID, entryDate)
+		if err != nil {
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	// Compare the eating window with the user's fasting protocol
+	var protocol *fasting.Protocol
+	if h.fastingRepo != nil {
+		protocol, err = h.fastingRepo.GetProtocol(userID)
+		if err != nil {
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
 	response := DailySummary{
 		Date:          dateStr,
 		TotalCalories: summary["calories"],
@@ -429,19 +549,25 @@ func (h *Handler) GetDailySummary(w http.ResponseWriter, r *http.Request) {
 		TotalCarbs:    summary["carbs"],
 		TotalFat:      summary["fat"],
 		TotalFiber:    summary["fiber"],
+		NetCalories:   roundToTwo(summary["calories"] - exerciseSummary.CaloriesBurned),
 		GoalCalories:  goalCalories,
 		GoalProtein:   goalProtein,
 		GoalCarbs:     goalCarbs,
 		GoalFat:       goalFat,
 		GoalFiber:     goalFiber,
 		Adherence:     adherence,
+		Exercise:      exerciseSummary,
 		RoutineCalories:    routineCalories,
 		ContextualCalories: contextualCalories,
 		RoutinePercent:     routinePercent,
 		ContextualPercent:  contextualPercent,
+		Meals:              meals,
+		Hydration:          BuildHydrationSummary(entries, logged, dayGoal),
+		EatingWindow:       BuildEatingWindow(entries, entryDate, calendar.FromRequest(r).Location, protocol),
 		Entries:       entries,
 	}
 
+	response.ConvertUnits(units.FromRequest(r))
 	httputil.WriteJSON(w, http.StatusOK, response)
 }
 
@@ -469,6 +595,8 @@ func (h *Handler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
 		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	prefs := units.FromRequest(r)
+	req.NormalizeUnits(prefs)
 
 	entry, err := h.repo.GetByID(uint(id), userID)
 	if err != nil {
@@ -490,6 +618,7 @@ func (h *Handler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
 				entry.Fat = roundToTwo(foodItem.Fat * multiplier)
 				entry.Fiber = roundToTwo(foodItem.Fiber * multiplier)
 				entry.FoodTag = foodItem.Tag
+				entry.setBeverages(foodItem.Water, foodItem.Caffeine, foodItem.Alcohol, req.QuantityGrams)
 			}
 		}
 	} else if entry.RecipeID != nil {
@@ -503,7 +632,7 @@ func (h *Handler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
 
 			customIngredients, totalCalories, totalProtein, totalCarbs, totalFat, totalFiber, totalWeight, calcErr := h.calculateCustomIngredientsNutrition(req.CustomIngredients)
 			if calcErr != nil {
-				httputil.WriteError(w, http.StatusInternalServerError, "Failed to calculate nutrition: "+calcErr.Error())
+				httputil.WriteErrorf(w, http.StatusInternalServerError, "Failed to calculate nutrition: %v", calcErr)
 				return
 			}
 
@@ -514,11 +643,12 @@ func (h *Handler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
 			entry.Fiber = roundToTwo(totalFiber)
 			entry.QuantityGrams = roundToTwo(totalWeight)
 			entry.CustomIngredients = customIngredients
+			entry.sumIngredientBeverages()
 		} else if req.QuantityGrams > 0 {
 			// Proportional scaling - convert to custom ingredients
 			customIngredients, totalCalories, totalProtein, totalCarbs, totalFat, totalFiber, calcErr := h.convertProportionalToCustomIngredients(int(*entry.RecipeID), req.QuantityGrams)
 			if calcErr != nil {
-				httputil.WriteError(w, http.StatusInternalServerError, "Failed to calculate nutrition: "+calcErr.Error())
+				httputil.WriteErrorf(w, http.StatusInternalServerError, "Failed to calculate nutrition: %v", calcErr)
 				return
 			}
 
@@ -529,13 +659,14 @@ func (h *Handler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
 			entry.Fiber = roundToTwo(totalFiber)
 			entry.QuantityGrams = req.QuantityGrams
 			entry.CustomIngredients = customIngredients
+			entry.sumIngredientBeverages()
 		}
 	} else if entry.InlineRecipeName != nil {
 		// Inline recipe entry - support custom ingredients update
 		if len(req.CustomIngredients) > 0 {
 			customIngredients, totalCalories, totalProtein, totalCarbs, totalFat, totalFiber, totalWeight, calcErr := h.calculateCustomIngredientsNutrition(req.CustomIngredients)
 			if calcErr != nil {
-				httputil.WriteError(w, http.StatusInternalServerError, "Failed to calculate nutrition: "+calcErr.Error())
+				httputil.WriteErrorf(w, http.StatusInternalServerError, "Failed to calculate nutrition: %v", calcErr)
 				return
 			}
 
@@ -546,6 +677,7 @@ func (h *Handler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
 			entry.Fiber = roundToTwo(totalFiber)
 			entry.QuantityGrams = roundToTwo(totalWeight)
 			entry.CustomIngredients = customIngredients
+			entry.sumIngredientBeverages()
 
 			// Recalculate tag when ingredients change
 			entry.RecipeTag = h.determineInlineRecipeTag(customIngredients)
@@ -575,6 +707,18 @@ func (h *Handler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
 			entry.InlineFoodFiber = req.InlineFoodFiber
 			nutritionUpdated = true
 		}
+		if req.InlineFoodWater != nil {
+			entry.InlineFoodWater = req.InlineFoodWater
+			nutritionUpdated = true
+		}
+		if req.InlineFoodCaffeine != nil {
+			entry.InlineFoodCaffeine = req.InlineFoodCaffeine
+			nutritionUpdated = true
+		}
+		if req.InlineFoodAlcohol != nil {
+			entry.InlineFoodAlcohol = req.InlineFoodAlcohol
+			nutritionUpdated = true
+		}
 		if req.InlineFoodTag != nil {
 			entry.InlineFoodTag = req.InlineFoodTag
 			entry.FoodTag = *req.InlineFoodTag // Update cached tag
@@ -603,6 +747,7 @@ func (h *Handler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
 			entry.Carbs = roundToTwo(*entry.InlineFoodCarbs * multiplier)
 			entry.Fat = roundToTwo(*entry.InlineFoodFat * multiplier)
 			entry.Fiber = roundToTwo(*entry.InlineFoodFiber * multiplier)
+			entry.setBeverages(valueOrZero(entry.InlineFoodWater), valueOrZero(entry.InlineFoodCaffeine), valueOrZero(entry.InlineFoodAlcohol), entry.QuantityGrams)
 		}
 	}
 
@@ -617,12 +762,22 @@ func (h *Handler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
 	if req.Notes != "" {
 		entry.Notes = req.Notes
 	}
+	if req.ConsumedAt != nil {
+		if req.ConsumedAt.After(time.Now().Add(time.Minute)) {
+			httputil.WriteError(w, http.StatusBadRequest, "consumed_at cannot be in the future")
+			return
+		}
+		entry.ConsumedAt = req.ConsumedAt
+	}
 
 	if err := h.repo.Update(entry); err != nil {
 		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.notifyDayChanged(userID, entry.Date)
 
+	h.publishLive(userID, live.EventEntryUpdated, entry, entry.Date)
+	entry.ConvertUnits(prefs)
 	httputil.WriteJSON(w, http.StatusOK, entry)
 }
 
@@ -645,10 +800,18 @@ func (h *Handler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	entry, err := h.repo.GetByID(uint(id), userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
 	if err := h.repo.Delete(uint(id), userID); err != nil {
 		httputil.WriteError(w, http.StatusNotFound, err.Error())
 		return
 	}
+	h.notifyDayChanged(userID, entry.Date)
+	h.publishLive(userID, live.EventEntryDeleted, DeletedEntry{ID: entry.ID, Date: entry.Date.Format("2006-01-02")}, entry.Date)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -716,7 +879,7 @@ func (h *Handler) SaveAsRecipe(w http.ResponseWriter, r *http.Request) {
 	// Create the recipe via recipe repository
 	savedRecipe, err := h.recipeRepo.CreateRecipe(userID, *entry.InlineRecipeName, entry.RecipeTag, ingredients)
 	if err != nil {
-		httputil.WriteError(w, http.StatusInternalServerError, "Failed to save recipe: "+err.Error())
+		httputil.WriteErrorf(w, http.StatusInternalServerError, "Failed to save recipe: %v", err)
 		return
 	}
 
@@ -725,7 +888,7 @@ func (h *Handler) SaveAsRecipe(w http.ResponseWriter, r *http.Request) {
 	entry.InlineRecipeName = nil // Clear inline name since it's now a saved recipe
 
 	if err := h.repo.Update(entry); err != nil {
-		httputil.WriteError(w, http.StatusInternalServerError, "Failed to update diary entry: "+err.Error())
+		httputil.WriteErrorf(w, http.StatusInternalServerError, "Failed to update diary entry: %v", err)
 		return
 	}
 
@@ -796,10 +959,13 @@ func (h *Handler) SaveAsFood(w http.ResponseWriter, r *http.Request) {
 		Carbs:       *entry.InlineFoodCarbs,
 		Fat:         *entry.InlineFoodFat,
 		Fiber:       *entry.InlineFoodFiber,
+		Water:       valueOrZero(entry.InlineFoodWater),
+		Caffeine:    valueOrZero(entry.InlineFoodCaffeine),
+		Alcohol:     valueOrZero(entry.InlineFoodAlcohol),
 		Tag:         tag,
 	})
 	if err != nil {
-		httputil.WriteError(w, http.StatusInternalServerError, "Failed to save food: "+err.Error())
+		httputil.WriteErrorf(w, http.StatusInternalServerError, "Failed to save food: %v", err)
 		return
 	}
 
@@ -813,12 +979,15 @@ func (h *Handler) SaveAsFood(w http.ResponseWriter, r *http.Request) {
 	entry.InlineFoodCarbs = nil
 	entry.InlineFoodFat = nil
 	entry.InlineFoodFiber = nil
+	entry.InlineFoodWater = nil
+	entry.InlineFoodCaffeine = nil
+	entry.InlineFoodAlcohol = nil
 	entry.InlineFoodTag = nil
 
 	// Keep cached nutrition (historical accuracy)
 
 	if err := h.repo.Update(entry); err != nil {
-		httputil.WriteError(w, http.StatusInternalServerError, "Failed to update diary entry: "+err.Error())
+		httputil.WriteErrorf(w, http.StatusInternalServerError, "Failed to update diary entry: %v", err)
 		return
 	}
 
@@ -843,6 +1012,8 @@ func (h *Handler) CreateEntryFromOpenFoodFacts(w http.ResponseWriter, r *http.Re
 		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	prefs := units.FromRequest(r)
+	req.NormalizeUnits(prefs)
 
 	// Validate required fields
 	if req.ProductName == "" {
@@ -870,17 +1041,11 @@ func (h *Handler) CreateEntryFromOpenFoodFacts(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Parse date
-	var entryDate time.Time
-	if req.Date == "" {
-		entryDate = time.Now()
-	} else {
-		var err error
-		entryDate, err = time.Parse("2006-01-02", req.Date)
-		if err != nil {
-			httputil.WriteError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
-			return
-		}
+	// Parse date, defaulting to the day the food was consumed
+	entryDate, err := ResolveEntryDate(req.Date, req.ConsumedAt, calendar.FromRequest(r), time.Now())
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	// Build description from product name and brands
@@ -904,6 +1069,7 @@ func (h *Handler) CreateEntryFromOpenFoodFacts(w http.ResponseWriter, r *http.Re
 		InlineFoodFiber:       &req.Fiber,
 		InlineFoodTag:         &tag,
 		Date:                  entryDate,
+		ConsumedAt:            req.ConsumedAt,
 		MealType:              req.MealType,
 		QuantityGrams:         req.QuantityGrams,
 		Notes:                 req.Notes,
@@ -920,7 +1086,11 @@ func (h *Handler) CreateEntryFromOpenFoodFacts(w http.ResponseWriter, r *http.Re
 		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.notifyDayChanged(userID, entry.Date)
+	h.webhooks.Notify(userID, webhook.EventDiaryEntryCreated, entry)
 
+	h.publishLive(userID, live.EventEntryCreated, entry, entry.Date)
+	entry.ConvertUnits(prefs)
 	httputil.WriteJSON(w, http.StatusCreated, entry)
 }
 
@@ -961,8 +1131,72 @@ func calculateCaloriesByTag(entries []DiaryEntry) (routineCalories, contextualCa
 	return
 }
 
+// DayType handles GET, PUT and DELETE /diary/days/{date}
+// Tags a day as a training, rest or refeed day and returns the goal resolved for it
+func (h *Handler) DayType(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", strings.TrimPrefix(r.URL.Path, "/diary/days/"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req SetDayTypeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if !goal.ValidDayType(req.DayType) {
+			httputil.WriteError(w, http.StatusBadRequest, "day_type must be 'training', 'rest' or 'refeed'")
+			return
+		}
+		if _, err := h.goalRepo.SetDayType(userID, date, req.DayType); err != nil {
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	case http.MethodDelete:
+		if err := h.goalRepo.ClearDayType(userID, date); err != nil {
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	default:
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if r.Method != http.MethodGet {
+		// The goal of the day follows its day type
+		h.notifyDayChanged(userID, date)
+	}
+
+	dayType, err := h.goalRepo.GetDayType(userID, date)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := DayTypeResponse{
+		Date:    date.Format("2006-01-02"),
+		Weekday: strings.ToLower(date.Weekday().String()),
+		DayType: dayType,
+	}
+	if dayGoal, err := h.goalRepo.GetForDate(userID, date); err == nil {
+		response.Goal = dayGoal
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, response)
+}
+
 // GetWeeklySummary handles GET /diary/weekly?start_date=YYYY-MM-DD
-// Returns a 7-element array representing routine calorie achievement for each day (Mon-Sun)
+// Returns a 7-element array representing routine calorie achievement for each day of the week,
+// starting on the user's first day of the week (Monday by default)
 // Values: true (>75% routine), false (≤75% routine), null (no entries)
 func (h *Handler) GetWeeklySummary(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -976,18 +1210,12 @@ func (h *Handler) GetWeeklySummary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse start_date query param or default to current week's Monday
+	// Parse start_date query param or default to the start of the user's current week
 	startDateStr := r.URL.Query().Get("start_date")
 	var startDate time.Time
 	if startDateStr == "" {
-		// Default to current week's Monday
-		now := time.Now()
-		weekday := int(now.Weekday())
-		if weekday == 0 { // Sunday
-			weekday = 7
-		}
-		daysFromMonday := weekday - 1
-		startDate = now.AddDate(0, 0, -daysFromMonday)
+		settings := calendar.FromRequest(r)
+		startDate = settings.StartOfWeek(settings.Today())
 	} else {
 		var err error
 		startDate, err = time.Parse("2006-01-02", startDateStr)
@@ -997,38 +1225,360 @@ func (h *Handler) GetWeeklySummary(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	startDate = time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
+
+	// Aggregate the whole week in one query
+	totals, err := h.repo.GetMealTotalsByDay(userID, startDate, startDate.AddDate(0, 0, 7))
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	dayCalories := make(map[string]float64)
+	dayRoutine := make(map[string]float64)
+	for _, row := range totals {
+		key := row.Day.Format("2006-01-02")
+		dayCalories[key] += row.Calories
+		dayRoutine[key] += row.RoutineCalories
+	}
+
 	// Build weekly achievement array
-	var weeklyAchievements [7]interface{} // Mon-Sun
+	var weeklyAchievements [7]interface{} // One per day from startDate
 
 	for i := 0; i < 7; i++ {
-		currentDate := startDate.AddDate(0, 0, i)
+		key := startDate.AddDate(0, 0, i).Format("2006-01-02")
+		totalCalories := dayCalories[key]
+
+		// Determine achievement value
+		if totalCalories == 0 {
+			weeklyAchievements[i] = nil // No data
+		} else {
+			weeklyAchievements[i] = dayRoutine[key]/totalCalories*100 > 75.0 // >75% = true
+		}
+	}
+
+	// Return simple array
+	httputil.WriteJSON(w, http.StatusOK, weeklyAchievements)
+}
+
+// GetRangeSummary handles GET /diary/summary?from=YYYY-MM-DD&to=YYYY-MM-DD&group_by=day|week|month
+// Returns totals, daily averages, per-meal breakdowns and goal adherence for each period and the whole range
+func (h *Handler) GetRangeSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := time.Parse("2006-01-02", query.Get("from"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "from is required (use YYYY-MM-DD)")
+		return
+	}
+	to, err := time.Parse("2006-01-02", query.Get("to"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "to is required (use YYYY-MM-DD)")
+		return
+	}
+	if to.Before(from) {
+		httputil.WriteError(w, http.StatusBadRequest, "to must not be before from")
+		return
+	}
+	if to.Sub(from) >= MaxSummaryDays*24*time.Hour {
+		httputil.WriteError(w, http.StatusBadRequest, "Summary range is limited to 366 days")
+		return
+	}
+
+	groupBy := query.Get("group_by")
+	if groupBy == "" {
+		groupBy = GroupByDay
+	}
+	if !ValidGroupBy(groupBy) {
+		httputil.WriteError(w, http.StatusBadRequest, "group_by must be 'day', 'week' or 'month'")
+		return
+	}
+
+	totals, err := h.repo.GetMealTotalsByDay(userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	goals, err := h.goalRepo.GetAll(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	dayTypes, err := h.goalRepo.GetDayTypes(userID, from, to)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	mealTimes, err := h.repo.GetMealTimesByDay(userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	settings := calendar.FromRequest(r)
+	summary := BuildRangeSummary(from, to, groupBy, settings.WeekStart, totals, goals, dayTypes)
+	summary.AddMealTiming(mealTimes, settings.Location)
+	summary.ConvertUnits(units.FromRequest(r))
+	httputil.WriteJSON(w, http.StatusOK, summary)
+}
+
+// ExportDiary handles GET /diary/export?from=YYYY-MM-DD&to=YYYY-MM-DD&format=csv|json|pdf&report=weekly|monthly
+// Streams every entry of the range with names, macros, daily totals and adherence to the goal of each day
+func (h *Handler) ExportDiary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := time.Parse("2006-01-02", query.Get("from"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "from is required (use YYYY-MM-DD)")
+		return
+	}
+	to, err := time.Parse("2006-01-02", query.Get("to"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "to is required (use YYYY-MM-DD)")
+		return
+	}
+	if to.Before(from) {
+		httputil.WriteError(w, http.StatusBadRequest, "to must not be before from")
+		return
+	}
+	if to.Sub(from) >= MaxExportDays*24*time.Hour {
+		httputil.WriteError(w, http.StatusBadRequest, "Export range is limited to 366 days")
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = ExportJSON
+	}
+	report := query.Get("report")
+	if report == "" {
+		report = ReportWeekly
+	}
+	if report != ReportWeekly && report != ReportMonthly {
+		httputil.WriteError(w, http.StatusBadRequest, "report must be 'weekly' or 'monthly'")
+		return
+	}
+
+	goals, err := h.goalRepo.GetAll(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	dayTypes, err := h.goalRepo.GetDayTypes(userID, from, to)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
-		// Get entries for this day
-		entries, err := h.repo.GetByDate(userID, currentDate)
+	contentTypes := map[string]string{
+		ExportCSV:  "text/csv",
+		ExportJSON: "application/json",
+		ExportPDF:  "application/pdf",
+	}
+	contentType, ok := contentTypes[format]
+	if !ok {
+		httputil.WriteError(w, http.StatusBadRequest, "format must be 'csv', 'json' or 'pdf'")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"diary-%s-%s.%s\"",
+		from.Format("2006-01-02"), to.Format("2006-01-02"), format))
+
+	writer, err := NewExportWriter(w, format, report, from, to, calendar.FromRequest(r).WeekStart)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Load a month at a time so long ranges are streamed rather than held in memory
+	end := to.AddDate(0, 0, 1)
+	for chunkStart := from; chunkStart.Before(end); chunkStart = chunkStart.AddDate(0, 1, 0) {
+		chunkEnd := chunkStart.AddDate(0, 1, 0)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+
+		entries, err := h.repo.GetByDateRange(userID, chunkStart, chunkEnd)
 		if err != nil {
-			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			log.Printf("Diary export failed for user %d: %v", userID, err)
 			return
 		}
 
-		// Calculate routine percentage using existing helper
-		_, _, routinePercent, _ := calculateCaloriesByTag(entries)
-
-		// Calculate total calories
-		var totalCalories float64
+		byDate := make(map[string][]DiaryEntry)
 		for _, entry := range entries {
-			totalCalories += entry.Calories
+			key := entry.Date.Format("2006-01-02")
+			byDate[key] = append(byDate[key], entry)
 		}
 
-		// Determine achievement value
-		if totalCalories == 0 {
-			weeklyAchievements[i] = nil // No data
+		for day := chunkStart; day.Before(chunkEnd); day = day.AddDate(0, 0, 1) {
+			key := day.Format("2006-01-02")
+			summary := BuildDaySummary(key, byDate[key], EffectiveGoal(goals, dayTypes, day))
+			if err := writer.WriteDay(summary); err != nil {
+				log.Printf("Diary export failed for user %d: %v", userID, err)
+				return
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Printf("Diary export failed for user %d: %v", userID, err)
+	}
+}
+
+// maxImportSize limits the size of uploaded import files
+const maxImportSize = 10 << 20 // 10 MB
+
+// ImportDiary handles POST /diary/import?mode=preview|commit&format=&weight_unit=kg|lb|st
+// Accepts a MyFitnessPal or Cronometer CSV export, either as the raw body or as the
+// "file" field of a multipart form. The default preview mode reports what would be
+// imported; commit creates the new rows and skips duplicates.
+func (h *Handler) ImportDiary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = "preview"
+	}
+	if mode != "preview" && mode != "commit" {
+		httputil.WriteError(w, http.StatusBadRequest, "mode must be 'preview' or 'commit'")
+		return
+	}
+
+	format := ImportFormat(query.Get("format"))
+	if format != "" && format != FormatMyFitnessPal && format != FormatCronometer {
+		httputil.WriteError(w, http.StatusBadRequest, "format must be 'myfitnesspal' or 'cronometer'")
+		return
+	}
+
+	weightUnit := query.Get("weight_unit")
+	if weightUnit == "" {
+		weightUnit = units.FromRequest(r).Weight
+	}
+	if !units.ValidWeight(weightUnit) {
+		httputil.WriteError(w, http.StatusBadRequest, "weight_unit must be 'kg', 'lb' or 'st'")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	body := r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "A CSV file is required in the 'file' field")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	parsed, err := ParseImport(body, format, weightUnit)
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if parsed.Kind == KindWeight && h.metricsRepo == nil {
+		httputil.WriteError(w, http.StatusInternalServerError, "Metrics repository not initialized")
+		return
+	}
+
+	// Flag rows that already exist so a re-import does not double-count
+	existingEntries := []DiaryEntry{}
+	existingWeights := make(map[string]float64)
+	if start, end, ok := parsed.DateRange(); ok {
+		if parsed.Kind == KindNutrition {
+			existingEntries, err = h.repo.GetByDateRange(userID, start, end.AddDate(0, 0, 1))
 		} else {
-			weeklyAchievements[i] = routinePercent > 75.0 // >75% = true
+			var weights []metrics.BodyMetric
+			weights, err = h.metricsRepo.GetByDateRange(userID, start, end.AddDate(0, 0, 1))
+			for _, weight := range weights {
+				existingWeights[weight.Date.Format("2006-01-02")] = weight.Weight
+			}
+		}
+		if err != nil {
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
 		}
 	}
+	parsed.MarkDuplicates(existingEntries, existingWeights)
 
-	// Return simple array
-	httputil.WriteJSON(w, http.StatusOK, weeklyAchievements)
+	response := ImportResponse{ParsedImport: *parsed, Invalid: len(parsed.Errors)}
+	var newEntries []DiaryEntry
+	var newWeights []metrics.BodyMetric
+
+	for _, imported := range parsed.Entries {
+		if imported.Status != ImportStatusNew {
+			response.Duplicates++
+			continue
+		}
+		response.New++
+		newEntries = append(newEntries, imported.toDiaryEntry(userID))
+	}
+
+	for _, imported := range parsed.Weights {
+		if imported.Status != ImportStatusNew {
+			response.Duplicates++
+			continue
+		}
+		response.New++
+		date, _ := time.Parse("2006-01-02", imported.Date)
+		newWeights = append(newWeights, metrics.BodyMetric{UserID: userID, Date: date, Weight: imported.Weight})
+	}
+
+	if mode == "commit" {
+		if err := h.repo.CreateBatch(newEntries); err != nil {
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		importedDates := make([]time.Time, len(newEntries))
+		for i, entry := range newEntries {
+			importedDates[i] = entry.Date
+		}
+		h.notifyDayChanged(userID, importedDates...)
+		h.live.Publish(userID, live.EventEntriesImported, EntriesImported{Entries: len(newEntries), Dates: importedDays(newEntries)})
+		for i := range newWeights {
+			if err := h.metricsRepo.Create(&newWeights[i]); err != nil {
+				httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		response.Committed = true
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, response)
 }
 
 // roundToTwo rounds a float to 2 decimal places
@@ -1104,6 +1654,9 @@ func (h *Handler) calculateCustomIngredientsNutrition(customIngredients []Custom
 			Carbs:         roundToTwo(ingredientCarbs),
 			Fat:           roundToTwo(ingredientFat),
 			Fiber:         roundToTwo(ingredientFiber),
+			Water:         roundToTwo(foodItem.Water * multiplier),
+			Caffeine:      roundToTwo(foodItem.Caffeine * multiplier),
+			Alcohol:       roundToTwo(foodItem.Alcohol * multiplier),
 		})
 
 		// Accumulate totals
@@ -0,0 +1,63 @@
+package diary
+
+import (
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/hydration"
+)
+
+// setBeverages caches the water, caffeine and alcohol of the entry from the food's values per 100g
+func (e *DiaryEntry) setBeverages(water, caffeine, alcohol, quantityGrams float64) {
+	multiplier := quantityGrams / 100.0
+	e.Water = roundToTwo(water * multiplier)
+	e.Caffeine = roundToTwo(caffeine * multiplier)
+	e.Alcohol = roundToTwo(alcohol * multiplier)
+}
+
+// sumIngredientBeverages caches the water, caffeine and alcohol of the entry's custom ingredients
+func (e *DiaryEntry) sumIngredientBeverages() {
+	var water, caffeine, alcohol float64
+	for _, ingredient := range e.CustomIngredients {
+		water += ingredient.Water
+		caffeine += ingredient.Caffeine
+		alcohol += ingredient.Alcohol
+	}
+	e.Water = roundToTwo(water)
+	e.Caffeine = roundToTwo(caffeine)
+	e.Alcohol = roundToTwo(alcohol)
+}
+
+// BuildHydrationSummary adds the drinks logged on a day to the water, caffeine and alcohol of its
+// entries and compares the water to the hydration target of the day's goal (nil without a goal)
+func BuildHydrationSummary(entries []DiaryEntry, logged hydration.Totals, dayGoal *goal.NutritionGoal) HydrationSummary {
+	summary := HydrationSummary{
+		WaterLogged:   logged.Water,
+		TotalCaffeine: logged.Caffeine,
+		TotalAlcohol:  logged.Alcohol,
+	}
+	for _, entry := range entries {
+		summary.WaterFromFoods += entry.Water
+		summary.TotalCaffeine += entry.Caffeine
+		summary.TotalAlcohol += entry.Alcohol
+	}
+	summary.TotalWater = summary.WaterLogged + summary.WaterFromFoods
+
+	if dayGoal != nil {
+		summary.GoalWater = dayGoal.Water
+		summary.Adherence = roundToTwo(calculateAdherence(summary.TotalWater, dayGoal.Water))
+	}
+
+	summary.TotalWater = roundToTwo(summary.TotalWater)
+	summary.WaterLogged = roundToTwo(summary.WaterLogged)
+	summary.WaterFromFoods = roundToTwo(summary.WaterFromFoods)
+	summary.TotalCaffeine = roundToTwo(summary.TotalCaffeine)
+	summary.TotalAlcohol = roundToTwo(summary.TotalAlcohol)
+	return summary
+}
+
+// valueOrZero returns the value of an optional inline food field
+func valueOrZero(value *float64) float64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
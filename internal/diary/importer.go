@@ -0,0 +1,439 @@
+package diary
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"ultra-bis/internal/csvimport"
+	"ultra-bis/internal/units"
+)
+
+// ImportFormat identifies the app a CSV export comes from
+type ImportFormat string
+
+const (
+	FormatMyFitnessPal ImportFormat = "myfitnesspal"
+	FormatCronometer   ImportFormat = "cronometer"
+)
+
+// ImportKind identifies what a CSV export contains
+type ImportKind string
+
+const (
+	KindNutrition ImportKind = "nutrition" // Food or meal rows, imported as diary entries
+	KindWeight    ImportKind = "weight"    // Weigh-ins, imported as body metrics
+)
+
+// Import row statuses
+const (
+	ImportStatusNew       = "new"       // Will be (or was) imported
+	ImportStatusDuplicate = "duplicate" // Exact copy of existing data or of an earlier row, skipped
+	ImportStatusConflict  = "conflict"  // A different weight is already logged that day, skipped
+)
+
+// ImportedEntry is a diary row parsed from an export
+// Nutrition values are totals for the row
+type ImportedEntry struct {
+	Line          int      `json:"line"`
+	Date          string   `json:"date"`
+	MealType      MealType `json:"meal_type"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description,omitempty"`
+	QuantityGrams float64  `json:"quantity_grams"`
+	Calories      float64  `json:"calories"`
+	Protein       float64  `json:"protein"`
+	Carbs         float64  `json:"carbs"`
+	Fat           float64  `json:"fat"`
+	Fiber         float64  `json:"fiber"`
+	Status        string   `json:"status"`
+}
+
+// ImportedWeight is a weigh-in parsed from an export
+type ImportedWeight struct {
+	Line   int     `json:"line"`
+	Date   string  `json:"date"`
+	Weight float64 `json:"weight"` // in kg
+	Status string  `json:"status"`
+}
+
+// ImportRowError reports a row that could not be parsed
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ParsedImport is the content of an export file
+type ParsedImport struct {
+	Format  ImportFormat     `json:"format"`
+	Kind    ImportKind       `json:"kind"`
+	Entries []ImportedEntry  `json:"entries,omitempty"`
+	Weights []ImportedWeight `json:"weights,omitempty"`
+	Errors  []ImportRowError `json:"errors,omitempty"`
+}
+
+// ImportResponse is returned by POST /diary/import
+type ImportResponse struct {
+	ParsedImport
+	Committed  bool `json:"committed"`  // False for a preview
+	New        int  `json:"new"`        // Rows imported (or to be imported)
+	Duplicates int  `json:"duplicates"` // Rows skipped as duplicates or conflicts
+	Invalid    int  `json:"invalid"`    // Rows that could not be parsed
+}
+
+// ParseImport reads a MyFitnessPal or Cronometer CSV export
+// format may be empty to detect it from the header; weightUnit ("kg", "lb" or "st") applies
+// to weight exports that do not state their unit
+func ParseImport(r io.Reader, format ImportFormat, weightUnit string) (*ParsedImport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := csvimport.IndexColumns(header)
+
+	detectedFormat, kind, err := detectImport(columns)
+	if err != nil {
+		return nil, err
+	}
+	if format != "" && format != detectedFormat {
+		return nil, fmt.Errorf("file looks like a %s export, not %s", detectedFormat, format)
+	}
+
+	result := &ParsedImport{Format: detectedFormat, Kind: kind}
+
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			result.Errors = append(result.Errors, ImportRowError{Line: line, Error: err.Error()})
+			continue
+		}
+		if csvimport.IsBlankRecord(record) {
+			continue
+		}
+
+		row := csvimport.NewRow(columns, record)
+		switch {
+		case detectedFormat == FormatMyFitnessPal && kind == KindNutrition:
+			err = result.addMyFitnessPalMeal(line, row)
+		case detectedFormat == FormatCronometer && kind == KindNutrition:
+			err = result.addCronometerServing(line, row)
+		case detectedFormat == FormatMyFitnessPal && kind == KindWeight:
+			err = result.addMyFitnessPalWeight(line, row, weightUnit)
+		default:
+			err = result.addCronometerBiometric(line, row)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, ImportRowError{Line: line, Error: err.Error()})
+		}
+	}
+
+	return result, nil
+}
+
+// MapMealType maps a meal or group name from another app to a MealType
+// Unrecognised names (e.g. "Snacks", "Uncategorized") become snacks
+func MapMealType(name string) MealType {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "breakfast"):
+		return Breakfast
+	case strings.Contains(name, "lunch"):
+		return Lunch
+	case strings.Contains(name, "dinner"), strings.Contains(name, "supper"):
+		return Dinner
+	default:
+		return Snack
+	}
+}
+
+// detectImport identifies the export from its header
+func detectImport(columns map[string]int) (ImportFormat, ImportKind, error) {
+	has := func(names ...string) bool {
+		for _, name := range names {
+			if _, ok := columns[name]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case has("day", "food name", "energy (kcal)"):
+		return FormatCronometer, KindNutrition, nil
+	case has("day", "metric", "unit", "amount"):
+		return FormatCronometer, KindWeight, nil
+	case has("date", "meal", "calories"):
+		return FormatMyFitnessPal, KindNutrition, nil
+	case has("date", "weight"):
+		return FormatMyFitnessPal, KindWeight, nil
+	default:
+		return "", "", fmt.Errorf("unrecognised CSV export: expected a MyFitnessPal nutrition or measurement export, or a Cronometer servings or biometrics export")
+	}
+}
+
+// addMyFitnessPalMeal parses a MyFitnessPal "Nutrition" row (one row per meal and day)
+func (p *ParsedImport) addMyFitnessPalMeal(line int, row csvimport.Row) error {
+	date, err := row.Date("date")
+	if err != nil {
+		return err
+	}
+	meal := row.Text("meal")
+
+	entry := ImportedEntry{
+		Line:          line,
+		Date:          date,
+		MealType:      MapMealType(meal),
+		Name:          fmt.Sprintf("%s (MyFitnessPal)", strings.TrimSpace(meal)),
+		Description:   row.Text("note"),
+		QuantityGrams: 100,
+		Calories:      row.Number("calories"),
+		Protein:       row.Number("protein (g)"),
+		Carbs:         row.Number("carbohydrates (g)"),
+		Fat:           row.Number("fat (g)"),
+		Fiber:         row.Number("fiber"),
+	}
+	return p.addEntry(entry)
+}
+
+// addCronometerServing parses a Cronometer "Servings" row (one row per food)
+func (p *ParsedImport) addCronometerServing(line int, row csvimport.Row) error {
+	date, err := row.Date("day")
+	if err != nil {
+		return err
+	}
+
+	name := row.Text("food name")
+	if name == "" {
+		return fmt.Errorf("food name is empty")
+	}
+
+	amount := row.Text("amount")
+	entry := ImportedEntry{
+		Line:          line,
+		Date:          date,
+		MealType:      MapMealType(row.Text("group")),
+		Name:          name,
+		Description:   amount,
+		QuantityGrams: gramsFromAmount(amount),
+		Calories:      row.Number("energy (kcal)"),
+		Protein:       row.Number("protein (g)"),
+		Carbs:         row.Number("carbs (g)"),
+		Fat:           row.Number("fat (g)"),
+		Fiber:         row.Number("fiber (g)"),
+	}
+	return p.addEntry(entry)
+}
+
+// addMyFitnessPalWeight parses a MyFitnessPal "Measurement" row
+func (p *ParsedImport) addMyFitnessPalWeight(line int, row csvimport.Row, weightUnit string) error {
+	date, err := row.Date("date")
+	if err != nil {
+		return err
+	}
+	return p.addWeight(line, date, row.Number("weight"), weightUnit)
+}
+
+// addCronometerBiometric parses a Cronometer "Biometrics" row; metrics other than weight are ignored
+func (p *ParsedImport) addCronometerBiometric(line int, row csvimport.Row) error {
+	if !strings.EqualFold(row.Text("metric"), "weight") {
+		return nil
+	}
+
+	date, err := row.Date("day")
+	if err != nil {
+		return err
+	}
+	return p.addWeight(line, date, row.Number("amount"), row.Text("unit"))
+}
+
+// addEntry validates and records a parsed diary row
+func (p *ParsedImport) addEntry(entry ImportedEntry) error {
+	if entry.Calories < 0 || entry.Protein < 0 || entry.Carbs < 0 || entry.Fat < 0 || entry.Fiber < 0 {
+		return fmt.Errorf("nutrition values must be non-negative")
+	}
+	if entry.Calories == 0 && entry.Protein == 0 && entry.Carbs == 0 && entry.Fat == 0 {
+		return fmt.Errorf("row has no nutrition values")
+	}
+
+	entry.Calories = roundImported(entry.Calories)
+	entry.Protein = roundImported(entry.Protein)
+	entry.Carbs = roundImported(entry.Carbs)
+	entry.Fat = roundImported(entry.Fat)
+	entry.Fiber = roundImported(entry.Fiber)
+	entry.Status = ImportStatusNew
+	p.Entries = append(p.Entries, entry)
+	return nil
+}
+
+// toDiaryEntry converts an imported row to an inline-food diary entry
+// Inline food values are per 100g, so they are scaled from the row totals
+func (e ImportedEntry) toDiaryEntry(userID uint) DiaryEntry {
+	date, _ := time.Parse("2006-01-02", e.Date)
+	per100g := 100 / e.QuantityGrams
+	name := e.Name
+	tag := "routine"
+
+	calories := roundImported(e.Calories * per100g)
+	protein := roundImported(e.Protein * per100g)
+	carbs := roundImported(e.Carbs * per100g)
+	fat := roundImported(e.Fat * per100g)
+	fiber := roundImported(e.Fiber * per100g)
+
+	entry := DiaryEntry{
+		UserID:             userID,
+		Date:               date,
+		MealType:           e.MealType,
+		QuantityGrams:      e.QuantityGrams,
+		InlineFoodName:     &name,
+		InlineFoodCalories: &calories,
+		InlineFoodProtein:  &protein,
+		InlineFoodCarbs:    &carbs,
+		InlineFoodFat:      &fat,
+		InlineFoodFiber:    &fiber,
+		InlineFoodTag:      &tag,
+		FoodTag:            tag,
+		Calories:           e.Calories,
+		Protein:            e.Protein,
+		Carbs:              e.Carbs,
+		Fat:                e.Fat,
+		Fiber:              e.Fiber,
+	}
+	if e.Description != "" {
+		description := e.Description
+		entry.InlineFoodDescription = &description
+	}
+	return entry
+}
+
+// addWeight validates, converts to kg and records a weigh-in
+func (p *ParsedImport) addWeight(line int, date string, weight float64, unit string) error {
+	if weight <= 0 {
+		return fmt.Errorf("weight must be greater than 0")
+	}
+
+	switch strings.ToLower(strings.TrimSpace(unit)) {
+	case "", "kg", "kgs":
+		unit = units.Kilogram
+	case "lb", "lbs", "pound", "pounds":
+		unit = units.Pound
+	case "st":
+		unit = units.Stone
+	default:
+		return fmt.Errorf("unsupported weight unit: %s", unit)
+	}
+	weight = units.Preferences{Weight: unit}.WeightToKg(weight)
+
+	p.Weights = append(p.Weights, ImportedWeight{
+		Line:   line,
+		Date:   date,
+		Weight: roundImported(weight),
+		Status: ImportStatusNew,
+	})
+	return nil
+}
+
+// DateRange returns the first and last dates (YYYY-MM-DD) found in the import
+func (p *ParsedImport) DateRange() (time.Time, time.Time, bool) {
+	var dates []string
+	for _, entry := range p.Entries {
+		dates = append(dates, entry.Date)
+	}
+	for _, weight := range p.Weights {
+		dates = append(dates, weight.Date)
+	}
+	if len(dates) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+
+	first, last := dates[0], dates[0]
+	for _, date := range dates[1:] {
+		if date < first {
+			first = date
+		}
+		if date > last {
+			last = date
+		}
+	}
+
+	start, _ := time.Parse("2006-01-02", first)
+	end, _ := time.Parse("2006-01-02", last)
+	return start, end, true
+}
+
+// MarkDuplicates flags rows matching existing data or an earlier row of the same file
+// An entry is a duplicate when date, meal, name and macros are identical; a weight when
+// the same weight is logged that day (a different weight is a conflict)
+func (p *ParsedImport) MarkDuplicates(existingEntries []DiaryEntry, existingWeights map[string]float64) {
+	seen := make(map[string]bool)
+	for _, entry := range existingEntries {
+		if entry.InlineFoodName == nil {
+			continue
+		}
+		seen[entryKey(entry.Date.Format("2006-01-02"), entry.MealType, *entry.InlineFoodName,
+			entry.Calories, entry.Protein, entry.Carbs, entry.Fat)] = true
+	}
+
+	for i := range p.Entries {
+		entry := &p.Entries[i]
+		key := entryKey(entry.Date, entry.MealType, entry.Name, entry.Calories, entry.Protein, entry.Carbs, entry.Fat)
+		if seen[key] {
+			entry.Status = ImportStatusDuplicate
+			continue
+		}
+		seen[key] = true
+	}
+
+	weights := make(map[string]float64, len(existingWeights))
+	for date, weight := range existingWeights {
+		weights[date] = weight
+	}
+	for i := range p.Weights {
+		weight := &p.Weights[i]
+		existing, ok := weights[weight.Date]
+		switch {
+		case !ok:
+			weights[weight.Date] = weight.Weight
+		case roundImported(existing) == weight.Weight:
+			weight.Status = ImportStatusDuplicate
+		default:
+			weight.Status = ImportStatusConflict
+		}
+	}
+}
+
+// entryKey builds the key used to detect duplicate diary rows
+func entryKey(date string, meal MealType, name string, calories, protein, carbs, fat float64) string {
+	return fmt.Sprintf("%s|%s|%s|%.2f|%.2f|%.2f|%.2f", date, meal, strings.ToLower(name),
+		roundImported(calories), roundImported(protein), roundImported(carbs), roundImported(fat))
+}
+
+// gramsFromAmount returns the grams of a Cronometer amount such as "150.00 g",
+// or 100 when the amount is in another unit (nutrition is then kept as a total)
+func gramsFromAmount(amount string) float64 {
+	fields := strings.Fields(strings.ToLower(amount))
+	if len(fields) == 2 && (fields[1] == "g" || fields[1] == "gram" || fields[1] == "grams") {
+		if grams, err := strconv.ParseFloat(fields[0], 64); err == nil && grams > 0 {
+			return grams
+		}
+	}
+	return 100
+}
+
+// roundImported rounds to 2 decimal places; unlike roundToTwo it rounds to nearest,
+// so values read from a file (e.g. 80.1) are kept as written
+func roundImported(val float64) float64 {
+	return math.Round(val*100) / 100
+}
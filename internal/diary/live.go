@@ -0,0 +1,107 @@
+package diary
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"ultra-bis/internal/live"
+	"ultra-bis/internal/units"
+)
+
+// DayTotals is the data of a diary.summary.updated event: a day's totals after its entries changed
+type DayTotals struct {
+	Date          string  `json:"date"`
+	TotalCalories float64 `json:"total_calories"`
+	TotalProtein  float64 `json:"total_protein"`
+	TotalCarbs    float64 `json:"total_carbs"`
+	TotalFat      float64 `json:"total_fat"`
+	TotalFiber    float64 `json:"total_fiber"`
+}
+
+// DeletedEntry is the data of a diary.entry.deleted event
+type DeletedEntry struct {
+	ID   uint   `json:"id"`
+	Date string `json:"date"`
+}
+
+// EntriesImported is the data of a diary.entries.imported event; clients reload the imported days
+type EntriesImported struct {
+	Entries int      `json:"entries"`
+	Dates   []string `json:"dates"`
+}
+
+// ConvertUnits converts the totals' energy to prefs
+func (t *DayTotals) ConvertUnits(prefs units.Preferences) {
+	t.TotalCalories = prefs.EnergyFromKcal(t.TotalCalories)
+}
+
+// SetLiveHub sets the hub pushing entry changes and day totals to the user's event streams
+func (h *Handler) SetLiveHub(hub *live.Hub) {
+	h.live = hub
+}
+
+// LiveConverters convert the diary's live events to the units of each stream
+var LiveConverters = map[string]live.Converter{
+	live.EventEntryCreated:   convertLiveEntry,
+	live.EventEntryUpdated:   convertLiveEntry,
+	live.EventSummaryUpdated: convertLiveTotals,
+}
+
+// convertLiveEntry converts an entry event to prefs
+func convertLiveEntry(data json.RawMessage, prefs units.Preferences) (interface{}, error) {
+	var entry DiaryEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	entry.ConvertUnits(prefs)
+	return entry, nil
+}
+
+// convertLiveTotals converts a day totals event to prefs
+func convertLiveTotals(data json.RawMessage, prefs units.Preferences) (interface{}, error) {
+	var totals DayTotals
+	if err := json.Unmarshal(data, &totals); err != nil {
+		return nil, err
+	}
+	totals.ConvertUnits(prefs)
+	return totals, nil
+}
+
+// publishLive pushes an entry change, then the new totals of its day, to the user's event streams
+// Values are published in metric units and kcal; each stream converts them to its own units
+func (h *Handler) publishLive(userID uint, eventType string, data interface{}, date time.Time) {
+	if h.live == nil {
+		return
+	}
+	h.live.Publish(userID, eventType, data)
+
+	summary, err := h.repo.GetDailySummary(userID, date)
+	if err != nil {
+		log.Printf("Failed to compute live totals for user %d: %v", userID, err)
+		return
+	}
+	totals := DayTotals{
+		Date:          date.Format("2006-01-02"),
+		TotalCalories: summary["calories"],
+		TotalProtein:  summary["protein"],
+		TotalCarbs:    summary["carbs"],
+		TotalFat:      summary["fat"],
+		TotalFiber:    summary["fiber"],
+	}
+	h.live.Publish(userID, live.EventSummaryUpdated, totals)
+}
+
+// importedDays lists the distinct days of imported entries, in import order
+func importedDays(entries []DiaryEntry) []string {
+	seen := make(map[string]bool)
+	var days []string
+	for _, entry := range entries {
+		day := entry.Date.Format("2006-01-02")
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	return days
+}
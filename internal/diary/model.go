@@ -7,6 +7,9 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/units"
 )
 
 // MealType represents the type of meal
@@ -24,11 +27,15 @@ type CustomIngredient struct {
 	FoodID        uint    `json:"food_id"`
 	FoodName      string  `json:"food_name,omitempty"`
 	QuantityGrams float64 `json:"quantity_grams"`
+	Quantity      float64 `json:"quantity,omitempty"` // In the user's quantity unit, set in responses only
 	Calories      float64 `json:"calories"`
 	Protein       float64 `json:"protein"`
 	Carbs         float64 `json:"carbs"`
 	Fat           float64 `json:"fat"`
 	Fiber         float64 `json:"fiber"`
+	Water         float64 `json:"water,omitempty"`    // ml
+	Caffeine      float64 `json:"caffeine,omitempty"` // mg
+	Alcohol       float64 `json:"alcohol,omitempty"`  // g
 }
 
 // CustomIngredients is a custom type for JSONB storage
@@ -76,9 +83,13 @@ type DiaryEntry struct {
 	InlineFoodCarbs       *float64 `json:"inline_food_carbs,omitempty" gorm:"type:decimal(10,2)"`
 	InlineFoodFat         *float64 `json:"inline_food_fat,omitempty" gorm:"type:decimal(10,2)"`
 	InlineFoodFiber       *float64 `json:"inline_food_fiber,omitempty" gorm:"type:decimal(10,2)"`
+	InlineFoodWater       *float64 `json:"inline_food_water,omitempty" gorm:"type:decimal(10,2)"`
+	InlineFoodCaffeine    *float64 `json:"inline_food_caffeine,omitempty" gorm:"type:decimal(10,2)"`
+	InlineFoodAlcohol     *float64 `json:"inline_food_alcohol,omitempty" gorm:"type:decimal(10,2)"`
 	InlineFoodTag         *string  `json:"inline_food_tag,omitempty" gorm:"type:varchar(20)"`
 
 	Date             time.Time      `json:"date" gorm:"not null;index:idx_user_date"`
+	ConsumedAt    *time.Time     `json:"consumed_at,omitempty"` // When the food was eaten, for meal timing
 	MealType      MealType       `json:"meal_type" gorm:"type:varchar(20);not null"`
 	QuantityGrams float64        `json:"quantity_grams" gorm:"type:decimal(10,2);not null"` // Grams consumed
 	Notes         string         `json:"notes" gorm:"type:text"`
@@ -90,6 +101,11 @@ type DiaryEntry struct {
 	Fat      float64 `json:"fat" gorm:"type:decimal(10,2)"`
 	Fiber    float64 `json:"fiber" gorm:"type:decimal(10,2)"`
 
+	// Cached beverage values, from the water content, caffeine and alcohol the foods declare
+	Water    float64 `json:"water" gorm:"type:decimal(10,2)"`    // ml
+	Caffeine float64 `json:"caffeine" gorm:"type:decimal(10,2)"` // mg
+	Alcohol  float64 `json:"alcohol" gorm:"type:decimal(10,2)"`  // g
+
 	// Cached tag values (for historical accuracy)
 	FoodTag   string `json:"food_tag,omitempty" gorm:"type:varchar(20)"`
 	RecipeTag string `json:"recipe_tag,omitempty" gorm:"type:varchar(20)"`
@@ -100,12 +116,17 @@ type DiaryEntry struct {
 	// Additional fields for display (not persisted)
 	FoodName   string `json:"food_name,omitempty" gorm:"-"`
 	RecipeName string `json:"recipe_name,omitempty" gorm:"-"`
+
+	// Quantity in the user's unit and the units of the response values (not persisted)
+	Quantity float64            `json:"quantity" gorm:"-"`
+	Units    *units.Preferences `json:"units,omitempty" gorm:"-"`
 }
 
 // CustomIngredientRequest represents a custom ingredient quantity in the request
 type CustomIngredientRequest struct {
 	FoodID        uint    `json:"food_id"`
 	QuantityGrams float64 `json:"quantity_grams"`
+	Quantity      float64 `json:"quantity"` // In the user's quantity unit, instead of quantity_grams
 }
 
 // CreateDiaryEntryRequest represents the request to create a diary entry
@@ -122,11 +143,16 @@ type CreateDiaryEntryRequest struct {
 	InlineFoodCarbs       float64 `json:"inline_food_carbs"`
 	InlineFoodFat         float64 `json:"inline_food_fat"`
 	InlineFoodFiber       float64 `json:"inline_food_fiber"`
+	InlineFoodWater       float64 `json:"inline_food_water,omitempty"`    // ml per 100g
+	InlineFoodCaffeine    float64 `json:"inline_food_caffeine,omitempty"` // mg per 100g
+	InlineFoodAlcohol     float64 `json:"inline_food_alcohol,omitempty"`  // g per 100g
 	InlineFoodTag         string  `json:"inline_food_tag,omitempty"`
 
 	Date              string                     `json:"date"` // YYYY-MM-DD format
+	ConsumedAt        *time.Time                 `json:"consumed_at"` // Optional; sets date when date is empty
 	MealType          MealType                   `json:"meal_type"`
 	QuantityGrams     float64                    `json:"quantity_grams"`      // For food entries or proportional recipe scaling
+	Quantity          float64                    `json:"quantity"`            // In the user's quantity unit, instead of quantity_grams
 	CustomIngredients []CustomIngredientRequest  `json:"custom_ingredients"`  // For custom recipe ingredient quantities
 	Notes             string                     `json:"notes"`
 }
@@ -143,12 +169,17 @@ type UpdateDiaryEntryRequest struct {
 	InlineFoodCarbs       *float64 `json:"inline_food_carbs,omitempty"`
 	InlineFoodFat         *float64 `json:"inline_food_fat,omitempty"`
 	InlineFoodFiber       *float64 `json:"inline_food_fiber,omitempty"`
+	InlineFoodWater       *float64 `json:"inline_food_water,omitempty"`
+	InlineFoodCaffeine    *float64 `json:"inline_food_caffeine,omitempty"`
+	InlineFoodAlcohol     *float64 `json:"inline_food_alcohol,omitempty"`
 	InlineFoodTag         *string  `json:"inline_food_tag,omitempty"`
 
 	QuantityGrams     float64                    `json:"quantity_grams"`
+	Quantity          float64                    `json:"quantity"`            // In the user's quantity unit, instead of quantity_grams
 	CustomIngredients []CustomIngredientRequest  `json:"custom_ingredients"`  // For updating recipe ingredient quantities
 	MealType          MealType                   `json:"meal_type"`
 	Notes             string                     `json:"notes"`
+	ConsumedAt        *time.Time                 `json:"consumed_at,omitempty"`
 }
 
 // CreateEntryFromOpenFoodFactsRequest represents creating diary entry from Open Food Facts product
@@ -161,8 +192,10 @@ type CreateEntryFromOpenFoodFactsRequest struct {
 	Fat           float64  `json:"fat"`              // per 100g
 	Fiber         float64  `json:"fiber"`            // per 100g
 	Date          string   `json:"date"`             // YYYY-MM-DD
+	ConsumedAt    *time.Time `json:"consumed_at"`    // Optional; sets date when date is empty
 	MealType      MealType `json:"meal_type"`
 	QuantityGrams float64  `json:"quantity_grams"`
+	Quantity      float64  `json:"quantity"`         // In the user's quantity unit, instead of quantity_grams
 	Notes         string   `json:"notes"`
 	Tag           string   `json:"tag"`              // Optional: "routine" or "contextual"
 }
@@ -175,20 +208,90 @@ type DailySummary struct {
 	TotalCarbs    float64          `json:"total_carbs"`
 	TotalFat      float64          `json:"total_fat"`
 	TotalFiber    float64          `json:"total_fiber"`
-	GoalCalories  float64          `json:"goal_calories"`
+	NetCalories   float64          `json:"net_calories"`  // Intake minus the calories burned exercising
+	GoalCalories  float64          `json:"goal_calories"` // Goals include the exercise adjustment
 	GoalProtein   float64          `json:"goal_protein"`
 	GoalCarbs     float64          `json:"goal_carbs"`
 	GoalFat       float64          `json:"goal_fat"`
 	GoalFiber     float64          `json:"goal_fiber"`
 	Adherence     AdherencePercent `json:"adherence"`
 
+	// Calories burned exercising and what the goal's exercise policy added to the targets
+	Exercise ExerciseSummary `json:"exercise"`
+
 	// Calorie breakdown by tag type
 	RoutineCalories    float64 `json:"routine_calories"`
 	ContextualCalories float64 `json:"contextual_calories"`
 	RoutinePercent     float64 `json:"routine_percent"`     // Percentage of calories from routine foods
 	ContextualPercent  float64 `json:"contextual_percent"`  // Percentage of calories from contextual foods
 
+	// Adherence to the goal's per-meal targets, one item per targeted meal
+	Meals []MealAdherence `json:"meals,omitempty"`
+
+	// Water drunk and from foods against the hydration goal, with caffeine and alcohol
+	Hydration HydrationSummary `json:"hydration"`
+
+	// First and last meal times of the entries with a consumed_at, nil when none has one
+	EatingWindow *EatingWindow `json:"eating_window,omitempty"`
+
 	Entries       []DiaryEntry     `json:"entries"`
+
+	Units *units.Preferences `json:"units,omitempty"` // Units of the response values
+}
+
+// ExerciseSummary represents a day's exercise and its adjustment of the calorie target
+type ExerciseSummary struct {
+	CaloriesBurned   float64 `json:"calories_burned"`
+	Minutes          float64 `json:"minutes"`
+	Policy           string  `json:"policy,omitempty"`   // The goal's exercise policy
+	CaloriesAdded    float64 `json:"calories_added"`     // Added to the calorie target
+	BaseGoalCalories float64 `json:"base_goal_calories"` // Calorie target before the adjustment
+}
+
+// HydrationSummary represents a day's water intake against the goal's hydration target
+type HydrationSummary struct {
+	TotalWater     float64 `json:"total_water"`      // ml, logged and from foods
+	WaterLogged    float64 `json:"water_logged"`     // ml from the water log
+	WaterFromFoods float64 `json:"water_from_foods"` // ml declared by the foods eaten
+	GoalWater      float64 `json:"goal_water"`       // ml, 0 without a hydration target
+	Adherence      float64 `json:"adherence"`
+	TotalCaffeine  float64 `json:"total_caffeine"` // mg, logged and from foods
+	TotalAlcohol   float64 `json:"total_alcohol"`  // g, logged and from foods
+}
+
+// EatingWindow represents the span between a day's first and last timed meals
+type EatingWindow struct {
+	FirstMeal      string  `json:"first_meal"` // HH:MM in the user's time zone
+	LastMeal       string  `json:"last_meal"`
+	Hours          float64 `json:"hours"`
+	TimedEntries   int     `json:"timed_entries"`
+	Protocol       string  `json:"protocol,omitempty"`        // The user's fasting protocol, if any
+	WithinProtocol *bool   `json:"within_protocol,omitempty"` // Whether the window fits the protocol's eating window
+}
+
+// SetDayTypeRequest represents the request to tag a day with a day type
+type SetDayTypeRequest struct {
+	DayType string `json:"day_type"` // "training", "rest" or "refeed"
+}
+
+// DayTypeResponse represents a day's tag and the goal resolved for it
+type DayTypeResponse struct {
+	Date    string              `json:"date"`
+	Weekday string              `json:"weekday"`
+	DayType string              `json:"day_type,omitempty"`
+	Goal    *goal.NutritionGoal `json:"goal,omitempty"` // Effective goal for the day, if any
+}
+
+// MealAdherence represents a meal's intake against its target
+type MealAdherence struct {
+	MealType      MealType         `json:"meal_type"`
+	TotalCalories float64          `json:"total_calories"`
+	TotalProtein  float64          `json:"total_protein"`
+	TotalCarbs    float64          `json:"total_carbs"`
+	TotalFat      float64          `json:"total_fat"`
+	TotalFiber    float64          `json:"total_fiber"`
+	Target        goal.MealMacros  `json:"target"`
+	Adherence     AdherencePercent `json:"adherence"`
 }
 
 // AdherencePercent represents goal adherence percentages
@@ -200,6 +303,100 @@ type AdherencePercent struct {
 	Fiber    float64 `json:"fiber"`
 }
 
+// MealDayTotals holds the nutrition of one meal type on one day, aggregated in SQL
+type MealDayTotals struct {
+	Day                time.Time
+	MealType           MealType
+	Entries            int
+	Calories           float64
+	Protein            float64
+	Carbs              float64
+	Fat                float64
+	Fiber              float64
+	RoutineCalories    float64
+	ContextualCalories float64
+}
+
+// RangeSummary represents nutrition over an arbitrary date range, grouped by day, week or month
+type RangeSummary struct {
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	GroupBy string          `json:"group_by"`
+	Overall PeriodSummary   `json:"overall"`
+	Periods []PeriodSummary `json:"periods"`
+
+	Units *units.Preferences `json:"units,omitempty"` // Units of the response values
+}
+
+// PeriodSummary represents totals, daily averages and adherence for one period
+// Averages and adherence only count days with at least one entry
+type PeriodSummary struct {
+	StartDate    string `json:"start_date"`
+	EndDate      string `json:"end_date"`
+	Days         int    `json:"days"`
+	DaysLogged   int    `json:"days_logged"`
+	DaysWithGoal int    `json:"days_with_goal"` // Logged days covered by a goal
+
+	TotalCalories float64 `json:"total_calories"`
+	TotalProtein  float64 `json:"total_protein"`
+	TotalCarbs    float64 `json:"total_carbs"`
+	TotalFat      float64 `json:"total_fat"`
+	TotalFiber    float64 `json:"total_fiber"`
+
+	AverageCalories float64 `json:"average_calories"`
+	AverageProtein  float64 `json:"average_protein"`
+	AverageCarbs    float64 `json:"average_carbs"`
+	AverageFat      float64 `json:"average_fat"`
+	AverageFiber    float64 `json:"average_fiber"`
+
+	// Average daily goal and average daily adherence over logged days with a goal
+	GoalCalories float64          `json:"goal_calories"`
+	GoalProtein  float64          `json:"goal_protein"`
+	GoalCarbs    float64          `json:"goal_carbs"`
+	GoalFat      float64          `json:"goal_fat"`
+	GoalFiber    float64          `json:"goal_fiber"`
+	Adherence    AdherencePercent `json:"adherence"`
+
+	RoutinePercent    float64 `json:"routine_percent"`
+	ContextualPercent float64 `json:"contextual_percent"`
+
+	Meals []MealBreakdown `json:"meals"`
+
+	// Meal times over the days with timed entries, nil when no entry of the period has a consumed_at
+	Timing *MealTiming `json:"timing,omitempty"`
+}
+
+// MealTiming represents average and extreme meal times over a period, as HH:MM in the user's time zone
+type MealTiming struct {
+	DaysTimed           int     `json:"days_timed"`
+	AverageFirstMeal    string  `json:"average_first_meal"`
+	AverageLastMeal     string  `json:"average_last_meal"`
+	AverageEatingWindow float64 `json:"average_eating_window"` // Hours
+	EarliestFirstMeal   string  `json:"earliest_first_meal"`
+	LatestLastMeal      string  `json:"latest_last_meal"`
+}
+
+// MealTimes holds the first and last consumed_at of one day's entries, aggregated in SQL
+type MealTimes struct {
+	Day     time.Time
+	FirstAt time.Time
+	LastAt  time.Time
+	Entries int
+}
+
+// MealBreakdown represents the share of one meal type in a period
+type MealBreakdown struct {
+	MealType        MealType `json:"meal_type"`
+	Entries         int      `json:"entries"`
+	TotalCalories   float64  `json:"total_calories"`
+	TotalProtein    float64  `json:"total_protein"`
+	TotalCarbs      float64  `json:"total_carbs"`
+	TotalFat        float64  `json:"total_fat"`
+	TotalFiber      float64  `json:"total_fiber"`
+	AverageCalories float64  `json:"average_calories"` // Per logged day of the period
+	CaloriePercent  float64  `json:"calorie_percent"`  // Share of the period's calories
+}
+
 // WeeklySummary represents a weekly overview
 type WeeklySummary struct {
 	StartDate     string         `json:"start_date"`
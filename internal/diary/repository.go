@@ -27,6 +27,18 @@ func (r *Repository) Create(entry *DiaryEntry) error {
 	return nil
 }
 
+// CreateBatch creates several diary entries in a single transaction
+func (r *Repository) CreateBatch(entries []DiaryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	result := r.db.CreateInBatches(entries, 100)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create diary entries: %w", result.Error)
+	}
+	return nil
+}
+
 // GetByID retrieves a diary entry by ID and user ID
 func (r *Repository) GetByID(id, userID uint) (*DiaryEntry, error) {
 	var entry DiaryEntry
@@ -155,6 +167,48 @@ func (r *Repository) GetDailySummary(userID uint, date time.Time) (map[string]fl
 	}, nil
 }
 
+// GetMealTotalsByDay aggregates a user's entries per day and meal type in SQL
+// Rows are ordered by day then meal type; days without entries are absent
+func (r *Repository) GetMealTotalsByDay(userID uint, startDate, endDate time.Time) ([]MealDayTotals, error) {
+	var totals []MealDayTotals
+
+	result := r.db.Model(&DiaryEntry{}).
+		Select(`DATE(date) AS day, meal_type, COUNT(*) AS entries,
+			COALESCE(SUM(calories), 0) AS calories, COALESCE(SUM(protein), 0) AS protein,
+			COALESCE(SUM(carbs), 0) AS carbs, COALESCE(SUM(fat), 0) AS fat, COALESCE(SUM(fiber), 0) AS fiber,
+			COALESCE(SUM(CASE WHEN COALESCE(NULLIF(food_tag, ''), recipe_tag) = 'routine' THEN calories ELSE 0 END), 0) AS routine_calories,
+			COALESCE(SUM(CASE WHEN COALESCE(NULLIF(food_tag, ''), recipe_tag) = 'contextual' THEN calories ELSE 0 END), 0) AS contextual_calories`).
+		Where("user_id = ? AND date >= ? AND date < ?", userID, startDate, endDate).
+		Group("DATE(date), meal_type").
+		Order("day, meal_type").
+		Scan(&totals)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to aggregate diary entries: %w", result.Error)
+	}
+
+	return totals, nil
+}
+
+// GetMealTimesByDay finds the first and last consumed_at of a user's entries per day in SQL
+// Only entries with a consumed_at count; rows are ordered by day
+func (r *Repository) GetMealTimesByDay(userID uint, startDate, endDate time.Time) ([]MealTimes, error) {
+	var times []MealTimes
+
+	result := r.db.Model(&DiaryEntry{}).
+		Select("DATE(date) AS day, MIN(consumed_at) AS first_at, MAX(consumed_at) AS last_at, COUNT(*) AS entries").
+		Where("user_id = ? AND date >= ? AND date < ? AND consumed_at IS NOT NULL", userID, startDate, endDate).
+		Group("DATE(date)").
+		Order("day").
+		Scan(&times)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to aggregate meal times: %w", result.Error)
+	}
+
+	return times, nil
+}
+
 // populateNames populates food_name and recipe_name for diary entries
 func (r *Repository) populateNames(entries *[]DiaryEntry) {
 	for i := range *entries {
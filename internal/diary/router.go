@@ -60,8 +60,13 @@ func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
 		}
 	}))
 
+	mux.HandleFunc("/diary/import", auth.JWTMiddleware(handler.ImportDiary))
+	mux.HandleFunc("/diary/export", auth.JWTMiddleware(handler.ExportDiary))
+
+	mux.HandleFunc("/diary/summary", auth.JWTMiddleware(handler.GetRangeSummary))
 	mux.HandleFunc("/diary/summary/", auth.JWTMiddleware(handler.GetDailySummary))
 	mux.HandleFunc("/diary/weekly", auth.JWTMiddleware(handler.GetWeeklySummary))
+	mux.HandleFunc("/diary/days/", auth.JWTMiddleware(handler.DayType))
 
 	// Open Food Facts integration endpoint
 	mux.HandleFunc("/diary/entries/from-openfoodfacts", auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,281 @@
+package diary
+
+import (
+	"sort"
+	"time"
+
+	"ultra-bis/internal/goal"
+)
+
+// Aggregation levels for range summaries
+const (
+	GroupByDay   = "day"
+	GroupByWeek  = "week"
+	GroupByMonth = "month"
+)
+
+// MaxSummaryDays limits the range of a single summary
+const MaxSummaryDays = 366
+
+// mealOrder is the order meals are listed in breakdowns
+var mealOrder = []MealType{Breakfast, Lunch, Dinner, Snack}
+
+// ValidGroupBy reports whether groupBy is a supported aggregation level
+func ValidGroupBy(groupBy string) bool {
+	return groupBy == GroupByDay || groupBy == GroupByWeek || groupBy == GroupByMonth
+}
+
+// PeriodStart returns the first day of the period containing date
+// Weeks start on weekStart, the user's first day of the week
+func PeriodStart(date time.Time, groupBy string, weekStart time.Weekday) time.Time {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+
+	switch groupBy {
+	case GroupByWeek:
+		offset := (int(day.Weekday()) - int(weekStart) + 7) % 7
+		return day.AddDate(0, 0, -offset)
+	case GroupByMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	default:
+		return day
+	}
+}
+
+// nextPeriodStart returns the first day of the period following the one starting at start
+func nextPeriodStart(start time.Time, groupBy string) time.Time {
+	switch groupBy {
+	case GroupByWeek:
+		return start.AddDate(0, 0, 7)
+	case GroupByMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// BuildRangeSummary rolls SQL-aggregated day/meal totals up into periods between from and to (inclusive)
+// Periods are clipped to the range, and each logged day is compared with the goal in effect that day
+// dayTypes maps YYYY-MM-DD to the day type of that date and may be nil
+func BuildRangeSummary(from, to time.Time, groupBy string, weekStart time.Weekday, rows []MealDayTotals, goals []goal.NutritionGoal, dayTypes map[string]string) RangeSummary {
+	byDay := make(map[string][]MealDayTotals)
+	for _, row := range rows {
+		key := row.Day.Format("2006-01-02")
+		byDay[key] = append(byDay[key], row)
+	}
+
+	summary := RangeSummary{
+		From:    from.Format("2006-01-02"),
+		To:      to.Format("2006-01-02"),
+		GroupBy: groupBy,
+		Overall: summarizePeriod(from, to, byDay, goals, dayTypes),
+		Periods: []PeriodSummary{},
+	}
+
+	for start := PeriodStart(from, groupBy, weekStart); !start.After(to); start = nextPeriodStart(start, groupBy) {
+		periodFrom := start
+		if periodFrom.Before(from) {
+			periodFrom = from
+		}
+		periodTo := nextPeriodStart(start, groupBy).AddDate(0, 0, -1)
+		if periodTo.After(to) {
+			periodTo = to
+		}
+		summary.Periods = append(summary.Periods, summarizePeriod(periodFrom, periodTo, byDay, goals, dayTypes))
+	}
+
+	return summary
+}
+
+// summarizePeriod computes totals, daily averages, meal breakdowns and adherence for the days from start to end
+func summarizePeriod(start, end time.Time, byDay map[string][]MealDayTotals, goals []goal.NutritionGoal, dayTypes map[string]string) PeriodSummary {
+	period := PeriodSummary{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+		Meals:     []MealBreakdown{},
+	}
+
+	meals := make(map[MealType]*MealBreakdown)
+	var routineCalories, contextualCalories float64
+	var adherence AdherencePercent
+
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		period.Days++
+
+		rows := byDay[date.Format("2006-01-02")]
+		if len(rows) == 0 {
+			continue
+		}
+		period.DaysLogged++
+
+		var day MealDayTotals
+		for _, row := range rows {
+			day.Calories += row.Calories
+			day.Protein += row.Protein
+			day.Carbs += row.Carbs
+			day.Fat += row.Fat
+			day.Fiber += row.Fiber
+			routineCalories += row.RoutineCalories
+			contextualCalories += row.ContextualCalories
+
+			meal, ok := meals[row.MealType]
+			if !ok {
+				meal = &MealBreakdown{MealType: row.MealType}
+				meals[row.MealType] = meal
+			}
+			meal.Entries += row.Entries
+			meal.TotalCalories += row.Calories
+			meal.TotalProtein += row.Protein
+			meal.TotalCarbs += row.Carbs
+			meal.TotalFat += row.Fat
+			meal.TotalFiber += row.Fiber
+		}
+
+		period.TotalCalories += day.Calories
+		period.TotalProtein += day.Protein
+		period.TotalCarbs += day.Carbs
+		period.TotalFat += day.Fat
+		period.TotalFiber += day.Fiber
+
+		if dayGoal := EffectiveGoal(goals, dayTypes, date); dayGoal != nil {
+			period.DaysWithGoal++
+			period.GoalCalories += dayGoal.Calories
+			period.GoalProtein += dayGoal.Protein
+			period.GoalCarbs += dayGoal.Carbs
+			period.GoalFat += dayGoal.Fat
+			period.GoalFiber += dayGoal.Fiber
+
+			adherence.Calories += calculateAdherence(day.Calories, dayGoal.Calories)
+			adherence.Protein += calculateAdherence(day.Protein, dayGoal.Protein)
+			adherence.Carbs += calculateAdherence(day.Carbs, dayGoal.Carbs)
+			adherence.Fat += calculateAdherence(day.Fat, dayGoal.Fat)
+			adherence.Fiber += calculateAdherence(day.Fiber, dayGoal.Fiber)
+		}
+	}
+
+	if period.DaysLogged > 0 {
+		days := float64(period.DaysLogged)
+		period.AverageCalories = roundToTwo(period.TotalCalories / days)
+		period.AverageProtein = roundToTwo(period.TotalProtein / days)
+		period.AverageCarbs = roundToTwo(period.TotalCarbs / days)
+		period.AverageFat = roundToTwo(period.TotalFat / days)
+		period.AverageFiber = roundToTwo(period.TotalFiber / days)
+	}
+
+	if period.DaysWithGoal > 0 {
+		days := float64(period.DaysWithGoal)
+		period.GoalCalories = roundToTwo(period.GoalCalories / days)
+		period.GoalProtein = roundToTwo(period.GoalProtein / days)
+		period.GoalCarbs = roundToTwo(period.GoalCarbs / days)
+		period.GoalFat = roundToTwo(period.GoalFat / days)
+		period.GoalFiber = roundToTwo(period.GoalFiber / days)
+		period.Adherence = AdherencePercent{
+			Calories: roundToTwo(adherence.Calories / days),
+			Protein:  roundToTwo(adherence.Protein / days),
+			Carbs:    roundToTwo(adherence.Carbs / days),
+			Fat:      roundToTwo(adherence.Fat / days),
+			Fiber:    roundToTwo(adherence.Fiber / days),
+		}
+	}
+
+	if period.TotalCalories > 0 {
+		period.RoutinePercent = roundToTwo(routineCalories / period.TotalCalories * 100)
+		period.ContextualPercent = roundToTwo(contextualCalories / period.TotalCalories * 100)
+	}
+
+	for _, mealType := range mealOrder {
+		meal, ok := meals[mealType]
+		if !ok {
+			continue
+		}
+		delete(meals, mealType)
+		period.Meals = append(period.Meals, finishMealBreakdown(*meal, period))
+	}
+	// Meal types outside the standard four are listed last, alphabetically
+	others := make([]string, 0, len(meals))
+	for mealType := range meals {
+		others = append(others, string(mealType))
+	}
+	sort.Strings(others)
+	for _, mealType := range others {
+		period.Meals = append(period.Meals, finishMealBreakdown(*meals[MealType(mealType)], period))
+	}
+
+	period.TotalCalories = roundToTwo(period.TotalCalories)
+	period.TotalProtein = roundToTwo(period.TotalProtein)
+	period.TotalCarbs = roundToTwo(period.TotalCarbs)
+	period.TotalFat = roundToTwo(period.TotalFat)
+	period.TotalFiber = roundToTwo(period.TotalFiber)
+
+	return period
+}
+
+// finishMealBreakdown rounds a meal's totals and computes its averages and share of the period
+func finishMealBreakdown(meal MealBreakdown, period PeriodSummary) MealBreakdown {
+	if period.DaysLogged > 0 {
+		meal.AverageCalories = roundToTwo(meal.TotalCalories / float64(period.DaysLogged))
+	}
+	if period.TotalCalories > 0 {
+		meal.CaloriePercent = roundToTwo(meal.TotalCalories / period.TotalCalories * 100)
+	}
+
+	meal.TotalCalories = roundToTwo(meal.TotalCalories)
+	meal.TotalProtein = roundToTwo(meal.TotalProtein)
+	meal.TotalCarbs = roundToTwo(meal.TotalCarbs)
+	meal.TotalFat = roundToTwo(meal.TotalFat)
+	meal.TotalFiber = roundToTwo(meal.TotalFiber)
+
+	return meal
+}
+
+// BuildMealAdherence compares each targeted meal's intake with the goal's meal targets
+// Returns nil when the goal is nil or has no meal targets
+func BuildMealAdherence(entries []DiaryEntry, dayGoal *goal.NutritionGoal) []MealAdherence {
+	if dayGoal == nil || len(dayGoal.MealTargets) == 0 {
+		return nil
+	}
+
+	meals := make([]MealAdherence, 0, len(dayGoal.MealTargets))
+	for _, mealType := range mealOrder {
+		for _, target := range dayGoal.MealTargets {
+			if target.MealType != string(mealType) {
+				continue
+			}
+
+			meal := MealAdherence{MealType: mealType, Target: target.Resolve(dayGoal)}
+			for _, entry := range entries {
+				if entry.MealType != mealType {
+					continue
+				}
+				meal.TotalCalories += entry.Calories
+				meal.TotalProtein += entry.Protein
+				meal.TotalCarbs += entry.Carbs
+				meal.TotalFat += entry.Fat
+				meal.TotalFiber += entry.Fiber
+			}
+
+			meal.Adherence = AdherencePercent{
+				Calories: roundToTwo(calculateAdherence(meal.TotalCalories, meal.Target.Calories)),
+				Protein:  roundToTwo(calculateAdherence(meal.TotalProtein, meal.Target.Protein)),
+				Carbs:    roundToTwo(calculateAdherence(meal.TotalCarbs, meal.Target.Carbs)),
+				Fat:      roundToTwo(calculateAdherence(meal.TotalFat, meal.Target.Fat)),
+				Fiber:    roundToTwo(calculateAdherence(meal.TotalFiber, meal.Target.Fiber)),
+			}
+			meal.TotalCalories = roundToTwo(meal.TotalCalories)
+			meal.TotalProtein = roundToTwo(meal.TotalProtein)
+			meal.TotalCarbs = roundToTwo(meal.TotalCarbs)
+			meal.TotalFat = roundToTwo(meal.TotalFat)
+			meal.TotalFiber = roundToTwo(meal.TotalFiber)
+			meal.Target = goal.MealMacros{
+				Calories: roundToTwo(meal.Target.Calories),
+				Protein:  roundToTwo(meal.Target.Protein),
+				Carbs:    roundToTwo(meal.Target.Carbs),
+				Fat:      roundToTwo(meal.Target.Fat),
+				Fiber:    roundToTwo(meal.Target.Fiber),
+			}
+
+			meals = append(meals, meal)
+		}
+	}
+
+	return meals
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"ultra-bis/internal/diary"
+	"ultra-bis/internal/goal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportTestDays() []diary.DailySummary {
+	dayGoal := &goal.NutritionGoal{Calories: 2000, Protein: 150, Carbs: 200, Fat: 70, Fiber: 30}
+	entries := []diary.DiaryEntry{
+		{MealType: diary.Breakfast, FoodName: "Oats", QuantityGrams: 80, Calories: 303.2, Protein: 10.7, Carbs: 54.2, Fat: 5.4, Fiber: 8.1, FoodTag: "routine"},
+		{MealType: diary.Lunch, RecipeName: "Chicken bowl", QuantityGrams: 450, Calories: 1196.8, Protein: 89.3, Carbs: 95.8, Fat: 40.6, Fiber: 11.9, RecipeTag: "routine", Notes: "with rice, extra"},
+	}
+	return []diary.DailySummary{
+		diary.BuildDaySummary("2025-01-06", entries, dayGoal),
+		diary.BuildDaySummary("2025-01-07", nil, dayGoal),
+	}
+}
+
+func TestBuildDaySummary(t *testing.T) {
+	days := exportTestDays()
+
+	day := days[0]
+	assert.Equal(t, 1500.0, day.TotalCalories)
+	assert.Equal(t, 100.0, day.TotalProtein)
+	assert.Equal(t, 2000.0, day.GoalCalories)
+	assert.Equal(t, 75.0, day.Adherence.Calories)
+	assert.Equal(t, 1500.0, day.RoutineCalories)
+
+	empty := days[1]
+	assert.Equal(t, 0.0, empty.TotalCalories)
+	assert.NotNil(t, empty.Entries)
+
+	noGoal := diary.BuildDaySummary("2025-01-08", nil, nil)
+	assert.Equal(t, 0.0, noGoal.GoalCalories)
+	assert.Equal(t, 0.0, noGoal.Adherence.Calories)
+}
+
+func TestGoalForDate(t *testing.T) {
+	jan := func(day int) time.Time { return time.Date(2025, 1, day, 0, 0, 0, 0, time.UTC) }
+	end := jan(9)
+	goals := []goal.NutritionGoal{
+		{ID: 1, Calories: 2500, StartDate: jan(1), EndDate: &end},
+		{ID: 2, Calories: 2200, StartDate: jan(10).Add(9 * time.Hour)},
+	}
+
+	assert.Nil(t, diary.GoalForDate(goals, time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, uint(1), diary.GoalForDate(goals, jan(5)).ID)
+	assert.Equal(t, uint(1), diary.GoalForDate(goals, jan(9)).ID)
+	assert.Equal(t, uint(2), diary.GoalForDate(goals, jan(10)).ID) // Started later that day
+	assert.Equal(t, uint(2), diary.GoalForDate(goals, jan(20)).ID)
+}
+
+func TestExportWriter_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	from, to := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC)
+	writer, err := diary.NewExportWriter(&buf, diary.ExportCSV, "", from, to, time.Monday)
+	require.NoError(t, err)
+	for _, day := range exportTestDays() {
+		require.NoError(t, writer.WriteDay(day))
+	}
+	require.NoError(t, writer.Close())
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 5) // header, 2 entries, 2 daily totals
+
+	assert.Equal(t, "row_type", rows[0][0])
+	assert.Equal(t, []string{"entry", "2025-01-06", "breakfast", "Oats"}, rows[1][:4])
+	assert.Equal(t, "Chicken bowl", rows[2][4])
+	assert.Equal(t, "with rice, extra", rows[2][len(rows[2])-1])
+	assert.Equal(t, "daily_total", rows[3][0])
+	assert.Equal(t, "1500", rows[3][6])
+	assert.Equal(t, "2000", rows[3][11])
+	assert.Equal(t, "75", rows[3][16])
+	assert.Equal(t, []string{"daily_total", "2025-01-07"}, rows[4][:2])
+}
+
+func TestExportWriter_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	from, to := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC)
+	writer, err := diary.NewExportWriter(&buf, diary.ExportJSON, "", from, to, time.Monday)
+	require.NoError(t, err)
+	for _, day := range exportTestDays() {
+		require.NoError(t, writer.WriteDay(day))
+	}
+	require.NoError(t, writer.Close())
+
+	var export struct {
+		From string               `json:"from"`
+		To   string               `json:"to"`
+		Days []diary.DailySummary `json:"days"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &export))
+	assert.Equal(t, "2025-01-06", export.From)
+	assert.Equal(t, "2025-01-07", export.To)
+	require.Len(t, export.Days, 2)
+	assert.Len(t, export.Days[0].Entries, 2)
+	assert.Equal(t, 75.0, export.Days[0].Adherence.Calories)
+}
+
+func TestExportWriter_PDF(t *testing.T) {
+	var buf bytes.Buffer
+	from, to := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC)
+	writer, err := diary.NewExportWriter(&buf, diary.ExportPDF, diary.ReportWeekly, from, to, time.Monday)
+	require.NoError(t, err)
+	for _, day := range exportTestDays() {
+		require.NoError(t, writer.WriteDay(day))
+	}
+	require.NoError(t, writer.Close())
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "%PDF-"))
+	assert.Contains(t, out, "Week of Mon 06 Jan 2025")
+	assert.Contains(t, out, "Chicken bowl")
+
+	_, err = diary.NewExportWriter(&buf, "xml", "", from, to, time.Monday)
+	assert.Error(t, err)
+}
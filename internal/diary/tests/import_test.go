@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"ultra-bis/internal/diary"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImport_MyFitnessPalNutrition(t *testing.T) {
+	csv := "Date,Meal,Calories,Fat (g),Carbohydrates (g),Fiber,Sugar,Protein (g),Note\n" +
+		"2025-01-06,Breakfast,520,18,60,8,12,30,oats\n" +
+		"2025-01-06,Snacks,\"1,210\",9,20,3,14,8,\n" +
+		"bad-date,Lunch,700,20,80,9,5,40,\n"
+
+	parsed, err := diary.ParseImport(strings.NewReader(csv), "", "kg")
+	require.NoError(t, err)
+
+	assert.Equal(t, diary.FormatMyFitnessPal, parsed.Format)
+	assert.Equal(t, diary.KindNutrition, parsed.Kind)
+	require.Len(t, parsed.Entries, 2)
+	require.Len(t, parsed.Errors, 1)
+	assert.Equal(t, 4, parsed.Errors[0].Line)
+
+	breakfast := parsed.Entries[0]
+	assert.Equal(t, "2025-01-06", breakfast.Date)
+	assert.Equal(t, diary.Breakfast, breakfast.MealType)
+	assert.Equal(t, "Breakfast (MyFitnessPal)", breakfast.Name)
+	assert.Equal(t, "oats", breakfast.Description)
+	assert.Equal(t, 520.0, breakfast.Calories)
+	assert.Equal(t, 30.0, breakfast.Protein)
+	assert.Equal(t, 60.0, breakfast.Carbs)
+	assert.Equal(t, 18.0, breakfast.Fat)
+	assert.Equal(t, 8.0, breakfast.Fiber)
+
+	assert.Equal(t, diary.Snack, parsed.Entries[1].MealType)
+	assert.Equal(t, 1210.0, parsed.Entries[1].Calories)
+}
+
+func TestParseImport_CronometerServings(t *testing.T) {
+	csv := "Day,Time,Group,Food Name,Amount,Energy (kcal),Carbs (g),Fiber (g),Fat (g),Protein (g)\n" +
+		"2025-01-06,08:10,Breakfast,\"Oats, rolled\",80.00 g,303.2,54.2,8.1,5.4,10.7\n" +
+		"2025-01-06,21:00,Uncategorized,Milk,1.00 cup,122,11.7,0,4.8,8.1\n"
+
+	parsed, err := diary.ParseImport(strings.NewReader(csv), diary.FormatCronometer, "kg")
+	require.NoError(t, err)
+
+	assert.Equal(t, diary.FormatCronometer, parsed.Format)
+	require.Len(t, parsed.Entries, 2)
+
+	oats := parsed.Entries[0]
+	assert.Equal(t, "Oats, rolled", oats.Name)
+	assert.Equal(t, diary.Breakfast, oats.MealType)
+	assert.Equal(t, 80.0, oats.QuantityGrams)
+	assert.Equal(t, 303.2, oats.Calories)
+
+	// Non-gram amounts keep the nutrition as a total over a nominal 100g
+	milk := parsed.Entries[1]
+	assert.Equal(t, diary.Snack, milk.MealType)
+	assert.Equal(t, 100.0, milk.QuantityGrams)
+	assert.Equal(t, "1.00 cup", milk.Description)
+}
+
+func TestParseImport_FormatMismatch(t *testing.T) {
+	csv := "Date,Meal,Calories\n2025-01-06,Lunch,500\n"
+
+	_, err := diary.ParseImport(strings.NewReader(csv), diary.FormatCronometer, "kg")
+	assert.Error(t, err)
+
+	_, err = diary.ParseImport(strings.NewReader("Foo,Bar\n1,2\n"), "", "kg")
+	assert.Error(t, err)
+}
+
+func TestParseImport_Weights(t *testing.T) {
+	// MyFitnessPal measurements carry no unit; the caller provides it
+	parsed, err := diary.ParseImport(strings.NewReader("Date,Weight\n2025-01-06,176.4\n"), "", "lb")
+	require.NoError(t, err)
+	assert.Equal(t, diary.KindWeight, parsed.Kind)
+	require.Len(t, parsed.Weights, 1)
+	assert.InDelta(t, 80.01, parsed.Weights[0].Weight, 0.01)
+
+	parsed, err = diary.ParseImport(strings.NewReader("Date,Weight\n2025-01-06,12.6\n"), "", "st")
+	require.NoError(t, err)
+	require.Len(t, parsed.Weights, 1)
+	assert.InDelta(t, 80.01, parsed.Weights[0].Weight, 0.01)
+
+	// A decimal comma, as in exports from European locales
+	parsed, err = diary.ParseImport(strings.NewReader("Date,Weight\n2025-01-06,\"79,6\"\n"), "", "kg")
+	require.NoError(t, err)
+	require.Len(t, parsed.Weights, 1)
+	assert.Equal(t, 79.6, parsed.Weights[0].Weight)
+
+	// Cronometer biometrics state the unit and mix in other metrics
+	csv := "Day,Group,Metric,Unit,Amount\n" +
+		"2025-01-06,Uncategorized,Weight,kg,80.1\n" +
+		"2025-01-06,Uncategorized,Heart Rate,bpm,58\n"
+	parsed, err = diary.ParseImport(strings.NewReader(csv), "", "kg")
+	require.NoError(t, err)
+	assert.Equal(t, diary.FormatCronometer, parsed.Format)
+	require.Len(t, parsed.Weights, 1)
+	assert.Equal(t, 80.1, parsed.Weights[0].Weight)
+	assert.Empty(t, parsed.Errors)
+}
+
+func TestParsedImport_MarkDuplicates(t *testing.T) {
+	csv := "Date,Meal,Calories,Fat (g),Carbohydrates (g),Fiber,Protein (g)\n" +
+		"2025-01-06,Breakfast,520,18,60,8,30\n" +
+		"2025-01-06,Lunch,780,25,85,10,55\n" +
+		"2025-01-06,Lunch,780,25,85,10,55\n"
+
+	parsed, err := diary.ParseImport(strings.NewReader(csv), "", "kg")
+	require.NoError(t, err)
+
+	name := "Breakfast (MyFitnessPal)"
+	existing := []diary.DiaryEntry{{
+		Date:           time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC),
+		MealType:       diary.Breakfast,
+		InlineFoodName: &name,
+		Calories:       520,
+		Protein:        30,
+		Carbs:          60,
+		Fat:            18,
+	}}
+	parsed.MarkDuplicates(existing, nil)
+
+	assert.Equal(t, diary.ImportStatusDuplicate, parsed.Entries[0].Status) // Already in the diary
+	assert.Equal(t, diary.ImportStatusNew, parsed.Entries[1].Status)
+	assert.Equal(t, diary.ImportStatusDuplicate, parsed.Entries[2].Status) // Repeated in the file
+
+	weights, err := diary.ParseImport(strings.NewReader("Date,Weight\n2025-01-06,80.4\n2025-01-07,80.0\n2025-01-08,79.8\n"), "", "kg")
+	require.NoError(t, err)
+	weights.MarkDuplicates(nil, map[string]float64{"2025-01-06": 80.4, "2025-01-07": 81.2})
+
+	assert.Equal(t, diary.ImportStatusDuplicate, weights.Weights[0].Status)
+	assert.Equal(t, diary.ImportStatusConflict, weights.Weights[1].Status)
+	assert.Equal(t, diary.ImportStatusNew, weights.Weights[2].Status)
+}
+
+func TestMapMealType(t *testing.T) {
+	assert.Equal(t, diary.Breakfast, diary.MapMealType("Breakfast"))
+	assert.Equal(t, diary.Lunch, diary.MapMealType("LUNCH"))
+	assert.Equal(t, diary.Dinner, diary.MapMealType("Supper"))
+	assert.Equal(t, diary.Snack, diary.MapMealType("Snacks"))
+	assert.Equal(t, diary.Snack, diary.MapMealType("Uncategorized"))
+}
@@ -0,0 +1,307 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/diary"
+	"ultra-bis/internal/exercise"
+	"ultra-bis/internal/fasting"
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/hydration"
+	"ultra-bis/internal/live"
+	"ultra-bis/internal/units"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func summaryDate(value string) time.Time {
+	date, _ := time.Parse("2006-01-02", value)
+	return date
+}
+
+func summaryTestRows() []diary.MealDayTotals {
+	return []diary.MealDayTotals{
+		{Day: summaryDate("2025-01-06"), MealType: diary.Breakfast, Entries: 1, Calories: 500, Protein: 30, RoutineCalories: 500},
+		{Day: summaryDate("2025-01-06"), MealType: diary.Dinner, Entries: 2, Calories: 1500, Protein: 90, ContextualCalories: 1500},
+		{Day: summaryDate("2025-01-08"), MealType: diary.Lunch, Entries: 1, Calories: 1800, Protein: 120, RoutineCalories: 1800},
+		{Day: summaryDate("2025-01-13"), MealType: diary.Breakfast, Entries: 1, Calories: 2200, Protein: 160, RoutineCalories: 2200},
+	}
+}
+
+func TestBuildRangeSummary_GroupByWeek(t *testing.T) {
+	goals := []goal.NutritionGoal{
+		{Calories: 2000, Protein: 150, StartDate: summaryDate("2025-01-01")},
+	}
+
+	summary := diary.BuildRangeSummary(summaryDate("2025-01-07"), summaryDate("2025-01-14"), diary.GroupByWeek, time.Monday, summaryTestRows(), goals, nil)
+
+	// Rows outside the range are ignored and the periods are clipped to it
+	require.Len(t, summary.Periods, 2)
+	first := summary.Periods[0]
+	assert.Equal(t, "2025-01-07", first.StartDate)
+	assert.Equal(t, "2025-01-12", first.EndDate)
+	assert.Equal(t, 6, first.Days)
+	assert.Equal(t, 1, first.DaysLogged)
+	assert.Equal(t, 1800.0, first.TotalCalories)
+	assert.Equal(t, 90.0, first.Adherence.Calories)
+	assert.Equal(t, 80.0, first.Adherence.Protein)
+
+	second := summary.Periods[1]
+	assert.Equal(t, "2025-01-13", second.StartDate)
+	assert.Equal(t, "2025-01-14", second.EndDate)
+	assert.Equal(t, 2200.0, second.AverageCalories)
+
+	assert.Equal(t, 8, summary.Overall.Days)
+	assert.Equal(t, 2, summary.Overall.DaysLogged)
+	assert.Equal(t, 2000.0, summary.Overall.AverageCalories)
+	assert.Equal(t, 2000.0, summary.Overall.GoalCalories)
+	assert.Equal(t, 100.0, summary.Overall.Adherence.Calories)
+	assert.Equal(t, 100.0, summary.Overall.RoutinePercent)
+}
+
+func TestBuildRangeSummary_MealBreakdown(t *testing.T) {
+	summary := diary.BuildRangeSummary(summaryDate("2025-01-06"), summaryDate("2025-01-31"), diary.GroupByMonth, time.Monday, summaryTestRows(), nil, nil)
+
+	require.Len(t, summary.Periods, 1)
+	month := summary.Periods[0]
+	assert.Equal(t, 3, month.DaysLogged)
+	assert.Equal(t, 0, month.DaysWithGoal)
+	assert.Equal(t, 0.0, month.Adherence.Calories)
+	assert.Equal(t, 6000.0, month.TotalCalories)
+	assert.Equal(t, 25.0, month.ContextualPercent)
+
+	require.Len(t, month.Meals, 3)
+	assert.Equal(t, diary.Breakfast, month.Meals[0].MealType)
+	assert.Equal(t, 2700.0, month.Meals[0].TotalCalories)
+	assert.Equal(t, 900.0, month.Meals[0].AverageCalories)
+	assert.Equal(t, 45.0, month.Meals[0].CaloriePercent)
+	assert.Equal(t, diary.Lunch, month.Meals[1].MealType)
+	assert.Equal(t, diary.Dinner, month.Meals[2].MealType)
+	assert.Equal(t, 2, month.Meals[2].Entries)
+}
+
+func TestPeriodStart(t *testing.T) {
+	sunday := summaryDate("2025-01-12")
+	assert.Equal(t, "2025-01-06", diary.PeriodStart(sunday, diary.GroupByWeek, time.Monday).Format("2006-01-02"))
+	assert.Equal(t, "2025-01-01", diary.PeriodStart(sunday, diary.GroupByMonth, time.Monday).Format("2006-01-02"))
+	assert.Equal(t, "2025-01-12", diary.PeriodStart(sunday, diary.GroupByDay, time.Monday).Format("2006-01-02"))
+
+	// Users whose week starts on Sunday
+	assert.Equal(t, "2025-01-12", diary.PeriodStart(sunday, diary.GroupByWeek, time.Sunday).Format("2006-01-02"))
+	assert.Equal(t, "2025-01-12", diary.PeriodStart(summaryDate("2025-01-18"), diary.GroupByWeek, time.Sunday).Format("2006-01-02"))
+	assert.False(t, diary.ValidGroupBy("year"))
+}
+
+func TestBuildMealAdherence(t *testing.T) {
+	dayGoal := &goal.NutritionGoal{
+		Calories: 2000, Protein: 150,
+		MealTargets: goal.MealTargets{
+			{MealType: "dinner", Unit: goal.TargetUnitAbsolute, Protein: 40},
+			{MealType: "breakfast", Unit: goal.TargetUnitPercent, Calories: 30},
+		},
+	}
+	entries := []diary.DiaryEntry{
+		{MealType: diary.Breakfast, Calories: 450, Protein: 20},
+		{MealType: diary.Dinner, Calories: 700, Protein: 25},
+		{MealType: diary.Dinner, Calories: 100, Protein: 5},
+	}
+
+	meals := diary.BuildMealAdherence(entries, dayGoal)
+
+	require.Len(t, meals, 2)
+	assert.Equal(t, diary.Breakfast, meals[0].MealType)
+	assert.Equal(t, 600.0, meals[0].Target.Calories)
+	assert.Equal(t, 75.0, meals[0].Adherence.Calories)
+	assert.Equal(t, diary.Dinner, meals[1].MealType)
+	assert.Equal(t, 30.0, meals[1].TotalProtein)
+	assert.Equal(t, 75.0, meals[1].Adherence.Protein)
+
+	summary := diary.BuildDaySummary("2025-01-06", entries, dayGoal)
+	assert.Len(t, summary.Meals, 2)
+
+	assert.Nil(t, diary.BuildMealAdherence(entries, &goal.NutritionGoal{Calories: 2000}))
+	assert.Nil(t, diary.BuildMealAdherence(entries, nil))
+}
+
+func TestEffectiveGoal(t *testing.T) {
+	goals := []goal.NutritionGoal{{
+		ID: 1, Calories: 2000, StartDate: summaryDate("2025-01-01"),
+		DayTypeTargets: goal.DayTargets{goal.DayTypeTraining: {Calories: 2400}},
+	}}
+	dayTypes := map[string]string{"2025-01-07": goal.DayTypeTraining}
+
+	assert.Equal(t, 2400.0, diary.EffectiveGoal(goals, dayTypes, summaryDate("2025-01-07")).Calories)
+	assert.Equal(t, 2000.0, diary.EffectiveGoal(goals, dayTypes, summaryDate("2025-01-08")).Calories)
+	assert.Nil(t, diary.EffectiveGoal(goals, dayTypes, summaryDate("2024-12-31")))
+
+	// Adherence in range summaries uses the day's override
+	rows := []diary.MealDayTotals{{Day: summaryDate("2025-01-07"), MealType: diary.Lunch, Entries: 1, Calories: 2400}}
+	summary := diary.BuildRangeSummary(summaryDate("2025-01-07"), summaryDate("2025-01-07"), diary.GroupByDay, time.Monday, rows, goals, dayTypes)
+	assert.Equal(t, 100.0, summary.Overall.Adherence.Calories)
+}
+
+func TestBuildHydrationSummary(t *testing.T) {
+	entries := []diary.DiaryEntry{
+		{Calories: 50, Water: 230, Caffeine: 95},
+		{Calories: 300, Water: 120.5},
+		{Calories: 150, Water: 140, Alcohol: 14},
+	}
+	logged := hydration.Totals{Water: 1500, Caffeine: 65}
+
+	summary := diary.BuildHydrationSummary(entries, logged, &goal.NutritionGoal{Water: 2500})
+	assert.Equal(t, 1500.0, summary.WaterLogged)
+	assert.Equal(t, 490.5, summary.WaterFromFoods)
+	assert.Equal(t, 1990.5, summary.TotalWater)
+	assert.Equal(t, 2500.0, summary.GoalWater)
+	assert.Equal(t, 79.62, summary.Adherence)
+	assert.Equal(t, 160.0, summary.TotalCaffeine)
+	assert.Equal(t, 14.0, summary.TotalAlcohol)
+
+	// Without a goal, or a goal without a hydration target, there is no adherence
+	summary = diary.BuildHydrationSummary(entries, hydration.Totals{}, nil)
+	assert.Equal(t, 490.5, summary.TotalWater)
+	assert.Zero(t, summary.GoalWater)
+	assert.Zero(t, summary.Adherence)
+	summary = diary.BuildHydrationSummary(nil, logged, &goal.NutritionGoal{Calories: 2000})
+	assert.Zero(t, summary.Adherence)
+}
+
+func TestBuildEatingWindow(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	date := summaryDate("2025-01-06")
+	at := func(hour, minute int) *time.Time {
+		consumedAt := time.Date(2025, 1, 6, hour, minute, 0, 0, paris).UTC()
+		return &consumedAt
+	}
+
+	entries := []diary.DiaryEntry{
+		{MealType: diary.Dinner, ConsumedAt: at(19, 45)},
+		{MealType: diary.Lunch, ConsumedAt: at(12, 15)},
+		{MealType: diary.Snack}, // Untimed entries are ignored
+	}
+
+	window := diary.BuildEatingWindow(entries, date, paris, fasting.FindProtocol(fasting.Protocol16To8))
+	require.NotNil(t, window)
+	assert.Equal(t, "12:15", window.FirstMeal)
+	assert.Equal(t, "19:45", window.LastMeal)
+	assert.Equal(t, 7.5, window.Hours)
+	assert.Equal(t, 2, window.TimedEntries)
+	assert.Equal(t, "16:8", window.Protocol)
+	require.NotNil(t, window.WithinProtocol)
+	assert.True(t, *window.WithinProtocol)
+
+	window = diary.BuildEatingWindow(entries, date, paris, fasting.FindProtocol(fasting.ProtocolOMAD))
+	assert.False(t, *window.WithinProtocol)
+
+	// 5:2 does not limit the daily window, and no protocol means no comparison
+	assert.Nil(t, diary.BuildEatingWindow(entries, date, paris, fasting.FindProtocol(fasting.Protocol5To2)).WithinProtocol)
+	assert.Empty(t, diary.BuildEatingWindow(entries, date, paris, nil).Protocol)
+
+	// A snack after midnight logged on the day extends its window
+	entries = append(entries, diary.DiaryEntry{MealType: diary.Snack, ConsumedAt: at(24, 30)})
+	window = diary.BuildEatingWindow(entries, date, paris, nil)
+	assert.Equal(t, "00:30", window.LastMeal)
+	assert.Equal(t, 12.25, window.Hours)
+
+	assert.Nil(t, diary.BuildEatingWindow([]diary.DiaryEntry{{MealType: diary.Lunch}}, date, paris, nil))
+}
+
+func TestAddMealTiming(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	times := []diary.MealTimes{
+		{Day: summaryDate("2025-01-06"), FirstAt: at(6, 8, 0), LastAt: at(6, 20, 0), Entries: 3},
+		{Day: summaryDate("2025-01-07"), FirstAt: at(7, 10, 0), LastAt: at(7, 18, 0), Entries: 2},
+		{Day: summaryDate("2025-01-13"), FirstAt: at(13, 12, 30), LastAt: at(13, 19, 30), Entries: 2},
+	}
+
+	summary := diary.BuildRangeSummary(summaryDate("2025-01-06"), summaryDate("2025-01-19"), diary.GroupByWeek, time.Monday, summaryTestRows(), nil, nil)
+	summary.AddMealTiming(times, time.UTC)
+
+	overall := summary.Overall.Timing
+	require.NotNil(t, overall)
+	assert.Equal(t, 3, overall.DaysTimed)
+	assert.Equal(t, "10:10", overall.AverageFirstMeal)
+	assert.Equal(t, "19:10", overall.AverageLastMeal)
+	assert.Equal(t, 9.0, overall.AverageEatingWindow)
+	assert.Equal(t, "08:00", overall.EarliestFirstMeal)
+	assert.Equal(t, "20:00", overall.LatestLastMeal)
+
+	require.Len(t, summary.Periods, 2)
+	require.NotNil(t, summary.Periods[0].Timing)
+	assert.Equal(t, 2, summary.Periods[0].Timing.DaysTimed)
+	assert.Equal(t, 10.0, summary.Periods[0].Timing.AverageEatingWindow)
+	assert.Equal(t, 1, summary.Periods[1].Timing.DaysTimed)
+
+	// Periods without timed entries have no timing
+	summary.AddMealTiming(times[:2], time.UTC)
+	assert.Nil(t, summary.Periods[1].Timing)
+}
+
+func TestResolveEntryDate(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	settings := calendar.Settings{Location: tokyo, WeekStart: time.Monday}
+	now := time.Date(2025, 1, 6, 20, 0, 0, 0, time.UTC) // Already the 7th in Tokyo
+
+	date, err := diary.ResolveEntryDate("2025-01-05", nil, settings, now)
+	require.NoError(t, err)
+	assert.Equal(t, summaryDate("2025-01-05"), date)
+
+	date, err = diary.ResolveEntryDate("", nil, settings, now)
+	require.NoError(t, err)
+	assert.Equal(t, summaryDate("2025-01-07"), date)
+
+	// Without a date, the entry goes on the day it was consumed in the user's time zone
+	consumedAt := time.Date(2025, 1, 5, 23, 30, 0, 0, time.UTC)
+	date, err = diary.ResolveEntryDate("", &consumedAt, settings, now)
+	require.NoError(t, err)
+	assert.Equal(t, summaryDate("2025-01-06"), date)
+
+	future := now.Add(time.Hour)
+	_, err = diary.ResolveEntryDate("", &future, settings, now)
+	assert.EqualError(t, err, "consumed_at cannot be in the future")
+	_, err = diary.ResolveEntryDate("06/01/2025", nil, settings, now)
+	assert.Error(t, err)
+}
+
+func TestExerciseSummary_AdjustGoal(t *testing.T) {
+	dayGoal := &goal.NutritionGoal{Calories: 2000, Protein: 150, Carbs: 200, Fat: 60, ExercisePolicy: goal.ExercisePolicyHalf}
+
+	summary := diary.NewExerciseSummary(exercise.Totals{Calories: 500, Minutes: 45})
+	adjusted := summary.AdjustGoal(dayGoal)
+	assert.Equal(t, 2250.0, adjusted.Calories)
+	assert.Equal(t, 500.0, summary.CaloriesBurned)
+	assert.Equal(t, 45.0, summary.Minutes)
+	assert.Equal(t, goal.ExercisePolicyHalf, summary.Policy)
+	assert.Equal(t, 250.0, summary.CaloriesAdded)
+	assert.Equal(t, 2000.0, summary.BaseGoalCalories)
+
+	// Percentage meal targets follow the adjusted goal
+	adjusted.MealTargets = goal.MealTargets{{MealType: "dinner", Unit: goal.TargetUnitPercent, Calories: 40}}
+	meals := diary.BuildMealAdherence([]diary.DiaryEntry{{MealType: diary.Dinner, Calories: 900}}, adjusted)
+	require.Len(t, meals, 1)
+	assert.Equal(t, 900.0, meals[0].Target.Calories)
+}
+
+func TestLiveConverters(t *testing.T) {
+	kilojoules := units.Metric
+	kilojoules.Energy = units.Kilojoule
+
+	entry, err := diary.LiveConverters[live.EventEntryCreated](json.RawMessage(`{"id":4,"quantity_grams":100,"calories":200}`), kilojoules)
+	require.NoError(t, err)
+	converted := entry.(diary.DiaryEntry)
+	assert.Equal(t, uint(4), converted.ID)
+	assert.InDelta(t, 836.8, converted.Calories, 0.01)
+
+	totals, err := diary.LiveConverters[live.EventSummaryUpdated](json.RawMessage(`{"date":"2025-01-06","total_calories":1000,"total_protein":80}`), kilojoules)
+	require.NoError(t, err)
+	assert.InDelta(t, 4184, totals.(diary.DayTotals).TotalCalories, 0.01)
+	assert.Equal(t, 80.0, totals.(diary.DayTotals).TotalProtein)
+}
@@ -31,6 +31,7 @@ func setupTagBreakdownTest(t *testing.T) (*gorm.DB, *diary.Repository, *food.Rep
 		&recipe.RecipeIngredient{},
 		&diary.DiaryEntry{},
 		&goal.NutritionGoal{},
+		&goal.DayTypeAssignment{},
 	); err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
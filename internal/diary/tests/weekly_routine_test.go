@@ -34,6 +34,7 @@ func setupWeeklyRoutineTest(t *testing.T) (*gorm.DB, *diary.Handler, *food.Repos
 		&recipe.RecipeIngredient{},
 		&diary.DiaryEntry{},
 		&goal.NutritionGoal{},
+		&goal.DayTypeAssignment{},
 	); err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
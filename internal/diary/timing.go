@@ -0,0 +1,127 @@
+package diary
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/fasting"
+)
+
+// ResolveEntryDate returns the date of a new entry: the requested date, else the day of
+// consumedAt in the user's time zone, else today
+func ResolveEntryDate(date string, consumedAt *time.Time, settings calendar.Settings, now time.Time) (time.Time, error) {
+	if consumedAt != nil && consumedAt.After(now.Add(time.Minute)) {
+		return time.Time{}, errors.New("consumed_at cannot be in the future")
+	}
+	if date != "" {
+		entryDate, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return time.Time{}, errors.New("Invalid date format (use YYYY-MM-DD)")
+		}
+		return entryDate, nil
+	}
+	if consumedAt != nil {
+		return settings.DateOf(*consumedAt), nil
+	}
+	return settings.DateOf(now), nil
+}
+
+// minutesFromMidnight returns the minutes between midnight of date in loc and t
+// A late snack after midnight counts past 24:00 on the day it was logged
+func minutesFromMidnight(date, t time.Time, loc *time.Location) float64 {
+	midnight := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	return t.Sub(midnight).Minutes()
+}
+
+// formatClock formats minutes from midnight as HH:MM
+func formatClock(minutes float64) string {
+	total := int(math.Round(minutes))
+	total = ((total % 1440) + 1440) % 1440
+	return fmt.Sprintf("%02d:%02d", total/60, total%60)
+}
+
+// BuildEatingWindow finds the first and last timed entries of the day and compares the
+// span with the protocol's eating window (protocol may be nil); nil when no entry is timed
+func BuildEatingWindow(entries []DiaryEntry, date time.Time, loc *time.Location, protocol *fasting.Protocol) *EatingWindow {
+	var first, last float64
+	timed := 0
+	for _, entry := range entries {
+		if entry.ConsumedAt == nil {
+			continue
+		}
+		minutes := minutesFromMidnight(date, *entry.ConsumedAt, loc)
+		if timed == 0 || minutes < first {
+			first = minutes
+		}
+		if timed == 0 || minutes > last {
+			last = minutes
+		}
+		timed++
+	}
+	if timed == 0 {
+		return nil
+	}
+
+	window := &EatingWindow{
+		FirstMeal:    formatClock(first),
+		LastMeal:     formatClock(last),
+		Hours:        roundToTwo((last - first) / 60),
+		TimedEntries: timed,
+	}
+	if protocol != nil {
+		window.Protocol = protocol.Code
+		if protocol.EatingWindowHours > 0 {
+			within := window.Hours <= protocol.EatingWindowHours
+			window.WithinProtocol = &within
+		}
+	}
+	return window
+}
+
+// AddMealTiming adds the average and extreme meal times of the days in times to the
+// overall summary and to each period, reading the times in loc
+func (s *RangeSummary) AddMealTiming(times []MealTimes, loc *time.Location) {
+	s.Overall.Timing = summarizeMealTimes(times, s.Overall.StartDate, s.Overall.EndDate, loc)
+	for i := range s.Periods {
+		s.Periods[i].Timing = summarizeMealTimes(times, s.Periods[i].StartDate, s.Periods[i].EndDate, loc)
+	}
+}
+
+// summarizeMealTimes computes the meal timing of the days from start to end (YYYY-MM-DD, inclusive)
+func summarizeMealTimes(times []MealTimes, start, end string, loc *time.Location) *MealTiming {
+	var firstTotal, lastTotal, earliest, latest float64
+	days := 0
+	for _, day := range times {
+		key := day.Day.Format("2006-01-02")
+		if key < start || key > end {
+			continue
+		}
+		first := minutesFromMidnight(day.Day, day.FirstAt, loc)
+		last := minutesFromMidnight(day.Day, day.LastAt, loc)
+		if days == 0 || first < earliest {
+			earliest = first
+		}
+		if days == 0 || last > latest {
+			latest = last
+		}
+		firstTotal += first
+		lastTotal += last
+		days++
+	}
+	if days == 0 {
+		return nil
+	}
+
+	n := float64(days)
+	return &MealTiming{
+		DaysTimed:           days,
+		AverageFirstMeal:    formatClock(firstTotal / n),
+		AverageLastMeal:     formatClock(lastTotal / n),
+		AverageEatingWindow: roundToTwo((lastTotal - firstTotal) / n / 60),
+		EarliestFirstMeal:   formatClock(earliest),
+		LatestLastMeal:      formatClock(latest),
+	}
+}
@@ -0,0 +1,107 @@
+package diary
+
+import "ultra-bis/internal/units"
+
+// Quantities are stored in grams and energies in kcal. Requests may give quantities
+// in the user's unit with "quantity", and responses are converted to the user's
+// units and state them in "units".
+
+// NormalizeUnits converts the quantities and inline food energy of a request to grams and kcal
+func (req *CreateDiaryEntryRequest) NormalizeUnits(prefs units.Preferences) {
+	req.QuantityGrams = quantityGrams(req.QuantityGrams, req.Quantity, prefs)
+	normalizeCustomIngredients(req.CustomIngredients, prefs)
+	req.InlineFoodCalories = prefs.EnergyToKcal(req.InlineFoodCalories)
+}
+
+// NormalizeUnits converts the quantities and inline food energy of a request to grams and kcal
+func (req *UpdateDiaryEntryRequest) NormalizeUnits(prefs units.Preferences) {
+	req.QuantityGrams = quantityGrams(req.QuantityGrams, req.Quantity, prefs)
+	normalizeCustomIngredients(req.CustomIngredients, prefs)
+	if req.InlineFoodCalories != nil {
+		kcal := prefs.EnergyToKcal(*req.InlineFoodCalories)
+		req.InlineFoodCalories = &kcal
+	}
+}
+
+// NormalizeUnits converts the quantity of a request to grams
+// Product nutrition is kept in kcal per 100g, as Open Food Facts returns it
+func (req *CreateEntryFromOpenFoodFactsRequest) NormalizeUnits(prefs units.Preferences) {
+	req.QuantityGrams = quantityGrams(req.QuantityGrams, req.Quantity, prefs)
+}
+
+// normalizeCustomIngredients converts custom ingredient quantities to grams
+func normalizeCustomIngredients(ingredients []CustomIngredientRequest, prefs units.Preferences) {
+	for i := range ingredients {
+		ingredients[i].QuantityGrams = quantityGrams(ingredients[i].QuantityGrams, ingredients[i].Quantity, prefs)
+	}
+}
+
+// quantityGrams returns grams when given, otherwise quantity converted from the user's unit
+func quantityGrams(grams, quantity float64, prefs units.Preferences) float64 {
+	if grams == 0 && quantity > 0 {
+		return prefs.QuantityToGrams(quantity)
+	}
+	return grams
+}
+
+// ConvertUnits converts the entry's quantity and energies to prefs and states its units
+func (e *DiaryEntry) ConvertUnits(prefs units.Preferences) {
+	e.Quantity = prefs.QuantityFromGrams(e.QuantityGrams)
+	e.Calories = prefs.EnergyFromKcal(e.Calories)
+	if e.InlineFoodCalories != nil {
+		energy := prefs.EnergyFromKcal(*e.InlineFoodCalories)
+		e.InlineFoodCalories = &energy
+	}
+
+	for i := range e.CustomIngredients {
+		e.CustomIngredients[i].Quantity = prefs.QuantityFromGrams(e.CustomIngredients[i].QuantityGrams)
+		e.CustomIngredients[i].Calories = prefs.EnergyFromKcal(e.CustomIngredients[i].Calories)
+	}
+
+	e.Units = &prefs
+}
+
+// ConvertEntryUnits converts a list of entries to prefs
+func ConvertEntryUnits(entries []DiaryEntry, prefs units.Preferences) {
+	for i := range entries {
+		entries[i].ConvertUnits(prefs)
+	}
+}
+
+// ConvertUnits converts the summary's energies and entries to prefs and states its units
+func (s *DailySummary) ConvertUnits(prefs units.Preferences) {
+	s.TotalCalories = prefs.EnergyFromKcal(s.TotalCalories)
+	s.NetCalories = prefs.EnergyFromKcal(s.NetCalories)
+	s.GoalCalories = prefs.EnergyFromKcal(s.GoalCalories)
+	s.Exercise.CaloriesBurned = prefs.EnergyFromKcal(s.Exercise.CaloriesBurned)
+	s.Exercise.CaloriesAdded = prefs.EnergyFromKcal(s.Exercise.CaloriesAdded)
+	s.Exercise.BaseGoalCalories = prefs.EnergyFromKcal(s.Exercise.BaseGoalCalories)
+	s.RoutineCalories = prefs.EnergyFromKcal(s.RoutineCalories)
+	s.ContextualCalories = prefs.EnergyFromKcal(s.ContextualCalories)
+	for i := range s.Meals {
+		s.Meals[i].TotalCalories = prefs.EnergyFromKcal(s.Meals[i].TotalCalories)
+		s.Meals[i].Target.Calories = prefs.EnergyFromKcal(s.Meals[i].Target.Calories)
+	}
+	ConvertEntryUnits(s.Entries, prefs)
+	s.Units = &prefs
+}
+
+// ConvertUnits converts the summary's energies to prefs and states its units
+func (s *RangeSummary) ConvertUnits(prefs units.Preferences) {
+	s.Overall.convertUnits(prefs)
+	for i := range s.Periods {
+		s.Periods[i].convertUnits(prefs)
+	}
+	s.Units = &prefs
+}
+
+// convertUnits converts the period's energies to prefs
+func (p *PeriodSummary) convertUnits(prefs units.Preferences) {
+	p.TotalCalories = prefs.EnergyFromKcal(p.TotalCalories)
+	p.AverageCalories = prefs.EnergyFromKcal(p.AverageCalories)
+	p.GoalCalories = prefs.EnergyFromKcal(p.GoalCalories)
+	for i := range p.Meals {
+		p.Meals[i].TotalCalories = prefs.EnergyFromKcal(p.Meals[i].TotalCalories)
+		p.Meals[i].AverageCalories = prefs.EnergyFromKcal(p.Meals[i].AverageCalories)
+	}
+}
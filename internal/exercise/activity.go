@@ -0,0 +1,64 @@
+package exercise
+
+// Intensity levels of an exercise
+const (
+	IntensityLow      = "low"
+	IntensityModerate = "moderate"
+	IntensityHigh     = "high"
+)
+
+// Activity is a type of exercise with its metabolic equivalents (MET) per intensity,
+// after the Compendium of Physical Activities
+type Activity struct {
+	Code     string  `json:"code"`
+	Low      float64 `json:"low"`
+	Moderate float64 `json:"moderate"`
+	High     float64 `json:"high"`
+}
+
+// Activities lists the supported activities
+var Activities = []Activity{
+	{Code: "walking", Low: 2.8, Moderate: 3.5, High: 5.0},
+	{Code: "running", Low: 7.0, Moderate: 9.8, High: 12.3},
+	{Code: "cycling", Low: 4.0, Moderate: 6.8, High: 10.0},
+	{Code: "swimming", Low: 5.8, Moderate: 8.3, High: 10.0},
+	{Code: "rowing", Low: 4.8, Moderate: 7.0, High: 8.5},
+	{Code: "hiking", Low: 5.3, Moderate: 6.0, High: 7.8},
+	{Code: "elliptical", Low: 4.6, Moderate: 5.0, High: 6.3},
+	{Code: "strength", Low: 3.5, Moderate: 5.0, High: 6.0},
+	{Code: "hiit", Low: 6.0, Moderate: 8.0, High: 10.0},
+	{Code: "yoga", Low: 2.0, Moderate: 2.5, High: 4.0},
+	{Code: "dancing", Low: 3.0, Moderate: 5.0, High: 7.3},
+	{Code: "team_sports", Low: 5.0, Moderate: 7.0, High: 8.0},
+	{Code: "other", Low: 3.0, Moderate: 5.0, High: 7.0},
+}
+
+// FindActivity returns the activity with code, or nil when code is unknown
+func FindActivity(code string) *Activity {
+	for i := range Activities {
+		if Activities[i].Code == code {
+			activity := Activities[i]
+			return &activity
+		}
+	}
+	return nil
+}
+
+// MET returns the activity's metabolic equivalent at an intensity, 0 for an unknown intensity
+func (a Activity) MET(intensity string) float64 {
+	switch intensity {
+	case IntensityLow:
+		return a.Low
+	case IntensityModerate:
+		return a.Moderate
+	case IntensityHigh:
+		return a.High
+	}
+	return 0
+}
+
+// EstimateCalories returns the kcal burned at met for minutes by someone weighing weightKg
+// One MET is about 1 kcal per kg of body weight per hour
+func EstimateCalories(met, weightKg, minutes float64) float64 {
+	return met * weightKg * minutes / 60
+}
@@ -0,0 +1,204 @@
+package exercise
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/metrics"
+	"ultra-bis/internal/units"
+	"ultra-bis/internal/user"
+)
+
+// Handler handles exercise log requests
+type Handler struct {
+	repo        *Repository
+	userRepo    *user.Repository
+	metricsRepo *metrics.Repository
+	dayObserver DayObserver
+}
+
+// DayObserver is notified of the days whose exercises changed, e.g. to update streaks
+// against the exercise-adjusted calorie goal (diary.DayObserver, which this package cannot import)
+type DayObserver interface {
+	DayChanged(userID uint, dates ...time.Time) error
+}
+
+// NewHandler creates a new exercise handler
+// The user and metrics repositories provide the weight MET estimates need
+func NewHandler(repo *Repository, userRepo *user.Repository, metricsRepo *metrics.Repository) *Handler {
+	return &Handler{repo: repo, userRepo: userRepo, metricsRepo: metricsRepo}
+}
+
+// SetDayObserver sets the observer notified when a day's exercises change
+func (h *Handler) SetDayObserver(observer DayObserver) {
+	h.dayObserver = observer
+}
+
+// notifyDayChanged tells the day observer about a changed day; a failure is logged
+// rather than failing a request whose exercise was already saved
+func (h *Handler) notifyDayChanged(userID uint, date time.Time) {
+	if h.dayObserver == nil {
+		return
+	}
+	if err := h.dayObserver.DayChanged(userID, date); err != nil {
+		log.Printf("Failed to update streaks for user %d: %v", userID, err)
+	}
+}
+
+// ListActivities handles GET /exercise/activities
+func (h *Handler) ListActivities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, Activities)
+}
+
+// LogExercise handles POST /exercise
+// Logs an exercise with the calories entered, or estimated from the activity's MET and the latest weight
+func (h *Handler) LogExercise(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req LogExerciseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Validated in kcal, whatever energy unit the calories were entered in
+	prefs := units.FromRequest(r)
+	req.Calories = prefs.EnergyToKcal(req.Calories)
+
+	now := time.Now()
+	if err := ValidateLogExerciseRequest(&req, now); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var weight float64
+	if req.Calories == 0 {
+		weight = h.latestWeight(userID)
+	}
+	entry, ok := NewEntry(userID, &req, weight)
+	if !ok {
+		httputil.WriteError(w, http.StatusBadRequest, "Log your weight or enter calories to log this exercise")
+		return
+	}
+
+	entry.PerformedAt = now
+	if req.PerformedAt != nil {
+		entry.PerformedAt = *req.PerformedAt
+	}
+	entry.Date = calendar.FromRequest(r).DateOf(entry.PerformedAt)
+
+	if err := h.repo.Create(entry); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.notifyDayChanged(userID, entry.Date)
+
+	entry.ConvertUnits(prefs)
+	httputil.WriteJSON(w, http.StatusCreated, entry)
+}
+
+// GetExercise handles GET /exercise?date=YYYY-MM-DD
+// Returns the exercises logged on a day (today by default) and their totals
+func (h *Handler) GetExercise(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	date := calendar.FromRequest(r).Today()
+	if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+		var err error
+		date, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
+			return
+		}
+	}
+
+	entries, err := h.repo.GetByDate(userID, date)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := DayLog{Date: date.Format("2006-01-02"), Entries: entries}
+	for _, entry := range entries {
+		response.Calories += entry.Calories
+		response.Minutes += entry.DurationMinutes
+	}
+
+	response.ConvertUnits(units.FromRequest(r))
+	httputil.WriteJSON(w, http.StatusOK, response)
+}
+
+// DeleteExercise handles DELETE /exercise/{id}
+func (h *Handler) DeleteExercise(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := extractID(r.URL.Path, "/exercise/")
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	entry, err := h.repo.Delete(uint(id), userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Exercise not found")
+		return
+	}
+	h.notifyDayChanged(userID, entry.Date)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// latestWeight returns the user's latest logged weight, or the profile weight, in kg (0 if unknown)
+func (h *Handler) latestWeight(userID uint) float64 {
+	if latest, err := h.metricsRepo.GetLatest(userID); err == nil && latest.Weight > 0 {
+		return latest.Weight
+	}
+	if profile, err := h.userRepo.GetByID(userID); err == nil {
+		return profile.Weight
+	}
+	return 0
+}
+
+// extractID extracts the ID from the URL path
+func extractID(path, prefix string) (int, error) {
+	idStr := strings.TrimPrefix(path, prefix)
+	return strconv.Atoi(idStr)
+}
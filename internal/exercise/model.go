@@ -0,0 +1,78 @@
+package exercise
+
+import (
+	"time"
+
+	"ultra-bis/internal/units"
+)
+
+// Exercise limits
+const (
+	MaxDurationMinutes = 1440.0  // One day
+	MaxCalories        = 10000.0 // kcal for a single exercise
+)
+
+// Sources of an exercise's calories
+const (
+	SourceMET    = "met"    // Estimated from the activity's MET, the duration and the user's weight
+	SourceManual = "manual" // Entered by the user, e.g. from a watch
+)
+
+// ExerciseEntry records an exercise and the calories it burned
+type ExerciseEntry struct {
+	ID              uint      `json:"id" gorm:"primarykey"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	UserID          uint      `json:"user_id" gorm:"not null;index:idx_exercise_user_date"`
+	Date            time.Time `json:"date" gorm:"type:date;not null;index:idx_exercise_user_date"` // Day of PerformedAt in the user's time zone
+	PerformedAt     time.Time `json:"performed_at" gorm:"not null"`
+	Activity        string    `json:"activity" gorm:"type:varchar(20);not null"`
+	Name            string    `json:"name,omitempty" gorm:"type:varchar(100)"`
+	DurationMinutes float64   `json:"duration_minutes" gorm:"type:decimal(6,2);not null"`
+	Intensity       string    `json:"intensity" gorm:"type:varchar(10);not null"`
+	MET             float64   `json:"met,omitempty" gorm:"type:decimal(4,1)"` // 0 when the calories were entered
+	Calories        float64   `json:"calories" gorm:"type:decimal(10,2);not null"`
+	Source          string    `json:"source" gorm:"type:varchar(10);not null"` // "met" or "manual"
+	Notes           string    `json:"notes,omitempty" gorm:"type:text"`
+}
+
+// LogExerciseRequest represents the request to log an exercise
+// Without calories, they are estimated from the activity's MET and the user's latest weight
+type LogExerciseRequest struct {
+	Activity        string     `json:"activity"`
+	Name            string     `json:"name"`
+	DurationMinutes float64    `json:"duration_minutes"`
+	Intensity       string     `json:"intensity"`    // "low", "moderate" (default) or "high"
+	Calories        float64    `json:"calories"`     // In the user's energy unit, optional
+	PerformedAt     *time.Time `json:"performed_at"` // RFC 3339, defaults to now
+	Notes           string     `json:"notes"`
+}
+
+// Totals represents the exercise of one day
+type Totals struct {
+	Calories float64 `json:"calories"` // kcal burned
+	Minutes  float64 `json:"minutes"`
+}
+
+// DayLog represents the exercises logged on one day, as returned by GET /exercise
+type DayLog struct {
+	Date string `json:"date"`
+	Totals
+	Entries []ExerciseEntry `json:"entries"`
+
+	Units *units.Preferences `json:"units,omitempty"` // Units of the response values
+}
+
+// ConvertUnits converts the entry's calories to prefs
+func (e *ExerciseEntry) ConvertUnits(prefs units.Preferences) {
+	e.Calories = prefs.EnergyFromKcal(e.Calories)
+}
+
+// ConvertUnits converts the day's calories to prefs and states its units
+func (d *DayLog) ConvertUnits(prefs units.Preferences) {
+	d.Calories = prefs.EnergyFromKcal(d.Calories)
+	for i := range d.Entries {
+		d.Entries[i].ConvertUnits(prefs)
+	}
+	d.Units = &prefs
+}
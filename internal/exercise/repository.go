@@ -0,0 +1,90 @@
+package exercise
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository handles database operations for the exercise log
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new exercise repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Create records an exercise
+func (r *Repository) Create(entry *ExerciseEntry) error {
+	if err := r.db.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to create exercise: %w", err)
+	}
+	return nil
+}
+
+// GetByDate retrieves the exercises a user logged on a day, in the order they were performed
+func (r *Repository) GetByDate(userID uint, date time.Time) ([]ExerciseEntry, error) {
+	var entries []ExerciseEntry
+	err := r.db.Where("user_id = ? AND date = ?", userID, date.Format("2006-01-02")).
+		Order("performed_at, id").
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exercises: %w", err)
+	}
+	return entries, nil
+}
+
+// GetDayTotals adds up the calories and minutes a user exercised on a day
+func (r *Repository) GetDayTotals(userID uint, date time.Time) (Totals, error) {
+	var totals Totals
+	err := r.db.Model(&ExerciseEntry{}).
+		Select("COALESCE(SUM(calories), 0) AS calories, COALESCE(SUM(duration_minutes), 0) AS minutes").
+		Where("user_id = ? AND date = ?", userID, date.Format("2006-01-02")).
+		Scan(&totals).Error
+	if err != nil {
+		return Totals{}, fmt.Errorf("failed to calculate exercise totals: %w", err)
+	}
+	return totals, nil
+}
+
+// GetTotalsByDay adds up the calories and minutes a user exercised on each day of [from, to],
+// keyed by YYYY-MM-DD; days without exercise are absent
+func (r *Repository) GetTotalsByDay(userID uint, from, to time.Time) (map[string]Totals, error) {
+	var rows []struct {
+		Day      time.Time
+		Calories float64
+		Minutes  float64
+	}
+	err := r.db.Model(&ExerciseEntry{}).
+		Select("date AS day, COALESCE(SUM(calories), 0) AS calories, COALESCE(SUM(duration_minutes), 0) AS minutes").
+		Where("user_id = ? AND date >= ? AND date <= ?", userID, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Group("date").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate exercise totals: %w", err)
+	}
+
+	totals := make(map[string]Totals, len(rows))
+	for _, row := range rows {
+		totals[row.Day.Format("2006-01-02")] = Totals{Calories: row.Calories, Minutes: row.Minutes}
+	}
+	return totals, nil
+}
+
+// Delete removes one of a user's exercises and returns it
+func (r *Repository) Delete(id, userID uint) (*ExerciseEntry, error) {
+	var entry ExerciseEntry
+	result := r.db.Clauses(clause.Returning{}).Where("id = ? AND user_id = ?", id, userID).Delete(&entry)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to delete exercise: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("exercise not found")
+	}
+	return &entry, nil
+}
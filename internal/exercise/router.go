@@ -0,0 +1,25 @@
+package exercise
+
+import (
+	"net/http"
+
+	"ultra-bis/internal/auth"
+)
+
+// RegisterRoutes registers all exercise-related routes to the provided mux
+func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
+	// All exercise routes are protected with JWT
+
+	mux.HandleFunc("/exercise", auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handler.GetExercise(w, r)
+		case http.MethodPost:
+			handler.LogExercise(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/exercise/activities", auth.JWTMiddleware(handler.ListActivities))
+	mux.HandleFunc("/exercise/", auth.JWTMiddleware(handler.DeleteExercise))
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"ultra-bis/internal/exercise"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivities(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, activity := range exercise.Activities {
+		assert.False(t, seen[activity.Code], "duplicate activity %s", activity.Code)
+		seen[activity.Code] = true
+		assert.True(t, activity.Low > 0 && activity.Low <= activity.Moderate && activity.Moderate <= activity.High, activity.Code)
+	}
+
+	running := exercise.FindActivity("running")
+	require.NotNil(t, running)
+	assert.Equal(t, 9.8, running.MET(exercise.IntensityModerate))
+	assert.Equal(t, 12.3, running.MET(exercise.IntensityHigh))
+	assert.Zero(t, running.MET("extreme"))
+	assert.Nil(t, exercise.FindActivity("quidditch"))
+}
+
+func TestEstimateCalories(t *testing.T) {
+	// 10 MET for an hour at 70 kg
+	assert.Equal(t, 700.0, exercise.EstimateCalories(10, 70, 60))
+	assert.Equal(t, 175.0, exercise.EstimateCalories(3.5, 60, 50))
+}
+
+func TestValidateLogExerciseRequest(t *testing.T) {
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+
+	req := exercise.LogExerciseRequest{Activity: "cycling", DurationMinutes: 45, Name: "  Commute "}
+	require.NoError(t, exercise.ValidateLogExerciseRequest(&req, now))
+	assert.Equal(t, exercise.IntensityModerate, req.Intensity)
+	assert.Equal(t, "Commute", req.Name)
+
+	later := now.Add(time.Hour)
+	cases := map[string]exercise.LogExerciseRequest{
+		"Unknown activity": {Activity: "quidditch", DurationMinutes: 30},
+		"duration_minutes must be between 0 and 1440":   {Activity: "running"},
+		"intensity must be 'low', 'moderate' or 'high'": {Activity: "running", DurationMinutes: 30, Intensity: "extreme"},
+		"calories must be between 0 and 10000":          {Activity: "running", DurationMinutes: 30, Calories: -5},
+		"performed_at cannot be in the future":          {Activity: "running", DurationMinutes: 30, PerformedAt: &later},
+		"name must be at most 100 characters":           {Activity: "running", DurationMinutes: 30, Name: strings.Repeat("a", 101)},
+	}
+	for message, req := range cases {
+		assert.EqualError(t, exercise.ValidateLogExerciseRequest(&req, now), message)
+	}
+}
+
+func TestNewEntry(t *testing.T) {
+	// Estimated from the MET and the weight
+	req := exercise.LogExerciseRequest{Activity: "running", DurationMinutes: 30, Intensity: exercise.IntensityModerate}
+	entry, ok := exercise.NewEntry(1, &req, 80)
+	require.True(t, ok)
+	assert.Equal(t, 9.8, entry.MET)
+	assert.Equal(t, 392.0, entry.Calories)
+	assert.Equal(t, exercise.SourceMET, entry.Source)
+
+	// Entered calories win, and need no weight
+	req.Calories = 420
+	entry, ok = exercise.NewEntry(1, &req, 0)
+	require.True(t, ok)
+	assert.Equal(t, 420.0, entry.Calories)
+	assert.Zero(t, entry.MET)
+	assert.Equal(t, exercise.SourceManual, entry.Source)
+
+	// Without either, the calories are unknown
+	req.Calories = 0
+	_, ok = exercise.NewEntry(1, &req, 0)
+	assert.False(t, ok)
+}
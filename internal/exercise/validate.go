@@ -0,0 +1,63 @@
+package exercise
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+// ValidateLogExerciseRequest checks an exercise and defaults its intensity to moderate
+func ValidateLogExerciseRequest(req *LogExerciseRequest, now time.Time) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Intensity == "" {
+		req.Intensity = IntensityModerate
+	}
+
+	if FindActivity(req.Activity) == nil {
+		return errors.New("Unknown activity")
+	}
+	if len(req.Name) > 100 {
+		return errors.New("name must be at most 100 characters")
+	}
+	if req.DurationMinutes <= 0 || req.DurationMinutes > MaxDurationMinutes {
+		return errors.New("duration_minutes must be between 0 and 1440")
+	}
+	if req.Intensity != IntensityLow && req.Intensity != IntensityModerate && req.Intensity != IntensityHigh {
+		return errors.New("intensity must be 'low', 'moderate' or 'high'")
+	}
+	if req.Calories < 0 || req.Calories > MaxCalories {
+		return errors.New("calories must be between 0 and 10000")
+	}
+	if req.PerformedAt != nil && req.PerformedAt.After(now.Add(time.Minute)) {
+		return errors.New("performed_at cannot be in the future")
+	}
+	return nil
+}
+
+// NewEntry builds the entry of a validated request, using the entered calories (kcal) or
+// estimating them from the activity's MET and weightKg
+// It returns false when the calories must be estimated but the weight is unknown
+func NewEntry(userID uint, req *LogExerciseRequest, weightKg float64) (*ExerciseEntry, bool) {
+	entry := &ExerciseEntry{
+		UserID:          userID,
+		Activity:        req.Activity,
+		Name:            req.Name,
+		DurationMinutes: req.DurationMinutes,
+		Intensity:       req.Intensity,
+		Calories:        req.Calories,
+		Source:          SourceManual,
+		Notes:           req.Notes,
+	}
+	if req.Calories > 0 {
+		return entry, true
+	}
+	if weightKg <= 0 {
+		return nil, false
+	}
+
+	entry.MET = FindActivity(req.Activity).MET(req.Intensity)
+	entry.Calories = math.Round(EstimateCalories(entry.MET, weightKg, req.DurationMinutes)*100) / 100
+	entry.Source = SourceMET
+	return entry, true
+}
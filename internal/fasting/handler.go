@@ -0,0 +1,293 @@
+package fasting
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/httputil"
+)
+
+// Fasting history ranges
+const (
+	DefaultHistoryDays = 30
+	MaxHistoryDays     = 366
+)
+
+// Handler handles fasting protocol and session requests
+type Handler struct {
+	repo *Repository
+}
+
+// NewHandler creates a new fasting handler
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// Protocol handles GET and PUT /fasting/protocol
+// GET returns the user's protocol and the supported ones; PUT chooses one ("" to stop following one)
+func (h *Handler) Protocol(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req SetProtocolRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if req.Protocol != "" && FindProtocol(req.Protocol) == nil {
+			httputil.WriteError(w, http.StatusBadRequest, "protocol must be '16:8', '5:2' or 'omad'")
+			return
+		}
+		if err := h.repo.SetProtocol(userID, req.Protocol); err != nil {
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	default:
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	protocol, err := h.repo.GetProtocol(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, ProtocolResponse{Protocol: protocol, Protocols: Protocols})
+}
+
+// Start handles POST /fasting/start
+// Starts a fast now or at started_at, targeting target_hours or the protocol's fasting hours
+func (h *Handler) Start(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req StartRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	current, err := h.repo.GetCurrent(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if current != nil {
+		httputil.WriteError(w, http.StatusConflict, "A fast is already in progress")
+		return
+	}
+
+	protocol, err := h.repo.GetProtocol(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	now := time.Now()
+	startedAt := now
+	if req.StartedAt != nil {
+		startedAt = *req.StartedAt
+	}
+	lastEnd, err := h.repo.GetLastEnd(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	targetHours := TargetHours(req.TargetHours, protocol)
+	if err := ValidateStart(startedAt, targetHours, lastEnd, now); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session := &FastingSession{UserID: userID, StartedAt: startedAt, TargetHours: targetHours}
+	if protocol != nil {
+		session.Protocol = protocol.Code
+	}
+	if err := h.repo.Create(session); err != nil {
+		if errors.Is(err, ErrFastInProgress) {
+			httputil.WriteError(w, http.StatusConflict, "A fast is already in progress")
+			return
+		}
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	session.Measure(now)
+	httputil.WriteJSON(w, http.StatusCreated, session)
+}
+
+// Stop handles POST /fasting/stop
+// Ends the fast in progress now or at ended_at
+func (h *Handler) Stop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req StopRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	session, err := h.repo.GetCurrent(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if session == nil {
+		httputil.WriteError(w, http.StatusNotFound, "No fast in progress")
+		return
+	}
+
+	now := time.Now()
+	endedAt := now
+	if req.EndedAt != nil {
+		endedAt = *req.EndedAt
+	}
+	if err := ValidateStop(session, endedAt, now); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session.EndedAt = &endedAt
+	if err := h.repo.Update(session); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	session.Measure(now)
+	httputil.WriteJSON(w, http.StatusOK, session)
+}
+
+// GetHistory handles GET /fasting?from=YYYY-MM-DD&to=YYYY-MM-DD
+// Returns the fast in progress and the fasts started in the range (the last 30 days by default) with their stats
+func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	settings := calendar.FromRequest(r)
+	query := r.URL.Query()
+	to := settings.Today()
+	from := to.AddDate(0, 0, -(DefaultHistoryDays - 1))
+	if query.Get("from") != "" || query.Get("to") != "" {
+		var err error
+		if from, err = time.Parse("2006-01-02", query.Get("from")); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "from is required (use YYYY-MM-DD)")
+			return
+		}
+		if to, err = time.Parse("2006-01-02", query.Get("to")); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "to is required (use YYYY-MM-DD)")
+			return
+		}
+	}
+	if to.Before(from) {
+		httputil.WriteError(w, http.StatusBadRequest, "to must not be before from")
+		return
+	}
+	if to.Sub(from) >= MaxHistoryDays*24*time.Hour {
+		httputil.WriteError(w, http.StatusBadRequest, "Fasting history is limited to 366 days")
+		return
+	}
+
+	// The range's days start at midnight in the user's time zone
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, settings.Location)
+	end := time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, settings.Location)
+	sessions, err := h.repo.GetByRange(userID, start, end)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	current, err := h.repo.GetCurrent(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	protocol, err := h.repo.GetProtocol(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	now := time.Now()
+	if current != nil {
+		current.Measure(now)
+	}
+	if sessions == nil {
+		sessions = []FastingSession{}
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, HistoryResponse{
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		Protocol: protocol,
+		Current:  current,
+		Sessions: sessions,
+		Stats:    Summarize(sessions, now),
+	})
+}
+
+// DeleteSession handles DELETE /fasting/{id}
+func (h *Handler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/fasting/"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	if err := h.repo.Delete(uint(id), userID); err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Fast not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,64 @@
+package fasting
+
+import (
+	"time"
+)
+
+// MaxTargetHours is the longest fast that can be targeted
+const MaxTargetHours = 72.0
+
+// FastingSession is a fast started and, once over, stopped by the user
+type FastingSession struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	UserID      uint       `json:"user_id" gorm:"not null;index:idx_fasting_user_started;uniqueIndex:idx_fasting_user_open,where:ended_at IS NULL"` // One fast in progress per user
+	StartedAt   time.Time  `json:"started_at" gorm:"not null;index:idx_fasting_user_started"`
+	EndedAt     *time.Time `json:"ended_at,omitempty"` // Nil while the fast is in progress
+	TargetHours float64    `json:"target_hours" gorm:"type:decimal(5,2);not null"`
+	Protocol    string     `json:"protocol,omitempty" gorm:"type:varchar(10)"` // Protocol followed when the fast started
+
+	// Computed in responses (not persisted)
+	Hours     float64 `json:"hours" gorm:"-"`     // Length so far, or total length once stopped
+	Completed bool    `json:"completed" gorm:"-"` // Reached the target
+}
+
+// StartRequest represents the request to start a fast
+type StartRequest struct {
+	StartedAt   *time.Time `json:"started_at"`   // RFC 3339, defaults to now
+	TargetHours float64    `json:"target_hours"` // Defaults to the protocol's fasting hours
+}
+
+// StopRequest represents the request to stop the fast in progress
+type StopRequest struct {
+	EndedAt *time.Time `json:"ended_at"` // RFC 3339, defaults to now
+}
+
+// SetProtocolRequest represents the request to choose a fasting protocol
+type SetProtocolRequest struct {
+	Protocol string `json:"protocol"` // "16:8", "5:2", "omad" or "" to stop following one
+}
+
+// ProtocolResponse represents the user's protocol and the supported ones
+type ProtocolResponse struct {
+	Protocol  *Protocol  `json:"protocol"` // Nil when the user follows none
+	Protocols []Protocol `json:"protocols"`
+}
+
+// Stats summarizes the finished fasts of a range
+type Stats struct {
+	Fasts        int     `json:"fasts"`
+	Completed    int     `json:"completed"`
+	AverageHours float64 `json:"average_hours"`
+	LongestHours float64 `json:"longest_hours"`
+}
+
+// HistoryResponse represents the fast in progress and the fasts started in a range
+type HistoryResponse struct {
+	From     string           `json:"from"`
+	To       string           `json:"to"`
+	Protocol *Protocol        `json:"protocol"`
+	Current  *FastingSession  `json:"current"` // Nil when not fasting
+	Sessions []FastingSession `json:"sessions"`
+	Stats    Stats            `json:"stats"`
+}
@@ -0,0 +1,38 @@
+package fasting
+
+// Protocol is an intermittent fasting schedule a user can follow
+type Protocol struct {
+	Code              string  `json:"code"`
+	FastingHours      float64 `json:"fasting_hours"`                // Default target of a fast
+	EatingWindowHours float64 `json:"eating_window_hours"`          // Longest daily eating window, 0 when the protocol does not limit it
+	FastDaysPerWeek   int     `json:"fast_days_per_week,omitempty"` // For protocols fasting on some days only
+	FastDayCalories   float64 `json:"fast_day_calories,omitempty"`  // Calories allowed on a fast day
+}
+
+// Protocol codes
+const (
+	Protocol16To8 = "16:8" // 16 hours fasting, 8 hours eating window every day
+	Protocol5To2  = "5:2"  // Normal eating 5 days a week, about 500 kcal on 2 fast days
+	ProtocolOMAD  = "omad" // One meal a day: a one-hour eating window
+)
+
+// Protocols lists the supported protocols
+var Protocols = []Protocol{
+	{Code: Protocol16To8, FastingHours: 16, EatingWindowHours: 8},
+	{Code: Protocol5To2, FastingHours: 24, FastDaysPerWeek: 2, FastDayCalories: 500},
+	{Code: ProtocolOMAD, FastingHours: 23, EatingWindowHours: 1},
+}
+
+// DefaultTargetHours is the target of a fast started without a protocol or target
+const DefaultTargetHours = 16.0
+
+// FindProtocol returns the protocol with code, or nil when code is unknown
+func FindProtocol(code string) *Protocol {
+	for i := range Protocols {
+		if Protocols[i].Code == code {
+			protocol := Protocols[i]
+			return &protocol
+		}
+	}
+	return nil
+}
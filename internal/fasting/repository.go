@@ -0,0 +1,118 @@
+package fasting
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ultra-bis/internal/user"
+)
+
+// ErrFastInProgress is returned when a fast is started while another one is still in progress
+var ErrFastInProgress = errors.New("fast already in progress")
+
+// Repository handles database operations for fasting sessions and the users' protocols
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new fasting repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// GetProtocol returns the protocol a user follows, or nil when they follow none
+func (r *Repository) GetProtocol(userID uint) (*Protocol, error) {
+	var u user.User
+	result := r.db.Select("id", "fasting_protocol").Limit(1).Find(&u, userID)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get fasting protocol: %w", result.Error)
+	}
+	return FindProtocol(u.FastingProtocol), nil
+}
+
+// SetProtocol sets the protocol a user follows; an empty code clears it
+func (r *Repository) SetProtocol(userID uint, code string) error {
+	result := r.db.Model(&user.User{}).Where("id = ?", userID).Update("fasting_protocol", code)
+	if result.Error != nil {
+		return fmt.Errorf("failed to set fasting protocol: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// Create records a new fast
+// The unique index on the fasts in progress rejects a concurrent start with ErrFastInProgress
+func (r *Repository) Create(session *FastingSession) error {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(session)
+	if result.Error != nil {
+		return fmt.Errorf("failed to start fast: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrFastInProgress
+	}
+	return nil
+}
+
+// Update saves a fast
+func (r *Repository) Update(session *FastingSession) error {
+	if err := r.db.Save(session).Error; err != nil {
+		return fmt.Errorf("failed to update fast: %w", err)
+	}
+	return nil
+}
+
+// GetCurrent retrieves the fast in progress, or nil when the user is not fasting
+func (r *Repository) GetCurrent(userID uint) (*FastingSession, error) {
+	var session FastingSession
+	result := r.db.Where("user_id = ? AND ended_at IS NULL", userID).Order("started_at DESC").Limit(1).Find(&session)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get current fast: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+// GetLastEnd returns when the user's latest stopped fast ended, or nil when they never stopped one
+func (r *Repository) GetLastEnd(userID uint) (*time.Time, error) {
+	var session FastingSession
+	result := r.db.Where("user_id = ? AND ended_at IS NOT NULL", userID).Order("ended_at DESC").Limit(1).Find(&session)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get last fast: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return session.EndedAt, nil
+}
+
+// GetByRange retrieves the fasts started in [start, end), most recent first
+func (r *Repository) GetByRange(userID uint, start, end time.Time) ([]FastingSession, error) {
+	var sessions []FastingSession
+	err := r.db.Where("user_id = ? AND started_at >= ? AND started_at < ?", userID, start, end).
+		Order("started_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fasts: %w", err)
+	}
+	return sessions, nil
+}
+
+// Delete removes one of a user's fasts
+func (r *Repository) Delete(id, userID uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&FastingSession{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete fast: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("fast not found")
+	}
+	return nil
+}
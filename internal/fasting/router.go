@@ -0,0 +1,18 @@
+package fasting
+
+import (
+	"net/http"
+
+	"ultra-bis/internal/auth"
+)
+
+// RegisterRoutes registers all fasting-related routes to the provided mux
+func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
+	// All fasting routes are protected with JWT
+
+	mux.HandleFunc("/fasting", auth.JWTMiddleware(handler.GetHistory))
+	mux.HandleFunc("/fasting/protocol", auth.JWTMiddleware(handler.Protocol))
+	mux.HandleFunc("/fasting/start", auth.JWTMiddleware(handler.Start))
+	mux.HandleFunc("/fasting/stop", auth.JWTMiddleware(handler.Stop))
+	mux.HandleFunc("/fasting/", auth.JWTMiddleware(handler.DeleteSession))
+}
@@ -0,0 +1,77 @@
+package fasting
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ValidateStart checks the start and target of a new fast
+// It cannot start before lastEnd, the end of the latest stopped fast (nil when there is none)
+func ValidateStart(startedAt time.Time, targetHours float64, lastEnd *time.Time, now time.Time) error {
+	if startedAt.After(now.Add(time.Minute)) {
+		return errors.New("started_at cannot be in the future")
+	}
+	if lastEnd != nil && startedAt.Before(*lastEnd) {
+		return errors.New("started_at cannot be before the end of the previous fast")
+	}
+	if targetHours <= 0 || targetHours > MaxTargetHours {
+		return errors.New("target_hours must be between 0 and 72")
+	}
+	return nil
+}
+
+// ValidateStop checks the end of the fast in progress
+func ValidateStop(session *FastingSession, endedAt time.Time, now time.Time) error {
+	if endedAt.After(now.Add(time.Minute)) {
+		return errors.New("ended_at cannot be in the future")
+	}
+	if !endedAt.After(session.StartedAt) {
+		return errors.New("ended_at must be after started_at")
+	}
+	return nil
+}
+
+// TargetHours returns the target of a fast: the requested one, the protocol's, or the default
+func TargetHours(requested float64, protocol *Protocol) float64 {
+	if requested != 0 {
+		return requested
+	}
+	if protocol != nil {
+		return protocol.FastingHours
+	}
+	return DefaultTargetHours
+}
+
+// Measure sets the length of the fast, up to now while it is in progress, and whether it reached its target
+func (s *FastingSession) Measure(now time.Time) {
+	end := now
+	if s.EndedAt != nil {
+		end = *s.EndedAt
+	}
+	hours := end.Sub(s.StartedAt).Hours()
+	s.Hours = math.Round(hours*100) / 100
+	s.Completed = hours >= s.TargetHours
+}
+
+// Summarize measures the sessions and computes the stats of the finished ones
+func Summarize(sessions []FastingSession, now time.Time) Stats {
+	var stats Stats
+	var total float64
+	for i := range sessions {
+		sessions[i].Measure(now)
+		if sessions[i].EndedAt == nil {
+			continue
+		}
+		stats.Fasts++
+		if sessions[i].Completed {
+			stats.Completed++
+		}
+		total += sessions[i].Hours
+		stats.LongestHours = math.Max(stats.LongestHours, sessions[i].Hours)
+	}
+	if stats.Fasts > 0 {
+		stats.AverageHours = math.Round(total/float64(stats.Fasts)*100) / 100
+	}
+	return stats
+}
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ultra-bis/internal/fasting"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindProtocol(t *testing.T) {
+	omad := fasting.FindProtocol(fasting.ProtocolOMAD)
+	require.NotNil(t, omad)
+	assert.Equal(t, 23.0, omad.FastingHours)
+	assert.Equal(t, 1.0, omad.EatingWindowHours)
+
+	fiveTwo := fasting.FindProtocol(fasting.Protocol5To2)
+	require.NotNil(t, fiveTwo)
+	assert.Equal(t, 2, fiveTwo.FastDaysPerWeek)
+	assert.Zero(t, fiveTwo.EatingWindowHours)
+
+	assert.Nil(t, fasting.FindProtocol("20:4"))
+	assert.Nil(t, fasting.FindProtocol(""))
+
+	// The result is a copy, not the shared list entry
+	omad.FastingHours = 1
+	assert.Equal(t, 23.0, fasting.FindProtocol(fasting.ProtocolOMAD).FastingHours)
+}
+
+func TestTargetHours(t *testing.T) {
+	assert.Equal(t, 18.0, fasting.TargetHours(18, fasting.FindProtocol(fasting.Protocol16To8)))
+	assert.Equal(t, 16.0, fasting.TargetHours(0, fasting.FindProtocol(fasting.Protocol16To8)))
+	assert.Equal(t, 24.0, fasting.TargetHours(0, fasting.FindProtocol(fasting.Protocol5To2)))
+	assert.Equal(t, fasting.DefaultTargetHours, fasting.TargetHours(0, nil))
+}
+
+func TestValidateStartAndStop(t *testing.T) {
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, fasting.ValidateStart(now.Add(-2*time.Hour), 16, nil, now))
+	assert.EqualError(t, fasting.ValidateStart(now.Add(time.Hour), 16, nil, now), "started_at cannot be in the future")
+	assert.EqualError(t, fasting.ValidateStart(now, 0, nil, now), "target_hours must be between 0 and 72")
+	assert.EqualError(t, fasting.ValidateStart(now, 100, nil, now), "target_hours must be between 0 and 72")
+
+	lastEnd := now.Add(-time.Hour)
+	assert.NoError(t, fasting.ValidateStart(lastEnd, 16, &lastEnd, now))
+	assert.EqualError(t, fasting.ValidateStart(now.Add(-2*time.Hour), 16, &lastEnd, now), "started_at cannot be before the end of the previous fast")
+
+	session := &fasting.FastingSession{StartedAt: now.Add(-16 * time.Hour), TargetHours: 16}
+	assert.NoError(t, fasting.ValidateStop(session, now, now))
+	assert.EqualError(t, fasting.ValidateStop(session, now.Add(time.Hour), now), "ended_at cannot be in the future")
+	assert.EqualError(t, fasting.ValidateStop(session, session.StartedAt, now), "ended_at must be after started_at")
+}
+
+func TestSummarize(t *testing.T) {
+	now := time.Date(2025, 1, 10, 8, 0, 0, 0, time.UTC)
+	end := func(start time.Time, hours float64) *time.Time {
+		ended := start.Add(time.Duration(hours * float64(time.Hour)))
+		return &ended
+	}
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 20, 0, 0, 0, time.UTC) }
+
+	sessions := []fasting.FastingSession{
+		{StartedAt: now.Add(-3 * time.Hour), TargetHours: 16}, // In progress
+		{StartedAt: day(8), EndedAt: end(day(8), 17.5), TargetHours: 16},
+		{StartedAt: day(7), EndedAt: end(day(7), 14), TargetHours: 16},
+		{StartedAt: day(6), EndedAt: end(day(6), 23), TargetHours: 23},
+	}
+
+	stats := fasting.Summarize(sessions, now)
+	assert.Equal(t, 3, stats.Fasts)
+	assert.Equal(t, 2, stats.Completed)
+	assert.Equal(t, 18.17, stats.AverageHours)
+	assert.Equal(t, 23.0, stats.LongestHours)
+
+	// Every session is measured, including the one in progress
+	assert.Equal(t, 3.0, sessions[0].Hours)
+	assert.False(t, sessions[0].Completed)
+	assert.Equal(t, 17.5, sessions[1].Hours)
+	assert.True(t, sessions[1].Completed)
+	assert.False(t, sessions[2].Completed)
+}
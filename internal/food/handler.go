@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/i18n"
 )
 
 // Handler handles HTTP requests for food resources
@@ -288,6 +289,11 @@ func (h *Handler) SearchGeneralFoods(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	locale := i18n.FromRequest(r)
+	for i := range foods {
+		foods[i].Localize(locale)
+	}
+
 	// Build response
 	response := GeneralFoodSearchResponse{
 		Count:    count,
@@ -298,3 +304,129 @@ func (h *Handler) SearchGeneralFoods(w http.ResponseWriter, r *http.Request) {
 
 	httputil.WriteJSON(w, http.StatusOK, response)
 }
+
+// CreateGeneralFood handles POST /admin/general-foods (admin only)
+func (h *Handler) CreateGeneralFood(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req GeneralFoodRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if msg := validateGeneralFoodRequest(req); msg != "" {
+		httputil.WriteError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	food, err := h.generalFoodRepo.Create(req)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	food.Localize(i18n.FromRequest(r))
+
+	httputil.WriteJSON(w, http.StatusCreated, food)
+}
+
+// UpdateGeneralFood handles PUT /admin/general-foods/{id} (admin only)
+func (h *Handler) UpdateGeneralFood(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := extractID(r.URL.Path, "/admin/general-foods/")
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	var req GeneralFoodRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if msg := validateGeneralFoodRequest(req); msg != "" {
+		httputil.WriteError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	food, err := h.generalFoodRepo.Update(uint(id), req)
+	if err != nil {
+		if err.Error() == "general food not found" {
+			httputil.WriteError(w, http.StatusNotFound, "General food not found")
+			return
+		}
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	food.Localize(i18n.FromRequest(r))
+
+	httputil.WriteJSON(w, http.StatusOK, food)
+}
+
+// DeleteGeneralFood handles DELETE /admin/general-foods/{id} (admin only)
+func (h *Handler) DeleteGeneralFood(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := extractID(r.URL.Path, "/admin/general-foods/")
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	if err := h.generalFoodRepo.Delete(uint(id)); err != nil {
+		if err.Error() == "general food not found" {
+			httputil.WriteError(w, http.StatusNotFound, "General food not found")
+			return
+		}
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAdminGeneralFoodsWithID routes /admin/general-foods/{id} requests by HTTP method
+func (h *Handler) handleAdminGeneralFoodsWithID(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPut:
+		h.UpdateGeneralFood(w, r)
+	case http.MethodDelete:
+		h.DeleteGeneralFood(w, r)
+	default:
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// validateGeneralFoodRequest returns a validation message, or "" if the request is valid
+func validateGeneralFoodRequest(req GeneralFoodRequest) string {
+	if req.Name == "" {
+		return "Name is required"
+	}
+	if req.Calories < 0 || req.Protein < 0 || req.Carbs < 0 || req.Fat < 0 || req.Fiber < 0 ||
+		req.Water < 0 || req.Caffeine < 0 || req.Alcohol < 0 {
+		return "Nutrition values must be non-negative"
+	}
+	if req.Tag != "" && !ValidateTag(req.Tag) {
+		return "Tag must be 'routine', 'contextual', or 'general'"
+	}
+	for locale, name := range req.NameTranslations {
+		if locale == "" || len(locale) > 10 || name == "" {
+			return "name_translations must map locale codes to non-empty names"
+		}
+	}
+	if len(req.NameLocale) > 10 {
+		return "name_locale must be a locale code"
+	}
+	return ""
+}
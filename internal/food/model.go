@@ -3,11 +3,14 @@ package food
 import (
 	"time"
 
+	"ultra-bis/internal/i18n"
+
 	"gorm.io/gorm"
 )
 
 // Food represents a food item with nutritional information
-// All nutritional values (Calories, Protein, Carbs, Fat, Fiber) are per 100 grams
+// All nutritional values (Calories, Protein, Carbs, Fat, Fiber) are per 100 grams, as are the
+// optional beverage values: Water in ml, Caffeine in mg and Alcohol in grams
 type Food struct {
 	ID          uint           `json:"id" gorm:"primarykey"`
 	CreatedAt   time.Time      `json:"created_at"`
@@ -20,6 +23,9 @@ type Food struct {
 	Carbs       float64        `json:"carbs" gorm:"type:decimal(10,2)"`
 	Fat         float64        `json:"fat" gorm:"type:decimal(10,2)"`
 	Fiber       float64        `json:"fiber" gorm:"type:decimal(10,2)"`
+	Water       float64        `json:"water" gorm:"type:decimal(10,2)"`
+	Caffeine    float64        `json:"caffeine" gorm:"type:decimal(10,2)"`
+	Alcohol     float64        `json:"alcohol" gorm:"type:decimal(10,2)"`
 	Tag         string         `json:"tag" gorm:"type:varchar(20);not null;default:'routine'"`
 }
 
@@ -32,6 +38,9 @@ type CreateFoodRequest struct {
 	Carbs       float64 `json:"carbs"`
 	Fat         float64 `json:"fat"`
 	Fiber       float64 `json:"fiber"`
+	Water       float64 `json:"water"`    // ml per 100g
+	Caffeine    float64 `json:"caffeine"` // mg per 100g
+	Alcohol     float64 `json:"alcohol"`  // g per 100g
 	Tag         string  `json:"tag"`
 }
 
@@ -44,6 +53,9 @@ type UpdateFoodRequest struct {
 	Carbs       float64 `json:"carbs"`
 	Fat         float64 `json:"fat"`
 	Fiber       float64 `json:"fiber"`
+	Water       float64 `json:"water"`    // ml per 100g
+	Caffeine    float64 `json:"caffeine"` // mg per 100g
+	Alcohol     float64 `json:"alcohol"`  // g per 100g
 	Tag         string  `json:"tag"`
 }
 
@@ -61,19 +73,56 @@ func ValidateTag(tag string) bool {
 
 // GeneralFood represents a food item from the general food database
 // This is a reference table of common foods, separate from user-created custom foods
-// All nutritional values (Calories, Protein, Carbs, Fat, Fiber) are per 100 grams
+// All nutritional values are per 100 grams, with the same units as Food
 type GeneralFood struct {
-	ID          uint      `json:"id" gorm:"primarykey"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Name        string    `json:"name" gorm:"type:varchar(255);not null;index"`
-	Description string    `json:"description" gorm:"type:text"`
-	Calories    float64   `json:"calories" gorm:"type:decimal(10,2)"`
-	Protein     float64   `json:"protein" gorm:"type:decimal(10,2)"`
-	Carbs       float64   `json:"carbs" gorm:"type:decimal(10,2)"`
-	Fat         float64   `json:"fat" gorm:"type:decimal(10,2)"`
-	Fiber       float64   `json:"fiber" gorm:"type:decimal(10,2)"`
-	Tag         string    `json:"tag" gorm:"type:varchar(20);not null;default:'general';index"`
+	ID               uint       `json:"id" gorm:"primarykey"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	Name             string     `json:"name" gorm:"type:varchar(255);not null;index"`
+	Description      string     `json:"description" gorm:"type:text"`
+	Calories         float64    `json:"calories" gorm:"type:decimal(10,2)"`
+	Protein          float64    `json:"protein" gorm:"type:decimal(10,2)"`
+	Carbs            float64    `json:"carbs" gorm:"type:decimal(10,2)"`
+	Fat              float64    `json:"fat" gorm:"type:decimal(10,2)"`
+	Fiber            float64    `json:"fiber" gorm:"type:decimal(10,2)"`
+	Water            float64    `json:"water" gorm:"type:decimal(10,2)"`
+	Caffeine         float64    `json:"caffeine" gorm:"type:decimal(10,2)"`
+	Alcohol          float64    `json:"alcohol" gorm:"type:decimal(10,2)"`
+	Tag              string     `json:"tag" gorm:"type:varchar(20);not null;default:'general';index"`
+	NameLocale       string     `json:"name_locale" gorm:"type:varchar(10);not null;default:'fr'"` // Locale Name is written in
+	NameTranslations i18n.Texts `json:"name_translations,omitempty" gorm:"type:jsonb"`             // Optional translations of Name
+	LocalizedName    string     `json:"localized_name" gorm:"-"`                                   // Name in the request's locale
+}
+
+// DefaultGeneralFoodLocale is the locale of general foods imported from CIQUAL
+const DefaultGeneralFoodLocale = "fr"
+
+// Localize sets LocalizedName to the translation for locale, or the original name
+func (f *GeneralFood) Localize(locale string) {
+	f.LocalizedName = f.Name
+	if locale == f.NameLocale {
+		return
+	}
+	if translation := f.NameTranslations.Get(locale); translation != "" {
+		f.LocalizedName = translation
+	}
+}
+
+// GeneralFoodRequest represents the request body for creating or updating a general food (admin only)
+type GeneralFoodRequest struct {
+	Name             string     `json:"name"`
+	Description      string     `json:"description"`
+	Calories         float64    `json:"calories"`
+	Protein          float64    `json:"protein"`
+	Carbs            float64    `json:"carbs"`
+	Fat              float64    `json:"fat"`
+	Fiber            float64    `json:"fiber"`
+	Water            float64    `json:"water"`
+	Caffeine         float64    `json:"caffeine"`
+	Alcohol          float64    `json:"alcohol"`
+	Tag              string     `json:"tag"`
+	NameLocale       string     `json:"name_locale"` // Defaults to DefaultGeneralFoodLocale
+	NameTranslations i18n.Texts `json:"name_translations"`
 }
 
 // GeneralFoodSearchResponse represents paginated search results for general foods
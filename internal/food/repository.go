@@ -33,6 +33,9 @@ func (r *Repository) Create(req CreateFoodRequest) (*Food, error) {
 		Carbs:       req.Carbs,
 		Fat:         req.Fat,
 		Fiber:       req.Fiber,
+		Water:       req.Water,
+		Caffeine:    req.Caffeine,
+		Alcohol:     req.Alcohol,
 		Tag:         tag,
 	}
 
@@ -91,6 +94,9 @@ func (r *Repository) Update(id int, req UpdateFoodRequest) (*Food, error) {
 	food.Carbs = req.Carbs
 	food.Fat = req.Fat
 	food.Fiber = req.Fiber
+	food.Water = req.Water
+	food.Caffeine = req.Caffeine
+	food.Alcohol = req.Alcohol
 	if req.Tag != "" {
 		food.Tag = req.Tag
 	}
@@ -151,6 +157,9 @@ func (r *Repository) GetByTag(tag string) ([]Food, error) {
 type GeneralFoodRepository interface {
 	Search(query string, page int, pageSize int) ([]GeneralFood, int64, error)
 	GetByID(id uint) (*GeneralFood, error)
+	Create(req GeneralFoodRequest) (*GeneralFood, error)
+	Update(id uint, req GeneralFoodRequest) (*GeneralFood, error)
+	Delete(id uint) error
 }
 
 // generalFoodRepository implements GeneralFoodRepository
@@ -173,7 +182,8 @@ func (r *generalFoodRepository) Search(query string, page int, pageSize int) ([]
 
 	// Apply name filter if provided
 	if query != "" {
-		db = db.Where("LOWER(name) LIKE LOWER(?)", "%"+query+"%")
+		// Translated names are searched too
+		db = db.Where("LOWER(name) LIKE LOWER(?) OR LOWER(CAST(name_translations AS TEXT)) LIKE LOWER(?)", "%"+query+"%", "%"+query+"%")
 	}
 
 	// Get total count
@@ -204,3 +214,85 @@ func (r *generalFoodRepository) GetByID(id uint) (*GeneralFood, error) {
 	}
 	return &food, nil
 }
+
+// Create inserts a new general food (admin curation)
+func (r *generalFoodRepository) Create(req GeneralFoodRequest) (*GeneralFood, error) {
+	tag := req.Tag
+	if tag == "" {
+		tag = TagGeneral
+	}
+
+	nameLocale := req.NameLocale
+	if nameLocale == "" {
+		nameLocale = DefaultGeneralFoodLocale
+	}
+
+	food := &GeneralFood{
+		Name:             req.Name,
+		Description:      req.Description,
+		Calories:         req.Calories,
+		Protein:          req.Protein,
+		Carbs:            req.Carbs,
+		Fat:              req.Fat,
+		Fiber:            req.Fiber,
+		Water:            req.Water,
+		Caffeine:         req.Caffeine,
+		Alcohol:          req.Alcohol,
+		Tag:              tag,
+		NameLocale:       nameLocale,
+		NameTranslations: req.NameTranslations,
+	}
+
+	if err := r.db.Create(food).Error; err != nil {
+		return nil, fmt.Errorf("failed to create general food: %w", err)
+	}
+
+	return food, nil
+}
+
+// Update modifies an existing general food (admin curation)
+func (r *generalFoodRepository) Update(id uint, req GeneralFoodRequest) (*GeneralFood, error) {
+	food, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	food.Name = req.Name
+	food.Description = req.Description
+	food.Calories = req.Calories
+	food.Protein = req.Protein
+	food.Carbs = req.Carbs
+	food.Fat = req.Fat
+	food.Fiber = req.Fiber
+	food.Water = req.Water
+	food.Caffeine = req.Caffeine
+	food.Alcohol = req.Alcohol
+	if req.Tag != "" {
+		food.Tag = req.Tag
+	}
+	if req.NameLocale != "" {
+		food.NameLocale = req.NameLocale
+	}
+	food.NameTranslations = req.NameTranslations
+
+	if err := r.db.Save(food).Error; err != nil {
+		return nil, fmt.Errorf("failed to update general food: %w", err)
+	}
+
+	return food, nil
+}
+
+// Delete removes a general food (admin curation)
+func (r *generalFoodRepository) Delete(id uint) error {
+	result := r.db.Delete(&GeneralFood{}, id)
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete general food: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("general food not found")
+	}
+
+	return nil
+}
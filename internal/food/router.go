@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"ultra-bis/internal/auth"
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/user"
 )
 
 // RegisterRoutes registers all food-related routes to the provided mux
@@ -14,4 +16,17 @@ func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
 
 	// General foods reference data (public - no authentication required)
 	mux.HandleFunc("GET /general-foods", handler.SearchGeneralFoods)
+
+	// General foods curation (admin only)
+	requireAdmin := httputil.RequireRole(string(user.RoleAdmin))
+	mux.HandleFunc("/admin/general-foods", httputil.ChainMiddleware(
+		handler.CreateGeneralFood,
+		auth.JWTMiddleware,
+		requireAdmin,
+	))
+	mux.HandleFunc("/admin/general-foods/", httputil.ChainMiddleware(
+		handler.handleAdminGeneralFoodsWithID,
+		auth.JWTMiddleware,
+		requireAdmin,
+	))
 }
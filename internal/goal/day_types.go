@@ -0,0 +1,147 @@
+package goal
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Named day types a day can be tagged with
+const (
+	DayTypeTraining = "training"
+	DayTypeRest     = "rest"
+	DayTypeRefeed   = "refeed"
+)
+
+// DayTypes lists the supported day types
+var DayTypes = []string{DayTypeTraining, DayTypeRest, DayTypeRefeed}
+
+// ValidDayType reports whether dayType is a supported day type
+func ValidDayType(dayType string) bool {
+	for _, t := range DayTypes {
+		if t == dayType {
+			return true
+		}
+	}
+	return false
+}
+
+// DayTarget overrides the daily values of a goal
+// Zero values keep the goal's base value
+type DayTarget struct {
+	Calories float64 `json:"calories,omitempty"`
+	Protein  float64 `json:"protein,omitempty"`
+	Carbs    float64 `json:"carbs,omitempty"`
+	Fat      float64 `json:"fat,omitempty"`
+	Fiber    float64 `json:"fiber,omitempty"`
+}
+
+// DayTargets maps a weekday ("monday") or a day type ("training") to its override, stored as JSONB
+type DayTargets map[string]DayTarget
+
+// Value implements the driver.Valuer interface for JSONB serialization
+func (d DayTargets) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+// Scan implements the sql.Scanner interface for JSONB deserialization
+func (d *DayTargets) Scan(value interface{}) error {
+	if value == nil {
+		*d = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to scan DayTargets: not a byte slice")
+	}
+
+	return json.Unmarshal(bytes, d)
+}
+
+// validate checks the keys with isValidKey and that no value is negative
+func (d DayTargets) validate(field string, isValidKey func(string) bool) error {
+	for key, target := range d {
+		if !isValidKey(key) {
+			return fmt.Errorf("invalid key %q in %s", key, field)
+		}
+		if target.Calories < 0 || target.Protein < 0 || target.Carbs < 0 || target.Fat < 0 || target.Fiber < 0 {
+			return fmt.Errorf("%s values for %s must not be negative", field, key)
+		}
+	}
+	return nil
+}
+
+// ValidateWeekdays checks that every key is a lowercase weekday name
+func (d DayTargets) ValidateWeekdays() error {
+	return d.validate("weekday_targets", func(key string) bool {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if key == weekdayKey(day) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ValidateDayTypes checks that every key is a supported day type
+func (d DayTargets) ValidateDayTypes() error {
+	return d.validate("day_type_targets", ValidDayType)
+}
+
+// weekdayKey returns the key used for a weekday in WeekdayTargets
+func weekdayKey(day time.Weekday) string {
+	return strings.ToLower(day.String())
+}
+
+// ResolveForDate returns a copy of the goal with the override for the date applied
+// A day type override takes precedence over a weekday override
+func (g NutritionGoal) ResolveForDate(date time.Time, dayType string) *NutritionGoal {
+	resolved := g
+
+	if target, ok := g.DayTypeTargets[dayType]; ok && dayType != "" {
+		resolved.applyTarget(target)
+		resolved.AppliedOverride = dayType
+	} else if target, ok := g.WeekdayTargets[weekdayKey(date.Weekday())]; ok {
+		resolved.applyTarget(target)
+		resolved.AppliedOverride = weekdayKey(date.Weekday())
+	}
+
+	resolved.DayType = dayType
+	return &resolved
+}
+
+// applyTarget replaces the goal's values with the non-zero values of the override
+func (g *NutritionGoal) applyTarget(target DayTarget) {
+	if target.Calories > 0 {
+		g.Calories = target.Calories
+	}
+	if target.Protein > 0 {
+		g.Protein = target.Protein
+	}
+	if target.Carbs > 0 {
+		g.Carbs = target.Carbs
+	}
+	if target.Fat > 0 {
+		g.Fat = target.Fat
+	}
+	if target.Fiber > 0 {
+		g.Fiber = target.Fiber
+	}
+}
+
+// DayTypeAssignment tags one of a user's days with a day type
+type DayTypeAssignment struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_day_type_user_date"`
+	Date      time.Time `json:"date" gorm:"type:date;not null;uniqueIndex:idx_day_type_user_date"`
+	DayType   string    `json:"day_type" gorm:"type:varchar(20);not null"`
+}
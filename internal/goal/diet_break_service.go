@@ -0,0 +1,84 @@
+package goal
+
+import (
+	"ultra-bis/internal/user"
+)
+
+// DietBreakCalculator alternates deficit phases with maintenance breaks
+type DietBreakCalculator struct{}
+
+// NewDietBreakCalculator creates a new calculator instance
+func NewDietBreakCalculator() *DietBreakCalculator {
+	return &DietBreakCalculator{}
+}
+
+// dietBreakProtocols lists the diet break schedules
+var dietBreakProtocols = []ProtocolInfo{
+	{Number: 1, Name: "Break every 6 weeks", Description: "Two cycles of 6 weeks at a 20% deficit followed by a 2-week maintenance break (16 weeks)"},
+	{Number: 2, Name: "Break every 4 weeks", Description: "Three cycles of 4 weeks at a 20% deficit followed by a 2-week maintenance break (18 weeks)"},
+}
+
+// dietBreakCycles holds the number of two-week deficit phases before each break, and the number of cycles
+var dietBreakCycles = map[int]struct{ deficitPhases, cycles int }{
+	1: {deficitPhases: 3, cycles: 2},
+	2: {deficitPhases: 2, cycles: 3},
+}
+
+// GetModelName returns the diet model name
+func (c *DietBreakCalculator) GetModelName() string {
+	return "dietBreak"
+}
+
+// Describe returns the diet break model and its protocols
+func (c *DietBreakCalculator) Describe() AvailableDiet {
+	return AvailableDiet{
+		ModelName:   c.GetModelName(),
+		DisplayName: "Diet breaks",
+		Description: "Fat loss in blocks separated by two-week maintenance breaks to limit metabolic adaptation",
+		Protocols:   dietBreakProtocols,
+	}
+}
+
+// ValidateUser validates that the user has age, height, weight and gender
+func (c *DietBreakCalculator) ValidateUser(user *user.User) error {
+	return validateBodyStats(user, false)
+}
+
+// ValidateProtocol validates that the protocol number is valid for diet breaks
+func (c *DietBreakCalculator) ValidateProtocol(protocol int) error {
+	return validateProtocolNumber(protocol, dietBreakProtocols, "Diet breaks")
+}
+
+// GetProtocolName returns the human-readable protocol name
+func (c *DietBreakCalculator) GetProtocolName(protocol int) string {
+	return protocolName(protocol, dietBreakProtocols)
+}
+
+// Calculate returns the two-week phases of the schedule, deficits and breaks in order
+func (c *DietBreakCalculator) Calculate(user *user.User, protocol int) (*DietCalculationResult, error) {
+	bmr, tdee := mifflinTDEE(user)
+	schedule := dietBreakCycles[protocol]
+
+	var phases []PhaseResult
+	for cycle := 0; cycle < schedule.cycles; cycle++ {
+		for i := 0; i < schedule.deficitPhases; i++ {
+			phases = append(phases, PhaseResult{Calories: tdee * 0.8, Description: "Deficit phase"})
+		}
+		phases = append(phases, PhaseResult{Calories: tdee, Description: "Maintenance break"})
+	}
+
+	for i := range phases {
+		phases[i].Phase = i + 1
+		splitMacros(&phases[i], user.Weight*1.8, phases[i].Calories*0.25/9)
+	}
+
+	return &DietCalculationResult{
+		ModelName:      c.GetModelName(),
+		Protocol:       protocol,
+		ProtocolName:   c.GetProtocolName(protocol),
+		BMR:            bmr,
+		MaintenanceMMR: tdee,
+		LeanMass:       leanMass(user),
+		Phases:         phases,
+	}, nil
+}
@@ -2,6 +2,11 @@ package goal
 
 import (
 	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"ultra-bis/internal/i18n"
 	"ultra-bis/internal/user"
 )
 
@@ -21,6 +26,9 @@ type DietCalculator interface {
 
 	// GetModelName returns the diet model name
 	GetModelName() string
+
+	// Describe returns the display name, description and protocols listed by GET /goals/diets
+	Describe() AvailableDiet
 }
 
 // DietCalculationResult holds the calculation results for any diet model
@@ -37,32 +45,157 @@ type DietCalculationResult struct {
 
 // PhaseResult holds the calorie and macro targets for a specific phase
 type PhaseResult struct {
-	Phase       int
-	Calories    float64
-	Protein     float64
-	Carbs       float64
-	Fat         float64
-	Description string
+	Phase        int
+	Calories     float64
+	Protein      float64
+	Carbs        float64
+	Fat          float64
+	Description  string
+	DurationDays int // 0 means PhaseDuration
 }
 
-// GetDietCalculator is a factory function that returns the appropriate calculator
-func GetDietCalculator(modelName string) (DietCalculator, error) {
-	switch modelName {
-	case "zeroToHero":
-		return NewZeroToHeroCalculator(), nil
-	default:
-		return nil, fmt.Errorf("unsupported diet model: %s. Supported models: zeroToHero", modelName)
+// Duration returns how long the phase lasts
+func (p PhaseResult) Duration() time.Duration {
+	if p.DurationDays <= 0 {
+		return PhaseDuration
+	}
+	return time.Duration(p.DurationDays) * 24 * time.Hour
+}
+
+// phaseLengther is implemented by calculators whose phases do not all last PhaseDuration
+type phaseLengther interface {
+	PhaseLength(protocol, phase int) time.Duration
+}
+
+// languageSetter is implemented by calculators with localized names and descriptions
+type languageSetter interface {
+	WithLanguage(lang string) DietCalculator
+}
+
+// PhaseLength returns how long a phase of a calculator's protocol lasts
+func PhaseLength(calculator DietCalculator, protocol, phase int) time.Duration {
+	if lengther, ok := calculator.(phaseLengther); ok {
+		return lengther.PhaseLength(protocol, phase)
+	}
+	return PhaseDuration
+}
+
+// Localize returns the calculator with its texts in lang
+// Definition-based calculators carry their own translations; the texts of the
+// formula-driven ones are looked up in the i18n message catalogs
+func Localize(calculator DietCalculator, lang string) DietCalculator {
+	if lang == "" {
+		return calculator
+	}
+	if setter, ok := calculator.(languageSetter); ok {
+		return setter.WithLanguage(lang)
+	}
+	return &catalogCalculator{DietCalculator: calculator, lang: lang}
+}
+
+// catalogCalculator translates a calculator's English texts through the message catalogs
+type catalogCalculator struct {
+	DietCalculator
+	lang string
+}
+
+// Describe returns the translated model and protocols
+func (c *catalogCalculator) Describe() AvailableDiet {
+	diet := c.DietCalculator.Describe()
+	diet.DisplayName = c.translate(diet.DisplayName)
+	diet.Description = c.translate(diet.Description)
+
+	protocols := make([]ProtocolInfo, len(diet.Protocols))
+	for i, protocol := range diet.Protocols {
+		protocols[i] = ProtocolInfo{
+			Number:      protocol.Number,
+			Name:        c.translate(protocol.Name),
+			Description: c.translate(protocol.Description),
+		}
 	}
+	diet.Protocols = protocols
+	return diet
 }
 
-// ValidateDietRequest validates the diet calculation request
-func ValidateDietRequest(req *CalculateDietRequest, user *user.User) error {
-	// Get the calculator for this model
-	calculator, err := GetDietCalculator(req.DietModel)
+// GetProtocolName returns the translated protocol name
+func (c *catalogCalculator) GetProtocolName(protocol int) string {
+	return c.translate(c.DietCalculator.GetProtocolName(protocol))
+}
+
+// Calculate translates the protocol name and phase descriptions of the result
+func (c *catalogCalculator) Calculate(user *user.User, protocol int) (*DietCalculationResult, error) {
+	result, err := c.DietCalculator.Calculate(user, protocol)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	result.ProtocolName = c.translate(result.ProtocolName)
+	for i := range result.Phases {
+		result.Phases[i].Description = c.translate(result.Phases[i].Description)
+	}
+	return result, nil
+}
+
+func (c *catalogCalculator) translate(text string) string {
+	_, translated := i18n.Translate(c.lang, text)
+	return translated
+}
+
+// dietRegistry holds the built-in calculators by model name, and dietOrder their registration order
+var (
+	dietRegistry = map[string]DietCalculator{}
+	dietOrder    []string
+)
+
+func init() {
+	// Declarative models embedded from protocols/*.json come first, then the formula-driven ones
+	for _, calculator := range loadBuiltinDefinitions() {
+		RegisterDietCalculator(calculator)
+	}
+	RegisterDietCalculator(NewMifflinStJeorCalculator())
+	RegisterDietCalculator(NewKatchMcArdleCalculator())
+	RegisterDietCalculator(NewReverseDietCalculator())
+	RegisterDietCalculator(NewDietBreakCalculator())
+	RegisterDietCalculator(NewKetoCalculator())
+}
+
+// RegisterDietCalculator adds a calculator to the registry; registering a model name twice panics
+func RegisterDietCalculator(calculator DietCalculator) {
+	name := calculator.GetModelName()
+	if _, exists := dietRegistry[name]; exists {
+		panic(fmt.Sprintf("diet calculator %q registered twice", name))
+	}
+	dietRegistry[name] = calculator
+	dietOrder = append(dietOrder, name)
+}
+
+// GetDietCalculator returns the registered calculator for a diet model
+func GetDietCalculator(modelName string) (DietCalculator, error) {
+	calculator, ok := dietRegistry[modelName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported diet model: %s. Supported models: %s", modelName, strings.Join(dietOrder, ", "))
+	}
+	return calculator, nil
+}
+
+// AvailableDiets describes every registered diet model, in registration order
+// Texts use lang when the model is localized; an empty lang keeps each model's default
+func AvailableDiets(lang string) []AvailableDiet {
+	diets := make([]AvailableDiet, 0, len(dietOrder))
+	for _, name := range dietOrder {
+		diets = append(diets, Localize(dietRegistry[name], lang).Describe())
 	}
+	return diets
+}
+
+// IsBuiltinDietModel reports whether a model name is taken by a built-in calculator
+func IsBuiltinDietModel(modelName string) bool {
+	_, ok := dietRegistry[modelName]
+	return ok
+}
 
+// ValidateDietRequest validates the diet calculation request against its calculator
+func ValidateDietRequest(calculator DietCalculator, req *CalculateDietRequest, user *user.User) error {
 	// Validate user data
 	if err := calculator.ValidateUser(user); err != nil {
 		return fmt.Errorf("user validation failed: %w", err)
@@ -75,3 +208,61 @@ func ValidateDietRequest(req *CalculateDietRequest, user *user.User) error {
 
 	return nil
 }
+
+// validateProtocolNumber checks that protocol is one of the numbered protocols
+func validateProtocolNumber(protocol int, protocols []ProtocolInfo, displayName string) error {
+	for _, info := range protocols {
+		if info.Number == protocol {
+			return nil
+		}
+	}
+	return fmt.Errorf("protocol must be between 1 and %d for %s", len(protocols), displayName)
+}
+
+// protocolName returns the name of a numbered protocol
+func protocolName(protocol int, protocols []ProtocolInfo) string {
+	for _, info := range protocols {
+		if info.Number == protocol {
+			return info.Name
+		}
+	}
+	return "Unknown Protocol"
+}
+
+// validateBodyStats checks the profile fields needed by the Mifflin-St Jeor based calculators
+func validateBodyStats(user *user.User, requireBodyFat bool) error {
+	return validateProfile(user, true, requireBodyFat)
+}
+
+// validateProfile checks age, height and weight, and gender or body fat when a formula needs them
+func validateProfile(user *user.User, requireGender, requireBodyFat bool) error {
+	if user.Age <= 0 {
+		return fmt.Errorf("age is required and must be greater than 0")
+	}
+	if user.Height <= 0 {
+		return fmt.Errorf("height is required and must be greater than 0")
+	}
+	if user.Weight <= 0 {
+		return fmt.Errorf("weight is required and must be greater than 0")
+	}
+	if requireGender && user.Gender != "male" && user.Gender != "female" {
+		return fmt.Errorf("gender is required and must be 'male' or 'female'")
+	}
+	if requireBodyFat && (user.BodyFat <= 0 || user.BodyFat >= 100) {
+		return fmt.Errorf("body fat is required and must be between 0 and 100")
+	}
+	return nil
+}
+
+// mifflinTDEE returns the Mifflin-St Jeor BMR and the maintenance calories for the user's activity level
+func mifflinTDEE(user *user.User) (bmr, tdee float64) {
+	bmr = calculateBMR(user.Weight, user.Height, float64(user.Age), user.Gender)
+	return bmr, bmr * getActivityMultiplier(user.ActivityLevel)
+}
+
+// splitMacros sets a phase's protein and fat in grams and gives the remaining calories to carbs
+func splitMacros(phase *PhaseResult, protein, fat float64) {
+	phase.Protein = protein
+	phase.Fat = fat
+	phase.Carbs = math.Max(0, (phase.Calories-protein*4-fat*9)/4)
+}
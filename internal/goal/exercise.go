@@ -0,0 +1,55 @@
+package goal
+
+import (
+	"errors"
+	"math"
+)
+
+// Exercise policies: how much of the calories burned exercising is added to the day's target
+const (
+	ExercisePolicyIgnore = "ignore" // The target stays the same
+	ExercisePolicyHalf   = "half"   // Half of the calories burned is added
+	ExercisePolicyFull   = "full"   // All the calories burned are added
+)
+
+// ExercisePolicies lists the supported exercise policies
+var ExercisePolicies = []string{ExercisePolicyIgnore, ExercisePolicyHalf, ExercisePolicyFull}
+
+// ValidateExercisePolicy checks an exercise policy; empty means ignore
+func ValidateExercisePolicy(policy string) error {
+	switch policy {
+	case "", ExercisePolicyIgnore, ExercisePolicyHalf, ExercisePolicyFull:
+		return nil
+	}
+	return errors.New("exercise_policy must be 'ignore', 'half' or 'full'")
+}
+
+// ExerciseShare returns the share of the calories burned that the policy adds to the target
+func ExerciseShare(policy string) float64 {
+	switch policy {
+	case ExercisePolicyHalf:
+		return 0.5
+	case ExercisePolicyFull:
+		return 1
+	}
+	return 0
+}
+
+// AdjustForExercise returns a copy of the goal with the share of caloriesBurned its policy allows
+// added to the calorie target. Protein, carbs and fat grow in proportion, keeping the macro split;
+// fiber and water are unchanged
+func (g NutritionGoal) AdjustForExercise(caloriesBurned float64) *NutritionGoal {
+	adjusted := g
+	added := math.Round(caloriesBurned*ExerciseShare(g.ExercisePolicy)*100) / 100
+	if added <= 0 || g.Calories <= 0 {
+		return &adjusted
+	}
+
+	ratio := (g.Calories + added) / g.Calories
+	adjusted.Calories = g.Calories + added
+	adjusted.Protein = math.Round(g.Protein*ratio*100) / 100
+	adjusted.Carbs = math.Round(g.Carbs*ratio*100) / 100
+	adjusted.Fat = math.Round(g.Fat*ratio*100) / 100
+	adjusted.ExerciseCalories = added
+	return &adjusted
+}
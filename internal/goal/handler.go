@@ -3,20 +3,36 @@ package goal
 import (
 	"ultra-bis/internal/httputil"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"math"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/i18n"
+	"ultra-bis/internal/metrics"
 	"ultra-bis/internal/user"
+	"ultra-bis/internal/webhook"
 )
 
 // Handler handles nutrition goal requests
 type Handler struct {
-	repo     *Repository
-	userRepo *user.Repository
+	repo        *Repository
+	userRepo    *user.Repository
+	metricsRepo *metrics.Repository
+	webhooks    *webhook.Publisher
+	dayObserver DayObserver
+}
+
+// DayObserver is notified of the days whose goal changed, e.g. to update streaks
+// It matches diary.DayObserver, which this package cannot import (diary imports goal)
+type DayObserver interface {
+	DayChanged(userID uint, dates ...time.Time) error
 }
 
 // NewHandler creates a new goal handler
@@ -24,6 +40,45 @@ func NewHandler(repo *Repository, userRepo *user.Repository) *Handler {
 	return &Handler{repo: repo, userRepo: userRepo}
 }
 
+// SetMetricsRepo sets the metrics repository used to recalculate protocol phases from the latest weigh-in
+func (h *Handler) SetMetricsRepo(metricsRepo *metrics.Repository) {
+	h.metricsRepo = metricsRepo
+}
+
+// SetWebhookPublisher sets the publisher of goal.activated events
+func (h *Handler) SetWebhookPublisher(publisher *webhook.Publisher) {
+	h.webhooks = publisher
+}
+
+// SetDayObserver sets the observer notified when goals change the target of past days
+func (h *Handler) SetDayObserver(observer DayObserver) {
+	h.dayObserver = observer
+}
+
+// notifyGoalDays tells the observer about the days from start to end (open-ended when nil) that
+// are already past, as their goal changed; a failure is logged rather than failing a request whose
+// goal was already saved
+func notifyGoalDays(observer DayObserver, userID uint, start time.Time, end *time.Time) {
+	if observer == nil {
+		return
+	}
+
+	last := time.Now()
+	if end != nil && end.Before(last) {
+		last = *end
+	}
+	var dates []time.Time
+	for date := start; !date.After(last); date = date.AddDate(0, 0, 1) {
+		dates = append(dates, date)
+	}
+	if len(dates) == 0 {
+		return
+	}
+	if err := observer.DayChanged(userID, dates...); err != nil {
+		log.Printf("Failed to update streaks for user %d: %v", userID, err)
+	}
+}
+
 
 // CreateGoal handles POST /goals
 func (h *Handler) CreateGoal(w http.ResponseWriter, r *http.Request) {
@@ -44,10 +99,27 @@ func (h *Handler) CreateGoal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set start date to today if not provided
+	if err := req.MealTargets.Validate(); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := ValidateExercisePolicy(req.ExercisePolicy); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := req.WeekdayTargets.ValidateWeekdays(); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := req.DayTypeTargets.ValidateDayTypes(); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Set start date to the user's today if not provided
 	startDate := req.StartDate.Time
 	if startDate.IsZero() {
-		startDate = time.Now()
+		startDate = calendar.FromRequest(r).Today()
 	}
 
 	var endDate *time.Time
@@ -55,22 +127,40 @@ func (h *Handler) CreateGoal(w http.ResponseWriter, r *http.Request) {
 		endDate = &req.EndDate.Time
 	}
 
+	exercisePolicy := req.ExercisePolicy
+	if exercisePolicy == "" {
+		exercisePolicy = ExercisePolicyIgnore
+	}
+
 	goal := &NutritionGoal{
-		UserID:    userID,
-		Calories:  req.Calories,
-		Protein:   req.Protein,
-		Carbs:     req.Carbs,
-		Fat:       req.Fat,
-		Fiber:     req.Fiber,
-		StartDate: startDate,
-		EndDate:   endDate,
-		IsActive:  true,
+		UserID:         userID,
+		Calories:       req.Calories,
+		Protein:        req.Protein,
+		Carbs:          req.Carbs,
+		Fat:            req.Fat,
+		Fiber:          req.Fiber,
+		Water:          req.Water,
+		ExercisePolicy: exercisePolicy,
+		StartDate:      startDate,
+		EndDate:        endDate,
+		IsActive:       true,
+		MealTargets:    req.MealTargets,
+		WeekdayTargets: req.WeekdayTargets,
+		DayTypeTargets: req.DayTypeTargets,
 	}
 
 	// Add protocol tracking if provided
 	if req.DietModel != nil && req.Protocol != nil {
-		now := time.Now()
-		expirationDate := now.AddDate(0, 0, 14) // 2 weeks from creation
+		// Phases last two weeks unless the protocol's definition sets another duration
+		phaseLength := PhaseDuration
+		if calculator, err := h.repo.DietCalculator(*req.DietModel); err == nil {
+			phase := 1
+			if req.Phase != nil {
+				phase = *req.Phase
+			}
+			phaseLength = PhaseLength(calculator, *req.Protocol, phase)
+		}
+		expirationDate := time.Now().Add(phaseLength)
 
 		goal.DietModel = req.DietModel
 		goal.Protocol = req.Protocol
@@ -82,6 +172,8 @@ func (h *Handler) CreateGoal(w http.ResponseWriter, r *http.Request) {
 		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.webhooks.Notify(userID, webhook.EventGoalActivated, goal)
+	notifyGoalDays(h.dayObserver, userID, goal.StartDate, goal.EndDate)
 
 	httputil.WriteJSON(w, http.StatusCreated, goal)
 }
@@ -160,6 +252,7 @@ func (h *Handler) UpdateGoal(w http.ResponseWriter, r *http.Request) {
 		httputil.WriteError(w, http.StatusNotFound, "Goal not found")
 		return
 	}
+	previousEnd := goal.EndDate
 
 	// Update fields
 	if req.Calories > 0 {
@@ -177,14 +270,51 @@ func (h *Handler) UpdateGoal(w http.ResponseWriter, r *http.Request) {
 	if req.Fiber > 0 {
 		goal.Fiber = req.Fiber
 	}
+	if req.Water > 0 {
+		goal.Water = req.Water
+	}
+	if req.ExercisePolicy != "" {
+		if err := ValidateExercisePolicy(req.ExercisePolicy); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		goal.ExercisePolicy = req.ExercisePolicy
+	}
 	if req.EndDate != nil {
 		goal.EndDate = req.EndDate
 	}
+	if req.MealTargets != nil {
+		if err := req.MealTargets.Validate(); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		goal.MealTargets = *req.MealTargets
+	}
+	if req.WeekdayTargets != nil {
+		if err := req.WeekdayTargets.ValidateWeekdays(); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		goal.WeekdayTargets = *req.WeekdayTargets
+	}
+	if req.DayTypeTargets != nil {
+		if err := req.DayTypeTargets.ValidateDayTypes(); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		goal.DayTypeTargets = *req.DayTypeTargets
+	}
 
 	if err := h.repo.Update(goal); err != nil {
 		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	// Moving the end date changes the goal of the days between the old and new ends as well
+	changedEnd := goal.EndDate
+	if previousEnd == nil || (changedEnd != nil && previousEnd.After(*changedEnd)) {
+		changedEnd = previousEnd
+	}
+	notifyGoalDays(h.dayObserver, userID, goal.StartDate, changedEnd)
 
 	httputil.WriteJSON(w, http.StatusOK, goal)
 }
@@ -208,10 +338,16 @@ func (h *Handler) DeleteGoal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.repo.Delete(uint(id), userID); err != nil {
+	goal, err := h.repo.GetByID(uint(id), userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Goal not found")
+		return
+	}
+	if err := h.repo.Delete(goal.ID, userID); err != nil {
 		httputil.WriteError(w, http.StatusNotFound, err.Error())
 		return
 	}
+	notifyGoalDays(h.dayObserver, userID, goal.StartDate, goal.EndDate)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -309,15 +445,23 @@ func (h *Handler) CalculateDietGoals(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate request using the factory pattern
-	if err := ValidateDietRequest(&req, user); err != nil {
-		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+	// Lean body mass uses the latest body fat measurement rather than the profile's
+	ApplyLatestBodyFat(user, h.metricsRepo)
+
+	// Get the appropriate calculator, built-in or coach-defined
+	calculator, err := h.repo.DietCalculator(req.DietModel)
+	if err != nil {
+		var protocolErr *ProtocolError
+		if errors.As(err, &protocolErr) {
+			httputil.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	calculator = Localize(calculator, i18n.FromRequest(r))
 
-	// Get the appropriate calculator
-	calculator, err := GetDietCalculator(req.DietModel)
-	if err != nil {
+	if err := ValidateDietRequest(calculator, &req, user); err != nil {
 		httputil.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -325,7 +469,7 @@ func (h *Handler) CalculateDietGoals(w http.ResponseWriter, r *http.Request) {
 	// Perform calculations
 	result, err := calculator.Calculate(user, req.Protocol)
 	if err != nil {
-		httputil.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Calculation failed: %v", err))
+		httputil.WriteErrorf(w, http.StatusInternalServerError, "Calculation failed: %v", err)
 		return
 	}
 
@@ -335,18 +479,53 @@ func (h *Handler) CalculateDietGoals(w http.ResponseWriter, r *http.Request) {
 	httputil.WriteJSON(w, http.StatusOK, response)
 }
 
+// GetTimeline handles GET /goals/timeline
+// Returns past and current protocol phases, followed by the phases planned after the current one
+func (h *Handler) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	goals, err := h.repo.GetProtocolGoals(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Planned phases are recalculated for the active protocol goal, if any
+	var planned []PhaseResult
+	for _, g := range goals {
+		if g.IsActive {
+			planned, err = CalculatePhases(h.repo, h.userRepo, h.metricsRepo, userID, *g.DietModel, *g.Protocol)
+			if err != nil {
+				planned = nil
+			}
+		}
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, BuildTimeline(goals, planned, time.Now()))
+}
+
 // buildDietResponse converts DietCalculationResult to CalculateDietResponse
 func (h *Handler) buildDietResponse(result *DietCalculationResult) CalculateDietResponse {
 	// Convert phases to response format with rounding
 	phases := make([]DietPhaseResponse, len(result.Phases))
 	for i, phase := range result.Phases {
 		phases[i] = DietPhaseResponse{
-			Phase:       phase.Phase,
-			Calories:    math.Round(phase.Calories),
-			Protein:     math.Round(phase.Protein),
-			Carbs:       math.Round(phase.Carbs),
-			Fat:         math.Round(phase.Fat),
-			Description: phase.Description,
+			Phase:        phase.Phase,
+			Calories:     math.Round(phase.Calories),
+			Protein:      math.Round(phase.Protein),
+			Carbs:        math.Round(phase.Carbs),
+			Fat:          math.Round(phase.Fat),
+			Description:  phase.Description,
+			DurationDays: int(phase.Duration().Hours() / 24),
 		}
 	}
 
@@ -395,40 +574,120 @@ func (h *Handler) GetAvailableDiets(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build the response with all available diet models and protocols
-	response := AvailableDietsResponse{
-		Diets: []AvailableDiet{
-			{
-				ModelName:   "zeroToHero",
-				DisplayName: "Zero to Hero",
-				Description: "Comprehensive diet program with 4 protocols for different goals: muscle building, recomposition, and fat loss",
-				Protocols: []ProtocolInfo{
-					{
-						Number:      1,
-						Name:        "Protocole 1 : Prise de muscle propre",
-						Description: "Muscle building protocol with progressive caloric surplus (3 phases: maintenance, moderate surplus, high surplus)",
-					},
-					{
-						Number:      2,
-						Name:        "Protocole 2 : Recomposition corporelle",
-						Description: "Body recomposition protocol for simultaneous fat loss and muscle gain (single phase with slight deficit)",
-					},
-					{
-						Number:      3,
-						Name:        "Protocole 3 : Créer le déficit parfait",
-						Description: "Perfect deficit protocol for controlled fat loss (2 phases: moderate and higher deficit)",
-					},
-					{
-						Number:      4,
-						Name:        "Protocole 4 : Perte de gras progressive",
-						Description: "Progressive fat loss protocol with gradual caloric reduction (3 phases: initial, moderate, and aggressive deficit)",
-					},
-				},
-			},
-		},
+	lang := i18n.FromRequest(r)
+
+	// Every calculator in the registry is listed, followed by the coach-defined diets
+	diets := AvailableDiets(lang)
+
+	customDiets, err := h.repo.ListCustomDiets()
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, custom := range customDiets {
+		calculator, err := NewDefinedDietCalculator(custom.Definition)
+		if err != nil {
+			continue // No longer valid; it cannot be calculated either
+		}
+		diet := Localize(calculator, lang).Describe()
+		coachID := custom.CoachID
+		diet.CoachID = &coachID
+		diets = append(diets, diet)
 	}
 
-	httputil.WriteJSON(w, http.StatusOK, response)
+	httputil.WriteJSON(w, http.StatusOK, AvailableDietsResponse{Diets: diets})
+}
+
+// CreateCustomDiet handles POST /goals/diets
+// Coaches and admins add a diet definition that every user can then calculate
+func (h *Handler) CreateCustomDiet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Diets belong to the coach who adds them, even when acting on a client's data
+	coachID, ok := httputil.GetActorID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	role, _ := httputil.GetUserRole(r)
+	if role != string(user.RoleCoach) && role != string(user.RoleAdmin) {
+		httputil.WriteError(w, http.StatusForbidden, "Only coaches can add diet definitions")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	calculator, err := ParseDietDefinition(body)
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	modelName := calculator.GetModelName()
+	if IsBuiltinDietModel(modelName) {
+		httputil.WriteError(w, http.StatusConflict, "A built-in diet model already uses this name")
+		return
+	}
+	if _, err := h.repo.GetCustomDiet(modelName); err == nil {
+		httputil.WriteError(w, http.StatusConflict, "A custom diet model already uses this name")
+		return
+	}
+
+	diet := &CustomDiet{
+		CoachID:    coachID,
+		ModelName:  modelName,
+		Definition: calculator.Definition(),
+	}
+	if err := h.repo.CreateCustomDiet(diet); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusCreated, diet)
+}
+
+// DeleteCustomDiet handles DELETE /goals/diets/{model}
+// Only the coach who added the diet, or an admin, can remove it
+func (h *Handler) DeleteCustomDiet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// The caller, not the client whose data they may be acting on
+	coachID, ok := httputil.GetActorID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	modelName := strings.TrimPrefix(r.URL.Path, "/goals/diets/")
+	diet, err := h.repo.GetCustomDiet(modelName)
+	if err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Custom diet not found")
+		return
+	}
+
+	role, _ := httputil.GetUserRole(r)
+	if diet.CoachID != coachID && role != string(user.RoleAdmin) {
+		httputil.WriteError(w, http.StatusForbidden, "Only the coach who added this diet can delete it")
+		return
+	}
+
+	if err := h.repo.DeleteCustomDiet(diet.ID); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // extractID extracts the ID from the URL path
@@ -0,0 +1,79 @@
+package goal
+
+import (
+	"ultra-bis/internal/user"
+)
+
+// KatchMcArdleCalculator sets targets from lean body mass (Katch-McArdle BMR)
+type KatchMcArdleCalculator struct{}
+
+// NewKatchMcArdleCalculator creates a new calculator instance
+func NewKatchMcArdleCalculator() *KatchMcArdleCalculator {
+	return &KatchMcArdleCalculator{}
+}
+
+// katchProtocols lists the Katch-McArdle protocols; they share the Mifflin-St Jeor calorie factors
+var katchProtocols = []ProtocolInfo{
+	{Number: 1, Name: "Maintenance", Description: "Eat at maintenance (lean-mass BMR × activity multiplier)"},
+	{Number: 2, Name: "Fat loss", Description: "20% deficit below maintenance"},
+	{Number: 3, Name: "Lean bulk", Description: "10% surplus above maintenance"},
+}
+
+// GetModelName returns the diet model name
+func (c *KatchMcArdleCalculator) GetModelName() string {
+	return "katchMcArdle"
+}
+
+// Describe returns the Katch-McArdle model and its protocols
+func (c *KatchMcArdleCalculator) Describe() AvailableDiet {
+	return AvailableDiet{
+		ModelName:   c.GetModelName(),
+		DisplayName: "Katch-McArdle",
+		Description: "Targets from lean body mass, suited to users who know their body fat; protein at 2.2 g/kg of lean mass and fat at 25% of calories",
+		Protocols:   katchProtocols,
+	}
+}
+
+// ValidateUser validates that the user has the body stats and body fat needed for lean mass
+func (c *KatchMcArdleCalculator) ValidateUser(user *user.User) error {
+	return validateBodyStats(user, true)
+}
+
+// ValidateProtocol validates that the protocol number is valid for Katch-McArdle
+func (c *KatchMcArdleCalculator) ValidateProtocol(protocol int) error {
+	return validateProtocolNumber(protocol, katchProtocols, "Katch-McArdle")
+}
+
+// GetProtocolName returns the human-readable protocol name
+func (c *KatchMcArdleCalculator) GetProtocolName(protocol int) string {
+	return protocolName(protocol, katchProtocols)
+}
+
+// Calculate returns a single phase at the protocol's share of maintenance
+func (c *KatchMcArdleCalculator) Calculate(user *user.User, protocol int) (*DietCalculationResult, error) {
+	lean := leanMass(user)
+
+	// Katch-McArdle: BMR = 370 + 21.6 × lean body mass (kg)
+	bmr := 370 + 21.6*lean
+	tdee := bmr * getActivityMultiplier(user.ActivityLevel)
+
+	phase := PhaseResult{
+		Phase:       1,
+		Calories:    tdee * mifflinFactors[protocol],
+		Description: c.GetProtocolName(protocol),
+	}
+	splitMacros(&phase, lean*2.2, phase.Calories*0.25/9)
+
+	return &DietCalculationResult{
+		ModelName:      c.GetModelName(),
+		Protocol:       protocol,
+		ProtocolName:   c.GetProtocolName(protocol),
+		BMR:            bmr,
+		MaintenanceMMR: tdee,
+		LeanMass:       lean,
+		Phases:         []PhaseResult{phase},
+		Metadata: map[string]interface{}{
+			"activity_multiplier": getActivityMultiplier(user.ActivityLevel),
+		},
+	}, nil
+}
@@ -0,0 +1,80 @@
+package goal
+
+import (
+	"math"
+
+	"ultra-bis/internal/user"
+)
+
+// KetoCalculator sets a ketogenic split: fixed low carbs, moderate protein, fat for the rest
+type KetoCalculator struct{}
+
+// NewKetoCalculator creates a new calculator instance
+func NewKetoCalculator() *KetoCalculator {
+	return &KetoCalculator{}
+}
+
+// ketoProtocols lists the ketogenic protocols and their calorie factor of maintenance
+var ketoProtocols = []ProtocolInfo{
+	{Number: 1, Name: "Keto maintenance", Description: "Maintenance calories with 25 g carbs and 1.6 g/kg protein"},
+	{Number: 2, Name: "Keto fat loss", Description: "20% deficit with 25 g carbs and 1.6 g/kg protein"},
+}
+
+var ketoFactors = map[int]float64{1: 1.0, 2: 0.8}
+
+// ketoCarbs is the daily carbohydrate limit in grams
+const ketoCarbs = 25
+
+// GetModelName returns the diet model name
+func (c *KetoCalculator) GetModelName() string {
+	return "keto"
+}
+
+// Describe returns the ketogenic model and its protocols
+func (c *KetoCalculator) Describe() AvailableDiet {
+	return AvailableDiet{
+		ModelName:   c.GetModelName(),
+		DisplayName: "Ketogenic",
+		Description: "Ketogenic split from Mifflin-St Jeor maintenance: carbs capped at 25 g, protein at 1.6 g/kg, fat for the remaining calories",
+		Protocols:   ketoProtocols,
+	}
+}
+
+// ValidateUser validates that the user has age, height, weight and gender
+func (c *KetoCalculator) ValidateUser(user *user.User) error {
+	return validateBodyStats(user, false)
+}
+
+// ValidateProtocol validates that the protocol number is valid for keto
+func (c *KetoCalculator) ValidateProtocol(protocol int) error {
+	return validateProtocolNumber(protocol, ketoProtocols, "Ketogenic")
+}
+
+// GetProtocolName returns the human-readable protocol name
+func (c *KetoCalculator) GetProtocolName(protocol int) string {
+	return protocolName(protocol, ketoProtocols)
+}
+
+// Calculate returns a single phase with the ketogenic macro split
+func (c *KetoCalculator) Calculate(user *user.User, protocol int) (*DietCalculationResult, error) {
+	bmr, tdee := mifflinTDEE(user)
+
+	phase := PhaseResult{
+		Phase:       1,
+		Calories:    tdee * ketoFactors[protocol],
+		Protein:     user.Weight * 1.6,
+		Carbs:       ketoCarbs,
+		Description: c.GetProtocolName(protocol),
+	}
+	phase.Fat = math.Max(0, (phase.Calories-phase.Protein*4-phase.Carbs*4)/9)
+
+	return &DietCalculationResult{
+		ModelName:      c.GetModelName(),
+		Protocol:       protocol,
+		ProtocolName:   c.GetProtocolName(protocol),
+		BMR:            bmr,
+		MaintenanceMMR: tdee,
+		LeanMass:       leanMass(user),
+		Phases:         []PhaseResult{phase},
+	}, nil
+}
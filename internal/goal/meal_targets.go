@@ -0,0 +1,118 @@
+package goal
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Units a meal target can be expressed in
+const (
+	TargetUnitAbsolute = "absolute" // kcal for calories, grams for macros
+	TargetUnitPercent  = "percent"  // Percentage of the daily goal
+)
+
+// mealTypes lists the meal types a target can apply to (mirrors diary.MealType)
+var mealTypes = map[string]bool{"breakfast": true, "lunch": true, "dinner": true, "snack": true}
+
+// MealTarget represents the share of the daily goal expected at one meal
+// Zero values mean no target for that nutrient
+type MealTarget struct {
+	MealType string  `json:"meal_type"`
+	Unit     string  `json:"unit"`
+	Calories float64 `json:"calories,omitempty"`
+	Protein  float64 `json:"protein,omitempty"`
+	Carbs    float64 `json:"carbs,omitempty"`
+	Fat      float64 `json:"fat,omitempty"`
+	Fiber    float64 `json:"fiber,omitempty"`
+}
+
+// MealMacros represents absolute nutrient amounts for a meal
+type MealMacros struct {
+	Calories float64 `json:"calories"`
+	Protein  float64 `json:"protein"`
+	Carbs    float64 `json:"carbs"`
+	Fat      float64 `json:"fat"`
+	Fiber    float64 `json:"fiber"`
+}
+
+// MealTargets is a slice of MealTarget stored as JSONB
+type MealTargets []MealTarget
+
+// Value implements the driver.Valuer interface for JSONB serialization
+func (m MealTargets) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return json.Marshal(m)
+}
+
+// Scan implements the sql.Scanner interface for JSONB deserialization
+func (m *MealTargets) Scan(value interface{}) error {
+	if value == nil {
+		*m = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to scan MealTargets: not a byte slice")
+	}
+
+	return json.Unmarshal(bytes, m)
+}
+
+// Validate checks meal types, units and that percentages of one nutrient do not exceed 100
+func (m MealTargets) Validate() error {
+	seen := make(map[string]bool)
+	var percentTotals MealMacros
+
+	for _, target := range m {
+		if !mealTypes[target.MealType] {
+			return fmt.Errorf("invalid meal_type %q in meal_targets", target.MealType)
+		}
+		if seen[target.MealType] {
+			return fmt.Errorf("duplicate meal target for %s", target.MealType)
+		}
+		seen[target.MealType] = true
+
+		if target.Calories < 0 || target.Protein < 0 || target.Carbs < 0 || target.Fat < 0 || target.Fiber < 0 {
+			return fmt.Errorf("meal target values for %s must not be negative", target.MealType)
+		}
+
+		switch target.Unit {
+		case TargetUnitAbsolute:
+		case TargetUnitPercent:
+			percentTotals.Calories += target.Calories
+			percentTotals.Protein += target.Protein
+			percentTotals.Carbs += target.Carbs
+			percentTotals.Fat += target.Fat
+			percentTotals.Fiber += target.Fiber
+		default:
+			return fmt.Errorf("meal target unit must be '%s' or '%s'", TargetUnitAbsolute, TargetUnitPercent)
+		}
+	}
+
+	if percentTotals.Calories > 100 || percentTotals.Protein > 100 || percentTotals.Carbs > 100 ||
+		percentTotals.Fat > 100 || percentTotals.Fiber > 100 {
+		return fmt.Errorf("meal target percentages of a nutrient must not add up to more than 100")
+	}
+
+	return nil
+}
+
+// Resolve converts the target into absolute amounts using the daily goal
+func (t MealTarget) Resolve(g *NutritionGoal) MealMacros {
+	if t.Unit != TargetUnitPercent {
+		return MealMacros{Calories: t.Calories, Protein: t.Protein, Carbs: t.Carbs, Fat: t.Fat, Fiber: t.Fiber}
+	}
+
+	return MealMacros{
+		Calories: g.Calories * t.Calories / 100,
+		Protein:  g.Protein * t.Protein / 100,
+		Carbs:    g.Carbs * t.Carbs / 100,
+		Fat:      g.Fat * t.Fat / 100,
+		Fiber:    g.Fiber * t.Fiber / 100,
+	}
+}
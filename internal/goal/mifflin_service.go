@@ -0,0 +1,85 @@
+package goal
+
+import (
+	"ultra-bis/internal/user"
+)
+
+// MifflinStJeorCalculator sets targets from the Mifflin-St Jeor BMR and the user's activity level
+type MifflinStJeorCalculator struct{}
+
+// NewMifflinStJeorCalculator creates a new calculator instance
+func NewMifflinStJeorCalculator() *MifflinStJeorCalculator {
+	return &MifflinStJeorCalculator{}
+}
+
+// mifflinProtocols lists the Mifflin-St Jeor protocols and their calorie factor of maintenance
+var mifflinProtocols = []ProtocolInfo{
+	{Number: 1, Name: "Maintenance", Description: "Eat at maintenance (BMR × activity multiplier)"},
+	{Number: 2, Name: "Fat loss", Description: "20% deficit below maintenance"},
+	{Number: 3, Name: "Lean bulk", Description: "10% surplus above maintenance"},
+}
+
+var mifflinFactors = map[int]float64{1: 1.0, 2: 0.8, 3: 1.1}
+
+// GetModelName returns the diet model name
+func (c *MifflinStJeorCalculator) GetModelName() string {
+	return "mifflinStJeor"
+}
+
+// Describe returns the Mifflin-St Jeor model and its protocols
+func (c *MifflinStJeorCalculator) Describe() AvailableDiet {
+	return AvailableDiet{
+		ModelName:   c.GetModelName(),
+		DisplayName: "Mifflin-St Jeor",
+		Description: "Targets from the Mifflin-St Jeor equation with activity multipliers; protein at 1.8 g/kg and fat at 25% of calories",
+		Protocols:   mifflinProtocols,
+	}
+}
+
+// ValidateUser validates that the user has age, height, weight and gender
+func (c *MifflinStJeorCalculator) ValidateUser(user *user.User) error {
+	return validateBodyStats(user, false)
+}
+
+// ValidateProtocol validates that the protocol number is valid for Mifflin-St Jeor
+func (c *MifflinStJeorCalculator) ValidateProtocol(protocol int) error {
+	return validateProtocolNumber(protocol, mifflinProtocols, "Mifflin-St Jeor")
+}
+
+// GetProtocolName returns the human-readable protocol name
+func (c *MifflinStJeorCalculator) GetProtocolName(protocol int) string {
+	return protocolName(protocol, mifflinProtocols)
+}
+
+// Calculate returns a single phase at the protocol's share of maintenance
+func (c *MifflinStJeorCalculator) Calculate(user *user.User, protocol int) (*DietCalculationResult, error) {
+	bmr, tdee := mifflinTDEE(user)
+
+	phase := PhaseResult{
+		Phase:       1,
+		Calories:    tdee * mifflinFactors[protocol],
+		Description: c.GetProtocolName(protocol),
+	}
+	splitMacros(&phase, user.Weight*1.8, phase.Calories*0.25/9)
+
+	return &DietCalculationResult{
+		ModelName:      c.GetModelName(),
+		Protocol:       protocol,
+		ProtocolName:   c.GetProtocolName(protocol),
+		BMR:            bmr,
+		MaintenanceMMR: tdee,
+		LeanMass:       leanMass(user),
+		Phases:         []PhaseResult{phase},
+		Metadata: map[string]interface{}{
+			"activity_multiplier": getActivityMultiplier(user.ActivityLevel),
+		},
+	}, nil
+}
+
+// leanMass returns the user's lean body mass, or 0 when body fat is unknown
+func leanMass(user *user.User) float64 {
+	if user.BodyFat <= 0 || user.BodyFat >= 100 {
+		return 0
+	}
+	return user.Weight * (100 - user.BodyFat) / 100
+}
@@ -51,9 +51,21 @@ type NutritionGoal struct {
 	Carbs     float64        `json:"carbs" gorm:"type:decimal(10,2)"`
 	Fat       float64        `json:"fat" gorm:"type:decimal(10,2)"`
 	Fiber     float64        `json:"fiber" gorm:"type:decimal(10,2)"`
+	Water     float64        `json:"water" gorm:"type:decimal(10,2)"` // Daily hydration target in ml, 0 for none
 	StartDate time.Time      `json:"start_date" gorm:"not null"`
 	EndDate   *time.Time     `json:"end_date"`
 	IsActive  bool           `json:"is_active" gorm:"default:true;index"`
+	// Optional per-meal targets, absolute or as a percentage of the daily values
+	MealTargets MealTargets `json:"meal_targets,omitempty" gorm:"type:jsonb"`
+	// Optional overrides of the daily values per weekday or per day type (carb cycling)
+	WeekdayTargets DayTargets `json:"weekday_targets,omitempty" gorm:"type:jsonb"`
+	DayTypeTargets DayTargets `json:"day_type_targets,omitempty" gorm:"type:jsonb"`
+	// Share of the calories burned exercising added to the day's targets: "ignore", "half" or "full"
+	ExercisePolicy string `json:"exercise_policy" gorm:"type:varchar(10);not null;default:'ignore'"`
+	// Set when the goal was resolved for a date (not persisted)
+	DayType          string  `json:"day_type,omitempty" gorm:"-"`
+	AppliedOverride  string  `json:"applied_override,omitempty" gorm:"-"`  // Weekday or day type whose override was applied
+	ExerciseCalories float64 `json:"exercise_calories,omitempty" gorm:"-"` // Calories added for the day's exercise
 	// Protocol tracking fields
 	DietModel      *string    `json:"diet_model,omitempty" gorm:"type:varchar(50);index"`
 	Protocol       *int       `json:"protocol,omitempty" gorm:"index"`
@@ -61,6 +73,34 @@ type NutritionGoal struct {
 	ExpirationDate *time.Time `json:"expiration_date,omitempty"`
 }
 
+// Phase statuses in the protocol timeline
+const (
+	PhaseStatusCompleted = "completed"
+	PhaseStatusCurrent   = "current"
+	PhaseStatusPlanned   = "planned"
+)
+
+// TimelinePhase represents one phase of a calculated protocol, past, current or planned
+type TimelinePhase struct {
+	GoalID      *uint   `json:"goal_id,omitempty"` // Nil for planned phases
+	DietModel   string  `json:"diet_model"`
+	Protocol    int     `json:"protocol"`
+	Phase       int     `json:"phase"`
+	Status      string  `json:"status"`
+	StartDate   string  `json:"start_date"`
+	EndDate     string  `json:"end_date,omitempty"`
+	Calories    float64 `json:"calories"`
+	Protein     float64 `json:"protein"`
+	Carbs       float64 `json:"carbs"`
+	Fat         float64 `json:"fat"`
+	Description string  `json:"description,omitempty"`
+}
+
+// TimelineResponse represents the user's protocol phase timeline
+type TimelineResponse struct {
+	Phases []TimelinePhase `json:"phases"`
+}
+
 // CreateGoalRequest represents the request to create a nutrition goal
 type CreateGoalRequest struct {
 	Calories  float64 `json:"calories"`
@@ -68,9 +108,16 @@ type CreateGoalRequest struct {
 	Carbs     float64 `json:"carbs"`
 	Fat       float64 `json:"fat"`
 	Fiber     float64 `json:"fiber"`
+	Water     float64 `json:"water"` // Daily hydration target in ml
 	StartDate Date    `json:"start_date"`
 	EndDate   *Date   `json:"end_date,omitempty"`
 
+	ExercisePolicy string `json:"exercise_policy,omitempty"` // "ignore" (default), "half" or "full"
+
+	MealTargets    MealTargets `json:"meal_targets,omitempty"`
+	WeekdayTargets DayTargets  `json:"weekday_targets,omitempty"`
+	DayTypeTargets DayTargets  `json:"day_type_targets,omitempty"`
+
 	// Optional protocol tracking - only populated when creating from calculation
 	DietModel *string `json:"diet_model,omitempty"`
 	Protocol  *int    `json:"protocol,omitempty"`
@@ -84,7 +131,15 @@ type UpdateGoalRequest struct {
 	Carbs    float64    `json:"carbs"`
 	Fat      float64    `json:"fat"`
 	Fiber    float64    `json:"fiber"`
+	Water    float64    `json:"water"`
 	EndDate  *time.Time `json:"end_date"`
+
+	ExercisePolicy string `json:"exercise_policy,omitempty"`
+
+	// Replace the meal targets and day overrides when present; an empty value removes them
+	MealTargets    *MealTargets `json:"meal_targets,omitempty"`
+	WeekdayTargets *DayTargets  `json:"weekday_targets,omitempty"`
+	DayTypeTargets *DayTargets  `json:"day_type_targets,omitempty"`
 }
 
 // RecommendedGoalRequest represents the request to calculate recommended goals
@@ -112,12 +167,13 @@ type CalculateDietRequest struct {
 
 // DietPhaseResponse represents a single phase of a diet protocol
 type DietPhaseResponse struct {
-	Phase       int     `json:"phase"`
-	Calories    float64 `json:"calories"`
-	Protein     float64 `json:"protein"`
-	Carbs       float64 `json:"carbs"`
-	Fat         float64 `json:"fat"`
-	Description string  `json:"description"`
+	Phase        int     `json:"phase"`
+	Calories     float64 `json:"calories"`
+	Protein      float64 `json:"protein"`
+	Carbs        float64 `json:"carbs"`
+	Fat          float64 `json:"fat"`
+	Description  string  `json:"description"`
+	DurationDays int     `json:"duration_days"`
 }
 
 // CalculateDietResponse represents the response from diet calculation
@@ -145,6 +201,7 @@ type AvailableDiet struct {
 	DisplayName string         `json:"display_name"`
 	Description string         `json:"description"`
 	Protocols   []ProtocolInfo `json:"protocols"`
+	CoachID     *uint          `json:"coach_id,omitempty"` // Set for diets added by a coach
 }
 
 // AvailableDietsResponse represents the response for available diets endpoint
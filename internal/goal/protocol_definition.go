@@ -0,0 +1,430 @@
+package goal
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"time"
+
+	"ultra-bis/internal/user"
+)
+
+// BMR formulas a diet definition can build maintenance calories from
+const (
+	BMRFormulaZeroToHero    = "zeroToHero"    // Average of a weight/height/age variant and the lean mass formula
+	BMRFormulaMifflinStJeor = "mifflinStJeor" // Mifflin-St Jeor, requires gender
+	BMRFormulaKatchMcArdle  = "katchMcArdle"  // 370 + 21.6 × lean mass, requires body fat
+)
+
+// fallbackLanguage is used when neither the requested nor the definition's default language has a text
+const fallbackLanguage = "en"
+
+// modelNamePattern restricts model names to the camelCase identifiers used by the built-in models
+var modelNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9]{1,49}$`)
+
+//go:embed protocols/*.json
+var builtinDefinitions embed.FS
+
+// LocalizedText holds a text per language code, e.g. {"fr": "...", "en": "..."}
+type LocalizedText map[string]string
+
+// Get returns the text in lang, falling back to defaultLang, then English, then any language
+func (t LocalizedText) Get(lang, defaultLang string) string {
+	for _, candidate := range []string{lang, defaultLang, fallbackLanguage} {
+		if text := t[candidate]; text != "" {
+			return text
+		}
+	}
+
+	languages := make([]string, 0, len(t))
+	for language := range t {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		if t[language] != "" {
+			return t[language]
+		}
+	}
+	return ""
+}
+
+// MacroFormula computes a macronutrient target in grams as the sum of its terms
+type MacroFormula struct {
+	PerKgBodyWeight float64 `json:"per_kg_body_weight,omitempty"` // Grams per kg of body weight
+	PerKgLeanMass   float64 `json:"per_kg_lean_mass,omitempty"`   // Grams per kg of lean body mass
+	PercentCalories float64 `json:"percent_calories,omitempty"`   // Percentage of the phase calories
+}
+
+// grams evaluates the formula; kcalPerGram converts the calorie percentage to grams
+func (f MacroFormula) grams(weight, leanMass, calories, kcalPerGram float64) float64 {
+	return weight*f.PerKgBodyWeight + leanMass*f.PerKgLeanMass + calories*f.PercentCalories/100/kcalPerGram
+}
+
+func (f MacroFormula) isZero() bool {
+	return f == MacroFormula{}
+}
+
+func (f MacroFormula) validate(name string) error {
+	if f.PerKgBodyWeight < 0 || f.PerKgLeanMass < 0 || f.PercentCalories < 0 {
+		return fmt.Errorf("%s formula cannot be negative", name)
+	}
+	if f.PercentCalories > 100 {
+		return fmt.Errorf("%s percent_calories cannot exceed 100", name)
+	}
+	if f.isZero() {
+		return fmt.Errorf("%s formula is required", name)
+	}
+	return nil
+}
+
+// PhaseDefinition describes one phase of a protocol relative to maintenance calories
+// Phase calories = maintenance × calorie_factor + calorie_offset
+type PhaseDefinition struct {
+	CalorieOffset float64       `json:"calorie_offset"`
+	CalorieFactor float64       `json:"calorie_factor,omitempty"` // 0 means 1 (maintenance)
+	DurationDays  int           `json:"duration_days,omitempty"`  // 0 means the default two weeks
+	Description   LocalizedText `json:"description"`
+}
+
+// ProtocolDefinition describes a numbered protocol and its phases
+// Protein and fat formulas override the diet's formulas when set
+type ProtocolDefinition struct {
+	Number      int               `json:"number"`
+	Name        LocalizedText     `json:"name"`
+	Description LocalizedText     `json:"description"`
+	Protein     *MacroFormula     `json:"protein,omitempty"`
+	Fat         *MacroFormula     `json:"fat,omitempty"`
+	Phases      []PhaseDefinition `json:"phases"`
+}
+
+// DietDefinition declares a diet model: how maintenance is estimated, how macros are split
+// and which protocols it offers. Carbohydrates always take the remaining calories.
+type DietDefinition struct {
+	ModelName             string               `json:"model_name"`
+	DisplayName           LocalizedText        `json:"display_name"`
+	Description           LocalizedText        `json:"description"`
+	DefaultLanguage       string               `json:"default_language,omitempty"`
+	BMRFormula            string               `json:"bmr_formula"`
+	MaintenanceMultiplier float64              `json:"maintenance_multiplier,omitempty"` // 0 uses the user's activity level
+	Protein               MacroFormula         `json:"protein"`
+	Fat                   MacroFormula         `json:"fat"`
+	Protocols             []ProtocolDefinition `json:"protocols"`
+}
+
+// Value implements the driver.Valuer interface for JSONB storage
+func (d DietDefinition) Value() (driver.Value, error) {
+	return json.Marshal(d)
+}
+
+// Scan implements the sql.Scanner interface for JSONB deserialization
+func (d *DietDefinition) Scan(value interface{}) error {
+	data, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to scan DietDefinition: not a byte slice")
+	}
+
+	return json.Unmarshal(data, d)
+}
+
+// Validate checks that the definition can be calculated; it is run whenever a definition is loaded
+func (d *DietDefinition) Validate() error {
+	if !modelNamePattern.MatchString(d.ModelName) {
+		return fmt.Errorf("model_name must be 2 to 50 letters or digits, starting with a letter")
+	}
+	if d.DisplayName.Get("", d.DefaultLanguage) == "" {
+		return fmt.Errorf("display_name is required")
+	}
+
+	switch d.BMRFormula {
+	case BMRFormulaZeroToHero, BMRFormulaMifflinStJeor, BMRFormulaKatchMcArdle:
+	default:
+		return fmt.Errorf("bmr_formula must be '%s', '%s', or '%s'", BMRFormulaZeroToHero, BMRFormulaMifflinStJeor, BMRFormulaKatchMcArdle)
+	}
+
+	if d.MaintenanceMultiplier != 0 && (d.MaintenanceMultiplier < 1 || d.MaintenanceMultiplier > 2.5) {
+		return fmt.Errorf("maintenance_multiplier must be between 1 and 2.5, or omitted to use the activity level")
+	}
+
+	if err := d.Protein.validate("protein"); err != nil {
+		return err
+	}
+	if err := d.Fat.validate("fat"); err != nil {
+		return err
+	}
+
+	if len(d.Protocols) == 0 {
+		return fmt.Errorf("at least one protocol is required")
+	}
+	for i, protocol := range d.Protocols {
+		if err := protocol.validate(i+1, d.DefaultLanguage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validate checks a protocol; protocols must be numbered 1, 2, 3... in order
+func (p *ProtocolDefinition) validate(number int, defaultLang string) error {
+	if p.Number != number {
+		return fmt.Errorf("protocols must be numbered from 1 in order: expected %d, got %d", number, p.Number)
+	}
+	if p.Name.Get("", defaultLang) == "" {
+		return fmt.Errorf("protocol %d: name is required", p.Number)
+	}
+	if p.Protein != nil {
+		if err := p.Protein.validate(fmt.Sprintf("protocol %d: protein", p.Number)); err != nil {
+			return err
+		}
+	}
+	if p.Fat != nil {
+		if err := p.Fat.validate(fmt.Sprintf("protocol %d: fat", p.Number)); err != nil {
+			return err
+		}
+	}
+
+	if len(p.Phases) == 0 || len(p.Phases) > 12 {
+		return fmt.Errorf("protocol %d: must have between 1 and 12 phases", p.Number)
+	}
+	for i, phase := range p.Phases {
+		if phase.CalorieFactor != 0 && (phase.CalorieFactor < 0.5 || phase.CalorieFactor > 1.5) {
+			return fmt.Errorf("protocol %d phase %d: calorie_factor must be between 0.5 and 1.5", p.Number, i+1)
+		}
+		if phase.CalorieOffset < -1500 || phase.CalorieOffset > 1500 {
+			return fmt.Errorf("protocol %d phase %d: calorie_offset must be between -1500 and 1500", p.Number, i+1)
+		}
+		if phase.DurationDays < 0 || phase.DurationDays > 90 {
+			return fmt.Errorf("protocol %d phase %d: duration_days must be between 1 and 90, or omitted", p.Number, i+1)
+		}
+	}
+
+	return nil
+}
+
+// requiresBodyFat reports whether any part of the definition depends on lean body mass
+func (d *DietDefinition) requiresBodyFat() bool {
+	if d.BMRFormula != BMRFormulaMifflinStJeor || d.Protein.PerKgLeanMass > 0 || d.Fat.PerKgLeanMass > 0 {
+		return true
+	}
+	for _, protocol := range d.Protocols {
+		if (protocol.Protein != nil && protocol.Protein.PerKgLeanMass > 0) || (protocol.Fat != nil && protocol.Fat.PerKgLeanMass > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefinedDietCalculator calculates a diet model from a DietDefinition
+type DefinedDietCalculator struct {
+	definition DietDefinition
+	lang       string
+}
+
+// NewDefinedDietCalculator validates a definition and creates its calculator
+func NewDefinedDietCalculator(definition DietDefinition) (*DefinedDietCalculator, error) {
+	if err := definition.Validate(); err != nil {
+		return nil, err
+	}
+	return &DefinedDietCalculator{definition: definition}, nil
+}
+
+// WithLanguage returns a copy of the calculator whose names and descriptions use lang when available
+func (c *DefinedDietCalculator) WithLanguage(lang string) DietCalculator {
+	localized := *c
+	localized.lang = lang
+	return &localized
+}
+
+// Definition returns the definition the calculator was built from
+func (c *DefinedDietCalculator) Definition() DietDefinition {
+	return c.definition
+}
+
+// GetModelName returns the diet model name
+func (c *DefinedDietCalculator) GetModelName() string {
+	return c.definition.ModelName
+}
+
+// Describe returns the model and its protocols in the calculator's language
+func (c *DefinedDietCalculator) Describe() AvailableDiet {
+	protocols := make([]ProtocolInfo, len(c.definition.Protocols))
+	for i, protocol := range c.definition.Protocols {
+		protocols[i] = ProtocolInfo{
+			Number:      protocol.Number,
+			Name:        c.text(protocol.Name),
+			Description: c.text(protocol.Description),
+		}
+	}
+
+	return AvailableDiet{
+		ModelName:   c.definition.ModelName,
+		DisplayName: c.text(c.definition.DisplayName),
+		Description: c.text(c.definition.Description),
+		Protocols:   protocols,
+	}
+}
+
+// ValidateUser validates that the user has the data the definition's formulas need
+func (c *DefinedDietCalculator) ValidateUser(user *user.User) error {
+	return validateProfile(user, c.definition.BMRFormula == BMRFormulaMifflinStJeor, c.definition.requiresBodyFat())
+}
+
+// ValidateProtocol validates that the protocol number is defined
+func (c *DefinedDietCalculator) ValidateProtocol(protocol int) error {
+	return validateProtocolNumber(protocol, c.Describe().Protocols, c.text(c.definition.DisplayName))
+}
+
+// GetProtocolName returns the protocol name in the calculator's language
+func (c *DefinedDietCalculator) GetProtocolName(protocol int) string {
+	if p := c.protocol(protocol); p != nil {
+		return c.text(p.Name)
+	}
+	return "Unknown Protocol"
+}
+
+// Calculate evaluates the protocol's phases against the user's maintenance calories
+func (c *DefinedDietCalculator) Calculate(user *user.User, protocol int) (*DietCalculationResult, error) {
+	p := c.protocol(protocol)
+	if p == nil {
+		return nil, fmt.Errorf("protocol %d is not defined for %s", protocol, c.definition.ModelName)
+	}
+
+	lean := leanMass(user)
+	metadata := map[string]interface{}{}
+
+	var bmr float64
+	switch c.definition.BMRFormula {
+	case BMRFormulaZeroToHero:
+		// (13.707 × weight) + (492.3 × height in m) − (6.673 × age) + 77.607, averaged with the lean mass formula
+		bmr1 := (13.707 * user.Weight) + (492.3 * user.Height / 100) - (6.673 * float64(user.Age)) + 77.607
+		bmr2 := (21.6 * lean) + 370
+		bmr = (bmr1 + bmr2) / 2
+		metadata["bmr1"] = bmr1
+		metadata["bmr2"] = bmr2
+	case BMRFormulaMifflinStJeor:
+		bmr = calculateBMR(user.Weight, user.Height, float64(user.Age), user.Gender)
+	case BMRFormulaKatchMcArdle:
+		bmr = 370 + 21.6*lean
+	}
+
+	multiplier := c.definition.MaintenanceMultiplier
+	if multiplier == 0 {
+		multiplier = getActivityMultiplier(user.ActivityLevel)
+	}
+	maintenance := bmr * multiplier
+	metadata["maintenance_multiplier"] = multiplier
+
+	protein, fat := c.definition.Protein, c.definition.Fat
+	if p.Protein != nil {
+		protein = *p.Protein
+	}
+	if p.Fat != nil {
+		fat = *p.Fat
+	}
+
+	phases := make([]PhaseResult, len(p.Phases))
+	for i, definition := range p.Phases {
+		factor := definition.CalorieFactor
+		if factor == 0 {
+			factor = 1
+		}
+
+		phases[i] = PhaseResult{
+			Phase:        i + 1,
+			Calories:     maintenance*factor + definition.CalorieOffset,
+			Description:  c.text(definition.Description),
+			DurationDays: definition.DurationDays,
+		}
+		splitMacros(&phases[i],
+			protein.grams(user.Weight, lean, phases[i].Calories, 4),
+			fat.grams(user.Weight, lean, phases[i].Calories, 9))
+	}
+
+	return &DietCalculationResult{
+		ModelName:      c.definition.ModelName,
+		Protocol:       protocol,
+		ProtocolName:   c.text(p.Name),
+		BMR:            bmr,
+		MaintenanceMMR: maintenance,
+		LeanMass:       lean,
+		Phases:         phases,
+		Metadata:       metadata,
+	}, nil
+}
+
+// PhaseLength returns how long a phase of a protocol lasts
+func (c *DefinedDietCalculator) PhaseLength(protocol, phase int) time.Duration {
+	if p := c.protocol(protocol); p != nil && phase >= 1 && phase <= len(p.Phases) {
+		return (PhaseResult{DurationDays: p.Phases[phase-1].DurationDays}).Duration()
+	}
+	return PhaseDuration
+}
+
+func (c *DefinedDietCalculator) protocol(number int) *ProtocolDefinition {
+	for i := range c.definition.Protocols {
+		if c.definition.Protocols[i].Number == number {
+			return &c.definition.Protocols[i]
+		}
+	}
+	return nil
+}
+
+func (c *DefinedDietCalculator) text(t LocalizedText) string {
+	return t.Get(c.lang, c.definition.DefaultLanguage)
+}
+
+// loadBuiltinDefinitions parses and validates the definitions embedded from protocols/*.json
+// A broken built-in definition is a programming error, so it panics at startup
+func loadBuiltinDefinitions() []*DefinedDietCalculator {
+	entries, err := builtinDefinitions.ReadDir("protocols")
+	if err != nil {
+		panic(fmt.Sprintf("failed to read built-in diet definitions: %v", err))
+	}
+
+	calculators := make([]*DefinedDietCalculator, 0, len(entries))
+	for _, entry := range entries {
+		data, err := builtinDefinitions.ReadFile(path.Join("protocols", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("failed to read diet definition %s: %v", entry.Name(), err))
+		}
+
+		calculator, err := ParseDietDefinition(data)
+		if err != nil {
+			panic(fmt.Sprintf("invalid diet definition %s: %v", entry.Name(), err))
+		}
+		calculators = append(calculators, calculator)
+	}
+	return calculators
+}
+
+// ParseDietDefinition decodes a JSON diet definition, rejecting unknown fields, and validates it
+func ParseDietDefinition(data []byte) (*DefinedDietCalculator, error) {
+	var definition DietDefinition
+	if err := strictUnmarshal(data, &definition); err != nil {
+		return nil, fmt.Errorf("failed to parse diet definition: %w", err)
+	}
+	return NewDefinedDietCalculator(definition)
+}
+
+func strictUnmarshal(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// CustomDiet is a diet definition added by a coach; it is available to every user
+type CustomDiet struct {
+	ID         uint           `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	CoachID    uint           `json:"coach_id" gorm:"not null;index"`
+	ModelName  string         `json:"model_name" gorm:"type:varchar(50);not null;uniqueIndex"`
+	Definition DietDefinition `json:"definition" gorm:"type:jsonb;not null"`
+}
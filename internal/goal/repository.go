@@ -6,8 +6,13 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrGoalNotActive is returned when a phase is advanced after its goal was ended elsewhere
+// (by another scheduler instance or by the user)
+var ErrGoalNotActive = errors.New("goal is no longer active")
+
 // Repository handles database operations for nutrition goals
 type Repository struct {
 	db *gorm.DB
@@ -105,6 +110,7 @@ func (r *Repository) Delete(id, userID uint) error {
 }
 
 // GetForDate retrieves the active goal for a specific date
+// The weekday or day type override of that date is applied to the returned goal
 func (r *Repository) GetForDate(userID uint, date time.Time) (*NutritionGoal, error) {
 	var goal NutritionGoal
 	query := r.db.Where("user_id = ? AND start_date <= ?", userID, date)
@@ -118,5 +124,184 @@ func (r *Repository) GetForDate(userID uint, date time.Time) (*NutritionGoal, er
 		return nil, fmt.Errorf("failed to get goal: %w", result.Error)
 	}
 
-	return &goal, nil
+	dayType, err := r.GetDayType(userID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	return goal.ResolveForDate(date, dayType), nil
+}
+
+// ListExpiredPhases retrieves active protocol goals whose phase expired at or before now
+func (r *Repository) ListExpiredPhases(now time.Time) ([]NutritionGoal, error) {
+	var goals []NutritionGoal
+	result := r.db.Where("is_active = ? AND diet_model IS NOT NULL AND protocol IS NOT NULL AND expiration_date <= ?", true, now).
+		Order("expiration_date").
+		Find(&goals)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get expired goals: %w", result.Error)
+	}
+
+	return goals, nil
+}
+
+// GetProtocolGoals retrieves a user's goals created from a diet protocol, oldest first
+func (r *Repository) GetProtocolGoals(userID uint) ([]NutritionGoal, error) {
+	var goals []NutritionGoal
+	result := r.db.Where("user_id = ? AND diet_model IS NOT NULL AND protocol IS NOT NULL", userID).
+		Order("start_date, id").
+		Find(&goals)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get protocol goals: %w", result.Error)
+	}
+
+	return goals, nil
+}
+
+// AdvancePhase ends an expired protocol goal and, when next is not nil, starts the next phase
+func (r *Repository) AdvancePhase(current, next *NutritionGoal) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Only end the goal if it is still active: the row lock makes a concurrent
+		// scheduler wait for this transaction, then find nothing left to advance
+		endDate := *current.ExpirationDate
+		result := tx.Model(&NutritionGoal{}).
+			Where("id = ? AND is_active = ?", current.ID, true).
+			Updates(map[string]interface{}{"end_date": endDate, "is_active": false})
+		if result.Error != nil {
+			return fmt.Errorf("failed to end goal: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrGoalNotActive
+		}
+		current.EndDate = &endDate
+		current.IsActive = false
+
+		if next == nil {
+			return nil
+		}
+		if err := tx.Create(next).Error; err != nil {
+			return fmt.Errorf("failed to create next phase goal: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetDayType retrieves the day type a user tagged a date with, or "" when untagged
+func (r *Repository) GetDayType(userID uint, date time.Time) (string, error) {
+	var assignment DayTypeAssignment
+	result := r.db.Where("user_id = ? AND date = ?", userID, date.Format("2006-01-02")).Limit(1).Find(&assignment)
+	if result.Error != nil {
+		return "", fmt.Errorf("failed to get day type: %w", result.Error)
+	}
+	return assignment.DayType, nil
+}
+
+// GetDayTypes retrieves the day types of a date range (inclusive), keyed by YYYY-MM-DD
+func (r *Repository) GetDayTypes(userID uint, from, to time.Time) (map[string]string, error) {
+	var assignments []DayTypeAssignment
+	result := r.db.Where("user_id = ? AND date >= ? AND date <= ?", userID, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Find(&assignments)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get day types: %w", result.Error)
+	}
+
+	dayTypes := make(map[string]string, len(assignments))
+	for _, assignment := range assignments {
+		dayTypes[assignment.Date.Format("2006-01-02")] = assignment.DayType
+	}
+	return dayTypes, nil
+}
+
+// SetDayType tags a date with a day type, replacing any previous tag
+func (r *Repository) SetDayType(userID uint, date time.Time, dayType string) (*DayTypeAssignment, error) {
+	assignment := DayTypeAssignment{
+		UserID:  userID,
+		Date:    time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC),
+		DayType: dayType,
+	}
+
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"day_type", "updated_at"}),
+	}).Create(&assignment)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to set day type: %w", result.Error)
+	}
+
+	return &assignment, nil
+}
+
+// ClearDayType removes the day type of a date
+func (r *Repository) ClearDayType(userID uint, date time.Time) error {
+	result := r.db.Where("user_id = ? AND date = ?", userID, date.Format("2006-01-02")).Delete(&DayTypeAssignment{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to clear day type: %w", result.Error)
+	}
+	return nil
+}
+
+// DietCalculator returns the calculator for a diet model, built-in or defined by a coach
+// An unknown model or an invalid definition is returned as a *ProtocolError
+func (r *Repository) DietCalculator(modelName string) (DietCalculator, error) {
+	if calculator, err := GetDietCalculator(modelName); err == nil {
+		return calculator, nil
+	}
+
+	var custom CustomDiet
+	result := r.db.Where("model_name = ?", modelName).Limit(1).Find(&custom)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get custom diet: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, &ProtocolError{Err: fmt.Errorf("unsupported diet model: %s", modelName)}
+	}
+
+	// Stored definitions are validated again so that a rule added since they were saved applies
+	calculator, err := NewDefinedDietCalculator(custom.Definition)
+	if err != nil {
+		return nil, &ProtocolError{Err: fmt.Errorf("diet model %s has an invalid definition: %w", modelName, err)}
+	}
+	return calculator, nil
+}
+
+// CreateCustomDiet stores a coach's diet definition
+func (r *Repository) CreateCustomDiet(diet *CustomDiet) error {
+	if err := r.db.Create(diet).Error; err != nil {
+		return fmt.Errorf("failed to create custom diet: %w", err)
+	}
+	return nil
+}
+
+// ListCustomDiets retrieves every coach-defined diet, oldest first
+func (r *Repository) ListCustomDiets() ([]CustomDiet, error) {
+	var diets []CustomDiet
+	if err := r.db.Order("id").Find(&diets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list custom diets: %w", err)
+	}
+	return diets, nil
+}
+
+// GetCustomDiet retrieves a coach-defined diet by model name
+func (r *Repository) GetCustomDiet(modelName string) (*CustomDiet, error) {
+	var diet CustomDiet
+	result := r.db.Where("model_name = ?", modelName).First(&diet)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("custom diet not found")
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get custom diet: %w", result.Error)
+	}
+
+	return &diet, nil
+}
+
+// DeleteCustomDiet removes a coach-defined diet
+func (r *Repository) DeleteCustomDiet(id uint) error {
+	if err := r.db.Delete(&CustomDiet{}, id).Error; err != nil {
+		return fmt.Errorf("failed to delete custom diet: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,91 @@
+package goal
+
+import (
+	"fmt"
+	"math"
+
+	"ultra-bis/internal/user"
+)
+
+// ReverseDietCalculator raises calories step by step from a deficit back to maintenance
+type ReverseDietCalculator struct{}
+
+// NewReverseDietCalculator creates a new calculator instance
+func NewReverseDietCalculator() *ReverseDietCalculator {
+	return &ReverseDietCalculator{}
+}
+
+// reverseDietProtocols lists the reverse diet protocols and their calorie increase per phase
+var reverseDietProtocols = []ProtocolInfo{
+	{Number: 1, Name: "Conservative reverse diet", Description: "Add 100 kcal every two weeks from a 20% deficit until maintenance"},
+	{Number: 2, Name: "Moderate reverse diet", Description: "Add 200 kcal every two weeks from a 20% deficit until maintenance"},
+}
+
+var reverseDietSteps = map[int]float64{1: 100, 2: 200}
+
+// GetModelName returns the diet model name
+func (c *ReverseDietCalculator) GetModelName() string {
+	return "reverseDiet"
+}
+
+// Describe returns the reverse diet model and its protocols
+func (c *ReverseDietCalculator) Describe() AvailableDiet {
+	return AvailableDiet{
+		ModelName:   c.GetModelName(),
+		DisplayName: "Reverse diet",
+		Description: "Gradual return to maintenance after a cut, one two-week phase per calorie step",
+		Protocols:   reverseDietProtocols,
+	}
+}
+
+// ValidateUser validates that the user has age, height, weight and gender
+func (c *ReverseDietCalculator) ValidateUser(user *user.User) error {
+	return validateBodyStats(user, false)
+}
+
+// ValidateProtocol validates that the protocol number is valid for the reverse diet
+func (c *ReverseDietCalculator) ValidateProtocol(protocol int) error {
+	return validateProtocolNumber(protocol, reverseDietProtocols, "Reverse diet")
+}
+
+// GetProtocolName returns the human-readable protocol name
+func (c *ReverseDietCalculator) GetProtocolName(protocol int) string {
+	return protocolName(protocol, reverseDietProtocols)
+}
+
+// Calculate returns one phase per step, starting at a 20% deficit and ending at maintenance
+func (c *ReverseDietCalculator) Calculate(user *user.User, protocol int) (*DietCalculationResult, error) {
+	bmr, tdee := mifflinTDEE(user)
+	step := reverseDietSteps[protocol]
+	start := tdee * 0.8
+
+	var phases []PhaseResult
+	for calories := start; ; calories += step {
+		calories = math.Min(calories, tdee)
+		phase := PhaseResult{
+			Phase:       len(phases) + 1,
+			Calories:    calories,
+			Description: fmt.Sprintf("Step %d: %.0f kcal", len(phases)+1, calories),
+		}
+		splitMacros(&phase, user.Weight*1.8, calories*0.25/9)
+		phases = append(phases, phase)
+
+		if calories >= tdee {
+			phases[len(phases)-1].Description = "Maintenance reached"
+			break
+		}
+	}
+
+	return &DietCalculationResult{
+		ModelName:      c.GetModelName(),
+		Protocol:       protocol,
+		ProtocolName:   c.GetProtocolName(protocol),
+		BMR:            bmr,
+		MaintenanceMMR: tdee,
+		LeanMass:       leanMass(user),
+		Phases:         phases,
+		Metadata: map[string]interface{}{
+			"step_calories": step,
+		},
+	}, nil
+}
@@ -24,7 +24,18 @@ func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
 	mux.HandleFunc("/goals/all", auth.JWTMiddleware(handler.GetAllGoals))
 	mux.HandleFunc("/goals/recommended", auth.JWTMiddleware(handler.GetRecommendedGoals))
 	mux.HandleFunc("/goals/calculate", auth.JWTMiddleware(handler.CalculateDietGoals))
-	mux.HandleFunc("/goals/diets", auth.JWTMiddleware(handler.GetAvailableDiets))
+	mux.HandleFunc("/goals/diets", auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handler.GetAvailableDiets(w, r)
+		case http.MethodPost:
+			handler.CreateCustomDiet(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/goals/diets/", auth.JWTMiddleware(handler.DeleteCustomDiet))
+	mux.HandleFunc("/goals/timeline", auth.JWTMiddleware(handler.GetTimeline))
 
 	mux.HandleFunc("/goals/", auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		if strings.TrimPrefix(r.URL.Path, "/goals/") == "" {
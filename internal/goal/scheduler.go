@@ -0,0 +1,285 @@
+package goal
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"ultra-bis/internal/metrics"
+	"ultra-bis/internal/user"
+	"ultra-bis/internal/webhook"
+)
+
+// PhaseDuration is how long a phase of a calculated protocol lasts unless its definition says otherwise
+const PhaseDuration = 14 * 24 * time.Hour
+
+// PhaseScheduler ends expired protocol goals and starts the next phase
+type PhaseScheduler struct {
+	repo        *Repository
+	userRepo    *user.Repository
+	metricsRepo *metrics.Repository
+	webhooks    *webhook.Publisher
+	dayObserver DayObserver
+}
+
+// ProtocolError is returned when a protocol cannot be calculated for a user and retrying will not
+// help, e.g. because their profile is incomplete or the diet's definition is no longer valid
+type ProtocolError struct {
+	Err error
+}
+
+func (e *ProtocolError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// NewPhaseScheduler creates a new phase scheduler
+func NewPhaseScheduler(repo *Repository, userRepo *user.Repository, metricsRepo *metrics.Repository) *PhaseScheduler {
+	return &PhaseScheduler{repo: repo, userRepo: userRepo, metricsRepo: metricsRepo}
+}
+
+// SetWebhookPublisher sets the publisher of goal.activated events for the phases started
+func (s *PhaseScheduler) SetWebhookPublisher(publisher *webhook.Publisher) {
+	s.webhooks = publisher
+}
+
+// SetDayObserver sets the observer notified of the days whose goal changed when a phase ends
+func (s *PhaseScheduler) SetDayObserver(observer DayObserver) {
+	s.dayObserver = observer
+}
+
+// AdvanceDue processes every active protocol goal that expired at or before now
+// A goal that fails is logged and retried on the next run without holding back the others
+// Returns how many goals were advanced to their next phase and how many were ended
+func (s *PhaseScheduler) AdvanceDue(now time.Time) (advanced, ended int, err error) {
+	goals, err := s.repo.ListExpiredPhases(now)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := range goals {
+		current := &goals[i]
+
+		next, err := s.nextPhase(current)
+		var protocolErr *ProtocolError
+		if errors.As(err, &protocolErr) {
+			// The protocol cannot continue (e.g. incomplete profile); end it rather than retrying forever
+			log.Printf("Ending goal %d without a next phase: %v", current.ID, err)
+		} else if err != nil {
+			// Possibly temporary (e.g. a database error): keep the goal active and retry on the next run
+			log.Printf("Failed to calculate the next phase of goal %d: %v", current.ID, err)
+			continue
+		}
+
+		if err := s.repo.AdvancePhase(current, next); err != nil {
+			// Already advanced by another instance, or failing for this goal only: move on to the next one
+			if !errors.Is(err, ErrGoalNotActive) {
+				log.Printf("Failed to advance goal %d: %v", current.ID, err)
+			}
+			continue
+		}
+		// The days since the phase expired now fall under the next phase or no goal at all
+		notifyGoalDays(s.dayObserver, current.UserID, *current.EndDate, nil)
+		if next != nil {
+			s.webhooks.Notify(next.UserID, webhook.EventGoalActivated, next)
+			advanced++
+		} else {
+			ended++
+		}
+	}
+
+	return advanced, ended, nil
+}
+
+// Run calls AdvanceDue every interval; it blocks and is meant to run in its own goroutine
+func (s *PhaseScheduler) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if advanced, ended, err := s.AdvanceDue(time.Now()); err != nil {
+			log.Printf("Diet phase progression failed: %v", err)
+		} else if advanced > 0 || ended > 0 {
+			log.Printf("Diet phases: %d advanced, %d ended", advanced, ended)
+		}
+		<-ticker.C
+	}
+}
+
+// nextPhase builds the goal for the phase following current, or returns nil when current was the last phase
+func (s *PhaseScheduler) nextPhase(current *NutritionGoal) (*NutritionGoal, error) {
+	phases, err := CalculatePhases(s.repo, s.userRepo, s.metricsRepo, current.UserID, *current.DietModel, *current.Protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	nextNumber := currentPhase(current) + 1
+	for _, phase := range phases {
+		if phase.Phase != nextNumber {
+			continue
+		}
+
+		start := *current.ExpirationDate
+		expiration := start.Add(phase.Duration())
+		protocol := *current.Protocol
+		dietModel := *current.DietModel
+
+		return &NutritionGoal{
+			UserID:         current.UserID,
+			Calories:       math.Round(phase.Calories),
+			Protein:        math.Round(phase.Protein),
+			Carbs:          math.Round(phase.Carbs),
+			Fat:            math.Round(phase.Fat),
+			Fiber:          current.Fiber,
+			Water:          current.Water,
+			ExercisePolicy: current.ExercisePolicy,
+			StartDate:      start,
+			IsActive:       true,
+			MealTargets:    current.MealTargets,
+			WeekdayTargets: current.WeekdayTargets,
+			DayTypeTargets: current.DayTypeTargets,
+			DietModel:      &dietModel,
+			Protocol:       &protocol,
+			Phase:          &nextNumber,
+			ExpirationDate: &expiration,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// CalculatePhases recalculates a protocol's phases from the user's profile and latest weigh-in
+// Errors that retrying cannot fix are returned as a *ProtocolError
+func CalculatePhases(repo *Repository, userRepo *user.Repository, metricsRepo *metrics.Repository, userID uint, dietModel string, protocol int) ([]PhaseResult, error) {
+	calculator, err := repo.DietCalculator(dietModel)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Recalculate from the latest body metrics rather than the weight entered at sign-up
+	if metricsRepo != nil {
+		if latest, err := metricsRepo.GetLatest(userID); err == nil && latest.Weight > 0 {
+			profile.Weight = latest.Weight
+		}
+	}
+	ApplyLatestBodyFat(profile, metricsRepo)
+
+	if err := calculator.ValidateUser(profile); err != nil {
+		return nil, &ProtocolError{Err: fmt.Errorf("user validation failed: %w", err)}
+	}
+	if err := calculator.ValidateProtocol(protocol); err != nil {
+		return nil, &ProtocolError{Err: fmt.Errorf("protocol validation failed: %w", err)}
+	}
+
+	result, err := calculator.Calculate(profile, protocol)
+	if err != nil {
+		return nil, &ProtocolError{Err: err}
+	}
+
+	return result.Phases, nil
+}
+
+// ApplyLatestBodyFat replaces the profile's body fat with the latest measured or estimated one, if any
+func ApplyLatestBodyFat(profile *user.User, metricsRepo *metrics.Repository) {
+	if metricsRepo == nil {
+		return
+	}
+	if latest, err := metricsRepo.GetLatestBodyFat(profile.ID); err == nil && latest != nil {
+		profile.BodyFat = *latest.BodyFat
+	}
+}
+
+// BuildTimeline lists protocol goals chronologically, followed by the phases still planned after the active one
+// planned holds the recalculated phases of the active goal's protocol and may be nil
+func BuildTimeline(goals []NutritionGoal, planned []PhaseResult, now time.Time) TimelineResponse {
+	timeline := TimelineResponse{Phases: []TimelinePhase{}}
+
+	var active *NutritionGoal
+	for i := range goals {
+		g := &goals[i]
+		if g.DietModel == nil || g.Protocol == nil {
+			continue
+		}
+
+		status := PhaseStatusCompleted
+		if g.IsActive && (g.EndDate == nil || !g.EndDate.Before(now)) {
+			status = PhaseStatusCurrent
+			active = g
+		}
+
+		end := g.EndDate
+		if end == nil {
+			end = g.ExpirationDate
+		}
+
+		id := g.ID
+		timeline.Phases = append(timeline.Phases, TimelinePhase{
+			GoalID:    &id,
+			DietModel: *g.DietModel,
+			Protocol:  *g.Protocol,
+			Phase:     currentPhase(g),
+			Status:    status,
+			StartDate: g.StartDate.Format("2006-01-02"),
+			EndDate:   formatOptionalDate(end),
+			Calories:  g.Calories,
+			Protein:   g.Protein,
+			Carbs:     g.Carbs,
+			Fat:       g.Fat,
+		})
+	}
+
+	if active == nil || active.ExpirationDate == nil {
+		return timeline
+	}
+
+	start := *active.ExpirationDate
+	for _, phase := range planned {
+		if phase.Phase <= currentPhase(active) {
+			continue
+		}
+
+		end := start.Add(phase.Duration())
+		timeline.Phases = append(timeline.Phases, TimelinePhase{
+			DietModel:   *active.DietModel,
+			Protocol:    *active.Protocol,
+			Phase:       phase.Phase,
+			Status:      PhaseStatusPlanned,
+			StartDate:   start.Format("2006-01-02"),
+			EndDate:     formatOptionalDate(&end),
+			Calories:    math.Round(phase.Calories),
+			Protein:     math.Round(phase.Protein),
+			Carbs:       math.Round(phase.Carbs),
+			Fat:         math.Round(phase.Fat),
+			Description: phase.Description,
+		})
+		start = end
+	}
+
+	return timeline
+}
+
+// currentPhase returns a goal's phase number; goals created without one start at phase 1
+func currentPhase(g *NutritionGoal) int {
+	if g.Phase == nil {
+		return 1
+	}
+	return *g.Phase
+}
+
+// formatOptionalDate formats a date as YYYY-MM-DD, or "" when nil
+func formatOptionalDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
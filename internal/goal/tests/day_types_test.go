@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ultra-bis/internal/goal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func cyclingGoal() goal.NutritionGoal {
+	return goal.NutritionGoal{
+		Calories: 2200, Protein: 160, Carbs: 220, Fat: 70, Fiber: 30,
+		WeekdayTargets: goal.DayTargets{
+			"saturday": {Calories: 2600, Carbs: 320},
+		},
+		DayTypeTargets: goal.DayTargets{
+			goal.DayTypeTraining: {Calories: 2500, Carbs: 300},
+			goal.DayTypeRest:     {Calories: 1900, Carbs: 120},
+		},
+	}
+}
+
+func TestNutritionGoal_ResolveForDate(t *testing.T) {
+	base := cyclingGoal()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	saturday := time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC)
+
+	// No override for an untagged Monday
+	resolved := base.ResolveForDate(monday, "")
+	assert.Equal(t, 2200.0, resolved.Calories)
+	assert.Empty(t, resolved.AppliedOverride)
+
+	// Day type override keeps base values it does not set
+	resolved = base.ResolveForDate(monday, goal.DayTypeTraining)
+	assert.Equal(t, 2500.0, resolved.Calories)
+	assert.Equal(t, 300.0, resolved.Carbs)
+	assert.Equal(t, 160.0, resolved.Protein)
+	assert.Equal(t, goal.DayTypeTraining, resolved.AppliedOverride)
+	assert.Equal(t, goal.DayTypeTraining, resolved.DayType)
+
+	// Weekday override applies to untagged days
+	resolved = base.ResolveForDate(saturday, "")
+	assert.Equal(t, 2600.0, resolved.Calories)
+	assert.Equal(t, "saturday", resolved.AppliedOverride)
+
+	// Day type takes precedence over the weekday
+	resolved = base.ResolveForDate(saturday, goal.DayTypeRest)
+	assert.Equal(t, 1900.0, resolved.Calories)
+
+	// A day type without an override falls back to the weekday
+	resolved = base.ResolveForDate(saturday, goal.DayTypeRefeed)
+	assert.Equal(t, 2600.0, resolved.Calories)
+	assert.Equal(t, goal.DayTypeRefeed, resolved.DayType)
+
+	// The original goal is not modified
+	assert.Equal(t, 2200.0, base.Calories)
+}
+
+func TestDayTargets_Validate(t *testing.T) {
+	assert.NoError(t, cyclingGoal().WeekdayTargets.ValidateWeekdays())
+	assert.NoError(t, cyclingGoal().DayTypeTargets.ValidateDayTypes())
+	assert.NoError(t, goal.DayTargets(nil).ValidateWeekdays())
+
+	assert.Error(t, goal.DayTargets{"Saturday": {Calories: 2000}}.ValidateWeekdays())
+	assert.Error(t, goal.DayTargets{"training": {Calories: 2000}}.ValidateWeekdays())
+	assert.Error(t, goal.DayTargets{"cheat": {Calories: 3000}}.ValidateDayTypes())
+	assert.Error(t, goal.DayTargets{"rest": {Carbs: -10}}.ValidateDayTypes())
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"testing"
+
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/user"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func calculatorUser() *user.User {
+	return &user.User{
+		Age:           30,
+		Height:        180,
+		Weight:        80,
+		BodyFat:       20,
+		Gender:        "male",
+		ActivityLevel: user.ModeratelyActive,
+	}
+}
+
+// Mifflin-St Jeor for calculatorUser: 10*80 + 6.25*180 - 5*30 + 5 = 1780 kcal, × 1.55 moderate
+const calculatorTDEE = 1780 * 1.55
+
+func TestAvailableDiets_ListsRegisteredModels(t *testing.T) {
+	var names []string
+	for _, diet := range goal.AvailableDiets("") {
+		names = append(names, diet.ModelName)
+		assert.NotEmpty(t, diet.Protocols, diet.ModelName)
+
+		calculator, err := goal.GetDietCalculator(diet.ModelName)
+		require.NoError(t, err)
+		assert.Equal(t, diet.ModelName, calculator.GetModelName())
+	}
+
+	assert.Equal(t, []string{"zeroToHero", "mifflinStJeor", "katchMcArdle", "reverseDiet", "dietBreak", "keto"}, names)
+
+	_, err := goal.GetDietCalculator("invalidModel")
+	assert.Error(t, err)
+}
+
+func TestDietCalculators_Validation(t *testing.T) {
+	for _, model := range []string{"mifflinStJeor", "katchMcArdle", "reverseDiet", "dietBreak", "keto"} {
+		calculator, err := goal.GetDietCalculator(model)
+		require.NoError(t, err)
+
+		assert.NoError(t, calculator.ValidateUser(calculatorUser()), model)
+		assert.NoError(t, calculator.ValidateProtocol(1), model)
+		assert.Error(t, calculator.ValidateProtocol(0), model)
+		assert.Error(t, calculator.ValidateProtocol(9), model)
+
+		noGender := calculatorUser()
+		noGender.Gender = ""
+		assert.Error(t, calculator.ValidateUser(noGender), model)
+	}
+
+	noBodyFat := calculatorUser()
+	noBodyFat.BodyFat = 0
+	katch, _ := goal.GetDietCalculator("katchMcArdle")
+	mifflin, _ := goal.GetDietCalculator("mifflinStJeor")
+	assert.Error(t, katch.ValidateUser(noBodyFat))
+	assert.NoError(t, mifflin.ValidateUser(noBodyFat))
+}
+
+func TestMifflinStJeorCalculator_Calculate(t *testing.T) {
+	result, err := goal.NewMifflinStJeorCalculator().Calculate(calculatorUser(), 2)
+	require.NoError(t, err)
+
+	assert.InDelta(t, 1780, result.BMR, 0.01)
+	assert.InDelta(t, calculatorTDEE, result.MaintenanceMMR, 0.01)
+	require.Len(t, result.Phases, 1)
+
+	phase := result.Phases[0]
+	assert.InDelta(t, calculatorTDEE*0.8, phase.Calories, 0.01)
+	assert.InDelta(t, 144, phase.Protein, 0.01)
+	assert.InDelta(t, phase.Calories*0.25/9, phase.Fat, 0.01)
+	assert.InDelta(t, phase.Calories, phase.Protein*4+phase.Carbs*4+phase.Fat*9, 0.01)
+}
+
+func TestKatchMcArdleCalculator_Calculate(t *testing.T) {
+	result, err := goal.NewKatchMcArdleCalculator().Calculate(calculatorUser(), 1)
+	require.NoError(t, err)
+
+	// Lean mass 64 kg: 370 + 21.6*64 = 1752.4
+	assert.InDelta(t, 64, result.LeanMass, 0.01)
+	assert.InDelta(t, 1752.4, result.BMR, 0.01)
+	require.Len(t, result.Phases, 1)
+	assert.InDelta(t, 1752.4*1.55, result.Phases[0].Calories, 0.01)
+	assert.InDelta(t, 64*2.2, result.Phases[0].Protein, 0.01)
+}
+
+func TestReverseDietCalculator_Calculate(t *testing.T) {
+	result, err := goal.NewReverseDietCalculator().Calculate(calculatorUser(), 2)
+	require.NoError(t, err)
+
+	// From 2207.2 kcal to 2759 kcal in 200 kcal steps
+	require.Len(t, result.Phases, 4)
+	assert.InDelta(t, calculatorTDEE*0.8, result.Phases[0].Calories, 0.01)
+	assert.InDelta(t, calculatorTDEE*0.8+200, result.Phases[1].Calories, 0.01)
+	assert.InDelta(t, calculatorTDEE, result.Phases[3].Calories, 0.01)
+	for i, phase := range result.Phases {
+		assert.Equal(t, i+1, phase.Phase)
+	}
+}
+
+func TestDietBreakCalculator_Calculate(t *testing.T) {
+	result, err := goal.NewDietBreakCalculator().Calculate(calculatorUser(), 1)
+	require.NoError(t, err)
+
+	require.Len(t, result.Phases, 8)
+	for i, phase := range result.Phases {
+		assert.Equal(t, i+1, phase.Phase)
+		if phase.Phase%4 == 0 {
+			assert.InDelta(t, calculatorTDEE, phase.Calories, 0.01, "phase %d should be a break", phase.Phase)
+		} else {
+			assert.InDelta(t, calculatorTDEE*0.8, phase.Calories, 0.01, "phase %d should be a deficit", phase.Phase)
+		}
+	}
+
+	result, err = goal.NewDietBreakCalculator().Calculate(calculatorUser(), 2)
+	require.NoError(t, err)
+	assert.Len(t, result.Phases, 9)
+}
+
+func TestKetoCalculator_Calculate(t *testing.T) {
+	result, err := goal.NewKetoCalculator().Calculate(calculatorUser(), 1)
+	require.NoError(t, err)
+
+	require.Len(t, result.Phases, 1)
+	phase := result.Phases[0]
+	assert.Equal(t, 25.0, phase.Carbs)
+	assert.InDelta(t, 128, phase.Protein, 0.01)
+	assert.InDelta(t, calculatorTDEE, phase.Protein*4+phase.Carbs*4+phase.Fat*9, 0.01)
+}
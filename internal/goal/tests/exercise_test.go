@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"testing"
+
+	"ultra-bis/internal/goal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateExercisePolicy(t *testing.T) {
+	for _, policy := range append([]string{""}, goal.ExercisePolicies...) {
+		assert.NoError(t, goal.ValidateExercisePolicy(policy), policy)
+	}
+	assert.EqualError(t, goal.ValidateExercisePolicy("double"), "exercise_policy must be 'ignore', 'half' or 'full'")
+}
+
+func TestNutritionGoal_AdjustForExercise(t *testing.T) {
+	base := goal.NutritionGoal{Calories: 2000, Protein: 150, Carbs: 200, Fat: 60, Fiber: 30, Water: 2500}
+
+	// Ignored by default and with the ignore policy
+	adjusted := base.AdjustForExercise(600)
+	assert.Equal(t, 2000.0, adjusted.Calories)
+	assert.Zero(t, adjusted.ExerciseCalories)
+
+	// Half of the calories burned, with the macros in proportion
+	base.ExercisePolicy = goal.ExercisePolicyHalf
+	adjusted = base.AdjustForExercise(600)
+	assert.Equal(t, 2300.0, adjusted.Calories)
+	assert.Equal(t, 300.0, adjusted.ExerciseCalories)
+	assert.Equal(t, 172.5, adjusted.Protein)
+	assert.Equal(t, 230.0, adjusted.Carbs)
+	assert.Equal(t, 69.0, adjusted.Fat)
+	assert.Equal(t, 30.0, adjusted.Fiber)
+	assert.Equal(t, 2500.0, adjusted.Water)
+
+	// All of them
+	base.ExercisePolicy = goal.ExercisePolicyFull
+	assert.Equal(t, 2600.0, base.AdjustForExercise(600).Calories)
+
+	// The goal itself is left untouched, and a day without exercise keeps its targets
+	assert.Equal(t, 2000.0, base.Calories)
+	assert.Equal(t, 2000.0, base.AdjustForExercise(0).Calories)
+}
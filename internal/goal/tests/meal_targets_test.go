@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"testing"
+
+	"ultra-bis/internal/goal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMealTargets_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets goal.MealTargets
+		wantErr bool
+	}{
+		{
+			name: "percent and absolute targets",
+			targets: goal.MealTargets{
+				{MealType: "breakfast", Unit: goal.TargetUnitPercent, Calories: 30},
+				{MealType: "dinner", Unit: goal.TargetUnitAbsolute, Protein: 40},
+			},
+		},
+		{name: "no targets", targets: nil},
+		{
+			name:    "unknown meal type",
+			targets: goal.MealTargets{{MealType: "brunch", Unit: goal.TargetUnitPercent, Calories: 30}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown unit",
+			targets: goal.MealTargets{{MealType: "lunch", Unit: "ounces", Protein: 30}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate meal type",
+			targets: goal.MealTargets{
+				{MealType: "lunch", Unit: goal.TargetUnitPercent, Calories: 30},
+				{MealType: "lunch", Unit: goal.TargetUnitAbsolute, Protein: 30},
+			},
+			wantErr: true,
+		},
+		{
+			name: "percentages above 100",
+			targets: goal.MealTargets{
+				{MealType: "lunch", Unit: goal.TargetUnitPercent, Calories: 60},
+				{MealType: "dinner", Unit: goal.TargetUnitPercent, Calories: 50},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "negative value",
+			targets: goal.MealTargets{{MealType: "snack", Unit: goal.TargetUnitAbsolute, Fat: -5}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.targets.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMealTarget_Resolve(t *testing.T) {
+	dailyGoal := &goal.NutritionGoal{Calories: 2000, Protein: 150, Carbs: 200, Fat: 70, Fiber: 30}
+
+	percent := goal.MealTarget{MealType: "breakfast", Unit: goal.TargetUnitPercent, Calories: 30, Protein: 20}
+	assert.Equal(t, goal.MealMacros{Calories: 600, Protein: 30}, percent.Resolve(dailyGoal))
+
+	absolute := goal.MealTarget{MealType: "dinner", Unit: goal.TargetUnitAbsolute, Protein: 40}
+	assert.Equal(t, goal.MealMacros{Protein: 40}, absolute.Resolve(dailyGoal))
+}
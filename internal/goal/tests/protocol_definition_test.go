@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/user"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const customDietJSON = `{
+  "model_name": "coachCut",
+  "display_name": {"en": "Coach cut", "fr": "Sèche du coach"},
+  "bmr_formula": "mifflinStJeor",
+  "protein": {"per_kg_body_weight": 2},
+  "fat": {"percent_calories": 25},
+  "protocols": [
+    {
+      "number": 1,
+      "name": {"en": "Short cut"},
+      "phases": [
+        {"calorie_factor": 0.85, "duration_days": 21, "description": {"en": "Cut"}},
+        {"calorie_offset": 0, "duration_days": 7, "description": {"en": "Break"}}
+      ]
+    }
+  ]
+}`
+
+func TestZeroToHeroDefinition_MatchesFormulas(t *testing.T) {
+	calculator, err := goal.GetDietCalculator("zeroToHero")
+	require.NoError(t, err)
+
+	u := &user.User{Age: 30, Height: 180, Weight: 80, BodyFat: 20}
+	require.NoError(t, calculator.ValidateUser(u))
+
+	result, err := calculator.Calculate(u, 4)
+	require.NoError(t, err)
+
+	bmr1 := 13.707*80 + 492.3*1.8 - 6.673*30 + 77.607
+	bmr2 := 21.6*64 + 370
+	maintenance := (bmr1 + bmr2) / 2 * 1.5
+	protein := (80*1.5 + 64*2) / 2
+	fat := 1.2 * 64
+
+	assert.InDelta(t, (bmr1+bmr2)/2, result.BMR, 0.01)
+	assert.InDelta(t, maintenance, result.MaintenanceMMR, 0.01)
+	assert.Equal(t, "Protocole 4 : Perte de gras progressive", result.ProtocolName)
+	require.Len(t, result.Phases, 3)
+	for i, offset := range []float64{-300, -500, -700} {
+		phase := result.Phases[i]
+		assert.Equal(t, i+1, phase.Phase)
+		assert.InDelta(t, maintenance+offset, phase.Calories, 0.01)
+		assert.InDelta(t, protein, phase.Protein, 0.01)
+		assert.InDelta(t, fat, phase.Fat, 0.01)
+		assert.InDelta(t, (phase.Calories-protein*4-fat*9)/4, phase.Carbs, 0.01)
+		assert.Equal(t, goal.PhaseDuration, phase.Duration())
+	}
+
+	// Body fat is required, gender is not
+	assert.Error(t, calculator.ValidateUser(&user.User{Age: 30, Height: 180, Weight: 80}))
+	assert.Error(t, calculator.ValidateProtocol(5))
+}
+
+func TestDefinedDietCalculator_Localize(t *testing.T) {
+	calculator, err := goal.GetDietCalculator("zeroToHero")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Protocole 2 : Recomposition corporelle", calculator.GetProtocolName(2))
+	assert.Equal(t, "Protocol 2: Body recomposition", goal.Localize(calculator, "en").GetProtocolName(2))
+	// Languages without a translation fall back to the definition's default
+	assert.Equal(t, "Protocole 2 : Recomposition corporelle", goal.Localize(calculator, "de").GetProtocolName(2))
+
+	text := goal.LocalizedText{"de": "Hallo", "es": "Hola"}
+	assert.Equal(t, "Hola", text.Get("es", "fr"))
+	assert.Equal(t, "Hallo", text.Get("it", "fr"))
+}
+
+func TestParseDietDefinition_Custom(t *testing.T) {
+	calculator, err := goal.ParseDietDefinition([]byte(customDietJSON))
+	require.NoError(t, err)
+	assert.Equal(t, "coachCut", calculator.GetModelName())
+	assert.Equal(t, "Sèche du coach", goal.Localize(calculator, "fr").Describe().DisplayName)
+
+	u := &user.User{Age: 30, Height: 180, Weight: 80, Gender: "male", ActivityLevel: user.Sedentary}
+	require.NoError(t, calculator.ValidateUser(u))
+	noGender := *u
+	noGender.Gender = ""
+	assert.Error(t, calculator.ValidateUser(&noGender))
+
+	result, err := calculator.Calculate(u, 1)
+	require.NoError(t, err)
+	maintenance := 1780 * 1.2
+	require.Len(t, result.Phases, 2)
+	assert.InDelta(t, maintenance*0.85, result.Phases[0].Calories, 0.01)
+	assert.InDelta(t, 160, result.Phases[0].Protein, 0.01)
+	assert.InDelta(t, maintenance*0.85*0.25/9, result.Phases[0].Fat, 0.01)
+	assert.InDelta(t, maintenance, result.Phases[1].Calories, 0.01)
+	assert.Equal(t, 21*24*time.Hour, result.Phases[0].Duration())
+
+	assert.Equal(t, 7*24*time.Hour, goal.PhaseLength(calculator, 1, 2))
+	assert.Equal(t, goal.PhaseDuration, goal.PhaseLength(goal.NewKetoCalculator(), 1, 1))
+}
+
+func TestParseDietDefinition_Invalid(t *testing.T) {
+	valid := func() goal.DietDefinition {
+		calculator, err := goal.ParseDietDefinition([]byte(customDietJSON))
+		require.NoError(t, err)
+		return calculator.Definition()
+	}
+
+	tests := []struct {
+		name   string
+		modify func(d *goal.DietDefinition)
+	}{
+		{"invalid model name", func(d *goal.DietDefinition) { d.ModelName = "coach cut!" }},
+		{"missing display name", func(d *goal.DietDefinition) { d.DisplayName = nil }},
+		{"unknown BMR formula", func(d *goal.DietDefinition) { d.BMRFormula = "harrisBenedict" }},
+		{"maintenance multiplier out of range", func(d *goal.DietDefinition) { d.MaintenanceMultiplier = 4 }},
+		{"missing protein formula", func(d *goal.DietDefinition) { d.Protein = goal.MacroFormula{} }},
+		{"negative fat formula", func(d *goal.DietDefinition) { d.Fat.PercentCalories = -5 }},
+		{"no protocols", func(d *goal.DietDefinition) { d.Protocols = nil }},
+		{"protocol numbering gap", func(d *goal.DietDefinition) { d.Protocols[0].Number = 2 }},
+		{"protocol without phases", func(d *goal.DietDefinition) { d.Protocols[0].Phases = nil }},
+		{"calorie factor out of range", func(d *goal.DietDefinition) { d.Protocols[0].Phases[0].CalorieFactor = 0.2 }},
+		{"calorie offset out of range", func(d *goal.DietDefinition) { d.Protocols[0].Phases[1].CalorieOffset = -2000 }},
+		{"duration out of range", func(d *goal.DietDefinition) { d.Protocols[0].Phases[0].DurationDays = 365 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			definition := valid()
+			tt.modify(&definition)
+			_, err := goal.NewDefinedDietCalculator(definition)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := goal.ParseDietDefinition([]byte(`{"model_name": "x", "unknown_field": 1}`))
+	assert.Error(t, err)
+}
+
+func TestLocalize_CatalogTranslations(t *testing.T) {
+	// Formula-driven calculators are translated through the message catalogs
+	for _, model := range []string{"mifflinStJeor", "katchMcArdle", "reverseDiet", "dietBreak", "keto"} {
+		calculator, err := goal.GetDietCalculator(model)
+		require.NoError(t, err)
+
+		english := calculator.Describe()
+		french := goal.Localize(calculator, "fr").Describe()
+		assert.NotEqual(t, english.Description, french.Description, model)
+		for i := range english.Protocols {
+			assert.NotEqual(t, english.Protocols[i].Description, french.Protocols[i].Description, "%s protocol %d", model, i+1)
+		}
+	}
+
+	result, err := goal.Localize(goal.NewDietBreakCalculator(), "fr").Calculate(calculatorUser(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Pause toutes les 6 semaines", result.ProtocolName)
+	assert.Equal(t, "Phase de déficit", result.Phases[0].Description)
+	assert.Equal(t, "Pause à l'équilibre", result.Phases[3].Description)
+}
@@ -20,8 +20,8 @@ func setupGoalTest(t *testing.T) (*gorm.DB, *goal.Repository) {
 	t.Helper()
 	db := testutil.SetupTestDB(t)
 
-	// Migrate both User and NutritionGoal models
-	if err := db.AutoMigrate(&user.User{}, &goal.NutritionGoal{}); err != nil {
+	// Migrate User, NutritionGoal, DayTypeAssignment and CustomDiet models
+	if err := db.AutoMigrate(&user.User{}, &goal.NutritionGoal{}, &goal.DayTypeAssignment{}, &goal.CustomDiet{}); err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
 
@@ -1118,3 +1118,151 @@ func TestRepository_Update_ProtocolTracking(t *testing.T) {
 
 	require.NotNil(t, updated.ExpirationDate)
 }
+
+// TestRepository_AdvancePhase tests ending an expired protocol phase and starting the next one
+func TestRepository_AdvancePhase(t *testing.T) {
+	db, repo := setupGoalTest(t)
+
+	testUser := testutil.CreateTestUser(t, db)
+
+	dietModel := "zeroToHero"
+	protocol, phase, nextPhase := 4, 1, 2
+	expiration := time.Now().Add(-time.Hour)
+	current := &goal.NutritionGoal{
+		UserID:         testUser.ID,
+		Calories:       2400,
+		StartDate:      expiration.Add(-goal.PhaseDuration),
+		IsActive:       true,
+		DietModel:      &dietModel,
+		Protocol:       &protocol,
+		Phase:          &phase,
+		ExpirationDate: &expiration,
+	}
+	db.Create(current)
+
+	expired, err := repo.ListExpiredPhases(time.Now())
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+
+	nextExpiration := expiration.Add(goal.PhaseDuration)
+	next := &goal.NutritionGoal{
+		UserID:         testUser.ID,
+		Calories:       2200,
+		StartDate:      expiration,
+		IsActive:       true,
+		DietModel:      &dietModel,
+		Protocol:       &protocol,
+		Phase:          &nextPhase,
+		ExpirationDate: &nextExpiration,
+	}
+	require.NoError(t, repo.AdvancePhase(&expired[0], next))
+
+	var ended goal.NutritionGoal
+	db.First(&ended, current.ID)
+	assert.False(t, ended.IsActive)
+	require.NotNil(t, ended.EndDate)
+
+	active, err := repo.GetActive(testUser.ID)
+	require.NoError(t, err)
+	assert.Equal(t, next.ID, active.ID)
+	assert.Equal(t, 2, *active.Phase)
+
+	// Nothing is due anymore
+	expired, err = repo.ListExpiredPhases(time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+
+	// A scheduler that listed the goal before it was advanced does not start the phase twice
+	stale := *current
+	duplicate := *next
+	duplicate.ID = 0
+	assert.ErrorIs(t, repo.AdvancePhase(&stale, &duplicate), goal.ErrGoalNotActive)
+
+	var phases int64
+	db.Model(&goal.NutritionGoal{}).Where("user_id = ? AND phase = ?", testUser.ID, nextPhase).Count(&phases)
+	assert.Equal(t, int64(1), phases)
+}
+
+// TestPhaseScheduler_KeepsGoalOnRepositoryError tests that a failing lookup does not end the protocol
+func TestPhaseScheduler_KeepsGoalOnRepositoryError(t *testing.T) {
+	db, repo := setupGoalTest(t)
+
+	testUser := testutil.CreateTestUser(t, db)
+
+	dietModel := "zeroToHero"
+	protocol, phase := 4, 1
+	expiration := time.Now().Add(-time.Hour)
+	current := &goal.NutritionGoal{
+		UserID:         testUser.ID,
+		Calories:       2400,
+		StartDate:      expiration.Add(-goal.PhaseDuration),
+		IsActive:       true,
+		DietModel:      &dietModel,
+		Protocol:       &protocol,
+		Phase:          &phase,
+		ExpirationDate: &expiration,
+	}
+	require.NoError(t, db.Create(current).Error)
+
+	// The user lookup fails while the table is unavailable
+	require.NoError(t, db.Exec("ALTER TABLE users RENAME TO users_unavailable").Error)
+	scheduler := goal.NewPhaseScheduler(repo, user.NewRepository(db), nil)
+	advanced, ended, err := scheduler.AdvanceDue(time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, advanced)
+	assert.Zero(t, ended)
+
+	var stillActive goal.NutritionGoal
+	require.NoError(t, db.First(&stillActive, current.ID).Error)
+	assert.True(t, stillActive.IsActive)
+	assert.Nil(t, stillActive.EndDate)
+
+	// It is retried on the next run
+	require.NoError(t, db.Exec("ALTER TABLE users_unavailable RENAME TO users").Error)
+	expired, err := repo.ListExpiredPhases(time.Now())
+	require.NoError(t, err)
+	assert.Len(t, expired, 1)
+}
+
+// dayRecorder records the days a DayObserver is notified of
+type dayRecorder struct {
+	dates []time.Time
+}
+
+func (d *dayRecorder) DayChanged(userID uint, dates ...time.Time) error {
+	d.dates = append(d.dates, dates...)
+	return nil
+}
+
+func TestPhaseScheduler_NotifiesDaysSinceExpiration(t *testing.T) {
+	db, repo := setupGoalTest(t)
+
+	testUser := testutil.CreateTestUser(t, db)
+
+	dietModel := "discontinued"
+	protocol, phase := 1, 1
+	expiration := time.Now().AddDate(0, 0, -2)
+	current := &goal.NutritionGoal{
+		UserID:         testUser.ID,
+		Calories:       2400,
+		StartDate:      expiration.Add(-goal.PhaseDuration),
+		IsActive:       true,
+		DietModel:      &dietModel,
+		Protocol:       &protocol,
+		Phase:          &phase,
+		ExpirationDate: &expiration,
+	}
+	require.NoError(t, db.Create(current).Error)
+
+	// The diet no longer exists, so the goal ends and the days since it expired lose their goal
+	observer := &dayRecorder{}
+	scheduler := goal.NewPhaseScheduler(repo, user.NewRepository(db), nil)
+	scheduler.SetDayObserver(observer)
+	_, ended, err := scheduler.AdvanceDue(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, ended)
+
+	require.Len(t, observer.dates, 3)
+	assert.Equal(t, expiration.Format("2006-01-02"), observer.dates[0].Format("2006-01-02"))
+	assert.Equal(t, time.Now().Format("2006-01-02"), observer.dates[2].Format("2006-01-02"))
+}
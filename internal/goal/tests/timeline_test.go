@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ultra-bis/internal/goal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	dietModel := "zeroToHero"
+	protocol := 4
+	phase1, phase2 := 1, 2
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(goal.PhaseDuration)
+	expiration := end.Add(goal.PhaseDuration)
+
+	goals := []goal.NutritionGoal{
+		{ID: 1, Calories: 2400, StartDate: start, EndDate: &end, ExpirationDate: &end,
+			DietModel: &dietModel, Protocol: &protocol, Phase: &phase1},
+		{ID: 2, Calories: 2200, StartDate: end, ExpirationDate: &expiration, IsActive: true,
+			DietModel: &dietModel, Protocol: &protocol, Phase: &phase2},
+		{ID: 3, Calories: 2000, StartDate: start}, // Manual goal, not part of the timeline
+	}
+	planned := []goal.PhaseResult{
+		{Phase: 1, Calories: 2400.4},
+		{Phase: 2, Calories: 2200.4},
+		{Phase: 3, Calories: 1999.6, Description: "Aggressive deficit phase"},
+	}
+
+	timeline := goal.BuildTimeline(goals, planned, end.Add(24*time.Hour))
+
+	require.Len(t, timeline.Phases, 3)
+	assert.Equal(t, goal.PhaseStatusCompleted, timeline.Phases[0].Status)
+	assert.Equal(t, "2025-01-15", timeline.Phases[0].EndDate)
+
+	assert.Equal(t, goal.PhaseStatusCurrent, timeline.Phases[1].Status)
+	assert.Equal(t, uint(2), *timeline.Phases[1].GoalID)
+	assert.Equal(t, "2025-01-29", timeline.Phases[1].EndDate)
+
+	next := timeline.Phases[2]
+	assert.Equal(t, goal.PhaseStatusPlanned, next.Status)
+	assert.Nil(t, next.GoalID)
+	assert.Equal(t, 3, next.Phase)
+	assert.Equal(t, 2000.0, next.Calories)
+	assert.Equal(t, "2025-01-29", next.StartDate)
+	assert.Equal(t, "2025-02-12", next.EndDate)
+}
+
+func TestBuildTimeline_NoActiveProtocol(t *testing.T) {
+	timeline := goal.BuildTimeline(nil, nil, time.Now())
+	assert.NotNil(t, timeline.Phases)
+	assert.Empty(t, timeline.Phases)
+}
@@ -12,9 +12,17 @@ type contextKey string
 const (
 	// UserIDKey is the context key for storing authenticated user ID
 	UserIDKey contextKey = "user_id"
+
+	// UserRoleKey is the context key for storing the authenticated user's role
+	UserRoleKey contextKey = "user_role"
+
+	// ActorIDKey is the context key for the authenticated caller when acting on another user's data
+	ActorIDKey contextKey = "actor_id"
 )
 
 // GetUserID extracts the user ID from the request context
+// This is the owner of the data being accessed: when a coach reads a client's
+// data, it is the client's ID (see GetActorID for the authenticated caller)
 // Returns the user ID and true if found, 0 and false otherwise
 func GetUserID(r *http.Request) (uint, bool) {
 	userID, ok := r.Context().Value(UserIDKey).(uint)
@@ -25,3 +33,45 @@ func GetUserID(r *http.Request) (uint, bool) {
 func SetUserID(ctx context.Context, userID uint) context.Context {
 	return context.WithValue(ctx, UserIDKey, userID)
 }
+
+// GetUserRole extracts the user role from the request context
+// Returns the role and true if found, empty string and false otherwise
+func GetUserRole(r *http.Request) (string, bool) {
+	return UserRoleFromContext(r.Context())
+}
+
+// UserRoleFromContext extracts the user role from a context
+// Used by service layers that receive the request context rather than the request
+func UserRoleFromContext(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(UserRoleKey).(string)
+	return role, ok
+}
+
+// SetUserRole creates a new context with the user role set
+func SetUserRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, UserRoleKey, role)
+}
+
+// GetActorID extracts the authenticated caller from the request context
+// It equals GetUserID unless the caller is acting on another user's data
+func GetActorID(r *http.Request) (uint, bool) {
+	if actorID, ok := r.Context().Value(ActorIDKey).(uint); ok {
+		return actorID, true
+	}
+	return GetUserID(r)
+}
+
+// SetActorID creates a new context with the acting user ID set
+func SetActorID(ctx context.Context, actorID uint) context.Context {
+	return context.WithValue(ctx, ActorIDKey, actorID)
+}
+
+// IsDelegated reports whether the caller is acting on another user's data
+func IsDelegated(r *http.Request) bool {
+	actorID, ok := r.Context().Value(ActorIDKey).(uint)
+	if !ok {
+		return false
+	}
+	userID, _ := GetUserID(r)
+	return actorID != userID
+}
@@ -27,6 +27,29 @@ func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// RequireRole middleware ensures the authenticated user has one of the given roles
+// Returns 401 Unauthorized if no user is in context, 403 Forbidden if the role does not match
+func RequireRole(roles ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := GetUserID(r); !ok {
+				WriteError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+
+			role, _ := GetUserRole(r)
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			WriteError(w, http.StatusForbidden, "Forbidden")
+		}
+	}
+}
+
 // ExtractPathID middleware extracts an ID from the URL path and adds it to context
 // The idPosition parameter indicates which path segment contains the ID
 // Returns a middleware function that can be chained
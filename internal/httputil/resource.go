@@ -0,0 +1,27 @@
+package httputil
+
+import "strings"
+
+// Resource names for data areas that can be shared or scoped
+const (
+	ResourceDiary   = "diary"
+	ResourceGoals   = "goals"
+	ResourceMetrics = "metrics"
+)
+
+// ResourceForPath maps a request path to the data resource it belongs to
+// Returns an empty string for paths outside the scoped resources
+func ResourceForPath(path string) string {
+	segment := strings.SplitN(strings.Trim(path, "/"), "/", 2)[0]
+	switch segment {
+	case ResourceDiary, ResourceGoals, ResourceMetrics:
+		return segment
+	default:
+		return ""
+	}
+}
+
+// IsReadOnlyMethod reports whether the HTTP method only reads data
+func IsReadOnlyMethod(method string) bool {
+	return method == "GET" || method == "HEAD"
+}
@@ -3,11 +3,15 @@ package httputil
 import (
 	"encoding/json"
 	"net/http"
+
+	"ultra-bis/internal/i18n"
 )
 
 // ErrorResponse represents a standard error response
+// Code is the message ID for messages from the catalog, stable across locales
 type ErrorResponse struct {
 	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
 }
 
 // WriteJSON writes a JSON response with the given status code
@@ -18,8 +22,23 @@ func WriteJSON(w http.ResponseWriter, status int, data interface{}) {
 }
 
 // WriteError writes a JSON error response
+// Messages found in the catalog are translated to the request's locale
 func WriteError(w http.ResponseWriter, status int, message string) {
-	WriteJSON(w, status, ErrorResponse{Error: message})
+	code, text := i18n.Translate(i18n.FromWriter(w), message)
+	WriteJSON(w, status, ErrorResponse{Error: text, Code: code})
+}
+
+// WriteErrorf writes a JSON error response with a formatted message
+// The format is translated like WriteError, then given the arguments
+func WriteErrorf(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	WriteErrorFrom(w, status, i18n.Errorf(format, args...))
+}
+
+// WriteErrorFrom writes the message of err as a JSON error response, translated like WriteError
+// Errors built with i18n.Errorf are translated with their arguments
+func WriteErrorFrom(w http.ResponseWriter, status int, err error) {
+	code, text := i18n.TranslateError(i18n.FromWriter(w), err)
+	WriteJSON(w, status, ErrorResponse{Error: text, Code: code})
 }
 
 // SuccessResponse represents a standard success message response
@@ -27,7 +46,8 @@ type SuccessResponse struct {
 	Message string `json:"message"`
 }
 
-// WriteSuccess writes a JSON success message response
+// WriteSuccess writes a JSON success message response, translated like WriteError
 func WriteSuccess(w http.ResponseWriter, status int, message string) {
-	WriteJSON(w, status, SuccessResponse{Message: message})
+	_, text := i18n.Translate(i18n.FromWriter(w), message)
+	WriteJSON(w, status, SuccessResponse{Message: text})
 }
@@ -124,3 +124,124 @@ func TestExtractIDFromPath(t *testing.T) {
 		})
 	}
 }
+
+// TestRequireRole tests role-based access control middleware
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name         string
+		setUser      bool
+		role         string
+		allowed      []string
+		expectedCode int
+	}{
+		{
+			name:         "matching role",
+			setUser:      true,
+			role:         "admin",
+			allowed:      []string{"admin"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "one of several allowed roles",
+			setUser:      true,
+			role:         "coach",
+			allowed:      []string{"coach", "admin"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "role not allowed",
+			setUser:      true,
+			role:         "user",
+			allowed:      []string{"admin"},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "authenticated without role",
+			setUser:      true,
+			role:         "",
+			allowed:      []string{"admin"},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "unauthenticated",
+			setUser:      false,
+			allowed:      []string{"admin"},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := httputil.RequireRole(tt.allowed...)(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/test", nil)
+			if tt.setUser {
+				ctx := httputil.SetUserID(req.Context(), 1)
+				if tt.role != "" {
+					ctx = httputil.SetUserRole(ctx, tt.role)
+				}
+				req = req.WithContext(ctx)
+			}
+
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rec.Code)
+			}
+		})
+	}
+}
+
+// TestResourceForPath tests mapping request paths to shareable resources
+func TestResourceForPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/diary/entries", httputil.ResourceDiary},
+		{"/diary/summary/2025-01-06", httputil.ResourceDiary},
+		{"/goals", httputil.ResourceGoals},
+		{"/goals/12", httputil.ResourceGoals},
+		{"/metrics/trends", httputil.ResourceMetrics},
+		{"/recipes", ""},
+		{"/diaryx", ""},
+		{"/", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := httputil.ResourceForPath(tt.path); got != tt.expected {
+				t.Errorf("ResourceForPath(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestGetActorID tests the actor fallback used for delegated access
+func TestGetActorID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/diary/entries", nil)
+	req = req.WithContext(httputil.SetUserID(req.Context(), 5))
+
+	// Without delegation the actor is the user
+	if actorID, ok := httputil.GetActorID(req); !ok || actorID != 5 {
+		t.Errorf("Expected actor 5, got %d (ok=%v)", actorID, ok)
+	}
+	if httputil.IsDelegated(req) {
+		t.Error("Expected request not to be delegated")
+	}
+
+	// A coach acting for client 5
+	req = req.WithContext(httputil.SetActorID(req.Context(), 9))
+	if actorID, ok := httputil.GetActorID(req); !ok || actorID != 9 {
+		t.Errorf("Expected actor 9, got %d (ok=%v)", actorID, ok)
+	}
+	if userID, _ := httputil.GetUserID(req); userID != 5 {
+		t.Errorf("Expected data owner 5, got %d", userID)
+	}
+	if !httputil.IsDelegated(req) {
+		t.Error("Expected request to be delegated")
+	}
+}
@@ -0,0 +1,286 @@
+package hydration
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/httputil"
+)
+
+// Handler handles water log and preset requests
+type Handler struct {
+	repo *Repository
+}
+
+// NewHandler creates a new hydration handler
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// LogWater handles POST /water
+// Logs a drink given its amount, or quick-adds one of the user's presets with preset_id
+func (h *Handler) LogWater(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req LogWaterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.PresetID != nil {
+		preset, err := h.repo.GetPreset(*req.PresetID, userID)
+		if err != nil {
+			httputil.WriteError(w, http.StatusNotFound, "Preset not found")
+			return
+		}
+		req.ApplyPreset(preset)
+	}
+
+	if err := ValidateLogWaterRequest(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	loggedAt := time.Now()
+	if req.LoggedAt != nil {
+		loggedAt = *req.LoggedAt
+	}
+
+	log := &WaterLog{
+		UserID:   userID,
+		Date:     calendar.FromRequest(r).DateOf(loggedAt),
+		LoggedAt: loggedAt,
+		Amount:   req.Amount,
+		Name:     req.Name,
+		Caffeine: req.Caffeine,
+		Alcohol:  req.Alcohol,
+		PresetID: req.PresetID,
+	}
+	if err := h.repo.CreateLog(log); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusCreated, log)
+}
+
+// GetWater handles GET /water?date=YYYY-MM-DD
+// Returns the drinks logged on a day (today by default) and their totals
+func (h *Handler) GetWater(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	date := calendar.FromRequest(r).Today()
+	if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+		var err error
+		date, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
+			return
+		}
+	}
+
+	logs, err := h.repo.GetLogsByDate(userID, date)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := DayLog{Date: date.Format("2006-01-02"), Logs: logs}
+	for _, log := range logs {
+		response.Water += log.Amount
+		response.Caffeine += log.Caffeine
+		response.Alcohol += log.Alcohol
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, response)
+}
+
+// DeleteWater handles DELETE /water/{id}
+func (h *Handler) DeleteWater(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := extractID(r.URL.Path, "/water/")
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	if err := h.repo.DeleteLog(uint(id), userID); err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Water log not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPresets handles GET /water/presets
+func (h *Handler) ListPresets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	presets, err := h.repo.ListPresets(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, presets)
+}
+
+// CreatePreset handles POST /water/presets
+func (h *Handler) CreatePreset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req PresetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := ValidatePresetRequest(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	preset := &DrinkPreset{UserID: userID}
+	preset.apply(&req)
+	if err := h.repo.SavePreset(preset); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusCreated, preset)
+}
+
+// UpdatePreset handles PUT /water/presets/{id}
+func (h *Handler) UpdatePreset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := extractID(r.URL.Path, "/water/presets/")
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	var req PresetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := ValidatePresetRequest(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	preset, err := h.repo.GetPreset(uint(id), userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Preset not found")
+		return
+	}
+
+	preset.apply(&req)
+	if err := h.repo.SavePreset(preset); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, preset)
+}
+
+// DeletePreset handles DELETE /water/presets/{id}
+func (h *Handler) DeletePreset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := extractID(r.URL.Path, "/water/presets/")
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	if err := h.repo.DeletePreset(uint(id), userID); err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Preset not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apply sets the preset's values from a validated request
+func (p *DrinkPreset) apply(req *PresetRequest) {
+	p.Name = req.Name
+	p.Amount = req.Amount
+	p.Caffeine = req.Caffeine
+	p.Alcohol = req.Alcohol
+}
+
+// extractID extracts the ID from the URL path
+func extractID(path, prefix string) (int, error) {
+	idStr := strings.TrimPrefix(path, prefix)
+	return strconv.Atoi(idStr)
+}
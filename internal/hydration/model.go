@@ -0,0 +1,68 @@
+package hydration
+
+import (
+	"time"
+)
+
+// MaxAmount is the largest drink that can be logged at once, in ml
+const MaxAmount = 5000.0
+
+// WaterLog records a drink: an amount of water at a point in time
+type WaterLog struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uint      `json:"user_id" gorm:"not null;index:idx_water_user_date"`
+	Date      time.Time `json:"date" gorm:"type:date;not null;index:idx_water_user_date"` // Day of LoggedAt in the user's time zone
+	LoggedAt  time.Time `json:"logged_at" gorm:"not null"`
+	Amount    float64   `json:"amount" gorm:"type:decimal(10,2);not null"` // ml
+	Name      string    `json:"name,omitempty" gorm:"type:varchar(100)"`
+	Caffeine  float64   `json:"caffeine" gorm:"type:decimal(10,2)"` // mg
+	Alcohol   float64   `json:"alcohol" gorm:"type:decimal(10,2)"`  // g
+	PresetID  *uint     `json:"preset_id,omitempty"`
+}
+
+// DrinkPreset is a drink a user logs often, e.g. "Bottle" (500 ml) or "Espresso" (30 ml, 65 mg caffeine)
+type DrinkPreset struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Name      string    `json:"name" gorm:"type:varchar(100);not null"`
+	Amount    float64   `json:"amount" gorm:"type:decimal(10,2);not null"` // ml
+	Caffeine  float64   `json:"caffeine" gorm:"type:decimal(10,2)"`        // mg
+	Alcohol   float64   `json:"alcohol" gorm:"type:decimal(10,2)"`         // g
+}
+
+// LogWaterRequest represents the request to log a drink
+// With a preset_id, the preset's values are used for the fields left empty
+type LogWaterRequest struct {
+	PresetID *uint      `json:"preset_id"`
+	Amount   float64    `json:"amount"`    // ml
+	LoggedAt *time.Time `json:"logged_at"` // RFC 3339, defaults to now
+	Name     string     `json:"name"`
+	Caffeine float64    `json:"caffeine"` // mg
+	Alcohol  float64    `json:"alcohol"`  // g
+}
+
+// PresetRequest represents the request to create or update a preset
+type PresetRequest struct {
+	Name     string  `json:"name"`
+	Amount   float64 `json:"amount"`   // ml
+	Caffeine float64 `json:"caffeine"` // mg
+	Alcohol  float64 `json:"alcohol"`  // g
+}
+
+// Totals represents what a user drank on one day, from the water log
+type Totals struct {
+	Water    float64 `json:"water"`    // ml
+	Caffeine float64 `json:"caffeine"` // mg
+	Alcohol  float64 `json:"alcohol"`  // g
+}
+
+// DayLog represents the drinks logged on one day, as returned by GET /water
+type DayLog struct {
+	Date string `json:"date"`
+	Totals
+	Logs []WaterLog `json:"logs"`
+}
@@ -0,0 +1,110 @@
+package hydration
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Repository handles database operations for the water log and presets
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new hydration repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateLog records a drink
+func (r *Repository) CreateLog(log *WaterLog) error {
+	if err := r.db.Create(log).Error; err != nil {
+		return fmt.Errorf("failed to create water log: %w", err)
+	}
+	return nil
+}
+
+// GetLogsByDate retrieves the drinks a user logged on a day, in the order they were drunk
+func (r *Repository) GetLogsByDate(userID uint, date time.Time) ([]WaterLog, error) {
+	var logs []WaterLog
+	err := r.db.Where("user_id = ? AND date = ?", userID, date.Format("2006-01-02")).
+		Order("logged_at, id").
+		Find(&logs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get water logs: %w", err)
+	}
+	return logs, nil
+}
+
+// GetDayTotals adds up the drinks a user logged on a day
+func (r *Repository) GetDayTotals(userID uint, date time.Time) (Totals, error) {
+	var totals Totals
+	err := r.db.Model(&WaterLog{}).
+		Select("COALESCE(SUM(amount), 0) AS water, COALESCE(SUM(caffeine), 0) AS caffeine, COALESCE(SUM(alcohol), 0) AS alcohol").
+		Where("user_id = ? AND date = ?", userID, date.Format("2006-01-02")).
+		Scan(&totals).Error
+	if err != nil {
+		return Totals{}, fmt.Errorf("failed to calculate water totals: %w", err)
+	}
+	return totals, nil
+}
+
+// DeleteLog removes a drink from a user's log
+func (r *Repository) DeleteLog(id, userID uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&WaterLog{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete water log: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("water log not found")
+	}
+	return nil
+}
+
+// ListPresets retrieves a user's presets, by name
+func (r *Repository) ListPresets(userID uint) ([]DrinkPreset, error) {
+	var presets []DrinkPreset
+	if err := r.db.Where("user_id = ?", userID).Order("name, id").Find(&presets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list presets: %w", err)
+	}
+	return presets, nil
+}
+
+// GetPreset retrieves one of a user's presets
+func (r *Repository) GetPreset(id, userID uint) (*DrinkPreset, error) {
+	var preset DrinkPreset
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&preset).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("preset not found")
+		}
+		return nil, fmt.Errorf("failed to get preset: %w", err)
+	}
+	return &preset, nil
+}
+
+// SavePreset creates or updates a preset
+func (r *Repository) SavePreset(preset *DrinkPreset) error {
+	if err := r.db.Save(preset).Error; err != nil {
+		return fmt.Errorf("failed to save preset: %w", err)
+	}
+	return nil
+}
+
+// DeletePreset removes one of a user's presets; drinks logged with it are kept
+func (r *Repository) DeletePreset(id, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&DrinkPreset{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete preset: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("preset not found")
+		}
+		if err := tx.Model(&WaterLog{}).Where("preset_id = ?", id).Update("preset_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to detach preset: %w", err)
+		}
+		return nil
+	})
+}
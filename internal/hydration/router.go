@@ -0,0 +1,45 @@
+package hydration
+
+import (
+	"net/http"
+
+	"ultra-bis/internal/auth"
+)
+
+// RegisterRoutes registers all hydration-related routes to the provided mux
+func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
+	// All hydration routes are protected with JWT
+
+	mux.HandleFunc("/water", auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handler.GetWater(w, r)
+		case http.MethodPost:
+			handler.LogWater(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/water/", auth.JWTMiddleware(handler.DeleteWater))
+
+	mux.HandleFunc("/water/presets", auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handler.ListPresets(w, r)
+		case http.MethodPost:
+			handler.CreatePreset(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/water/presets/", auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			handler.UpdatePreset(w, r)
+		case http.MethodDelete:
+			handler.DeletePreset(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+}
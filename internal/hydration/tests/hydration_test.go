@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"ultra-bis/internal/hydration"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLogWaterRequest(t *testing.T) {
+	req := hydration.LogWaterRequest{Amount: 250, Name: "  Glass  "}
+	assert.NoError(t, hydration.ValidateLogWaterRequest(&req))
+	assert.Equal(t, "Glass", req.Name)
+
+	assert.EqualError(t, hydration.ValidateLogWaterRequest(&hydration.LogWaterRequest{}), "Amount must be between 0 and 5000 ml")
+	assert.EqualError(t, hydration.ValidateLogWaterRequest(&hydration.LogWaterRequest{Amount: 6000}), "Amount must be between 0 and 5000 ml")
+	assert.EqualError(t, hydration.ValidateLogWaterRequest(&hydration.LogWaterRequest{Amount: 250, Caffeine: -1}), "Caffeine and alcohol must be non-negative")
+	assert.Error(t, hydration.ValidateLogWaterRequest(&hydration.LogWaterRequest{Amount: 250, Name: strings.Repeat("a", 101)}))
+}
+
+func TestValidatePresetRequest(t *testing.T) {
+	assert.NoError(t, hydration.ValidatePresetRequest(&hydration.PresetRequest{Name: "Espresso", Amount: 30, Caffeine: 65}))
+	assert.EqualError(t, hydration.ValidatePresetRequest(&hydration.PresetRequest{Name: " ", Amount: 30}), "Name is required")
+	assert.EqualError(t, hydration.ValidatePresetRequest(&hydration.PresetRequest{Name: "Beer", Amount: 330, Alcohol: -13}), "Caffeine and alcohol must be non-negative")
+}
+
+func TestApplyPreset(t *testing.T) {
+	preset := &hydration.DrinkPreset{Name: "Beer", Amount: 330, Alcohol: 13}
+
+	// Empty fields come from the preset
+	req := hydration.LogWaterRequest{}
+	req.ApplyPreset(preset)
+	assert.Equal(t, hydration.LogWaterRequest{Name: "Beer", Amount: 330, Alcohol: 13}, req)
+
+	// Given fields win, e.g. a bigger glass
+	req = hydration.LogWaterRequest{Amount: 500}
+	req.ApplyPreset(preset)
+	assert.Equal(t, 500.0, req.Amount)
+	assert.Equal(t, "Beer", req.Name)
+}
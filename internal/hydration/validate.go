@@ -0,0 +1,51 @@
+package hydration
+
+import (
+	"errors"
+	"strings"
+)
+
+// ApplyPreset fills the fields of the request left empty with the preset's values
+func (req *LogWaterRequest) ApplyPreset(preset *DrinkPreset) {
+	if req.Amount == 0 {
+		req.Amount = preset.Amount
+	}
+	if req.Name == "" {
+		req.Name = preset.Name
+	}
+	if req.Caffeine == 0 {
+		req.Caffeine = preset.Caffeine
+	}
+	if req.Alcohol == 0 {
+		req.Alcohol = preset.Alcohol
+	}
+}
+
+// ValidateLogWaterRequest checks the amounts of a drink, once its preset was applied
+func ValidateLogWaterRequest(req *LogWaterRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	return validateDrink(req.Name, req.Amount, req.Caffeine, req.Alcohol)
+}
+
+// ValidatePresetRequest checks a preset's name and amounts
+func ValidatePresetRequest(req *PresetRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("Name is required")
+	}
+	return validateDrink(req.Name, req.Amount, req.Caffeine, req.Alcohol)
+}
+
+// validateDrink checks the values shared by logs and presets
+func validateDrink(name string, amount, caffeine, alcohol float64) error {
+	if len(name) > 100 {
+		return errors.New("name must be at most 100 characters")
+	}
+	if amount <= 0 || amount > MaxAmount {
+		return errors.New("Amount must be between 0 and 5000 ml")
+	}
+	if caffeine < 0 || alcohol < 0 {
+		return errors.New("Caffeine and alcohol must be non-negative")
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package i18n
+
+import (
+	"database/sql/driver"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is used when no supported locale was requested
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalogs holds the messages of each supported locale, keyed by message ID
+var catalogs = map[string]map[string]string{}
+
+// sourceIDs maps a DefaultLocale text back to its message ID, so that the
+// English messages already passed to httputil.WriteError can be translated
+var sourceIDs = map[string]string{}
+
+func init() {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("failed to read locale catalogs: %v", err))
+	}
+
+	for _, entry := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("failed to read locale catalog %s: %v", entry.Name(), err))
+		}
+
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("invalid locale catalog %s: %v", entry.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+
+	source, ok := catalogs[DefaultLocale]
+	if !ok {
+		panic("missing catalog for the default locale " + DefaultLocale)
+	}
+
+	// Sorted so that a text shared by several IDs always maps to the same one
+	ids := make([]string, 0, len(source))
+	for id := range source {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if _, exists := sourceIDs[source[id]]; !exists {
+			sourceIDs[source[id]] = id
+		}
+	}
+}
+
+// Supported lists the locales with a catalog, sorted
+func Supported() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// IsSupported reports whether a locale has a catalog
+func IsSupported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// T returns the message with the given ID in locale, formatted with args
+// Missing translations fall back to DefaultLocale, then to the ID itself
+func T(locale, id string, args ...interface{}) string {
+	text, ok := catalogs[locale][id]
+	if !ok {
+		text, ok = catalogs[DefaultLocale][id]
+	}
+	if !ok {
+		text = id
+	}
+
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// Translate looks up an English message in the catalog and returns its ID and its text in locale
+// Messages that are not in the catalog (e.g. wrapped errors) are returned unchanged with an empty ID
+func Translate(locale, message string) (id, text string) {
+	id, ok := sourceIDs[message]
+	if !ok {
+		return "", message
+	}
+	return id, T(locale, id)
+}
+
+// Error is an error whose message is built from a catalog message, so that it can be translated
+// with its arguments rather than compared as a whole
+type Error struct {
+	Format string // English message, as in the DefaultLocale catalog
+	Args   []interface{}
+}
+
+// Errorf returns an error formatted like fmt.Errorf from a catalog message
+func Errorf(format string, args ...interface{}) error {
+	return &Error{Format: format, Args: args}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf(e.Format, e.Args...)
+}
+
+// TranslateError returns the message ID of an error and its text in locale
+// Errors built with Errorf are translated with their arguments, other errors like Translate
+func TranslateError(locale string, err error) (id, text string) {
+	localized, ok := err.(*Error)
+	if !ok {
+		return Translate(locale, err.Error())
+	}
+	id, ok = sourceIDs[localized.Format]
+	if !ok {
+		return "", err.Error()
+	}
+	return id, T(locale, id, localized.Args...)
+}
+
+// Label returns the localized label of an enumerated value, e.g. Label("fr", "meal_type", "breakfast")
+// Unknown values are returned unchanged
+func Label(locale, kind, value string) string {
+	id := kind + "." + value
+	if _, ok := catalogs[DefaultLocale][id]; !ok {
+		return value
+	}
+	return T(locale, id)
+}
+
+// Labels returns the localized labels of every value of a kind, keyed by value
+func Labels(locale, kind string) map[string]string {
+	prefix := kind + "."
+	labels := make(map[string]string)
+	for id := range catalogs[DefaultLocale] {
+		if strings.HasPrefix(id, prefix) {
+			labels[strings.TrimPrefix(id, prefix)] = T(locale, id)
+		}
+	}
+	return labels
+}
+
+// Texts holds a text per locale, for data that carries its own translations
+type Texts map[string]string
+
+// Get returns the text in locale, or "" when there is no translation
+func (t Texts) Get(locale string) string {
+	return t[locale]
+}
+
+// Value implements the driver.Valuer interface for JSONB storage
+func (t Texts) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
+
+// Scan implements the sql.Scanner interface for JSONB deserialization
+func (t *Texts) Scan(value interface{}) error {
+	if value == nil {
+		*t = nil
+		return nil
+	}
+
+	data, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to scan Texts: not a byte slice")
+	}
+
+	return json.Unmarshal(data, t)
+}
@@ -0,0 +1,47 @@
+package i18n
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// labelKinds lists the enumerated values clients may display, as catalog ID prefixes
+var labelKinds = []string{"meal_type", "tag", "day_type", "weekday"}
+
+// LocaleResponse describes the negotiated locale and the localized labels of enumerated values
+type LocaleResponse struct {
+	Locale    string                       `json:"locale"`
+	Supported []string                     `json:"supported"`
+	Labels    map[string]map[string]string `json:"labels"`
+}
+
+// GetLocale handles GET /i18n
+// Returns the locale negotiated for the request and the labels of meal types, tags, day types and weekdays
+func GetLocale(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{
+			"error": T(FromRequest(r), "error.method_not_allowed"),
+			"code":  "error.method_not_allowed",
+		})
+		return
+	}
+
+	locale := FromRequest(r)
+	response := LocaleResponse{
+		Locale:    locale,
+		Supported: Supported(),
+		Labels:    make(map[string]map[string]string, len(labelKinds)),
+	}
+	for _, kind := range labelKinds {
+		response.Labels[kind] = Labels(locale, kind)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// writeJSON mirrors httputil.WriteJSON, which this package cannot import
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,139 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// contextKey is a custom type for context keys to avoid collisions
+type contextKey string
+
+// localeKey is the context key for the request's locale negotiation
+const localeKey contextKey = "locale"
+
+// negotiation holds the locale chosen for a request; it is shared by the request
+// context and the response writer so that a later profile lookup updates both
+type negotiation struct {
+	locale   string
+	explicit bool // Chosen with ?lang=, which the profile locale does not override
+}
+
+// Negotiate picks the best supported locale from an Accept-Language header
+// e.g. "fr-CH, fr;q=0.9, en;q=0.8" gives "fr"; DefaultLocale when nothing matches
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		// Region subtags fall back to their language: "fr-CA" matches "fr"
+		language, _, _ := strings.Cut(tag, "-")
+		candidates = append(candidates, candidate{locale: language, q: q})
+	}
+
+	// Stable so that equal weights keep the header's order
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		if IsSupported(c.locale) {
+			return c.locale
+		}
+	}
+	return DefaultLocale
+}
+
+// Middleware negotiates the locale of each request from the lang query parameter,
+// then the Accept-Language header. Authenticated requests may then switch to the
+// user's profile locale with SetProfileLocale, unless lang was given.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := &negotiation{locale: Negotiate(r.Header.Get("Accept-Language"))}
+		if lang := strings.ToLower(r.URL.Query().Get("lang")); IsSupported(lang) {
+			state.locale = lang
+			state.explicit = true
+		}
+
+		ctx := context.WithValue(r.Context(), localeKey, state)
+		next.ServeHTTP(&localeWriter{ResponseWriter: w, state: state}, r.WithContext(ctx))
+	})
+}
+
+// SetProfileLocale applies the user's preferred locale to the request, unless lang was given
+func SetProfileLocale(r *http.Request, locale string) {
+	state, ok := r.Context().Value(localeKey).(*negotiation)
+	if !ok || state.explicit || !IsSupported(locale) {
+		return
+	}
+	state.locale = locale
+}
+
+// FromRequest returns the locale negotiated for a request
+func FromRequest(r *http.Request) string {
+	return FromContext(r.Context())
+}
+
+// FromContext returns the locale negotiated for a request context, or DefaultLocale
+func FromContext(ctx context.Context) string {
+	if state, ok := ctx.Value(localeKey).(*negotiation); ok {
+		return state.locale
+	}
+	return DefaultLocale
+}
+
+// FromWriter returns the locale negotiated for the request a response writer answers
+// Helpers that only receive the writer, such as httputil.WriteError, use it
+func FromWriter(w http.ResponseWriter) string {
+	for {
+		switch typed := w.(type) {
+		case *localeWriter:
+			return typed.state.locale
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = typed.Unwrap()
+		default:
+			return DefaultLocale
+		}
+	}
+}
+
+// localeWriter carries the request's locale to the response helpers
+type localeWriter struct {
+	http.ResponseWriter
+	state *negotiation
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController
+func (w *localeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush forwards flushes to the underlying writers when they support them
+func (w *localeWriter) Flush() {
+	w.FlushError()
+}
+
+// FlushError flushes through the wrapped writers, for http.ResponseController
+func (w *localeWriter) FlushError() error {
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
@@ -0,0 +1,11 @@
+package i18n
+
+import (
+	"net/http"
+)
+
+// RegisterRoutes registers the locale routes to the provided mux
+func RegisterRoutes(mux *http.ServeMux) {
+	// Public: clients call it before login to render labels in the negotiated locale
+	mux.HandleFunc("/i18n", GetLocale)
+}
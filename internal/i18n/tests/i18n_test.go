@@ -0,0 +1,225 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/i18n"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"fr", "fr"},
+		{"fr-CH, fr;q=0.9, en;q=0.8", "fr"},
+		{"en-US,en;q=0.9,fr;q=0.8", "en"},
+		{"de, fr;q=0.5", "fr"},
+		{"fr;q=0.2, en;q=0.7", "en"},
+		{"fr;q=0, de", "en"},
+		{"*", "en"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, i18n.Negotiate(tt.header), tt.header)
+	}
+}
+
+func TestCatalogs_HaveSameMessages(t *testing.T) {
+	assert.Equal(t, []string{"en", "fr"}, i18n.Supported())
+
+	// Every message must be translated; a missing ID would silently fall back to English
+	catalogs := make(map[string]map[string]string)
+	for _, locale := range i18n.Supported() {
+		data, err := os.ReadFile(filepath.Join("..", "locales", locale+".json"))
+		require.NoError(t, err)
+		var messages map[string]string
+		require.NoError(t, json.Unmarshal(data, &messages))
+		catalogs[locale] = messages
+	}
+	for id := range catalogs["en"] {
+		assert.NotEmpty(t, catalogs["fr"][id], "missing French translation for %s", id)
+	}
+	for id := range catalogs["fr"] {
+		assert.Contains(t, catalogs["en"], id, "French message %s has no English source", id)
+	}
+
+	assert.Equal(t, "Petit-déjeuner", i18n.Label("fr", "meal_type", "breakfast"))
+	assert.Equal(t, "unknown", i18n.Label("fr", "meal_type", "unknown"))
+}
+
+func TestT_Fallbacks(t *testing.T) {
+	assert.Equal(t, "Méthode non autorisée", i18n.T("fr", "error.method_not_allowed"))
+	assert.Equal(t, "Method not allowed", i18n.T("de", "error.method_not_allowed"))
+	assert.Equal(t, "error.unknown", i18n.T("fr", "error.unknown"))
+
+	id, text := i18n.Translate("fr", "Invalid request body")
+	assert.Equal(t, "error.invalid_request_body", id)
+	assert.Equal(t, "Corps de requête invalide", text)
+
+	id, text = i18n.Translate("fr", "failed to create goal: connection refused")
+	assert.Empty(t, id)
+	assert.Equal(t, "failed to create goal: connection refused", text)
+}
+
+func serveError(t *testing.T, target string, header string, profileLocale string) httputil.ErrorResponse {
+	handler := i18n.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if profileLocale != "" {
+			i18n.SetProfileLocale(r, profileLocale)
+		}
+		httputil.WriteError(w, http.StatusNotFound, "Goal not found")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if header != "" {
+		req.Header.Set("Accept-Language", header)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var response httputil.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	return response
+}
+
+func TestMiddleware_LocalizesErrors(t *testing.T) {
+	response := serveError(t, "/goals/1", "", "")
+	assert.Equal(t, "Goal not found", response.Error)
+	assert.Equal(t, "error.goal_not_found", response.Code)
+
+	response = serveError(t, "/goals/1", "fr-FR,fr;q=0.9", "")
+	assert.Equal(t, "Objectif introuvable", response.Error)
+	assert.Equal(t, "error.goal_not_found", response.Code)
+
+	// The profile locale wins over Accept-Language, and ?lang= over both
+	response = serveError(t, "/goals/1", "en", "fr")
+	assert.Equal(t, "Objectif introuvable", response.Error)
+
+	response = serveError(t, "/goals/1?lang=en", "fr", "fr")
+	assert.Equal(t, "Goal not found", response.Error)
+}
+
+func TestGetLocale(t *testing.T) {
+	handler := i18n.Middleware(http.HandlerFunc(i18n.GetLocale))
+
+	req := httptest.NewRequest(http.MethodGet, "/i18n?lang=fr", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response i18n.LocaleResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "fr", response.Locale)
+	assert.Equal(t, "Jour de repos", response.Labels["day_type"]["rest"])
+	assert.Equal(t, "Collation", response.Labels["meal_type"]["snack"])
+	assert.Equal(t, "samedi", response.Labels["weekday"]["saturday"])
+}
+
+func TestTranslateError(t *testing.T) {
+	err := i18n.Errorf("client has not shared %s with you", "diary")
+	assert.Equal(t, "client has not shared diary with you", err.Error())
+
+	id, text := i18n.TranslateError("fr", err)
+	assert.Equal(t, "error.client_has_not_shared_with_you", id)
+	assert.Equal(t, "le client ne partage pas diary avec vous", text)
+
+	// Plain errors are looked up by their message
+	id, text = i18n.TranslateError("fr", errors.New("Invalid request body"))
+	assert.Equal(t, "error.invalid_request_body", id)
+	assert.Equal(t, "Corps de requête invalide", text)
+}
+
+// messageCalls lists the functions whose string literal argument must be a catalog message,
+// with the position of that argument
+var messageCalls = map[string]int{
+	"WriteError":   2,
+	"WriteErrorf":  2,
+	"WriteSuccess": 2,
+	"Errorf":       0, // i18n.Errorf; fmt.Errorf is told apart by its package
+}
+
+// catalogMessages collects the string literals passed as messages in the Go files under root
+func catalogMessages(t *testing.T, root string) map[string]string {
+	messages := make(map[string]string)
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			var name string
+			switch fun := call.Fun.(type) {
+			case *ast.SelectorExpr:
+				pkg, ok := fun.X.(*ast.Ident)
+				if !ok || (pkg.Name != "httputil" && pkg.Name != "i18n") {
+					return true
+				}
+				name = fun.Sel.Name
+			case *ast.Ident:
+				// Calls within httputil and i18n themselves
+				name = fun.Name
+				if name == "Errorf" {
+					return true
+				}
+			default:
+				return true
+			}
+
+			arg, ok := messageCalls[name]
+			if !ok || len(call.Args) <= arg {
+				return true
+			}
+			if literal, ok := call.Args[arg].(*ast.BasicLit); ok && literal.Kind == token.STRING {
+				message, err := strconv.Unquote(literal.Value)
+				require.NoError(t, err)
+				messages[message] = fset.Position(literal.Pos()).String()
+			}
+			return true
+		})
+		return nil
+	})
+	require.NoError(t, err)
+	return messages
+}
+
+func TestCatalogs_HaveEveryErrorMessage(t *testing.T) {
+	// Messages are looked up by their English text: editing one at a call site without
+	// updating the catalogs would silently drop its translations
+	messages := catalogMessages(t, filepath.Join("..", ".."))
+	for message, position := range catalogMessages(t, filepath.Join("..", "..", "..", "cmd")) {
+		messages[message] = position
+	}
+	require.Greater(t, len(messages), 100, "no messages found, is the source tree readable?")
+
+	// TestCatalogs_HaveSameMessages checks that every locale translates the IDs found
+	for message, position := range messages {
+		id, _ := i18n.Translate(i18n.DefaultLocale, message)
+		assert.NotEmpty(t, id, "%s: %q is not in the catalog", position, message)
+	}
+}
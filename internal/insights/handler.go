@@ -0,0 +1,111 @@
+package insights
+
+import (
+	"net/http"
+	"time"
+
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/diary"
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/i18n"
+	"ultra-bis/internal/metrics"
+	"ultra-bis/internal/units"
+)
+
+// Handler handles insights requests
+type Handler struct {
+	diaryRepo   *diary.Repository
+	goalRepo    *goal.Repository
+	metricsRepo *metrics.Repository
+}
+
+// NewHandler creates a new insights handler
+func NewHandler(diaryRepo *diary.Repository, goalRepo *goal.Repository, metricsRepo *metrics.Repository) *Handler {
+	return &Handler{
+		diaryRepo:   diaryRepo,
+		goalRepo:    goalRepo,
+		metricsRepo: metricsRepo,
+	}
+}
+
+// GetInsights handles GET /insights?from=YYYY-MM-DD&to=YYYY-MM-DD
+// Correlates intake with the next-week weight change, relates the contextual calorie share to the
+// weekly weight change and measures adherence by weekday, over the last 90 days by default
+func (h *Handler) GetInsights(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	settings := calendar.FromRequest(r)
+	query := r.URL.Query()
+
+	to := settings.Today()
+	from := to.AddDate(0, 0, -(DefaultDays - 1))
+	if query.Get("from") != "" || query.Get("to") != "" {
+		var err error
+		from, err = time.Parse("2006-01-02", query.Get("from"))
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "from is required (use YYYY-MM-DD)")
+			return
+		}
+		to, err = time.Parse("2006-01-02", query.Get("to"))
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "to is required (use YYYY-MM-DD)")
+			return
+		}
+		if to.Before(from) {
+			httputil.WriteError(w, http.StatusBadRequest, "to must not be before from")
+			return
+		}
+		if to.Sub(from) >= MaxDays*24*time.Hour {
+			httputil.WriteError(w, http.StatusBadRequest, "Insights range is limited to 366 days")
+			return
+		}
+	}
+
+	totals, err := h.diaryRepo.GetMealTotalsByDay(userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Weigh-ins run a week past the range so that its last days have a next-week change
+	weights, err := h.metricsRepo.GetByDateRange(userID, from, to.AddDate(0, 0, 8).Add(-time.Nanosecond))
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	goals, err := h.goalRepo.GetAll(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	dayTypes, err := h.goalRepo.GetDayTypes(userID, from, to)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	report := Analyze(Input{
+		From:      from,
+		To:        to,
+		WeekStart: settings.WeekStart,
+		Totals:    totals,
+		Weights:   weights,
+		Goals:     goals,
+		DayTypes:  dayTypes,
+	})
+	report.ConvertUnits(units.FromRequest(r))
+	report.Explain(i18n.FromRequest(r))
+
+	httputil.WriteJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,476 @@
+package insights
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"ultra-bis/internal/diary"
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/i18n"
+	"ultra-bis/internal/metrics"
+)
+
+const (
+	// MaxDays bounds the analysed range, like the diary summaries
+	MaxDays = 366
+	// DefaultDays is the range analysed when none is given
+	DefaultDays = 90
+
+	// Below these sample sizes correlations are reported but not turned into findings
+	minDaySamples  = 14
+	minWeekSamples = 4
+	// minWeekDaysLogged is the number of logged days a week needs to count as a sample
+	minWeekDaysLogged = 3
+	// minCorrelation is the smallest |r| worth a finding
+	minCorrelation = 0.3
+
+	// A weekday needs minWeekdayDays logged days with a goal and an adherence at least
+	// minWeekdayDeviation points away from the overall one to be worth a finding
+	minWeekdayDays      = 3
+	minWeekdayDeviation = 10.0
+)
+
+// Input holds the data Analyze works from
+type Input struct {
+	From      time.Time
+	To        time.Time
+	WeekStart time.Weekday
+	Totals    []diary.MealDayTotals // Per day and meal type, from GetMealTotalsByDay
+	Weights   []metrics.BodyMetric  // Ascending; should extend a week past To so the last days have a next-week change
+	Goals     []goal.NutritionGoal
+	DayTypes  map[string]string // YYYY-MM-DD to day type, may be nil
+}
+
+// dayIntake holds the totals of one logged day
+type dayIntake struct {
+	calories, protein, carbs, fat, fiber float64
+	routine, contextual                  float64
+}
+
+// nutrient returns the intake of a nutrient
+func (d dayIntake) nutrient(name string) float64 {
+	switch name {
+	case NutrientProtein:
+		return d.protein
+	case NutrientCarbs:
+		return d.carbs
+	case NutrientFat:
+		return d.fat
+	case NutrientFiber:
+		return d.fiber
+	default:
+		return d.calories
+	}
+}
+
+// Analyze correlates daily intake with the trend weight change over the following week, relates the weekly
+// contextual calorie share to the weekly weight change and measures adherence by weekday.
+// Weights stay in kg and energies in kcal; call ConvertUnits then Explain to get findings.
+func Analyze(in Input) Report {
+	days := make(map[string]dayIntake)
+	for _, row := range in.Totals {
+		key := row.Day.Format("2006-01-02")
+		day := days[key]
+		day.calories += row.Calories
+		day.protein += row.Protein
+		day.carbs += row.Carbs
+		day.fat += row.Fat
+		day.fiber += row.Fiber
+		day.routine += row.RoutineCalories
+		day.contextual += row.ContextualCalories
+		days[key] = day
+	}
+
+	trend := newTrendLine(in.Weights)
+
+	report := Report{
+		From:     in.From.Format("2006-01-02"),
+		To:       in.To.Format("2006-01-02"),
+		Findings: []Finding{},
+	}
+	for _, weight := range in.Weights {
+		if !weight.Date.Before(in.From) && weight.Date.Before(in.To.AddDate(0, 0, 1)) {
+			report.WeighIns++
+		}
+	}
+
+	// Logged days and the trend weight change over the week that follows each of them
+	var logged []dayIntake
+	var changes []float64
+	for date := in.From; !date.After(in.To); date = date.AddDate(0, 0, 1) {
+		report.Days++
+		day, ok := days[date.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		report.DaysLogged++
+
+		start, okStart := trend.at(date)
+		end, okEnd := trend.at(date.AddDate(0, 0, 7))
+		if okStart && okEnd {
+			logged = append(logged, day)
+			changes = append(changes, end-start)
+		}
+	}
+
+	for _, name := range []string{NutrientCalories, NutrientProtein, NutrientCarbs, NutrientFat, NutrientFiber} {
+		intakes := make([]float64, len(logged))
+		for i, day := range logged {
+			intakes[i] = day.nutrient(name)
+		}
+		average, above, below := averageSplit(intakes, changes)
+		report.Correlations = append(report.Correlations, Correlation{
+			Nutrient:           name,
+			Samples:            len(intakes),
+			Coefficient:        roundToTwo(pearson(intakes, changes)),
+			AverageIntake:      roundToTwo(average),
+			ChangeAboveAverage: roundToTwo(above),
+			ChangeBelowAverage: roundToTwo(below),
+		})
+	}
+
+	report.TagShare = analyzeTagShare(in, days, trend)
+	report.Weekdays, report.CalorieAdherence = analyzeWeekdays(in, days)
+
+	return report
+}
+
+// analyzeTagShare computes the routine and contextual calorie share of each week against its trend weight change
+func analyzeTagShare(in Input, days map[string]dayIntake, trend trendLine) TagShareAnalysis {
+	analysis := TagShareAnalysis{Weeks: []TagShareWeek{}}
+	var shares, changes []float64
+
+	for start := diary.PeriodStart(in.From, diary.GroupByWeek, in.WeekStart); !start.After(in.To); start = start.AddDate(0, 0, 7) {
+		week := TagShareWeek{WeekStart: start.Format("2006-01-02")}
+
+		var routine, contextual float64
+		for date := start; date.Before(start.AddDate(0, 0, 7)); date = date.AddDate(0, 0, 1) {
+			if date.Before(in.From) || date.After(in.To) {
+				continue
+			}
+			day, ok := days[date.Format("2006-01-02")]
+			if !ok {
+				continue
+			}
+			week.DaysLogged++
+			week.Calories += day.calories
+			routine += day.routine
+			contextual += day.contextual
+		}
+		if week.Calories > 0 {
+			week.RoutinePercent = roundToTwo(routine / week.Calories * 100)
+			week.ContextualPercent = roundToTwo(contextual / week.Calories * 100)
+		}
+		week.Calories = roundToTwo(week.Calories)
+
+		weekStart, okStart := trend.at(start)
+		weekEnd, okEnd := trend.at(start.AddDate(0, 0, 7))
+		if okStart && okEnd {
+			change := roundToTwo(weekEnd - weekStart)
+			week.WeightChange = &change
+			if week.DaysLogged >= minWeekDaysLogged {
+				shares = append(shares, contextual/math.Max(week.Calories, 1)*100)
+				changes = append(changes, weekEnd-weekStart)
+			}
+		}
+
+		analysis.Weeks = append(analysis.Weeks, week)
+	}
+
+	average, above, below := averageSplit(shares, changes)
+	analysis.Samples = len(shares)
+	analysis.Coefficient = roundToTwo(pearson(shares, changes))
+	analysis.AverageContextual = roundToTwo(average)
+	analysis.ChangeAboveAverage = roundToTwo(above)
+	analysis.ChangeBelowAverage = roundToTwo(below)
+	return analysis
+}
+
+// analyzeWeekdays computes logging and goal adherence per weekday, starting from weekStart,
+// along with the overall calorie adherence
+func analyzeWeekdays(in Input, days map[string]dayIntake) ([]WeekdayAdherence, float64) {
+	type accumulator struct {
+		days, logged               int
+		calories                   float64
+		calorieSum, proteinSum     float64
+		calorieCount, proteinCount int
+	}
+	var weekdays [7]accumulator
+	var totalAdherence float64
+	var totalCount int
+
+	for date := in.From; !date.After(in.To); date = date.AddDate(0, 0, 1) {
+		acc := &weekdays[date.Weekday()]
+		acc.days++
+
+		day, ok := days[date.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		acc.logged++
+		acc.calories += day.calories
+
+		dayGoal := diary.EffectiveGoal(in.Goals, in.DayTypes, date)
+		if dayGoal == nil {
+			continue
+		}
+		if dayGoal.Calories > 0 {
+			adherence := day.calories / dayGoal.Calories * 100
+			acc.calorieSum += adherence
+			acc.calorieCount++
+			totalAdherence += adherence
+			totalCount++
+		}
+		if dayGoal.Protein > 0 {
+			acc.proteinSum += day.protein / dayGoal.Protein * 100
+			acc.proteinCount++
+		}
+	}
+
+	result := make([]WeekdayAdherence, 0, 7)
+	for i := 0; i < 7; i++ {
+		weekday := (in.WeekStart + time.Weekday(i)) % 7
+		acc := weekdays[weekday]
+		entry := WeekdayAdherence{
+			Weekday:    strings.ToLower(weekday.String()),
+			Days:       acc.days,
+			DaysLogged: acc.logged,
+		}
+		if acc.logged > 0 {
+			entry.AverageCalories = roundToTwo(acc.calories / float64(acc.logged))
+		}
+		if acc.calorieCount > 0 {
+			entry.CalorieAdherence = roundToTwo(acc.calorieSum / float64(acc.calorieCount))
+		}
+		if acc.proteinCount > 0 {
+			entry.ProteinAdherence = roundToTwo(acc.proteinSum / float64(acc.proteinCount))
+		}
+		result = append(result, entry)
+	}
+
+	var overall float64
+	if totalCount > 0 {
+		overall = roundToTwo(totalAdherence / float64(totalCount))
+	}
+	return result, overall
+}
+
+// Explain turns the numbers of the report into findings localized in locale, ranked strongest first
+// Units must already be converted, since messages quote weights and energies in the report's units
+func (r *Report) Explain(locale string) {
+	r.Findings = []Finding{}
+	weightUnit := r.Units.Weight
+	if weightUnit == "" {
+		weightUnit = "kg"
+	}
+
+	enoughDays := false
+	for _, c := range r.Correlations {
+		if c.Samples < minDaySamples {
+			continue
+		}
+		enoughDays = true
+		if math.Abs(c.Coefficient) < minCorrelation {
+			continue
+		}
+		r.Findings = append(r.Findings, Finding{
+			Kind: "intake_weight",
+			Message: i18n.T(locale, "insight.intake_weight", r.amount(locale, c.Nutrient, c.AverageIntake),
+				c.ChangeAboveAverage, weightUnit, c.ChangeBelowAverage, weightUnit, c.Coefficient, c.Samples),
+			Score: math.Abs(c.Coefficient),
+			Evidence: map[string]float64{
+				"coefficient":          c.Coefficient,
+				"samples":              float64(c.Samples),
+				"average_intake":       c.AverageIntake,
+				"change_above_average": c.ChangeAboveAverage,
+				"change_below_average": c.ChangeBelowAverage,
+			},
+		})
+	}
+
+	share := r.TagShare
+	if share.Samples >= minWeekSamples && math.Abs(share.Coefficient) >= minCorrelation {
+		r.Findings = append(r.Findings, Finding{
+			Kind: "contextual_share",
+			Message: i18n.T(locale, "insight.contextual_share", share.AverageContextual,
+				share.ChangeAboveAverage, weightUnit, share.ChangeBelowAverage, weightUnit, share.Coefficient, share.Samples),
+			Score: math.Abs(share.Coefficient),
+			Evidence: map[string]float64{
+				"coefficient":                share.Coefficient,
+				"samples":                    float64(share.Samples),
+				"average_contextual_percent": share.AverageContextual,
+				"change_above_average":       share.ChangeAboveAverage,
+				"change_below_average":       share.ChangeBelowAverage,
+			},
+		})
+	}
+
+	var over, under *WeekdayAdherence
+	for i := range r.Weekdays {
+		day := &r.Weekdays[i]
+		if day.DaysLogged < minWeekdayDays || day.CalorieAdherence == 0 {
+			continue
+		}
+		deviation := day.CalorieAdherence - r.CalorieAdherence
+		if deviation >= minWeekdayDeviation && (over == nil || day.CalorieAdherence > over.CalorieAdherence) {
+			over = day
+		}
+		if -deviation >= minWeekdayDeviation && (under == nil || day.CalorieAdherence < under.CalorieAdherence) {
+			under = day
+		}
+	}
+	for kind, day := range map[string]*WeekdayAdherence{"weekday_over": over, "weekday_under": under} {
+		if day == nil {
+			continue
+		}
+		deviation := math.Abs(day.CalorieAdherence - r.CalorieAdherence)
+		r.Findings = append(r.Findings, Finding{
+			Kind: kind,
+			Message: i18n.T(locale, "insight."+kind, i18n.Label(locale, "weekday", day.Weekday),
+				day.CalorieAdherence, r.CalorieAdherence),
+			Score: roundToTwo(math.Min(deviation/50, 1)),
+			Evidence: map[string]float64{
+				"calorie_adherence":         day.CalorieAdherence,
+				"overall_calorie_adherence": r.CalorieAdherence,
+				"days_logged":               float64(day.DaysLogged),
+			},
+		})
+	}
+
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		if r.Findings[i].Score != r.Findings[j].Score {
+			return r.Findings[i].Score > r.Findings[j].Score
+		}
+		return r.Findings[i].Kind < r.Findings[j].Kind
+	})
+
+	if len(r.Findings) > 0 {
+		return
+	}
+	if !enoughDays {
+		r.Findings = append(r.Findings, Finding{
+			Kind:     "not_enough_data",
+			Message:  i18n.T(locale, "insight.not_enough_data", minDaySamples),
+			Evidence: map[string]float64{"days_logged": float64(r.DaysLogged), "weigh_ins": float64(r.WeighIns)},
+		})
+		return
+	}
+	r.Findings = append(r.Findings, Finding{
+		Kind:     "no_pattern",
+		Message:  i18n.T(locale, "insight.no_pattern"),
+		Evidence: map[string]float64{"days_logged": float64(r.DaysLogged), "weigh_ins": float64(r.WeighIns)},
+	})
+}
+
+// amount formats an intake of a nutrient, e.g. "2100 kcal" or "150 g of protein"
+func (r *Report) amount(locale, nutrient string, value float64) string {
+	if nutrient == NutrientCalories {
+		energyUnit := r.Units.Energy
+		if energyUnit == "" {
+			energyUnit = "kcal"
+		}
+		return i18n.T(locale, "insight.amount.calories", value, energyUnit)
+	}
+	return i18n.T(locale, "insight.amount."+nutrient, value)
+}
+
+// trendLine interpolates the smoothed trend weight between weigh-ins
+type trendLine struct {
+	dates   []time.Time
+	weights []float64
+}
+
+// newTrendLine smooths weights with metrics.CalculateTrend
+func newTrendLine(weights []metrics.BodyMetric) trendLine {
+	var line trendLine
+	for _, point := range metrics.CalculateTrend(weights, 0).Points {
+		date, err := time.Parse("2006-01-02", point.Date)
+		if err != nil {
+			continue
+		}
+		line.dates = append(line.dates, date)
+		line.weights = append(line.weights, point.Trend)
+	}
+	return line
+}
+
+// at returns the trend weight on a date; ok is false outside the weighed-in range
+func (t trendLine) at(date time.Time) (float64, bool) {
+	n := len(t.dates)
+	if n == 0 || date.Before(t.dates[0]) || date.After(t.dates[n-1]) {
+		return 0, false
+	}
+
+	i := sort.Search(n, func(i int) bool { return !t.dates[i].Before(date) })
+	if t.dates[i].Equal(date) {
+		return t.weights[i], true
+	}
+	prev, next := t.dates[i-1], t.dates[i]
+	fraction := date.Sub(prev).Hours() / next.Sub(prev).Hours()
+	return t.weights[i-1] + (t.weights[i]-t.weights[i-1])*fraction, true
+}
+
+// pearson returns the correlation coefficient of xs and ys, or 0 when either does not vary
+func pearson(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	if len(xs) < 2 || len(xs) != len(ys) {
+		return 0
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var covariance, varianceX, varianceY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return 0
+	}
+	return covariance / math.Sqrt(varianceX*varianceY)
+}
+
+// averageSplit returns the average of xs and the average of ys for the samples above it and at or below it
+func averageSplit(xs, ys []float64) (average, above, below float64) {
+	if len(xs) == 0 {
+		return 0, 0, 0
+	}
+
+	for _, x := range xs {
+		average += x
+	}
+	average /= float64(len(xs))
+
+	var aboveCount, belowCount int
+	for i, x := range xs {
+		if x > average {
+			above += ys[i]
+			aboveCount++
+		} else {
+			below += ys[i]
+			belowCount++
+		}
+	}
+	if aboveCount > 0 {
+		above /= float64(aboveCount)
+	}
+	if belowCount > 0 {
+		below /= float64(belowCount)
+	}
+	return average, above, below
+}
+
+// roundToTwo rounds a float to 2 decimal places
+func roundToTwo(val float64) float64 {
+	return math.Round(val*100) / 100
+}
@@ -0,0 +1,106 @@
+package insights
+
+import (
+	"ultra-bis/internal/units"
+)
+
+// Nutrients correlated with the next-week weight change, in response order
+const (
+	NutrientCalories = "calories"
+	NutrientProtein  = "protein"
+	NutrientCarbs    = "carbs"
+	NutrientFat      = "fat"
+	NutrientFiber    = "fiber"
+)
+
+// Report holds the insights of GET /insights over a date range
+type Report struct {
+	From             string             `json:"from"`
+	To               string             `json:"to"`
+	Days             int                `json:"days"`
+	DaysLogged       int                `json:"days_logged"`
+	WeighIns         int                `json:"weigh_ins"`
+	CalorieAdherence float64            `json:"calorie_adherence"` // Average percentage of the calorie goal over logged days with a goal
+	Findings         []Finding          `json:"findings"`          // Ranked, strongest first
+	Correlations     []Correlation      `json:"correlations"`
+	TagShare         TagShareAnalysis   `json:"tag_share"`
+	Weekdays         []WeekdayAdherence `json:"weekdays"` // Ordered from the user's first day of the week
+	Units            units.Preferences  `json:"units"`
+}
+
+// Finding is a human-readable observation backed by the numbers of the report
+type Finding struct {
+	Kind     string             `json:"kind"`     // Stable identifier, e.g. "intake_weight" or "weekday_over"
+	Message  string             `json:"message"`  // Localized text
+	Score    float64            `json:"score"`    // Strength from 0 to 1, used for ranking
+	Evidence map[string]float64 `json:"evidence"` // Numbers the message is built from
+}
+
+// Correlation relates the intake of one nutrient on a day to the weight change over the following week
+type Correlation struct {
+	Nutrient           string  `json:"nutrient"`
+	Samples            int     `json:"samples"`              // Logged days whose next-week change is known
+	Coefficient        float64 `json:"coefficient"`          // Pearson r, from -1 to 1
+	AverageIntake      float64 `json:"average_intake"`       // kcal for calories, grams otherwise
+	ChangeAboveAverage float64 `json:"change_above_average"` // Average next-week change after days above the average intake
+	ChangeBelowAverage float64 `json:"change_below_average"` // Average next-week change after the other days
+}
+
+// TagShareAnalysis relates the weekly share of routine and contextual calories to the weekly weight change
+type TagShareAnalysis struct {
+	Weeks              []TagShareWeek `json:"weeks"`
+	Samples            int            `json:"samples"`     // Weeks with both logged calories and a weight change
+	Coefficient        float64        `json:"coefficient"` // Pearson r between contextual share and weight change
+	AverageContextual  float64        `json:"average_contextual_percent"`
+	ChangeAboveAverage float64        `json:"change_above_average"` // Average weight change of the weeks above the average share
+	ChangeBelowAverage float64        `json:"change_below_average"`
+}
+
+// TagShareWeek holds the routine and contextual calorie share and the trend weight change of one week
+type TagShareWeek struct {
+	WeekStart         string   `json:"week_start"`
+	DaysLogged        int      `json:"days_logged"`
+	Calories          float64  `json:"calories"`
+	RoutinePercent    float64  `json:"routine_percent"`
+	ContextualPercent float64  `json:"contextual_percent"`
+	WeightChange      *float64 `json:"weight_change,omitempty"` // Trend weight change over the week, when known
+}
+
+// WeekdayAdherence summarizes the logged days falling on one weekday
+type WeekdayAdherence struct {
+	Weekday          string  `json:"weekday"` // Lowercase English name, e.g. "monday"
+	Days             int     `json:"days"`
+	DaysLogged       int     `json:"days_logged"`
+	AverageCalories  float64 `json:"average_calories"`
+	CalorieAdherence float64 `json:"calorie_adherence"` // Average percentage of the calorie goal, over logged days with a goal
+	ProteinAdherence float64 `json:"protein_adherence"`
+}
+
+// ConvertUnits converts weight changes to the user's weight unit and energies to the user's energy unit
+func (r *Report) ConvertUnits(prefs units.Preferences) {
+	for i := range r.Correlations {
+		c := &r.Correlations[i]
+		if c.Nutrient == NutrientCalories {
+			c.AverageIntake = prefs.EnergyFromKcal(c.AverageIntake)
+		}
+		c.ChangeAboveAverage = prefs.WeightFromKg(c.ChangeAboveAverage)
+		c.ChangeBelowAverage = prefs.WeightFromKg(c.ChangeBelowAverage)
+	}
+
+	r.TagShare.ChangeAboveAverage = prefs.WeightFromKg(r.TagShare.ChangeAboveAverage)
+	r.TagShare.ChangeBelowAverage = prefs.WeightFromKg(r.TagShare.ChangeBelowAverage)
+	for i := range r.TagShare.Weeks {
+		week := &r.TagShare.Weeks[i]
+		week.Calories = prefs.EnergyFromKcal(week.Calories)
+		if week.WeightChange != nil {
+			change := prefs.WeightFromKg(*week.WeightChange)
+			week.WeightChange = &change
+		}
+	}
+
+	for i := range r.Weekdays {
+		r.Weekdays[i].AverageCalories = prefs.EnergyFromKcal(r.Weekdays[i].AverageCalories)
+	}
+
+	r.Units = prefs
+}
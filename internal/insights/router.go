@@ -0,0 +1,12 @@
+package insights
+
+import (
+	"net/http"
+
+	"ultra-bis/internal/auth"
+)
+
+// RegisterRoutes registers all insights routes to the provided mux
+func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("/insights", auth.JWTMiddleware(handler.GetInsights))
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ultra-bis/internal/diary"
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/insights"
+	"ultra-bis/internal/metrics"
+	"ultra-bis/internal/units"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// monday is the first day of the analysed range
+var monday = time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+
+// alternatingInput builds four weeks alternating surplus weeks, eaten half from contextual foods,
+// with routine deficit weeks, and Saturdays 1000 kcal above the rest. Weight follows the energy
+// balance (7700 kcal per kg around a 2200 kcal maintenance) and is logged a week past the range.
+func alternatingInput() insights.Input {
+	in := insights.Input{
+		From:      monday,
+		To:        monday.AddDate(0, 0, 27),
+		WeekStart: time.Monday,
+		Goals:     []goal.NutritionGoal{{StartDate: monday.AddDate(0, 0, -30), Calories: 2200, Protein: 150}},
+	}
+
+	weight := 80.0
+	for i := 0; i < 35; i++ {
+		date := monday.AddDate(0, 0, i)
+		in.Weights = append(in.Weights, metrics.BodyMetric{Date: date, Weight: weight})
+		if i >= 28 {
+			continue
+		}
+
+		calories, contextual := 1800.0, 0.0
+		if (i/7)%2 == 0 {
+			calories, contextual = 2600, 1300
+		}
+		if date.Weekday() == time.Saturday {
+			calories += 1000
+		}
+		in.Totals = append(in.Totals, diary.MealDayTotals{
+			Day:                date,
+			MealType:           diary.Lunch,
+			Entries:            3,
+			Calories:           calories,
+			Protein:            140,
+			Carbs:              calories / 8,
+			Fat:                60,
+			Fiber:              25,
+			RoutineCalories:    calories - contextual,
+			ContextualCalories: contextual,
+		})
+		weight += (calories - 2200) / 7700
+	}
+	return in
+}
+
+func TestAnalyze_IntakeCorrelation(t *testing.T) {
+	report := insights.Analyze(alternatingInput())
+
+	assert.Equal(t, 28, report.Days)
+	assert.Equal(t, 28, report.DaysLogged)
+	assert.Equal(t, 28, report.WeighIns)
+
+	require.Len(t, report.Correlations, 5)
+	calories := report.Correlations[0]
+	assert.Equal(t, insights.NutrientCalories, calories.Nutrient)
+	assert.Equal(t, 28, calories.Samples)
+	assert.Greater(t, calories.Coefficient, 0.5)
+	assert.Greater(t, calories.ChangeAboveAverage, calories.ChangeBelowAverage)
+
+	// Protein never varies, so it cannot correlate
+	protein := report.Correlations[1]
+	assert.Equal(t, 0.0, protein.Coefficient)
+	assert.Equal(t, 140.0, protein.AverageIntake)
+}
+
+func TestAnalyze_TagShare(t *testing.T) {
+	report := insights.Analyze(alternatingInput())
+
+	share := report.TagShare
+	require.Len(t, share.Weeks, 4)
+	assert.Equal(t, "2025-01-06", share.Weeks[0].WeekStart)
+	assert.Equal(t, 7, share.Weeks[0].DaysLogged)
+	assert.InDelta(t, 47.4, share.Weeks[0].ContextualPercent, 0.1)
+	assert.Equal(t, 0.0, share.Weeks[1].ContextualPercent)
+	assert.Equal(t, 100.0, share.Weeks[1].RoutinePercent)
+	require.NotNil(t, share.Weeks[0].WeightChange)
+
+	assert.Equal(t, 4, share.Samples)
+	assert.Greater(t, share.Coefficient, 0.5)
+	assert.Greater(t, share.ChangeAboveAverage, share.ChangeBelowAverage)
+}
+
+func TestAnalyze_Weekdays(t *testing.T) {
+	report := insights.Analyze(alternatingInput())
+
+	require.Len(t, report.Weekdays, 7)
+	assert.Equal(t, "monday", report.Weekdays[0].Weekday)
+	saturday := report.Weekdays[5]
+	assert.Equal(t, "saturday", saturday.Weekday)
+	assert.Equal(t, 4, saturday.DaysLogged)
+	assert.Equal(t, 3200.0, saturday.AverageCalories)
+	assert.InDelta(t, 145.45, saturday.CalorieAdherence, 0.01)
+	assert.InDelta(t, 93.33, saturday.ProteinAdherence, 0.01)
+	assert.Greater(t, saturday.CalorieAdherence-report.CalorieAdherence, 10.0)
+
+	// The week follows the user's first day of the week
+	in := alternatingInput()
+	in.WeekStart = time.Sunday
+	report = insights.Analyze(in)
+	assert.Equal(t, "sunday", report.Weekdays[0].Weekday)
+}
+
+func TestReport_Explain(t *testing.T) {
+	report := insights.Analyze(alternatingInput())
+	report.ConvertUnits(units.Metric)
+	report.Explain("en")
+
+	require.NotEmpty(t, report.Findings)
+	kinds := make(map[string]bool)
+	for i, finding := range report.Findings {
+		kinds[finding.Kind] = true
+		assert.NotEmpty(t, finding.Message)
+		if i > 0 {
+			assert.LessOrEqual(t, finding.Score, report.Findings[i-1].Score)
+		}
+	}
+	assert.True(t, kinds["intake_weight"])
+	assert.True(t, kinds["contextual_share"])
+	assert.True(t, kinds["weekday_over"])
+
+	for _, finding := range report.Findings {
+		if finding.Kind == "weekday_over" {
+			assert.Contains(t, finding.Message, "Saturday")
+		}
+	}
+
+	report.Explain("fr")
+	for _, finding := range report.Findings {
+		if finding.Kind == "weekday_over" {
+			assert.Contains(t, finding.Message, "samedi")
+		}
+	}
+}
+
+func TestReport_ExplainWithoutData(t *testing.T) {
+	report := insights.Analyze(insights.Input{From: monday, To: monday.AddDate(0, 0, 6), WeekStart: time.Monday})
+	report.ConvertUnits(units.Imperial)
+	report.Explain("en")
+
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, "not_enough_data", report.Findings[0].Kind)
+	assert.Equal(t, 0.0, report.Findings[0].Score)
+	assert.Equal(t, 0, report.DaysLogged)
+	assert.Equal(t, "lb", report.Units.Weight)
+}
+
+func TestReport_ConvertUnits(t *testing.T) {
+	report := insights.Analyze(alternatingInput())
+	kg := report.Correlations[0].ChangeAboveAverage
+
+	report.ConvertUnits(units.Imperial)
+	assert.InDelta(t, kg*2.20462, report.Correlations[0].ChangeAboveAverage, 0.01)
+}
@@ -0,0 +1,140 @@
+package live
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"ultra-bis/internal/auth"
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/units"
+)
+
+// Stream timings
+const (
+	KeepAliveInterval = 25 * time.Second // Comment lines keeping proxies from closing an idle stream; credentials are checked again as often
+	RetryDelay        = 3 * time.Second  // Reconnection delay suggested to clients
+)
+
+// Converter converts the data of an event, published in metric units and kcal, to a stream's units
+type Converter func(data json.RawMessage, prefs units.Preferences) (interface{}, error)
+
+// Handler handles the live event stream
+type Handler struct {
+	hub        *Hub
+	converters map[string]Converter
+}
+
+// NewHandler creates a new live event handler
+func NewHandler(hub *Hub) *Handler {
+	return &Handler{hub: hub, converters: make(map[string]Converter)}
+}
+
+// SetConverters sets the converters of the event types whose data carries units
+func (h *Handler) SetConverters(converters map[string]Converter) {
+	for eventType, convert := range converters {
+		h.converters[eventType] = convert
+	}
+}
+
+// convert returns msg with its data in prefs; the data is sent as published if it cannot be converted
+func (h *Handler) convert(msg Message, prefs units.Preferences) Message {
+	convert, ok := h.converters[msg.Type]
+	if !ok {
+		return msg
+	}
+	converted, err := convert(msg.Data, prefs)
+	if err == nil {
+		var data []byte
+		if data, err = json.Marshal(converted); err == nil {
+			msg.Data = data
+			return msg
+		}
+	}
+	log.Printf("Failed to convert %s live event: %v", msg.Type, err)
+	return msg
+}
+
+// Stream handles GET /events
+// Pushes the user's changes as Server-Sent Events until the client disconnects or its credentials
+// are no longer valid
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sub := h.hub.Subscribe(userID)
+	if sub == nil {
+		httputil.WriteError(w, http.StatusTooManyRequests, "Too many open event streams")
+		return
+	}
+	defer sub.Close()
+
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	if err := controller.Flush(); err != nil {
+		w.Header().Del("Content-Type")
+		httputil.WriteError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+	// The stream outlives any server write timeout
+	controller.SetWriteDeadline(time.Time{})
+
+	fmt.Fprintf(w, "retry: %d\n\n", RetryDelay.Milliseconds())
+	if err := WriteEvent(w, Message{UserID: userID, Type: "ready", Data: []byte("{}")}); err != nil {
+		return
+	}
+	controller.Flush()
+
+	// Values are sent in the subscriber's units
+	prefs := units.FromRequest(r)
+
+	keepAlive := time.NewTicker(KeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects and reloads
+				return
+			}
+			if err := WriteEvent(w, h.convert(msg, prefs)); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			// The token may have expired or been revoked, or the account disabled, since the
+			// stream opened; the client then reconnects and is refused
+			if err := auth.Revalidate(r); err != nil {
+				return
+			}
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// WriteEvent writes a message in the Server-Sent Events format, its type as the event name
+func WriteEvent(w io.Writer, msg Message) error {
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, msg.Data)
+	return err
+}
@@ -0,0 +1,172 @@
+package live
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// Event types pushed to the stream
+const (
+	EventEntryCreated    = "diary.entry.created"
+	EventEntryUpdated    = "diary.entry.updated"
+	EventEntryDeleted    = "diary.entry.deleted"
+	EventEntriesImported = "diary.entries.imported"
+	EventSummaryUpdated  = "diary.summary.updated"
+	EventMetricRecorded  = "metric.recorded"
+	EventMetricsImported = "metrics.imported"
+)
+
+// disconnectEvent is a control message closing every subscription of its user instead of being
+// delivered, so that their clients reconnect and authenticate again
+const disconnectEvent = "live.disconnect"
+
+// Subscription limits
+const (
+	BufferSize              = 32 // Messages a subscriber may fall behind before it is dropped
+	MaxSubscriptionsPerUser = 10
+)
+
+// Message is a change pushed to the streams of a user
+type Message struct {
+	UserID uint            `json:"user_id"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Fanout sends messages to every instance of the API, which then deliver them with Hub.Deliver
+type Fanout interface {
+	Send(msg Message) error
+}
+
+// Hub is an in-process publish/subscribe hub keyed by user
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[uint]map[*Subscription]struct{}
+	fanout      Fanout
+}
+
+// Subscription receives the messages of one user until it is closed
+// C is closed when the subscriber fell too far behind and was dropped
+type Subscription struct {
+	C      <-chan Message
+	ch     chan Message
+	userID uint
+	hub    *Hub
+}
+
+// NewHub creates a new hub delivering messages in process
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[uint]map[*Subscription]struct{})}
+}
+
+// SetFanout sets the fanout used to reach the subscribers of other instances
+func (h *Hub) SetFanout(fanout Fanout) {
+	h.fanout = fanout
+}
+
+// Subscribe opens a subscription to a user's messages
+// Returns nil when the user already has MaxSubscriptionsPerUser subscriptions
+func (h *Hub) Subscribe(userID uint) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.subscribers[userID]
+	if len(subs) >= MaxSubscriptionsPerUser {
+		return nil
+	}
+	if subs == nil {
+		subs = make(map[*Subscription]struct{})
+		h.subscribers[userID] = subs
+	}
+
+	ch := make(chan Message, BufferSize)
+	sub := &Subscription{C: ch, ch: ch, userID: userID, hub: h}
+	subs[sub] = struct{}{}
+	return sub
+}
+
+// Close ends the subscription; closing it twice is harmless
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// remove unregisters a subscription and closes its channel; the caller holds the lock
+func (h *Hub) remove(s *Subscription) {
+	subs := h.subscribers[s.userID]
+	if _, ok := subs[s]; !ok {
+		return
+	}
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.subscribers, s.userID)
+	}
+	close(s.ch)
+}
+
+// Publish sends a change of a user, with data as its JSON payload, to the user's subscribers
+// on every instance. Failures are logged: the change was already saved. A nil hub does nothing
+func (h *Hub) Publish(userID uint, eventType string, data interface{}) {
+	if h == nil {
+		return
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to encode %s live event: %v", eventType, err)
+		return
+	}
+	h.send(Message{UserID: userID, Type: eventType, Data: payload})
+}
+
+// Disconnect closes the subscriptions of a user on every instance, e.g. once their account was
+// disabled or a token revoked: streams that are still allowed reconnect, the others are refused.
+// It implements auth.SessionCloser; a nil hub does nothing
+func (h *Hub) Disconnect(userID uint) {
+	if h == nil {
+		return
+	}
+	h.send(Message{UserID: userID, Type: disconnectEvent, Data: []byte("{}")})
+}
+
+// send delivers a message through the fanout, or in process without one
+func (h *Hub) send(msg Message) {
+	if h.fanout != nil {
+		err := h.fanout.Send(msg)
+		if err == nil {
+			return
+		}
+		// Subscribers of this instance still get the message
+		log.Printf("Failed to fan out %s live event: %v", msg.Type, err)
+	}
+	h.Deliver(msg)
+}
+
+// Deliver sends a message to the subscribers of this instance without blocking
+// A subscriber whose buffer is full is dropped, so that its client reconnects and reloads;
+// a disconnect message drops them all
+func (h *Hub) Deliver(msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers[msg.UserID] {
+		if msg.Type == disconnectEvent {
+			h.remove(sub)
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Subscribers returns the number of open subscriptions of a user on this instance
+func (h *Hub) Subscribers(userID uint) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers[userID])
+}
@@ -0,0 +1,102 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// NotifyChannel is the Postgres channel the instances exchange messages on
+const NotifyChannel = "ultra_live"
+
+// maxNotifyPayload stays under the 8000 bytes Postgres allows in a notification
+const maxNotifyPayload = 7900
+
+// reconnectDelay is the pause before listening again after the connection was lost
+const reconnectDelay = 5 * time.Second
+
+// PostgresFanout shares messages between instances with Postgres LISTEN/NOTIFY
+// Every instance, including the sender, delivers the messages it is notified of
+type PostgresFanout struct {
+	db  *gorm.DB
+	hub *Hub
+}
+
+// NewPostgresFanout creates a fanout delivering the notifications it receives to hub
+func NewPostgresFanout(db *gorm.DB, hub *Hub) *PostgresFanout {
+	return &PostgresFanout{db: db, hub: hub}
+}
+
+// Send notifies every listening instance of a message
+func (f *PostgresFanout) Send(msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode live message: %w", err)
+	}
+	if len(payload) > maxNotifyPayload {
+		return fmt.Errorf("live message of %d bytes is too large to notify", len(payload))
+	}
+	if err := f.db.Exec("SELECT pg_notify(?, ?)", NotifyChannel, string(payload)).Error; err != nil {
+		return fmt.Errorf("failed to notify live message: %w", err)
+	}
+	return nil
+}
+
+// Listen holds a connection of the pool listening on NotifyChannel and delivers each
+// notification to the hub, until ctx is done or the connection fails
+func (f *PostgresFanout) Listen(ctx context.Context) error {
+	sqlDB, err := f.db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database: %w", err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get listen connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn interface{}) error {
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("database driver does not support LISTEN")
+		}
+		pgConn := stdConn.Conn()
+
+		if _, err := pgConn.Exec(ctx, "LISTEN "+NotifyChannel); err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		// The connection goes back to the pool
+		defer pgConn.Exec(context.Background(), "UNLISTEN "+NotifyChannel)
+
+		for {
+			notification, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to wait for notification: %w", err)
+			}
+
+			var msg Message
+			if err := json.Unmarshal([]byte(notification.Payload), &msg); err != nil {
+				log.Printf("Ignoring invalid live notification: %v", err)
+				continue
+			}
+			f.hub.Deliver(msg)
+		}
+	})
+}
+
+// Run listens for notifications, listening again after a failure; it blocks and is meant to
+// run in its own goroutine. Messages sent while the connection is down are lost
+func (f *PostgresFanout) Run() {
+	for {
+		if err := f.Listen(context.Background()); err != nil {
+			log.Printf("Live event listener stopped: %v", err)
+		}
+		time.Sleep(reconnectDelay)
+	}
+}
@@ -0,0 +1,13 @@
+package live
+
+import (
+	"net/http"
+
+	"ultra-bis/internal/auth"
+)
+
+// RegisterRoutes registers the live event stream to the provided mux
+func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
+	// Protected with JWT; EventSource clients may pass the token as ?access_token=
+	mux.HandleFunc("/events", auth.JWTMiddleware(handler.Stream))
+}
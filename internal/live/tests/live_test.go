@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/live"
+	"ultra-bis/internal/units"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_DeliversToTheUsersSubscribers(t *testing.T) {
+	hub := live.NewHub()
+	first := hub.Subscribe(1)
+	second := hub.Subscribe(1)
+	other := hub.Subscribe(2)
+	defer first.Close()
+	defer second.Close()
+	defer other.Close()
+
+	hub.Publish(1, live.EventMetricRecorded, map[string]float64{"weight": 80.4})
+
+	for _, sub := range []*live.Subscription{first, second} {
+		select {
+		case msg := <-sub.C:
+			assert.Equal(t, live.EventMetricRecorded, msg.Type)
+			assert.Equal(t, uint(1), msg.UserID)
+			assert.JSONEq(t, `{"weight":80.4}`, string(msg.Data))
+		default:
+			t.Fatal("expected a message")
+		}
+	}
+	assert.Empty(t, other.C)
+}
+
+func TestHub_SubscriptionLimits(t *testing.T) {
+	hub := live.NewHub()
+
+	var subs []*live.Subscription
+	for i := 0; i < live.MaxSubscriptionsPerUser; i++ {
+		sub := hub.Subscribe(1)
+		require.NotNil(t, sub)
+		subs = append(subs, sub)
+	}
+	assert.Nil(t, hub.Subscribe(1))
+	assert.NotNil(t, hub.Subscribe(2))
+
+	subs[0].Close()
+	subs[0].Close() // Closing twice is harmless
+	assert.Equal(t, live.MaxSubscriptionsPerUser-1, hub.Subscribers(1))
+	assert.NotNil(t, hub.Subscribe(1))
+}
+
+func TestHub_DropsSlowSubscribers(t *testing.T) {
+	hub := live.NewHub()
+	sub := hub.Subscribe(1)
+
+	for i := 0; i <= live.BufferSize; i++ {
+		hub.Publish(1, live.EventEntryCreated, i)
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	assert.Equal(t, live.BufferSize, received)
+	assert.Zero(t, hub.Subscribers(1))
+	sub.Close()
+}
+
+// stubFanout records the messages it sends and fails when err is set
+type stubFanout struct {
+	sent []live.Message
+	err  error
+}
+
+func (f *stubFanout) Send(msg live.Message) error {
+	f.sent = append(f.sent, msg)
+	return f.err
+}
+
+func TestHub_Fanout(t *testing.T) {
+	hub := live.NewHub()
+	fanout := &stubFanout{}
+	hub.SetFanout(fanout)
+	sub := hub.Subscribe(1)
+	defer sub.Close()
+
+	// The fanout delivers to every instance, this one included
+	hub.Publish(1, live.EventEntryDeleted, map[string]int{"id": 3})
+	require.Len(t, fanout.sent, 1)
+	assert.Empty(t, sub.C)
+
+	// Without the fanout, local subscribers still get the message
+	fanout.err = errors.New("connection refused")
+	hub.Publish(1, live.EventEntryDeleted, map[string]int{"id": 4})
+	assert.Len(t, sub.C, 1)
+
+	// A nil hub does nothing
+	var none *live.Hub
+	none.Publish(1, live.EventEntryCreated, nil)
+}
+
+func TestHub_Disconnect(t *testing.T) {
+	hub := live.NewHub()
+	fanout := &stubFanout{}
+	hub.SetFanout(fanout)
+	first := hub.Subscribe(1)
+	second := hub.Subscribe(1)
+	other := hub.Subscribe(2)
+	defer other.Close()
+
+	// The disconnect reaches every instance through the fanout, which delivers it here
+	hub.Disconnect(1)
+	require.Len(t, fanout.sent, 1)
+	hub.Deliver(fanout.sent[0])
+
+	for _, sub := range []*live.Subscription{first, second} {
+		_, open := <-sub.C
+		assert.False(t, open, "the subscription should be closed, not sent a message")
+		sub.Close()
+	}
+	assert.Zero(t, hub.Subscribers(1))
+	assert.Equal(t, 1, hub.Subscribers(2))
+	assert.Empty(t, other.C)
+
+	var none *live.Hub
+	none.Disconnect(1)
+}
+
+func TestWriteEvent(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, live.WriteEvent(&buf, live.Message{Type: live.EventSummaryUpdated, Data: []byte(`{"total_calories":1850}`)}))
+	assert.Equal(t, "event: diary.summary.updated\ndata: {\"total_calories\":1850}\n\n", buf.String())
+}
+
+func TestStream(t *testing.T) {
+	hub := live.NewHub()
+	handler := live.NewHandler(hub)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.Stream(w, r.WithContext(httputil.SetUserID(r.Context(), 1)))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	assert.Equal(t, "retry: 3000", readEvent(t, reader))
+	assert.Equal(t, "event: ready\ndata: {}", readEvent(t, reader))
+
+	// The stream is subscribed once the ready event was sent
+	require.Eventually(t, func() bool { return hub.Subscribers(1) == 1 }, time.Second, 10*time.Millisecond)
+	hub.Publish(2, live.EventMetricRecorded, map[string]float64{"weight": 60})
+	hub.Publish(1, live.EventMetricRecorded, map[string]float64{"weight": 80.4})
+	assert.Equal(t, "event: metric.recorded\ndata: {\"weight\":80.4}", readEvent(t, reader))
+
+	// Closing the connection ends the subscription
+	resp.Body.Close()
+	require.Eventually(t, func() bool { return hub.Subscribers(1) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+// readEvent reads the lines of the next event, up to the blank line ending it
+func readEvent(t *testing.T, reader *bufio.Reader) string {
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestStream_ConvertsToTheSubscribersUnits(t *testing.T) {
+	hub := live.NewHub()
+	handler := live.NewHandler(hub)
+	handler.SetConverters(map[string]live.Converter{
+		live.EventMetricRecorded: func(data json.RawMessage, prefs units.Preferences) (interface{}, error) {
+			var metric struct {
+				Weight float64 `json:"weight"`
+			}
+			if err := json.Unmarshal(data, &metric); err != nil {
+				return nil, err
+			}
+			metric.Weight = prefs.WeightFromKg(metric.Weight)
+			return metric, nil
+		},
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := httputil.SetUserID(r.Context(), 1)
+		ctx = units.WithPreferences(ctx, units.Imperial)
+		handler.Stream(w, r.WithContext(ctx))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	readEvent(t, reader) // retry
+	readEvent(t, reader) // ready
+
+	require.Eventually(t, func() bool { return hub.Subscribers(1) == 1 }, time.Second, 10*time.Millisecond)
+	hub.Publish(1, live.EventMetricRecorded, map[string]float64{"weight": 100})
+	assert.Equal(t, "event: metric.recorded\ndata: {\"weight\":220.46}", readEvent(t, reader))
+}
@@ -1,9 +1,16 @@
 package metrics
 
 import (
+	"ultra-bis/internal/calendar"
 	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/live"
+	"ultra-bis/internal/units"
+	"ultra-bis/internal/user"
+	"ultra-bis/internal/webhook"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
 	"strconv"
@@ -13,7 +20,10 @@ import (
 
 // Handler handles body metrics requests
 type Handler struct {
-	repo *Repository
+	repo     *Repository
+	userRepo *user.Repository
+	webhooks *webhook.Publisher
+	live     *live.Hub
 }
 
 // NewHandler creates a new metrics handler
@@ -21,6 +31,21 @@ func NewHandler(repo *Repository) *Handler {
 	return &Handler{repo: repo}
 }
 
+// SetUserRepo sets the user repository used to estimate body fat from the profile's gender and height
+func (h *Handler) SetUserRepo(userRepo *user.Repository) {
+	h.userRepo = userRepo
+}
+
+// SetWebhookPublisher sets the publisher of metric.recorded events
+func (h *Handler) SetWebhookPublisher(publisher *webhook.Publisher) {
+	h.webhooks = publisher
+}
+
+// SetLiveHub sets the hub pushing new weigh-ins to the user's event streams
+func (h *Handler) SetLiveHub(hub *live.Hub) {
+	h.live = hub
+}
+
 
 // CreateMetric handles POST /metrics
 func (h *Handler) CreateMetric(w http.ResponseWriter, r *http.Request) {
@@ -47,10 +72,14 @@ func (h *Handler) CreateMetric(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Weights are given in the user's unit and stored in kg
+	prefs := units.FromRequest(r)
+	weight := prefs.WeightToKg(req.Weight)
+
 	// Parse date
 	var metricDate time.Time
 	if req.Date == "" {
-		metricDate = time.Now()
+		metricDate = calendar.FromRequest(r).Today()
 	} else {
 		var err error
 		metricDate, err = time.Parse("2006-01-02", req.Date)
@@ -69,24 +98,30 @@ func (h *Handler) CreateMetric(w http.ResponseWriter, r *http.Request) {
 
 	if existingMetric != nil {
 		// Update existing metric
-		existingMetric.Weight = req.Weight
+		existingMetric.Weight = weight
 		if err := h.repo.Update(existingMetric); err != nil {
 			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		h.webhooks.Notify(userID, webhook.EventMetricRecorded, existingMetric)
+		h.live.Publish(userID, live.EventMetricRecorded, existingMetric)
+		existingMetric.ConvertUnits(prefs)
 		httputil.WriteJSON(w, http.StatusOK, existingMetric)
 	} else {
 		// Create new metric
 		metric := &BodyMetric{
 			UserID: userID,
 			Date:   metricDate,
-			Weight: req.Weight,
+			Weight: weight,
 		}
 
 		if err := h.repo.Create(metric); err != nil {
 			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		h.webhooks.Notify(userID, webhook.EventMetricRecorded, metric)
+		h.live.Publish(userID, live.EventMetricRecorded, metric)
+		metric.ConvertUnits(prefs)
 		httputil.WriteJSON(w, http.StatusCreated, metric)
 	}
 }
@@ -110,6 +145,7 @@ func (h *Handler) GetMetrics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ConvertMetricUnits(metrics, units.FromRequest(r))
 	httputil.WriteJSON(w, http.StatusOK, metrics)
 }
 
@@ -132,6 +168,7 @@ func (h *Handler) GetLatest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	metric.ConvertUnits(units.FromRequest(r))
 	httputil.WriteJSON(w, http.StatusOK, metric)
 }
 
@@ -148,12 +185,14 @@ func (h *Handler) GetWeekly(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	metrics, err := h.repo.GetWeekly(userID)
+	settings := calendar.FromRequest(r)
+	metrics, err := h.repo.GetWeekly(userID, settings.StartOfWeek(settings.Today()))
 	if err != nil {
 		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	ConvertMetricUnits(metrics, units.FromRequest(r))
 	httputil.WriteJSON(w, http.StatusOK, metrics)
 }
 
@@ -197,10 +236,11 @@ func (h *Handler) GetByDate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	metric.ConvertUnits(units.FromRequest(r))
 	httputil.WriteJSON(w, http.StatusOK, metric)
 }
 
-// GetTrends handles GET /metrics/trends?period=7d|30d|90d
+// GetTrends handles GET /metrics/trends?period=<N>d or ?from=YYYY-MM-DD&to=YYYY-MM-DD, with an optional target_weight
 func (h *Handler) GetTrends(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -213,29 +253,59 @@ func (h *Handler) GetTrends(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	period := r.URL.Query().Get("period")
-	if period == "" {
-		period = "30d"
-	}
+	query := r.URL.Query()
+	prefs := units.FromRequest(r)
 
-	// Calculate date range
-	var days int
-	switch period {
-	case "7d":
-		days = 7
-	case "30d":
-		days = 30
-	case "90d":
-		days = 90
-	default:
-		httputil.WriteError(w, http.StatusBadRequest, "Invalid period (use 7d, 30d, or 90d)")
-		return
+	// Calculate date range, days end in the user's time zone
+	var startDate, endDate time.Time
+	period := query.Get("period")
+	if query.Get("from") != "" {
+		var err error
+		startDate, err = time.Parse("2006-01-02", query.Get("from"))
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "from is required (use YYYY-MM-DD)")
+			return
+		}
+		endDate, err = time.Parse("2006-01-02", query.Get("to"))
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "to is required (use YYYY-MM-DD)")
+			return
+		}
+		if endDate.Before(startDate) {
+			httputil.WriteError(w, http.StatusBadRequest, "to must not be before from")
+			return
+		}
+		if endDate.Sub(startDate) > MaxTrendDays*24*time.Hour {
+			httputil.WriteError(w, http.StatusBadRequest, "Trend range is limited to 3660 days")
+			return
+		}
+		period = "custom"
+	} else {
+		if period == "" {
+			period = "30d"
+		}
+		days, err := strconv.Atoi(strings.TrimSuffix(period, "d"))
+		if err != nil || !strings.HasSuffix(period, "d") || days < 1 || days > MaxTrendDays {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid period (use a number of days such as 7d, 30d or 90d)")
+			return
+		}
+		endDate = calendar.FromRequest(r).Today()
+		startDate = endDate.AddDate(0, 0, -days)
 	}
 
-	endDate := time.Now()
-	startDate := endDate.AddDate(0, 0, -days)
+	// The target weight is given in the user's unit
+	var targetWeight float64
+	if targetStr := query.Get("target_weight"); targetStr != "" {
+		target, err := strconv.ParseFloat(targetStr, 64)
+		if err != nil || target <= 0 {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid target_weight")
+			return
+		}
+		targetWeight = prefs.WeightToKg(target)
+	}
 
-	metrics, err := h.repo.GetByDateRange(userID, startDate, endDate)
+	// GetByDateRange includes endDate
+	metrics, err := h.repo.GetByDateRange(userID, startDate, endDate.AddDate(0, 0, 1).Add(-time.Nanosecond))
 	if err != nil {
 		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -246,13 +316,18 @@ func (h *Handler) GetTrends(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Calculate trends
-	trend := calculateTrend(metrics)
+	// Calculate trends in kg, then convert to the user's unit
+	trend := CalculateTrend(metrics, targetWeight)
+	trend.ConvertUnits(prefs)
+	ConvertMetricUnits(metrics, prefs)
 
 	response := TrendResponse{
 		Period:  period,
+		From:    startDate.Format("2006-01-02"),
+		To:      endDate.Format("2006-01-02"),
 		Metrics: metrics,
 		Trend:   trend,
+		Units:   prefs,
 	}
 
 	httputil.WriteJSON(w, http.StatusOK, response)
@@ -285,26 +360,305 @@ func (h *Handler) DeleteMetric(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// calculateTrend calculates trend data from metrics
-func calculateTrend(metrics []BodyMetric) TrendData {
-	if len(metrics) == 0 {
-		return TrendData{}
+// CreateMeasurement handles POST /metrics/measurements
+// The values given are merged into the day's measurements
+func (h *Handler) CreateMeasurement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req MeasurementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := ValidateMeasurementRequest(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse date
+	var date time.Time
+	if req.Date == "" {
+		date = calendar.FromRequest(r).Today()
+	} else {
+		var err error
+		date, err = time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
+			return
+		}
+	}
+
+	measurement, err := h.repo.GetMeasurementByDate(userID, date)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 
-	var totalWeight float64
-	for _, m := range metrics {
-		totalWeight += m.Weight
+	status := http.StatusOK
+	if measurement == nil {
+		measurement = &BodyMeasurement{UserID: userID, Date: date}
+		status = http.StatusCreated
+	}
+
+	// Circumferences are given in the user's unit and stored in cm
+	prefs := units.FromRequest(r)
+	measurement.Merge(&req, prefs)
+
+	// Estimate body fat from the circumferences when it was not measured
+	if h.userRepo != nil {
+		if profile, err := h.userRepo.GetByID(userID); err == nil {
+			measurement.EstimateBodyFat(profile.Gender, profile.Height)
+		}
+	}
+
+	if err := h.repo.SaveMeasurement(measurement); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	measurement.ConvertUnits(prefs)
+	httputil.WriteJSON(w, status, measurement)
+}
+
+// GetMeasurements handles GET /metrics/measurements?from=YYYY-MM-DD&to=YYYY-MM-DD
+// Both bounds are optional; measurements are listed most recent first
+func (h *Handler) GetMeasurements(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
 	}
 
-	avgWeight := totalWeight / float64(len(metrics))
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
 
-	// Calculate change (first to last)
-	first := metrics[0]
-	last := metrics[len(metrics)-1]
+	query := r.URL.Query()
+	var startDate time.Time
+	endDate := calendar.FromRequest(r).Today()
+	if fromStr := query.Get("from"); fromStr != "" {
+		var err error
+		startDate, err = time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
+			return
+		}
+	}
+	if toStr := query.Get("to"); toStr != "" {
+		var err error
+		endDate, err = time.Parse("2006-01-02", toStr)
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
+			return
+		}
+	}
 
-	return TrendData{
-		WeightChange:  roundToTwo(last.Weight - first.Weight),
-		AverageWeight: roundToTwo(avgWeight),
+	// GetMeasurements includes endDate
+	measurements, err := h.repo.GetMeasurements(userID, startDate, endDate.AddDate(0, 0, 1).Add(-time.Nanosecond))
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ConvertMeasurementUnits(measurements, units.FromRequest(r))
+	httputil.WriteJSON(w, http.StatusOK, measurements)
+}
+
+// DeleteMeasurement handles DELETE /metrics/measurements/{id}
+func (h *Handler) DeleteMeasurement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := extractID(r.URL.Path, "/metrics/measurements/")
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	if err := h.repo.DeleteMeasurement(uint(id), userID); err != nil {
+		httputil.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// maxImportSize limits the size of uploaded import files, as for diary imports
+const maxImportSize = 10 << 20 // 10 MB
+
+// ImportMetrics handles POST /metrics/import?mode=preview|commit&source=&aggregation=first|min|average
+// Accepts an Apple Health export.xml, a Google Fit, Withings or Garmin CSV, or any CSV with
+// date_column, weight_column and body_fat_column, either as the raw body or as the "file" field
+// of a multipart form. The file is stream-parsed and readings are aggregated per day. The default
+// preview mode reports what would be imported; commit saves the new and updated days.
+func (h *Handler) ImportMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = "preview"
+	}
+	if mode != "preview" && mode != "commit" {
+		httputil.WriteError(w, http.StatusBadRequest, "mode must be 'preview' or 'commit'")
+		return
+	}
+
+	prefs := units.FromRequest(r)
+	opts := ImportOptions{
+		Source:      ImportSource(query.Get("source")),
+		Aggregation: Aggregation(query.Get("aggregation")),
+		WeightUnit:  query.Get("weight_unit"),
+		Calendar:    calendar.FromRequest(r),
+		Columns: ColumnMapping{
+			Date:    query.Get("date_column"),
+			Weight:  query.Get("weight_column"),
+			BodyFat: query.Get("body_fat_column"),
+		},
+	}
+	if opts.Source != "" && !ValidImportSource(opts.Source) {
+		httputil.WriteError(w, http.StatusBadRequest, "source must be 'apple_health', 'google_fit', 'withings', 'garmin' or 'csv'")
+		return
+	}
+	if opts.Aggregation != "" && !ValidAggregation(opts.Aggregation) {
+		httputil.WriteError(w, http.StatusBadRequest, "aggregation must be 'first', 'min' or 'average'")
+		return
+	}
+	if opts.WeightUnit == "" {
+		opts.WeightUnit = prefs.Weight
+	}
+	if !units.ValidWeight(opts.WeightUnit) {
+		httputil.WriteError(w, http.StatusBadRequest, "weight_unit must be 'kg', 'lb' or 'st'")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	body, err := importBody(r)
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := ParseImport(body, opts)
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Compare with the values already logged on the imported days
+	existingMetrics := make(map[string]*BodyMetric)
+	existingMeasurements := make(map[string]*BodyMeasurement)
+	if len(report.Days) > 0 {
+		start, _ := time.Parse("2006-01-02", report.Days[0].Date)
+		end, _ := time.Parse("2006-01-02", report.Days[len(report.Days)-1].Date)
+		end = end.AddDate(0, 0, 1).Add(-time.Nanosecond)
+
+		metrics, err := h.repo.GetByDateRange(userID, start, end)
+		if err != nil {
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for i := range metrics {
+			date := metrics[i].Date.Format("2006-01-02")
+			if _, exists := existingMetrics[date]; !exists {
+				existingMetrics[date] = &metrics[i]
+			}
+		}
+
+		measurements, err := h.repo.GetMeasurements(userID, start, end)
+		if err != nil {
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for i := range measurements {
+			existingMeasurements[measurements[i].Date.Format("2006-01-02")] = &measurements[i]
+		}
+	}
+
+	weights := make(map[string]float64, len(existingMetrics))
+	for date, metric := range existingMetrics {
+		weights[date] = metric.Weight
+	}
+	bodyFat := make(map[string]float64)
+	for date, measurement := range existingMeasurements {
+		if measurement.BodyFat != nil {
+			bodyFat[date] = *measurement.BodyFat
+		}
+	}
+	report.MarkExisting(weights, bodyFat)
+
+	if mode == "commit" {
+		recorded, err := h.repo.SaveImport(userID, report.Days, existingMetrics, existingMeasurements)
+		if err != nil {
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		report.Committed = true
+
+		var dates []string
+		for _, day := range report.Days {
+			if day.Status != ImportStatusUnchanged {
+				dates = append(dates, day.Date)
+			}
+		}
+		for i := range recorded {
+			h.webhooks.Notify(userID, webhook.EventMetricRecorded, recorded[i])
+		}
+		if len(dates) > 0 {
+			h.live.Publish(userID, live.EventMetricsImported, MetricsImported{Days: len(dates), Dates: dates})
+		}
+	}
+
+	report.ConvertUnits(prefs)
+	httputil.WriteJSON(w, http.StatusOK, report)
+}
+
+// importBody returns the uploaded file: the "file" part of a multipart form, read as a stream, or the raw body
+func importBody(r *http.Request) (io.Reader, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.Body, nil
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("A file is required in the 'file' field")
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, errors.New("A file is required in the 'file' field")
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
 	}
 }
 
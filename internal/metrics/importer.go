@@ -0,0 +1,687 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/csvimport"
+	"ultra-bis/internal/units"
+)
+
+// ImportSource identifies the app or device an export comes from
+type ImportSource string
+
+const (
+	SourceAppleHealth ImportSource = "apple_health" // export.xml from the Health app
+	SourceGoogleFit   ImportSource = "google_fit"   // "Daily activity metrics" CSV from Google Takeout
+	SourceWithings    ImportSource = "withings"     // weight.csv from the Withings data export
+	SourceGarmin      ImportSource = "garmin"       // Weight CSV exported from Garmin Connect
+	SourceCSV         ImportSource = "csv"          // Any CSV, with columns given in a ColumnMapping
+)
+
+// ValidImportSource checks if source is a known import source
+func ValidImportSource(source ImportSource) bool {
+	switch source {
+	case SourceAppleHealth, SourceGoogleFit, SourceWithings, SourceGarmin, SourceCSV:
+		return true
+	}
+	return false
+}
+
+// Aggregation selects the value kept for a day with several readings
+type Aggregation string
+
+const (
+	AggregateFirst   Aggregation = "first"   // Earliest reading of the day, e.g. the morning weigh-in
+	AggregateMin     Aggregation = "min"     // Lowest reading of the day
+	AggregateAverage Aggregation = "average" // Average of the day's readings
+)
+
+// ValidAggregation checks if aggregation is a known aggregation rule
+func ValidAggregation(aggregation Aggregation) bool {
+	return aggregation == AggregateFirst || aggregation == AggregateMin || aggregation == AggregateAverage
+}
+
+// Import day statuses
+const (
+	ImportStatusNew       = "new"       // Nothing logged that day yet
+	ImportStatusUpdated   = "updated"   // Replaces a different value logged that day
+	ImportStatusUnchanged = "unchanged" // Same values already logged, skipped
+)
+
+// maxImportErrors limits the row errors listed in a report; Invalid still counts them all
+const maxImportErrors = 100
+
+// Apple Health record types
+const (
+	appleBodyMass = "HKQuantityTypeIdentifierBodyMass"
+	appleBodyFat  = "HKQuantityTypeIdentifierBodyFatPercentage"
+)
+
+// ColumnMapping names the columns of a generic CSV export (case-insensitive)
+type ColumnMapping struct {
+	Date    string // Date or timestamp, required
+	Weight  string // Weight, in ImportOptions.WeightUnit unless the value states its unit
+	BodyFat string // Body fat percentage
+}
+
+// ImportOptions configures how an export is read
+type ImportOptions struct {
+	Source      ImportSource      // Empty to detect it from the file
+	Aggregation Aggregation       // Empty for AggregateFirst
+	Columns     ColumnMapping     // Only for SourceCSV
+	WeightUnit  string            // "kg", "lb" or "st", for weights without a unit
+	Calendar    calendar.Settings // Days of the readings, and time zone of timestamps without an offset
+}
+
+// ImportRowError reports a row that could not be parsed
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportedDay is the aggregated readings of a day
+type ImportedDay struct {
+	Date     string   `json:"date"`               // YYYY-MM-DD
+	Readings int      `json:"readings"`           // Readings of the day, duplicates excluded
+	Weight   *float64 `json:"weight,omitempty"`   // in kg, converted to the user's unit in responses
+	BodyFat  *float64 `json:"body_fat,omitempty"` // percentage
+	Status   string   `json:"status"`
+}
+
+// ImportReport is returned by POST /metrics/import
+type ImportReport struct {
+	Source      ImportSource       `json:"source"`
+	Aggregation Aggregation        `json:"aggregation"`
+	Committed   bool               `json:"committed"`  // False for a preview
+	Readings    int                `json:"readings"`   // Readings parsed, duplicates excluded
+	Duplicates  int                `json:"duplicates"` // Readings with a timestamp already seen in the file
+	Invalid     int                `json:"invalid"`    // Rows that could not be parsed
+	New         int                `json:"new"`        // Days imported (or to be imported) with nothing logged yet
+	Updated     int                `json:"updated"`    // Days whose logged values are replaced
+	Unchanged   int                `json:"unchanged"`  // Days skipped as already logged
+	Days        []ImportedDay      `json:"days"`
+	Errors      []ImportRowError   `json:"errors,omitempty"` // The first 100 row errors
+	Units       *units.Preferences `json:"units,omitempty"`
+}
+
+// ParseImport stream-parses an export, de-duplicates readings by timestamp and aggregates them by day
+// Only the per-day aggregates are kept in memory, so large Apple Health exports can be read
+func ParseImport(r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	if opts.Aggregation == "" {
+		opts.Aggregation = AggregateFirst
+	}
+	if opts.WeightUnit == "" {
+		opts.WeightUnit = units.Kilogram
+	}
+	if opts.Calendar.Location == nil {
+		opts.Calendar = calendar.Default
+	}
+
+	buffered := bufio.NewReader(r)
+	isXML := startsWithXML(buffered)
+	if opts.Source == "" && isXML {
+		opts.Source = SourceAppleHealth
+	}
+	if opts.Source == SourceAppleHealth && !isXML {
+		return nil, fmt.Errorf("an Apple Health export.xml is expected")
+	}
+
+	p := &importParser{
+		opts:        opts,
+		report:      &ImportReport{Aggregation: opts.Aggregation},
+		days:        make(map[string]*dayReadings),
+		seenWeight:  make(map[int64]bool),
+		seenBodyFat: make(map[int64]bool),
+	}
+
+	var err error
+	if opts.Source == SourceAppleHealth {
+		err = p.parseAppleHealth(buffered)
+	} else {
+		err = p.parseCSV(buffered)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.report.Source = p.opts.Source
+	p.report.Days = p.aggregate()
+	return p.report, nil
+}
+
+// MarkExisting sets the status of each day from the values already logged, keyed by YYYY-MM-DD
+// and in kg, and counts the days that are new, updated and unchanged
+func (report *ImportReport) MarkExisting(weights, bodyFat map[string]float64) {
+	report.New, report.Updated, report.Unchanged = 0, 0, 0
+	for i := range report.Days {
+		day := &report.Days[i]
+
+		isNew, changed := true, false
+		if day.Weight != nil {
+			if existing, ok := weights[day.Date]; ok {
+				isNew = false
+				changed = changed || roundToTwo(existing) != *day.Weight
+			} else {
+				changed = true
+			}
+		}
+		if day.BodyFat != nil {
+			if existing, ok := bodyFat[day.Date]; ok {
+				isNew = false
+				changed = changed || roundToTwo(existing) != *day.BodyFat
+			} else {
+				changed = true
+			}
+		}
+
+		switch {
+		case isNew:
+			day.Status = ImportStatusNew
+			report.New++
+		case changed:
+			day.Status = ImportStatusUpdated
+			report.Updated++
+		default:
+			day.Status = ImportStatusUnchanged
+			report.Unchanged++
+		}
+	}
+}
+
+// ConvertUnits converts the imported weights to prefs and states their units
+func (report *ImportReport) ConvertUnits(prefs units.Preferences) {
+	for i := range report.Days {
+		if weight := report.Days[i].Weight; weight != nil {
+			converted := prefs.WeightFromKg(*weight)
+			report.Days[i].Weight = &converted
+		}
+	}
+	report.Units = &prefs
+}
+
+// dayReadings aggregates the readings of a day as they are parsed
+type dayReadings struct {
+	readings int
+	weight   valueAggregate
+	bodyFat  valueAggregate
+}
+
+// valueAggregate tracks what each aggregation rule needs, without keeping the readings
+type valueAggregate struct {
+	count     int
+	firstTime time.Time
+	first     float64
+	min       float64
+	sum       float64
+}
+
+// add records a reading taken at t
+func (a *valueAggregate) add(t time.Time, value float64) {
+	if a.count == 0 || t.Before(a.firstTime) {
+		a.firstTime, a.first = t, value
+	}
+	if a.count == 0 || value < a.min {
+		a.min = value
+	}
+	a.sum += value
+	a.count++
+}
+
+// result applies an aggregation rule, or returns nil without readings
+func (a *valueAggregate) result(aggregation Aggregation) *float64 {
+	if a.count == 0 {
+		return nil
+	}
+
+	var value float64
+	switch aggregation {
+	case AggregateMin:
+		value = a.min
+	case AggregateAverage:
+		value = a.sum / float64(a.count)
+	default:
+		value = a.first
+	}
+	value = roundToTwo(value)
+	return &value
+}
+
+// importParser holds the state of an import being parsed
+type importParser struct {
+	opts        ImportOptions
+	report      *ImportReport
+	days        map[string]*dayReadings
+	seenWeight  map[int64]bool
+	seenBodyFat map[int64]bool
+}
+
+// addReading records a weight (kg) and/or body fat (%) reading; 0 means absent
+func (p *importParser) addReading(t time.Time, weight, bodyFat float64) {
+	key := t.UnixNano()
+	if weight > 0 && p.seenWeight[key] {
+		weight = 0
+	}
+	if bodyFat > 0 && p.seenBodyFat[key] {
+		bodyFat = 0
+	}
+	if weight == 0 && bodyFat == 0 {
+		p.report.Duplicates++
+		return
+	}
+
+	date := p.opts.Calendar.DateOf(t).Format("2006-01-02")
+	day, ok := p.days[date]
+	if !ok {
+		day = &dayReadings{}
+		p.days[date] = day
+	}
+
+	day.readings++
+	p.report.Readings++
+	if weight > 0 {
+		p.seenWeight[key] = true
+		day.weight.add(t, weight)
+	}
+	if bodyFat > 0 {
+		p.seenBodyFat[key] = true
+		day.bodyFat.add(t, bodyFat)
+	}
+}
+
+// addError records a row that could not be parsed
+func (p *importParser) addError(line int, err error) {
+	p.report.Invalid++
+	if len(p.report.Errors) < maxImportErrors {
+		p.report.Errors = append(p.report.Errors, ImportRowError{Line: line, Error: err.Error()})
+	}
+}
+
+// aggregate builds the imported days, sorted by date
+func (p *importParser) aggregate() []ImportedDay {
+	days := make([]ImportedDay, 0, len(p.days))
+	for date, readings := range p.days {
+		days = append(days, ImportedDay{
+			Date:     date,
+			Readings: readings.readings,
+			Weight:   readings.weight.result(p.opts.Aggregation),
+			BodyFat:  readings.bodyFat.result(p.opts.Aggregation),
+			Status:   ImportStatusNew,
+		})
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days
+}
+
+// parseAppleHealth reads the weight and body fat records of an Apple Health export.xml
+func (p *importParser) parseAppleHealth(r io.Reader) error {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read Apple Health export: %w", err)
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok || element.Name.Local != "Record" {
+			continue
+		}
+
+		attrs := make(map[string]string, len(element.Attr))
+		for _, attr := range element.Attr {
+			attrs[attr.Name.Local] = attr.Value
+		}
+		if attrs["type"] != appleBodyMass && attrs["type"] != appleBodyFat {
+			continue
+		}
+
+		line, _ := decoder.InputPos()
+		if err := p.addAppleRecord(attrs); err != nil {
+			p.addError(line, err)
+		}
+	}
+}
+
+// addAppleRecord parses a BodyMass or BodyFatPercentage record
+func (p *importParser) addAppleRecord(attrs map[string]string) error {
+	t, err := p.parseTime(attrs["startDate"])
+	if err != nil {
+		return err
+	}
+	value, err := strconv.ParseFloat(attrs["value"], 64)
+	if err != nil || value <= 0 {
+		return fmt.Errorf("invalid value %q", attrs["value"])
+	}
+
+	if attrs["type"] == appleBodyFat {
+		// Body fat is exported as a fraction with unit "%"
+		if value <= 1 {
+			value *= 100
+		}
+		return p.addBodyFat(t, value)
+	}
+
+	weight, err := p.weightInKg(value, attrs["unit"])
+	if err != nil {
+		return err
+	}
+	p.addReading(t, weight, 0)
+	return nil
+}
+
+// parseCSV reads a Google Fit, Withings, Garmin or generic CSV export
+func (p *importParser) parseCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := csvimport.IndexColumns(header)
+
+	if p.opts.Source == "" {
+		source, err := detectSource(columns)
+		if err != nil {
+			return err
+		}
+		p.opts.Source = source
+	}
+	if p.opts.Source == SourceCSV {
+		if err := p.checkMapping(columns); err != nil {
+			return err
+		}
+	}
+
+	// Garmin lists each day as a line of its own, followed by that day's readings
+	var garminDay string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				p.addError(parseErr.Line, err)
+				continue
+			}
+			return fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if csvimport.IsBlankRecord(record) {
+			continue
+		}
+
+		row := csvimport.NewRow(columns, record)
+		switch p.opts.Source {
+		case SourceGoogleFit:
+			err = p.addGoogleFitRow(row)
+		case SourceWithings:
+			err = p.addWithingsRow(row)
+		case SourceGarmin:
+			if day, ok := garminDate(record); ok {
+				garminDay = day
+				continue
+			}
+			err = p.addGarminRow(row, garminDay)
+		default:
+			err = p.addMappedRow(row)
+		}
+		if err != nil {
+			p.addError(line, err)
+		}
+	}
+}
+
+// detectSource identifies a CSV export from its header
+func detectSource(columns map[string]int) (ImportSource, error) {
+	has := func(names ...string) bool {
+		for _, name := range names {
+			if _, ok := columns[name]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case has("date", "average weight (kg)"):
+		return SourceGoogleFit, nil
+	case has("date", "weight (kg)"), has("date", "weight (lb)"):
+		return SourceWithings, nil
+	case has("time", "weight", "body fat"):
+		return SourceGarmin, nil
+	default:
+		return "", fmt.Errorf("unrecognised CSV export: expected a Google Fit, Withings or Garmin export, or use source=csv with date_column")
+	}
+}
+
+// checkMapping checks that the mapped columns of a generic CSV exist
+func (p *importParser) checkMapping(columns map[string]int) error {
+	mapping := &p.opts.Columns
+	mapping.Date = strings.ToLower(strings.TrimSpace(mapping.Date))
+	mapping.Weight = strings.ToLower(strings.TrimSpace(mapping.Weight))
+	mapping.BodyFat = strings.ToLower(strings.TrimSpace(mapping.BodyFat))
+
+	if mapping.Date == "" {
+		return fmt.Errorf("date_column is required for generic CSV imports")
+	}
+	if mapping.Weight == "" && mapping.BodyFat == "" {
+		return fmt.Errorf("weight_column or body_fat_column is required for generic CSV imports")
+	}
+	for _, name := range []string{mapping.Date, mapping.Weight, mapping.BodyFat} {
+		if _, ok := columns[name]; name != "" && !ok {
+			return fmt.Errorf("column %q not found", name)
+		}
+	}
+	return nil
+}
+
+// addGoogleFitRow parses a daily row; the min aggregation uses the day's minimum weight
+func (p *importParser) addGoogleFitRow(row csvimport.Row) error {
+	t, err := p.parseTime(row.Text("date"))
+	if err != nil {
+		return err
+	}
+
+	column := "average weight (kg)"
+	if p.opts.Aggregation == AggregateMin && row.Text("min weight (kg)") != "" {
+		column = "min weight (kg)"
+	}
+	if row.Text(column) == "" {
+		return nil // Days without a weigh-in
+	}
+	weight, ok := csvimport.ParseNumber(row.Text(column))
+	if !ok || weight <= 0 {
+		return fmt.Errorf("invalid weight %q", row.Text(column))
+	}
+	p.addReading(t, weight, 0)
+	return nil
+}
+
+// addWithingsRow parses a weigh-in with its fat mass
+func (p *importParser) addWithingsRow(row csvimport.Row) error {
+	t, err := p.parseTime(row.Text("date"))
+	if err != nil {
+		return err
+	}
+
+	unit, massUnit := units.Kilogram, "kg"
+	if row.Has("weight (lb)") {
+		unit, massUnit = units.Pound, "lb"
+	}
+	weight, ok := csvimport.ParseNumber(row.Text("weight (" + massUnit + ")"))
+	if !ok || weight <= 0 {
+		return fmt.Errorf("invalid weight %q", row.Text("weight ("+massUnit+")"))
+	}
+
+	// Body fat is exported as a fat mass, in the weight's unit
+	var bodyFat float64
+	if fatMass, ok := csvimport.ParseNumber(row.Text("fat mass (" + massUnit + ")")); ok && fatMass > 0 {
+		bodyFat = roundToTwo(fatMass / weight * 100)
+	}
+
+	weight, err = p.weightInKg(weight, unit)
+	if err != nil {
+		return err
+	}
+	p.addReading(t, weight, bodyFat)
+	return nil
+}
+
+// addGarminRow parses a reading such as "7:31 AM", "80.4 kg", ..., "20.1 %"
+func (p *importParser) addGarminRow(row csvimport.Row, day string) error {
+	timeText := row.Text("time")
+	t, err := p.parseTime(timeText)
+	if err != nil {
+		if day == "" {
+			return err
+		}
+		t, err = p.parseTime(day + " " + timeText)
+		if err != nil {
+			return err
+		}
+	}
+
+	weight, err := p.parseWeight(row.Text("weight"))
+	if err != nil {
+		return err
+	}
+	bodyFat, _ := csvimport.ParseNumber(strings.TrimSuffix(row.Text("body fat"), "%"))
+	if bodyFat < 0 || bodyFat >= 100 {
+		bodyFat = 0
+	}
+	p.addReading(t, weight, bodyFat)
+	return nil
+}
+
+// addMappedRow parses a row of a generic CSV with the column mapping
+func (p *importParser) addMappedRow(row csvimport.Row) error {
+	mapping := p.opts.Columns
+	t, err := p.parseTime(row.Text(mapping.Date))
+	if err != nil {
+		return err
+	}
+
+	var weight, bodyFat float64
+	if mapping.Weight != "" && row.Text(mapping.Weight) != "" {
+		if weight, err = p.parseWeight(row.Text(mapping.Weight)); err != nil {
+			return err
+		}
+	}
+	if mapping.BodyFat != "" && row.Text(mapping.BodyFat) != "" {
+		value, ok := csvimport.ParseNumber(strings.TrimSuffix(row.Text(mapping.BodyFat), "%"))
+		if !ok || value <= 0 || value >= 100 {
+			return fmt.Errorf("invalid body fat %q", row.Text(mapping.BodyFat))
+		}
+		bodyFat = value
+	}
+	if weight == 0 && bodyFat == 0 {
+		return nil
+	}
+	p.addReading(t, weight, bodyFat)
+	return nil
+}
+
+// addBodyFat records a body fat reading after checking it is a percentage
+func (p *importParser) addBodyFat(t time.Time, bodyFat float64) error {
+	if bodyFat <= 0 || bodyFat >= 100 {
+		return fmt.Errorf("invalid body fat %.2f", bodyFat)
+	}
+	p.addReading(t, 0, roundToTwo(bodyFat))
+	return nil
+}
+
+// parseWeight parses a weight such as "80.4", "80.4 kg" or "177.2 lbs" and converts it to kg
+func (p *importParser) parseWeight(text string) (float64, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, fmt.Errorf("invalid weight %q", text)
+	}
+	value, ok := csvimport.ParseNumber(fields[0])
+	if !ok || value <= 0 {
+		return 0, fmt.Errorf("invalid weight %q", text)
+	}
+
+	unit := ""
+	if len(fields) == 2 {
+		unit = fields[1]
+	}
+	return p.weightInKg(value, unit)
+}
+
+// weightInKg converts a weight in unit, or in the import's weight unit when unit is empty
+func (p *importParser) weightInKg(value float64, unit string) (float64, error) {
+	switch strings.ToLower(strings.TrimSpace(unit)) {
+	case "":
+		unit = p.opts.WeightUnit
+	case "kg", "kgs":
+		unit = units.Kilogram
+	case "lb", "lbs", "pound", "pounds":
+		unit = units.Pound
+	case "st":
+		unit = units.Stone
+	default:
+		return 0, fmt.Errorf("unsupported weight unit: %s", unit)
+	}
+	return roundToTwo(units.Preferences{Weight: unit}.WeightToKg(value)), nil
+}
+
+// timestampLayouts are the date and time formats found in exports
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05 -0700", // Apple Health
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"Jan 2, 2006 3:04 PM", // Garmin day line followed by a time
+	"Jan 2, 2006",
+}
+
+// parseTime parses a timestamp; those without an offset are in the user's time zone
+func (p *importParser) parseTime(text string) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, text, p.opts.Calendar.Location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", text)
+}
+
+// garminDate returns the day of a Garmin day line such as " Jan 2, 2024"
+func garminDate(record []string) (string, bool) {
+	for _, field := range record[1:] {
+		if strings.TrimSpace(field) != "" {
+			return "", false
+		}
+	}
+	day := strings.TrimSpace(record[0])
+	if _, err := time.Parse("Jan 2, 2006", day); err != nil {
+		return "", false
+	}
+	return day, true
+}
+
+// startsWithXML reports whether the input opens with an XML tag, after an optional byte order mark and blanks
+func startsWithXML(r *bufio.Reader) bool {
+	peeked, _ := r.Peek(512)
+	text := strings.TrimLeft(strings.TrimPrefix(string(peeked), "\ufeff"), " \t\r\n")
+	return strings.HasPrefix(text, "<")
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"encoding/json"
+
+	"ultra-bis/internal/live"
+	"ultra-bis/internal/units"
+)
+
+// MetricsImported is the data of a metrics.imported event; clients reload the imported days
+type MetricsImported struct {
+	Days  int      `json:"days"`
+	Dates []string `json:"dates"`
+}
+
+// LiveConverters convert the metrics' live events to the units of each stream
+var LiveConverters = map[string]live.Converter{
+	live.EventMetricRecorded: func(data json.RawMessage, prefs units.Preferences) (interface{}, error) {
+		var metric BodyMetric
+		if err := json.Unmarshal(data, &metric); err != nil {
+			return nil, err
+		}
+		metric.ConvertUnits(prefs)
+		return metric, nil
+	},
+}
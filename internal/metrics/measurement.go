@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+
+	"ultra-bis/internal/units"
+)
+
+// ValidateMeasurementRequest checks that a measurement request sets at least one plausible value
+func ValidateMeasurementRequest(req *MeasurementRequest) error {
+	circumferences := []*float64{req.Waist, req.Hips, req.Chest, req.Arm, req.Thigh, req.Neck}
+
+	empty := req.BodyFat == nil && req.RestingHeartRate == nil
+	for _, value := range circumferences {
+		if value != nil {
+			empty = false
+			if *value <= 0 {
+				return errors.New("Measurements must be greater than 0")
+			}
+		}
+	}
+	if empty {
+		return errors.New("At least one measurement is required")
+	}
+
+	if req.BodyFat != nil && (*req.BodyFat <= 0 || *req.BodyFat >= 100) {
+		return errors.New("Body fat must be between 0 and 100")
+	}
+	if req.RestingHeartRate != nil && (*req.RestingHeartRate < 20 || *req.RestingHeartRate > 250) {
+		return errors.New("Resting heart rate must be between 20 and 250")
+	}
+	return nil
+}
+
+// Merge sets the values given in req, converting circumferences from prefs to cm
+func (m *BodyMeasurement) Merge(req *MeasurementRequest, prefs units.Preferences) {
+	toCm := func(value *float64) *float64 {
+		cm := prefs.HeightToCm(*value)
+		return &cm
+	}
+
+	fields := []struct {
+		target **float64
+		value  *float64
+	}{
+		{&m.Waist, req.Waist}, {&m.Hips, req.Hips}, {&m.Chest, req.Chest},
+		{&m.Arm, req.Arm}, {&m.Thigh, req.Thigh}, {&m.Neck, req.Neck},
+	}
+	for _, field := range fields {
+		if field.value != nil {
+			*field.target = toCm(field.value)
+		}
+	}
+
+	if req.BodyFat != nil {
+		bodyFat := *req.BodyFat
+		m.BodyFat = &bodyFat
+		m.BodyFatSource = BodyFatMeasured
+	}
+	if req.RestingHeartRate != nil {
+		heartRate := *req.RestingHeartRate
+		m.RestingHeartRate = &heartRate
+	}
+}
+
+// EstimateBodyFat sets the body fat from the circumferences with the US Navy method,
+// unless it was measured. gender is "male" or "female" and height is in cm.
+func (m *BodyMeasurement) EstimateBodyFat(gender string, height float64) {
+	if m.BodyFatSource == BodyFatMeasured || m.Waist == nil || m.Neck == nil {
+		return
+	}
+
+	var hips float64
+	if m.Hips != nil {
+		hips = *m.Hips
+	}
+	bodyFat, ok := NavyBodyFat(gender, height, *m.Waist, *m.Neck, hips)
+	if !ok {
+		return
+	}
+	m.BodyFat = &bodyFat
+	m.BodyFatSource = BodyFatNavy
+}
+
+// NavyBodyFat estimates the body fat percentage with the US Navy circumference method
+// All lengths are in cm; hips are only used for women. ok is false when the inputs
+// do not allow an estimate (unknown gender, missing values or implausible result).
+func NavyBodyFat(gender string, height, waist, neck, hips float64) (bodyFat float64, ok bool) {
+	if height <= 0 || waist <= 0 || neck <= 0 {
+		return 0, false
+	}
+
+	switch gender {
+	case "male":
+		if waist <= neck {
+			return 0, false
+		}
+		bodyFat = 495/(1.0324-0.19077*math.Log10(waist-neck)+0.15456*math.Log10(height)) - 450
+	case "female":
+		if hips <= 0 || waist+hips <= neck {
+			return 0, false
+		}
+		bodyFat = 495/(1.29579-0.35004*math.Log10(waist+hips-neck)+0.22100*math.Log10(height)) - 450
+	default:
+		return 0, false
+	}
+
+	if bodyFat <= 0 || bodyFat >= 100 {
+		return 0, false
+	}
+	return roundToTwo(bodyFat), true
+}
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+
+	"ultra-bis/internal/units"
 )
 
 // BodyMetric represents a daily body weight entry
@@ -14,24 +16,149 @@ type BodyMetric struct {
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 	UserID    uint           `json:"user_id" gorm:"not null;index:idx_user_date"`
 	Date      time.Time      `json:"date" gorm:"not null;index:idx_user_date"`
-	Weight    float64        `json:"weight" gorm:"type:decimal(5,2);not null"` // in kg
+	Weight    float64        `json:"weight" gorm:"type:decimal(5,2);not null"` // in kg, converted to the user's unit in responses
+
+	Units *units.Preferences `json:"units,omitempty" gorm:"-"` // Units of the response values
+}
+
+// ConvertUnits converts the weight to prefs and states its units
+func (m *BodyMetric) ConvertUnits(prefs units.Preferences) {
+	m.Weight = prefs.WeightFromKg(m.Weight)
+	m.Units = &prefs
+}
+
+// ConvertMetricUnits converts a list of metrics to prefs
+func ConvertMetricUnits(metrics []BodyMetric, prefs units.Preferences) {
+	for i := range metrics {
+		metrics[i].ConvertUnits(prefs)
+	}
 }
 
 // CreateMetricRequest represents the request to create a body weight entry
 type CreateMetricRequest struct {
 	Date   string  `json:"date"`   // YYYY-MM-DD format, defaults to today
-	Weight float64 `json:"weight"` // in kg, required
+	Weight float64 `json:"weight"` // in the user's weight unit, required
 }
 
 // TrendResponse represents weight trend data over a period
 type TrendResponse struct {
-	Period  string       `json:"period"`  // "7d", "30d", "90d"
-	Metrics []BodyMetric `json:"metrics"`
-	Trend   TrendData    `json:"trend"`
+	Period  string            `json:"period"` // "<N>d", or "custom" when from and to are given
+	From    string            `json:"from"`   // YYYY-MM-DD
+	To      string            `json:"to"`     // YYYY-MM-DD
+	Metrics []BodyMetric      `json:"metrics"`
+	Trend   TrendData         `json:"trend"`
+	Units   units.Preferences `json:"units"`
 }
 
 // TrendData represents calculated weight trend information
 type TrendData struct {
-	WeightChange  float64 `json:"weight_change"`  // Change from first to last entry (kg)
-	AverageWeight float64 `json:"average_weight"` // Average weight over period (kg)
+	WeightChange  float64      `json:"weight_change"`      // Change from first to last entry
+	AverageWeight float64      `json:"average_weight"`     // Average weight over period
+	TrendWeight   float64      `json:"trend_weight"`       // Smoothed weight at the last entry
+	WeeklyRate    float64      `json:"weekly_rate"`        // Regression slope per week, negative when losing
+	Points        []TrendPoint `json:"points"`             // One point per entry
+	Forecast      *Forecast    `json:"forecast,omitempty"` // Only when a target weight is given
+}
+
+// TrendPoint represents the smoothed values at one entry
+type TrendPoint struct {
+	Date            string  `json:"date"` // YYYY-MM-DD
+	Weight          float64 `json:"weight"`
+	Trend           float64 `json:"trend"`             // Exponentially smoothed weight
+	MovingAverage7  float64 `json:"moving_average_7"`  // Average of the entries of the last 7 days
+	MovingAverage14 float64 `json:"moving_average_14"` // Average of the entries of the last 14 days
+}
+
+// Forecast represents when a target weight will be reached at the current rate
+type Forecast struct {
+	TargetWeight  float64 `json:"target_weight"`
+	CurrentWeight float64 `json:"current_weight"` // Regression value at the last entry
+	Reachable     bool    `json:"reachable"`      // False when the trend moves away from the target
+	DaysToTarget  int     `json:"days_to_target,omitempty"`
+	Date          string  `json:"date,omitempty"`          // YYYY-MM-DD
+	EarliestDate  string  `json:"earliest_date,omitempty"` // 95% confidence interval bounds
+	LatestDate    string  `json:"latest_date,omitempty"`   // Empty when the target may never be reached
+}
+
+// ConvertUnits converts the trend's weights to prefs
+func (t *TrendData) ConvertUnits(prefs units.Preferences) {
+	t.WeightChange = prefs.WeightFromKg(t.WeightChange)
+	t.AverageWeight = prefs.WeightFromKg(t.AverageWeight)
+	t.TrendWeight = prefs.WeightFromKg(t.TrendWeight)
+	t.WeeklyRate = prefs.WeightFromKg(t.WeeklyRate)
+	for i := range t.Points {
+		point := &t.Points[i]
+		point.Weight = prefs.WeightFromKg(point.Weight)
+		point.Trend = prefs.WeightFromKg(point.Trend)
+		point.MovingAverage7 = prefs.WeightFromKg(point.MovingAverage7)
+		point.MovingAverage14 = prefs.WeightFromKg(point.MovingAverage14)
+	}
+	if t.Forecast != nil {
+		t.Forecast.TargetWeight = prefs.WeightFromKg(t.Forecast.TargetWeight)
+		t.Forecast.CurrentWeight = prefs.WeightFromKg(t.Forecast.CurrentWeight)
+	}
+}
+
+// Body fat sources of a measurement
+const (
+	BodyFatMeasured = "measured" // Entered by the user, e.g. from a scale or calipers
+	BodyFatNavy     = "navy"     // Estimated from circumferences with the US Navy method
+)
+
+// BodyMeasurement represents the body measurements taken on a day; every value is optional
+type BodyMeasurement struct {
+	ID               uint           `json:"id" gorm:"primarykey"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	UserID           uint           `json:"user_id" gorm:"not null;index:idx_measurement_user_date"`
+	Date             time.Time      `json:"date" gorm:"not null;index:idx_measurement_user_date"`
+	BodyFat          *float64       `json:"body_fat,omitempty" gorm:"type:decimal(5,2)"`       // percentage
+	BodyFatSource    string         `json:"body_fat_source,omitempty" gorm:"type:varchar(10)"` // "measured" or "navy"
+	Waist            *float64       `json:"waist,omitempty" gorm:"type:decimal(5,2)"`          // Circumferences in cm, converted to the user's unit in responses
+	Hips             *float64       `json:"hips,omitempty" gorm:"type:decimal(5,2)"`
+	Chest            *float64       `json:"chest,omitempty" gorm:"type:decimal(5,2)"`
+	Arm              *float64       `json:"arm,omitempty" gorm:"type:decimal(5,2)"`
+	Thigh            *float64       `json:"thigh,omitempty" gorm:"type:decimal(5,2)"`
+	Neck             *float64       `json:"neck,omitempty" gorm:"type:decimal(5,2)"`
+	RestingHeartRate *int           `json:"resting_heart_rate,omitempty"` // beats per minute
+
+	Units *units.Preferences `json:"units,omitempty" gorm:"-"` // Units of the response values
+}
+
+// circumferences lists the measurement's circumference fields
+func (m *BodyMeasurement) circumferences() []**float64 {
+	return []**float64{&m.Waist, &m.Hips, &m.Chest, &m.Arm, &m.Thigh, &m.Neck}
+}
+
+// ConvertUnits converts the circumferences to prefs and states their units
+func (m *BodyMeasurement) ConvertUnits(prefs units.Preferences) {
+	for _, field := range m.circumferences() {
+		if *field != nil {
+			value := prefs.HeightFromCm(**field)
+			*field = &value
+		}
+	}
+	m.Units = &prefs
+}
+
+// ConvertMeasurementUnits converts a list of measurements to prefs
+func ConvertMeasurementUnits(measurements []BodyMeasurement, prefs units.Preferences) {
+	for i := range measurements {
+		measurements[i].ConvertUnits(prefs)
+	}
+}
+
+// MeasurementRequest represents the request to log body measurements
+// Only the values given are set; they are merged into the day's measurements
+type MeasurementRequest struct {
+	Date             string   `json:"date"`     // YYYY-MM-DD format, defaults to today
+	BodyFat          *float64 `json:"body_fat"` // percentage, estimated from waist, neck and hips when omitted
+	Waist            *float64 `json:"waist"`    // Circumferences in the user's height unit
+	Hips             *float64 `json:"hips"`
+	Chest            *float64 `json:"chest"`
+	Arm              *float64 `json:"arm"`
+	Thigh            *float64 `json:"thigh"`
+	Neck             *float64 `json:"neck"`
+	RestingHeartRate *int     `json:"resting_heart_rate"` // beats per minute
 }
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+
+	"ultra-bis/internal/units"
 )
 
 // Repository handles database operations for body metrics
@@ -98,22 +100,10 @@ func (r *Repository) GetAll(userID uint) ([]BodyMetric, error) {
 	return metrics, nil
 }
 
-// GetWeekly retrieves metrics for the current week (starting from previous Monday)
-func (r *Repository) GetWeekly(userID uint) ([]BodyMetric, error) {
-	now := time.Now()
-
-	// Calculate the start of the week (Monday)
-	weekday := now.Weekday()
-	daysToSubtract := int(weekday - time.Monday)
-	if weekday == time.Sunday {
-		// Sunday is 0, so we need to go back 6 days to get to Monday
-		daysToSubtract = 6
-	}
-
-	startOfWeek := now.AddDate(0, 0, -daysToSubtract)
+// GetWeekly retrieves metrics for the week starting on startOfWeek
+// The caller picks the first day of the week in the user's calendar
+func (r *Repository) GetWeekly(userID uint, startOfWeek time.Time) ([]BodyMetric, error) {
 	startOfWeek = time.Date(startOfWeek.Year(), startOfWeek.Month(), startOfWeek.Day(), 0, 0, 0, 0, startOfWeek.Location())
-
-	// End of week is Sunday at 23:59:59
 	endOfWeek := startOfWeek.AddDate(0, 0, 7)
 
 	var metrics []BodyMetric
@@ -142,3 +132,134 @@ func (r *Repository) Delete(id, userID uint) error {
 
 	return nil
 }
+
+// GetMeasurementByDate retrieves the body measurements of a user on a date, or nil if none
+func (r *Repository) GetMeasurementByDate(userID uint, date time.Time) (*BodyMeasurement, error) {
+	var measurement BodyMeasurement
+	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	endOfDay := startOfDay.Add(24 * time.Hour)
+
+	result := r.db.Where("user_id = ? AND date >= ? AND date < ?", userID, startOfDay, endOfDay).First(&measurement)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get measurement by date: %w", result.Error)
+	}
+
+	return &measurement, nil
+}
+
+// SaveMeasurement creates or updates body measurements
+func (r *Repository) SaveMeasurement(measurement *BodyMeasurement) error {
+	result := r.db.Save(measurement)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save measurement: %w", result.Error)
+	}
+	return nil
+}
+
+// GetMeasurements retrieves the body measurements of a user within a date range, most recent first
+func (r *Repository) GetMeasurements(userID uint, startDate, endDate time.Time) ([]BodyMeasurement, error) {
+	var measurements []BodyMeasurement
+
+	result := r.db.Where("user_id = ? AND date >= ? AND date <= ?", userID, startDate, endDate).
+		Order("date DESC").
+		Find(&measurements)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get measurements: %w", result.Error)
+	}
+
+	return measurements, nil
+}
+
+// GetLatestBodyFat retrieves the most recent measurement with a body fat percentage, or nil if none
+func (r *Repository) GetLatestBodyFat(userID uint) (*BodyMeasurement, error) {
+	var measurement BodyMeasurement
+	result := r.db.Where("user_id = ? AND body_fat IS NOT NULL", userID).Order("date DESC").First(&measurement)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get latest body fat: %w", result.Error)
+	}
+
+	return &measurement, nil
+}
+
+// DeleteMeasurement deletes body measurements
+func (r *Repository) DeleteMeasurement(id, userID uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&BodyMeasurement{})
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete measurement: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("measurement not found")
+	}
+
+	return nil
+}
+
+// SaveImport saves the new and updated days of an import in one transaction, so that a failure
+// leaves nothing half imported; the values already logged are keyed by YYYY-MM-DD
+// Returns the weigh-ins created or changed
+func (r *Repository) SaveImport(userID uint, days []ImportedDay, metrics map[string]*BodyMetric, measurements map[string]*BodyMeasurement) ([]BodyMetric, error) {
+	var recorded []BodyMetric
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := &Repository{db: tx}
+		for _, day := range days {
+			if day.Status == ImportStatusUnchanged {
+				continue
+			}
+			metric, err := txRepo.saveImportedDay(userID, day, metrics[day.Date], measurements[day.Date])
+			if err != nil {
+				return err
+			}
+			if metric != nil {
+				recorded = append(recorded, *metric)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recorded, nil
+}
+
+// saveImportedDay upserts the weight and body fat of an imported day, returning the weigh-in it recorded, if any
+func (r *Repository) saveImportedDay(userID uint, day ImportedDay, metric *BodyMetric, measurement *BodyMeasurement) (*BodyMetric, error) {
+	date, _ := time.Parse("2006-01-02", day.Date)
+
+	var recorded *BodyMetric
+	if day.Weight != nil {
+		if metric == nil {
+			recorded = &BodyMetric{UserID: userID, Date: date, Weight: *day.Weight}
+			if err := r.Create(recorded); err != nil {
+				return nil, err
+			}
+		} else if metric.Weight != *day.Weight {
+			metric.Weight = *day.Weight
+			if err := r.Update(metric); err != nil {
+				return nil, err
+			}
+			recorded = metric
+		}
+	}
+
+	if day.BodyFat != nil {
+		if measurement == nil {
+			measurement = &BodyMeasurement{UserID: userID, Date: date}
+		}
+		measurement.Merge(&MeasurementRequest{BodyFat: day.BodyFat}, units.Metric)
+		if err := r.SaveMeasurement(measurement); err != nil {
+			return nil, err
+		}
+	}
+	return recorded, nil
+}
@@ -26,6 +26,19 @@ func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
 	mux.HandleFunc("/metrics/weekly", auth.JWTMiddleware(handler.GetWeekly))
 	mux.HandleFunc("/metrics/trends", auth.JWTMiddleware(handler.GetTrends))
 	mux.HandleFunc("/metrics/date/", auth.JWTMiddleware(handler.GetByDate))
+	mux.HandleFunc("/metrics/import", auth.JWTMiddleware(handler.ImportMetrics))
+
+	mux.HandleFunc("/metrics/measurements", auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handler.GetMeasurements(w, r)
+		case http.MethodPost:
+			handler.CreateMeasurement(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/metrics/measurements/", auth.JWTMiddleware(handler.DeleteMeasurement))
 
 	mux.HandleFunc("/metrics/", auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		if strings.TrimPrefix(r.URL.Path, "/metrics/") == "" {
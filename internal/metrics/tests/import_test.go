@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/metrics"
+	"ultra-bis/internal/units"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const appleHealthExport = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE HealthData [
+<!ELEMENT HealthData (ExportDate,Me,(Record|Workout)*)>
+]>
+<HealthData locale="en_US">
+ <ExportDate value="2025-01-10 09:00:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierStepCount" unit="count" value="1200" startDate="2025-01-06 08:00:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierBodyMass" sourceName="Scale" unit="kg" value="80.4" startDate="2025-01-06 07:31:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierBodyMass" sourceName="Phone" unit="kg" value="80.4" startDate="2025-01-06 07:31:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierBodyMass" sourceName="Scale" unit="kg" value="81.2" startDate="2025-01-06 21:05:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierBodyFatPercentage" sourceName="Scale" unit="%" value="0.215" startDate="2025-01-06 07:31:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierBodyMass" sourceName="Scale" unit="lb" value="176" startDate="2025-01-07 07:40:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierBodyMass" sourceName="Scale" unit="kg" value="oops" startDate="2025-01-08 07:40:00 +0100"/>
+</HealthData>
+`
+
+func TestParseImport_AppleHealth(t *testing.T) {
+	report, err := metrics.ParseImport(strings.NewReader(appleHealthExport), metrics.ImportOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, metrics.SourceAppleHealth, report.Source)
+	assert.Equal(t, metrics.AggregateFirst, report.Aggregation)
+	assert.Equal(t, 1, report.Duplicates)
+	assert.Equal(t, 1, report.Invalid)
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, 4, report.Readings)
+
+	require.Len(t, report.Days, 2)
+	monday := report.Days[0]
+	assert.Equal(t, "2025-01-06", monday.Date)
+	assert.Equal(t, 3, monday.Readings)
+	assert.Equal(t, 80.4, *monday.Weight)
+	assert.Equal(t, 21.5, *monday.BodyFat)
+
+	tuesday := report.Days[1]
+	assert.Equal(t, 79.83, *tuesday.Weight)
+	assert.Nil(t, tuesday.BodyFat)
+}
+
+func TestParseImport_Aggregation(t *testing.T) {
+	report, err := metrics.ParseImport(strings.NewReader(appleHealthExport), metrics.ImportOptions{Aggregation: metrics.AggregateAverage})
+	require.NoError(t, err)
+	assert.Equal(t, 80.8, *report.Days[0].Weight)
+
+	// Days follow the user's time zone: 21:05 in Paris is already Jan 7 in Tokyo
+	tokyo := calendar.New("Asia/Tokyo", "")
+	report, err = metrics.ParseImport(strings.NewReader(appleHealthExport), metrics.ImportOptions{Aggregation: metrics.AggregateMin, Calendar: tokyo})
+	require.NoError(t, err)
+	require.Len(t, report.Days, 2)
+	assert.Equal(t, 80.4, *report.Days[0].Weight)
+	assert.Equal(t, "2025-01-07", report.Days[1].Date)
+	assert.Equal(t, 2, report.Days[1].Readings)
+	assert.Equal(t, 79.83, *report.Days[1].Weight)
+}
+
+func TestParseImport_Withings(t *testing.T) {
+	csv := "Date,\"Weight (kg)\",\"Fat mass (kg)\",\"Bone mass (kg)\",\"Muscle mass (kg)\",\"Hydration (kg)\",Comments\n" +
+		"2025-01-06 07:31:00,80.00,16.00,3.2,60.1,45.2,\n" +
+		"2025-01-06 07:31:00,80.00,16.00,3.2,60.1,45.2,\n" +
+		"2025-01-07 07:29:00,\"79,6\",,,,,\n"
+
+	report, err := metrics.ParseImport(strings.NewReader(csv), metrics.ImportOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, metrics.SourceWithings, report.Source)
+	assert.Equal(t, 1, report.Duplicates)
+	require.Len(t, report.Days, 2)
+	assert.Equal(t, 80.0, *report.Days[0].Weight)
+	assert.Equal(t, 20.0, *report.Days[0].BodyFat)
+	assert.Equal(t, 79.6, *report.Days[1].Weight)
+	assert.Nil(t, report.Days[1].BodyFat)
+}
+
+func TestParseImport_GoogleFit(t *testing.T) {
+	csv := "Date,Move Minutes count,Average weight (kg),Max weight (kg),Min weight (kg)\n" +
+		"2025-01-06,35,80.5,81.0,80.1\n" +
+		"2025-01-07,20,,,\n"
+
+	report, err := metrics.ParseImport(strings.NewReader(csv), metrics.ImportOptions{Aggregation: metrics.AggregateMin})
+	require.NoError(t, err)
+
+	assert.Equal(t, metrics.SourceGoogleFit, report.Source)
+	require.Len(t, report.Days, 1)
+	assert.Equal(t, 80.1, *report.Days[0].Weight)
+}
+
+func TestParseImport_Garmin(t *testing.T) {
+	csv := "Time,Weight,Change,BMI,Body Fat,Skeletal Muscle Mass,Bone Mass,Body Water\n" +
+		"\" Jan 6, 2025\",,,,,,,\n" +
+		"7:31 AM,177.2 lbs,0.4 lbs,24.1,20.1 %,--,--,--\n" +
+		"\" Jan 7, 2025\",,,,,,,\n" +
+		"7:40 AM,176.4 lbs,-0.8 lbs,24.0,--,--,--,--\n"
+
+	report, err := metrics.ParseImport(strings.NewReader(csv), metrics.ImportOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, metrics.SourceGarmin, report.Source)
+	assert.Equal(t, 0, report.Invalid)
+	require.Len(t, report.Days, 2)
+	assert.Equal(t, "2025-01-06", report.Days[0].Date)
+	assert.Equal(t, 80.38, *report.Days[0].Weight)
+	assert.Equal(t, 20.1, *report.Days[0].BodyFat)
+	assert.Equal(t, 80.01, *report.Days[1].Weight)
+}
+
+func TestParseImport_GenericCSV(t *testing.T) {
+	csv := "when,kilos,fat\n" +
+		"2025-01-06T07:00:00Z,80.2,18.5\n" +
+		"2025-01-07T07:00:00Z,,18.2\n"
+
+	_, err := metrics.ParseImport(strings.NewReader(csv), metrics.ImportOptions{})
+	assert.Error(t, err)
+
+	_, err = metrics.ParseImport(strings.NewReader(csv), metrics.ImportOptions{Source: metrics.SourceCSV, Columns: metrics.ColumnMapping{Date: "when", Weight: "pounds"}})
+	assert.EqualError(t, err, `column "pounds" not found`)
+
+	report, err := metrics.ParseImport(strings.NewReader(csv), metrics.ImportOptions{
+		Source:     metrics.SourceCSV,
+		Columns:    metrics.ColumnMapping{Date: "When", Weight: "kilos", BodyFat: "fat"},
+		WeightUnit: units.Kilogram,
+	})
+	require.NoError(t, err)
+	require.Len(t, report.Days, 2)
+	assert.Equal(t, 80.2, *report.Days[0].Weight)
+	assert.Nil(t, report.Days[1].Weight)
+	assert.Equal(t, 18.2, *report.Days[1].BodyFat)
+}
+
+func TestImportReport_MarkExisting(t *testing.T) {
+	report, err := metrics.ParseImport(strings.NewReader(appleHealthExport), metrics.ImportOptions{})
+	require.NoError(t, err)
+
+	report.MarkExisting(map[string]float64{"2025-01-06": 80.4}, map[string]float64{"2025-01-06": 21.5})
+	assert.Equal(t, metrics.ImportStatusUnchanged, report.Days[0].Status)
+	assert.Equal(t, metrics.ImportStatusNew, report.Days[1].Status)
+	assert.Equal(t, 1, report.New)
+	assert.Equal(t, 1, report.Unchanged)
+
+	report.MarkExisting(map[string]float64{"2025-01-06": 80.4}, nil)
+	assert.Equal(t, metrics.ImportStatusUpdated, report.Days[0].Status)
+	assert.Equal(t, 1, report.Updated)
+
+	report.ConvertUnits(units.Imperial)
+	assert.Equal(t, 177.25, *report.Days[0].Weight)
+}
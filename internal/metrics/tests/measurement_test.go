@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"ultra-bis/internal/live"
+	"ultra-bis/internal/metrics"
+	"ultra-bis/internal/units"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+func TestNavyBodyFat(t *testing.T) {
+	male, ok := metrics.NavyBodyFat("male", 178, 90, 38, 0)
+	require.True(t, ok)
+	assert.InDelta(t, 20.2, male, 0.1)
+
+	female, ok := metrics.NavyBodyFat("female", 165, 75, 33, 100)
+	require.True(t, ok)
+	assert.InDelta(t, 29.4, female, 0.1)
+
+	// Women need hips, and every estimate needs a known gender and plausible lengths
+	_, ok = metrics.NavyBodyFat("female", 165, 75, 33, 0)
+	assert.False(t, ok)
+	_, ok = metrics.NavyBodyFat("", 178, 90, 38, 0)
+	assert.False(t, ok)
+	_, ok = metrics.NavyBodyFat("male", 178, 38, 40, 0)
+	assert.False(t, ok)
+}
+
+func TestBodyMeasurement_MergeAndEstimate(t *testing.T) {
+	measurement := &metrics.BodyMeasurement{}
+
+	// Circumferences in inches are stored in cm
+	measurement.Merge(&metrics.MeasurementRequest{Waist: floatPtr(35.43), Neck: floatPtr(14.96)}, units.Imperial)
+	assert.InDelta(t, 90, *measurement.Waist, 0.01)
+	assert.InDelta(t, 38, *measurement.Neck, 0.01)
+
+	measurement.EstimateBodyFat("male", 178)
+	require.NotNil(t, measurement.BodyFat)
+	assert.Equal(t, metrics.BodyFatNavy, measurement.BodyFatSource)
+	assert.InDelta(t, 20.2, *measurement.BodyFat, 0.1)
+
+	// A measured body fat is kept over the estimate
+	measurement.Merge(&metrics.MeasurementRequest{BodyFat: floatPtr(18)}, units.Metric)
+	measurement.EstimateBodyFat("male", 178)
+	assert.Equal(t, 18.0, *measurement.BodyFat)
+	assert.Equal(t, metrics.BodyFatMeasured, measurement.BodyFatSource)
+
+	measurement.ConvertUnits(units.Imperial)
+	assert.Equal(t, 35.43, *measurement.Waist)
+	assert.Equal(t, 18.0, *measurement.BodyFat)
+	assert.Nil(t, measurement.Chest)
+}
+
+func TestValidateMeasurementRequest(t *testing.T) {
+	heartRate := 55
+	assert.NoError(t, metrics.ValidateMeasurementRequest(&metrics.MeasurementRequest{RestingHeartRate: &heartRate}))
+	assert.EqualError(t, metrics.ValidateMeasurementRequest(&metrics.MeasurementRequest{}), "At least one measurement is required")
+	assert.EqualError(t, metrics.ValidateMeasurementRequest(&metrics.MeasurementRequest{Waist: floatPtr(-1)}), "Measurements must be greater than 0")
+	assert.EqualError(t, metrics.ValidateMeasurementRequest(&metrics.MeasurementRequest{BodyFat: floatPtr(120)}), "Body fat must be between 0 and 100")
+
+	heartRate = 400
+	assert.EqualError(t, metrics.ValidateMeasurementRequest(&metrics.MeasurementRequest{RestingHeartRate: &heartRate}), "Resting heart rate must be between 20 and 250")
+}
+
+func TestLiveConverters_MetricRecorded(t *testing.T) {
+	converted, err := metrics.LiveConverters[live.EventMetricRecorded](json.RawMessage(`{"id":3,"weight":80}`), units.Imperial)
+	require.NoError(t, err)
+	metric := converted.(metrics.BodyMetric)
+	assert.Equal(t, uint(3), metric.ID)
+	assert.InDelta(t, 176.37, metric.Weight, 0.01)
+}
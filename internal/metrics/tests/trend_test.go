@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ultra-bis/internal/metrics"
+	"ultra-bis/internal/units"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trendMetrics returns one entry per day from 2025-01-01
+func trendMetrics(weights ...float64) []metrics.BodyMetric {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	list := make([]metrics.BodyMetric, len(weights))
+	for i, weight := range weights {
+		list[i] = metrics.BodyMetric{Date: start.AddDate(0, 0, i), Weight: weight}
+	}
+	return list
+}
+
+func TestCalculateTrend_Smoothing(t *testing.T) {
+	trend := metrics.CalculateTrend(trendMetrics(80, 81, 79, 80), 0)
+
+	assert.Equal(t, 0.0, trend.WeightChange)
+	assert.Equal(t, 80.0, trend.AverageWeight)
+	require.Len(t, trend.Points, 4)
+
+	// Each entry moves the trend 10% of the way towards it
+	assert.Equal(t, 80.0, trend.Points[0].Trend)
+	assert.Equal(t, 80.1, trend.Points[1].Trend)
+	assert.Equal(t, 79.99, trend.Points[2].Trend)
+	assert.Equal(t, 79.99, trend.TrendWeight)
+	assert.Equal(t, 80.5, trend.Points[1].MovingAverage7)
+	assert.Equal(t, 80.0, trend.Points[3].MovingAverage14)
+	assert.Nil(t, trend.Forecast)
+}
+
+func TestCalculateTrend_MovingAverageWindow(t *testing.T) {
+	list := trendMetrics(90, 90, 90, 90, 90, 90, 90, 90, 90, 90)
+	list = append(list, metrics.BodyMetric{Date: list[9].Date.AddDate(0, 0, 7), Weight: 83})
+
+	trend := metrics.CalculateTrend(list, 0)
+
+	// Windows are calendar days: the 7-day window of the last entry holds it alone
+	last := trend.Points[len(trend.Points)-1]
+	assert.Equal(t, 83.0, last.MovingAverage7)
+	assert.Equal(t, 89.13, last.MovingAverage14)
+}
+
+func TestCalculateTrend_Forecast(t *testing.T) {
+	// Losing 0.1 kg per day with some noise
+	trend := metrics.CalculateTrend(trendMetrics(90, 89.95, 89.75, 89.7, 89.6, 89.45, 89.4, 89.35, 89.15, 89.1), 85)
+
+	assert.Equal(t, -0.71, trend.WeeklyRate)
+	require.NotNil(t, trend.Forecast)
+	forecast := trend.Forecast
+	assert.True(t, forecast.Reachable)
+	assert.InDelta(t, 41, forecast.DaysToTarget, 1)
+	assert.Equal(t, time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC).AddDate(0, 0, forecast.DaysToTarget).Format("2006-01-02"), forecast.Date)
+	assert.NotEmpty(t, forecast.EarliestDate)
+	assert.NotEmpty(t, forecast.LatestDate)
+	assert.Less(t, forecast.EarliestDate, forecast.Date)
+	assert.Greater(t, forecast.LatestDate, forecast.Date)
+}
+
+func TestCalculateTrend_ForecastUnreachable(t *testing.T) {
+	trend := metrics.CalculateTrend(trendMetrics(80, 80.5, 81, 81.5), 75)
+	require.NotNil(t, trend.Forecast)
+	assert.False(t, trend.Forecast.Reachable)
+	assert.Empty(t, trend.Forecast.Date)
+
+	// A single entry has no rate
+	trend = metrics.CalculateTrend(trendMetrics(80), 75)
+	assert.Equal(t, 0.0, trend.WeeklyRate)
+	assert.False(t, trend.Forecast.Reachable)
+}
+
+func TestCalculateTrend_OneValuePerDay(t *testing.T) {
+	list := trendMetrics(80, 81)
+	// A second weigh-in on the first day, later in the evening
+	evening := metrics.BodyMetric{Date: list[0].Date.Add(20 * time.Hour), Weight: 82}
+	list = []metrics.BodyMetric{list[0], evening, list[1]}
+
+	trend := metrics.CalculateTrend(list, 0)
+
+	require.Len(t, trend.Points, 2)
+	assert.Equal(t, "2025-01-01", trend.Points[0].Date)
+	assert.Equal(t, 81.0, trend.Points[0].Weight)
+	assert.Equal(t, 81.0, trend.Points[1].Trend)
+	assert.Equal(t, 0.0, trend.WeightChange)
+	assert.Equal(t, 0.0, trend.WeeklyRate)
+}
+
+func TestCalculateTrend_ForecastBeyondHorizon(t *testing.T) {
+	// Losing 0.001 kg per day takes decades to lose 10 kg
+	trend := metrics.CalculateTrend(trendMetrics(80, 79.999, 79.998, 79.997), 70)
+
+	require.NotNil(t, trend.Forecast)
+	assert.False(t, trend.Forecast.Reachable)
+	assert.Zero(t, trend.Forecast.DaysToTarget)
+	assert.Empty(t, trend.Forecast.Date)
+	assert.Empty(t, trend.Forecast.EarliestDate)
+	assert.Empty(t, trend.Forecast.LatestDate)
+}
+
+func TestTrendData_ConvertUnits(t *testing.T) {
+	trend := metrics.CalculateTrend(trendMetrics(90, 89.5, 89), 85)
+	trend.ConvertUnits(units.Imperial)
+
+	assert.Equal(t, -2.2, trend.WeightChange)
+	assert.Equal(t, 196.21, trend.Points[2].Weight)
+	assert.Equal(t, -7.72, trend.WeeklyRate)
+	assert.Equal(t, 187.39, trend.Forecast.TargetWeight)
+}
@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"math"
+	"time"
+)
+
+// TrendSmoothing is the weight given to each new entry in the smoothed trend line
+// A low factor filters out day-to-day water swings (0.1 follows "The Hacker's Diet")
+const TrendSmoothing = 0.1
+
+// forecastZ is the normal quantile of the forecast's 95% confidence interval
+const forecastZ = 1.96
+
+// MaxTrendDays limits the range of a trend analysis
+const MaxTrendDays = 3660
+
+// CalculateTrend analyzes metrics sorted by date ascending, all weights in kg
+// Several weigh-ins on a day count as one, their average, so that a day weighs the same in the
+// trend and the fit however often it was logged.
+// targetWeight, when greater than 0, adds a forecast of the date it will be reached
+func CalculateTrend(metrics []BodyMetric, targetWeight float64) TrendData {
+	if len(metrics) == 0 {
+		return TrendData{Points: []TrendPoint{}}
+	}
+	metrics = dailyWeights(metrics)
+
+	var totalWeight float64
+	points := make([]TrendPoint, len(metrics))
+	smoothed := metrics[0].Weight
+	for i, m := range metrics {
+		totalWeight += m.Weight
+		if i > 0 {
+			smoothed += TrendSmoothing * (m.Weight - smoothed)
+		}
+		points[i] = TrendPoint{
+			Date:            m.Date.Format("2006-01-02"),
+			Weight:          m.Weight,
+			Trend:           roundToTwo(smoothed),
+			MovingAverage7:  roundToTwo(movingAverage(metrics, i, 7)),
+			MovingAverage14: roundToTwo(movingAverage(metrics, i, 14)),
+		}
+	}
+
+	first := metrics[0]
+	last := metrics[len(metrics)-1]
+	trend := TrendData{
+		WeightChange:  roundToTwo(last.Weight - first.Weight),
+		AverageWeight: roundToTwo(totalWeight / float64(len(metrics))),
+		TrendWeight:   roundToTwo(smoothed),
+		Points:        points,
+	}
+
+	fit, ok := fitLine(metrics)
+	if ok {
+		trend.WeeklyRate = roundToTwo(fit.slope * 7)
+	}
+	if targetWeight > 0 {
+		trend.Forecast = forecast(fit, ok, last.Date, targetWeight)
+	}
+	return trend
+}
+
+// dailyWeights collapses the metrics of each day, sorted by date ascending, into one with their
+// average weight
+func dailyWeights(metrics []BodyMetric) []BodyMetric {
+	days := make([]BodyMetric, 0, len(metrics))
+	var total float64
+	var count int
+	for i, m := range metrics {
+		total += m.Weight
+		count++
+		if i+1 < len(metrics) && metrics[i+1].Date.Format("2006-01-02") == m.Date.Format("2006-01-02") {
+			continue
+		}
+
+		year, month, day := m.Date.Date()
+		days = append(days, BodyMetric{
+			UserID: m.UserID,
+			Date:   time.Date(year, month, day, 0, 0, 0, 0, m.Date.Location()),
+			Weight: total / float64(count),
+		})
+		total, count = 0, 0
+	}
+	return days
+}
+
+// movingAverage averages the entries of the window of days calendar days ending on entry i
+func movingAverage(metrics []BodyMetric, i, days int) float64 {
+	windowStart := metrics[i].Date.AddDate(0, 0, -(days - 1))
+	var total float64
+	var count int
+	for j := i; j >= 0 && !metrics[j].Date.Before(windowStart); j-- {
+		total += metrics[j].Weight
+		count++
+	}
+	return total / float64(count)
+}
+
+// lineFit is a least-squares fit of weight against days since the first entry
+type lineFit struct {
+	slope     float64 // kg per day
+	intercept float64
+	slopeErr  float64 // Standard error of the slope, 0 with fewer than 3 entries
+	lastX     float64 // Days from the first to the last entry
+}
+
+// fitLine fits a line through the entries; it needs entries on at least 2 different days
+func fitLine(metrics []BodyMetric) (lineFit, bool) {
+	n := float64(len(metrics))
+	origin := metrics[0].Date
+
+	xs := make([]float64, len(metrics))
+	var sumX, sumY float64
+	for i, m := range metrics {
+		xs[i] = m.Date.Sub(origin).Hours() / 24
+		sumX += xs[i]
+		sumY += m.Weight
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var sxx, sxy float64
+	for i, m := range metrics {
+		sxx += (xs[i] - meanX) * (xs[i] - meanX)
+		sxy += (xs[i] - meanX) * (m.Weight - meanY)
+	}
+	if sxx == 0 {
+		return lineFit{}, false
+	}
+
+	fit := lineFit{slope: sxy / sxx, lastX: xs[len(xs)-1]}
+	fit.intercept = meanY - fit.slope*meanX
+
+	if len(metrics) > 2 {
+		var residuals float64
+		for i, m := range metrics {
+			r := m.Weight - (fit.intercept + fit.slope*xs[i])
+			residuals += r * r
+		}
+		fit.slopeErr = math.Sqrt(residuals / (n - 2) / sxx)
+	}
+	return fit, true
+}
+
+// forecast estimates when the fitted line reaches targetWeight, counting from the last entry
+// The interval uses the slope's 95% confidence bounds; a bound that never reaches the target, or only
+// after MaxTrendDays, is left empty
+func forecast(fit lineFit, ok bool, lastDate time.Time, targetWeight float64) *Forecast {
+	result := &Forecast{TargetWeight: targetWeight}
+	if !ok {
+		return result
+	}
+
+	current := fit.intercept + fit.slope*fit.lastX
+	result.CurrentWeight = roundToTwo(current)
+	remaining := targetWeight - current
+
+	daysAt := func(slope float64) (int, bool) {
+		if remaining == 0 {
+			return 0, true
+		}
+		if slope == 0 || (remaining > 0) != (slope > 0) {
+			return 0, false
+		}
+		// A line that takes longer than any trend range is too flat to forecast from
+		days := math.Ceil(remaining / slope)
+		if days > MaxTrendDays {
+			return 0, false
+		}
+		return int(days), true
+	}
+
+	days, reachable := daysAt(fit.slope)
+	if !reachable {
+		return result
+	}
+	result.Reachable = true
+	result.DaysToTarget = days
+	result.Date = lastDate.AddDate(0, 0, days).Format("2006-01-02")
+
+	if fit.slopeErr == 0 {
+		return result
+	}
+
+	// The steeper bound reaches the target first, the shallower one last
+	fast, slow := fit.slope+forecastZ*fit.slopeErr, fit.slope-forecastZ*fit.slopeErr
+	if fit.slope < 0 {
+		fast, slow = slow, fast
+	}
+	if earliest, ok := daysAt(fast); ok {
+		result.EarliestDate = lastDate.AddDate(0, 0, earliest).Format("2006-01-02")
+	}
+	if latest, ok := daysAt(slow); ok {
+		result.LatestDate = lastDate.AddDate(0, 0, latest).Format("2006-01-02")
+	}
+	return result
+}
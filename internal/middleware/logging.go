@@ -3,6 +3,7 @@ package middleware
 import (
 	"log"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -18,7 +19,7 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 
 		// Log the request in blue
 		if r.URL.RawQuery != "" {
-			log.Printf("%s[%s] %s?%s%s", colorBlue, r.Method, r.URL.Path, r.URL.RawQuery, colorReset)
+			log.Printf("%s[%s] %s?%s%s", colorBlue, r.Method, r.URL.Path, redactQuery(r.URL.RawQuery), colorReset)
 		} else {
 			log.Printf("%s[%s] %s%s", colorBlue, r.Method, r.URL.Path, colorReset)
 		}
@@ -38,6 +39,23 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// redactedParams are query parameters carrying credentials, e.g. the token of an event stream
+var redactedParams = []string{"access_token"}
+
+// redactQuery hides the values of credential parameters in a raw query, keeping the others as sent
+func redactQuery(rawQuery string) string {
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		name, _, _ := strings.Cut(param, "=")
+		for _, redacted := range redactedParams {
+			if name == redacted {
+				params[i] = name + "=REDACTED"
+			}
+		}
+	}
+	return strings.Join(params, "&")
+}
+
 // loggingResponseWriter wraps http.ResponseWriter to capture the status code
 type loggingResponseWriter struct {
 	http.ResponseWriter
@@ -49,3 +67,8 @@ func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	lrw.statusCode = code
 	lrw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap returns the underlying writer, so that http.ResponseController can flush streams
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
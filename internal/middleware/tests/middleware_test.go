@@ -1,10 +1,15 @@
 package tests
 
 import (
+	"bytes"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 
+	"ultra-bis/internal/i18n"
 	"ultra-bis/internal/middleware"
 )
 
@@ -66,3 +71,42 @@ func TestLoggingMiddleware_DifferentMethods(t *testing.T) {
 		})
 	}
 }
+
+// TestLoggingMiddleware_Flush tests that streaming handlers can flush through the middleware
+func TestLoggingMiddleware_Flush(t *testing.T) {
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Expected flush to be supported, got %v", err)
+		}
+	})
+
+	handler := middleware.LoggingMiddleware(i18n.Middleware(testHandler))
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if !rec.Flushed {
+		t.Error("Expected the response to be flushed")
+	}
+}
+
+// TestLoggingMiddleware_RedactsTokens tests that tokens passed in the URL are not logged
+func TestLoggingMiddleware_RedactsTokens(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	handler := middleware.LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/events?lang=fr&access_token=pat_secret", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if strings.Contains(buf.String(), "pat_secret") {
+		t.Errorf("Expected the token to be redacted, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "/events?lang=fr&access_token=REDACTED") {
+		t.Errorf("Expected the other parameters to be logged, got %q", buf.String())
+	}
+}
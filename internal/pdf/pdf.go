@@ -0,0 +1,215 @@
+// Package pdf writes simple text documents as PDF without external dependencies.
+// It only uses the standard Type 1 fonts (Helvetica, Helvetica-Bold and Courier),
+// which every PDF reader provides, so nothing has to be embedded.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Font selects one of the standard fonts
+type Font int
+
+const (
+	Regular   Font = iota // Helvetica
+	Bold                  // Helvetica-Bold
+	Monospace             // Courier, for aligned tables
+)
+
+// fontNames maps fonts to their resource name and base font
+var fontNames = []struct{ resource, base string }{
+	{"F1", "Helvetica"},
+	{"F2", "Helvetica-Bold"},
+	{"F3", "Courier"},
+}
+
+// A4 page size and margins in points
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	marginLeft   = 40.0
+	marginTop    = 50.0
+	marginBottom = 50.0
+)
+
+// Document is a multi-page text document laid out top to bottom
+type Document struct {
+	pages  []*bytes.Buffer
+	y      float64
+	footer string
+}
+
+// NewDocument creates an empty document
+// footer, if not empty, is printed at the bottom of every page with the page number
+func NewDocument(footer string) *Document {
+	return &Document{footer: footer}
+}
+
+// Heading writes a bold line of the given size
+func (d *Document) Heading(text string, size float64) {
+	d.Space(size * 0.4)
+	d.line(Bold, size, text)
+	d.Space(size * 0.3)
+}
+
+// Text writes a line of regular text
+func (d *Document) Text(text string) {
+	d.line(Regular, 10, text)
+}
+
+// Mono writes a line of monospaced text (use padded columns for tables)
+func (d *Document) Mono(text string) {
+	d.line(Monospace, 8.5, text)
+}
+
+// MonoBold writes a line of monospaced text followed by a rule, for table headers
+func (d *Document) MonoBold(text string) {
+	d.line(Monospace, 8.5, text)
+	d.Rule()
+}
+
+// Rule draws a thin horizontal line across the page
+func (d *Document) Rule() {
+	page := d.ensureSpace(6)
+	fmt.Fprintf(page, "0.5 w %.2f %.2f m %.2f %.2f l S\n", marginLeft, d.y-2, pageWidth-marginLeft, d.y-2)
+	d.y -= 6
+}
+
+// Space adds vertical space
+func (d *Document) Space(points float64) {
+	d.y -= points
+}
+
+// PageBreak starts a new page
+func (d *Document) PageBreak() {
+	d.newPage()
+}
+
+// line writes one line of text, starting a new page when the current one is full
+func (d *Document) line(font Font, size float64, text string) {
+	leading := size * 1.35
+	page := d.ensureSpace(leading)
+	d.y -= leading
+	fmt.Fprintf(page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+		fontNames[font].resource, size, marginLeft, d.y, escape(text))
+}
+
+// ensureSpace returns the current page, starting a new one if height does not fit
+func (d *Document) ensureSpace(height float64) *bytes.Buffer {
+	if len(d.pages) == 0 || d.y-height < marginBottom {
+		d.newPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// newPage appends an empty page
+func (d *Document) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - marginTop
+}
+
+// PageCount returns the number of pages written so far
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// WriteTo writes the document as a PDF file
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.newPage()
+	}
+
+	out := &countingWriter{w: bufio.NewWriter(w)}
+	var offsets []int64
+
+	// Object numbers: 1 catalog, 2 page tree, 3-5 fonts, then a page and a content stream per page
+	firstPage := 3 + len(fontNames)
+	object := func(body string) {
+		offsets = append(offsets, out.n)
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	fmt.Fprint(out, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+
+	var fontResources []string
+	for i, font := range fontNames {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", font.base))
+		fontResources = append(fontResources, fmt.Sprintf("/%s %d 0 R", font.resource, 3+i))
+	}
+	resources := fmt.Sprintf("<< /Font << %s >> >>", strings.Join(fontResources, " "))
+
+	for i, page := range d.pages {
+		content := page.String()
+		if d.footer != "" {
+			footer := fmt.Sprintf("%s - page %d of %d", d.footer, i+1, len(d.pages))
+			content += fmt.Sprintf("BT /F1 8.0 Tf %.2f %.2f Td (%s) Tj ET\n", marginLeft, marginBottom/2, escape(footer))
+		}
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources %s /Contents %d 0 R >>",
+			pageWidth, pageHeight, resources, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xref := out.n
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	if out.err != nil {
+		return out.n, out.err
+	}
+	return out.n, out.w.Flush()
+}
+
+// escape encodes text for a PDF string literal in WinAnsiEncoding
+// Characters outside Latin-1 are replaced with '?'
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString("    ")
+		case r < 32:
+			// Control characters are dropped
+		case r < 256:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// countingWriter tracks the byte offset needed for the cross-reference table
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
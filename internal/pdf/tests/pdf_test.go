@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ultra-bis/internal/pdf"
+)
+
+func TestDocument_WriteTo(t *testing.T) {
+	doc := pdf.NewDocument("Report")
+	doc.Heading("Title (draft)", 16)
+	doc.Text("Café \\ crème")
+	doc.MonoBold("Date        Calories")
+	doc.Mono("Mon 06 Jan      2100")
+
+	var buf bytes.Buffer
+	n, err := doc.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "%PDF-1.4"))
+	assert.True(t, strings.HasSuffix(out, "%%EOF\n"))
+	assert.Contains(t, out, "/BaseFont /Helvetica-Bold")
+	assert.Contains(t, out, `(Title \(draft\)) Tj`)
+	assert.Contains(t, out, "Caf\xe9 \\\\ cr\xe8me")
+	assert.Contains(t, out, "(Report - page 1 of 1) Tj")
+	assert.Equal(t, 1, doc.PageCount())
+
+	assertValidXref(t, buf.Bytes())
+}
+
+func TestDocument_PagesOverflow(t *testing.T) {
+	doc := pdf.NewDocument("")
+	for i := 0; i < 200; i++ {
+		doc.Mono(fmt.Sprintf("line %d", i))
+	}
+	assert.Greater(t, doc.PageCount(), 1)
+
+	var buf bytes.Buffer
+	_, err := doc.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), fmt.Sprintf("/Count %d", doc.PageCount()))
+	assertValidXref(t, buf.Bytes())
+}
+
+func TestDocument_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := pdf.NewDocument("").WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "/Count 1")
+}
+
+// assertValidXref checks that every cross-reference offset points at its object
+func assertValidXref(t *testing.T, content []byte) {
+	t.Helper()
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(content)
+	require.NotNil(t, startxref)
+	xrefOffset, err := strconv.Atoi(string(startxref[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(content[xrefOffset:], []byte("xref\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(content[xrefOffset:], -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(string(entry[1]))
+		require.NoError(t, err)
+		expected := fmt.Sprintf("%d 0 obj", i+1)
+		assert.True(t, bytes.HasPrefix(content[offset:], []byte(expected)), "object %d offset", i+1)
+	}
+}
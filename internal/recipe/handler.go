@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/units"
 )
 
 // Handler handles recipe HTTP requests
@@ -33,6 +34,8 @@ func (h *Handler) CreateRecipe(w http.ResponseWriter, r *http.Request) {
 		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	prefs := units.FromRequest(r)
+	req.NormalizeUnits(prefs)
 
 	recipe, err := h.service.CreateRecipe(r.Context(), userID, req)
 	if err != nil {
@@ -40,6 +43,7 @@ func (h *Handler) CreateRecipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recipe.ConvertUnits(prefs)
 	httputil.WriteJSON(w, http.StatusCreated, recipe)
 }
 
@@ -63,6 +67,7 @@ func (h *Handler) GetRecipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recipe.ConvertUnits(units.FromRequest(r))
 	httputil.WriteJSON(w, http.StatusOK, recipe)
 }
 
@@ -83,6 +88,7 @@ func (h *Handler) ListRecipes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ConvertRecipeUnits(recipes, units.FromRequest(r))
 	httputil.WriteJSON(w, http.StatusOK, recipes)
 }
 
@@ -111,6 +117,7 @@ func (h *Handler) ListRecipesByTag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ConvertRecipeUnits(recipes, units.FromRequest(r))
 	httputil.WriteJSON(w, http.StatusOK, recipes)
 }
 
@@ -140,6 +147,7 @@ func (h *Handler) UpdateRecipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recipe.ConvertUnits(units.FromRequest(r))
 	httputil.WriteJSON(w, http.StatusOK, recipe)
 }
 
@@ -185,6 +193,8 @@ func (h *Handler) AddIngredient(w http.ResponseWriter, r *http.Request) {
 		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	prefs := units.FromRequest(r)
+	req.NormalizeUnits(prefs)
 
 	ingredient, err := h.service.AddIngredient(r.Context(), userID, recipeID, req)
 	if err != nil {
@@ -192,6 +202,7 @@ func (h *Handler) AddIngredient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ingredient.ConvertUnits(prefs)
 	httputil.WriteJSON(w, http.StatusCreated, ingredient)
 }
 
@@ -220,6 +231,8 @@ func (h *Handler) UpdateIngredient(w http.ResponseWriter, r *http.Request) {
 		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	prefs := units.FromRequest(r)
+	req.NormalizeUnits(prefs)
 
 	ingredient, err := h.service.UpdateIngredient(r.Context(), userID, recipeID, ingredientID, req)
 	if err != nil {
@@ -227,6 +240,7 @@ func (h *Handler) UpdateIngredient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ingredient.ConvertUnits(prefs)
 	httputil.WriteJSON(w, http.StatusOK, ingredient)
 }
 
@@ -259,6 +273,38 @@ func (h *Handler) DeleteIngredient(w http.ResponseWriter, r *http.Request) {
 	httputil.WriteSuccess(w, http.StatusOK, "Ingredient deleted successfully")
 }
 
+// ListGlobalRecipes handles GET /admin/recipes (admin only)
+func (h *Handler) ListGlobalRecipes(w http.ResponseWriter, r *http.Request) {
+	recipes, err := h.service.ListGlobalRecipes(r.Context())
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	ConvertRecipeUnits(recipes, units.FromRequest(r))
+	httputil.WriteJSON(w, http.StatusOK, recipes)
+}
+
+// CreateGlobalRecipe handles POST /admin/recipes (admin only)
+func (h *Handler) CreateGlobalRecipe(w http.ResponseWriter, r *http.Request) {
+	var req CreateRecipeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	prefs := units.FromRequest(r)
+	req.NormalizeUnits(prefs)
+
+	recipe, err := h.service.CreateGlobalRecipe(r.Context(), req)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	recipe.ConvertUnits(prefs)
+	httputil.WriteJSON(w, http.StatusCreated, recipe)
+}
+
 // handleServiceError maps service layer errors to appropriate HTTP status codes
 func (h *Handler) handleServiceError(w http.ResponseWriter, err error) {
 	switch {
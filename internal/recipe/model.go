@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+
+	"ultra-bis/internal/units"
 )
 
 // Recipe represents a combination of foods
@@ -16,6 +18,8 @@ type Recipe struct {
 	UserID      *uint              `json:"user_id,omitempty" gorm:"index"` // NULL = global recipe
 	Tag         string             `json:"tag" gorm:"type:varchar(20);not null;default:'routine'"`
 	Ingredients []RecipeIngredient `json:"ingredients,omitempty" gorm:"foreignKey:RecipeID;constraint:OnDelete:CASCADE"`
+
+	Units *units.Preferences `json:"units,omitempty" gorm:"-"` // Units of the response values
 }
 
 // RecipeIngredient represents a food item within a recipe
@@ -27,6 +31,10 @@ type RecipeIngredient struct {
 	RecipeID      uint           `json:"recipe_id" gorm:"not null;index"`
 	FoodID        uint           `json:"food_id" gorm:"not null;index"`
 	QuantityGrams float64        `json:"quantity_grams" gorm:"type:decimal(10,2);not null"` // Amount in grams
+
+	// Amount in the user's quantity unit and the units of the response values (not persisted)
+	Quantity float64            `json:"quantity" gorm:"-"`
+	Units    *units.Preferences `json:"units,omitempty" gorm:"-"`
 }
 
 // CreateRecipeRequest represents the request to create a recipe
@@ -40,6 +48,7 @@ type CreateRecipeRequest struct {
 type CreateIngredientRequest struct {
 	FoodID        uint    `json:"food_id"`
 	QuantityGrams float64 `json:"quantity_grams"`
+	Quantity      float64 `json:"quantity"` // In the user's quantity unit, instead of quantity_grams
 }
 
 // UpdateRecipeRequest represents the request to update a recipe
@@ -52,17 +61,19 @@ type UpdateRecipeRequest struct {
 type AddIngredientRequest struct {
 	FoodID        uint    `json:"food_id"`
 	QuantityGrams float64 `json:"quantity_grams"`
+	Quantity      float64 `json:"quantity"` // In the user's quantity unit, instead of quantity_grams
 }
 
 // UpdateIngredientRequest represents the request to update an ingredient quantity
 type UpdateIngredientRequest struct {
 	QuantityGrams float64 `json:"quantity_grams"`
+	Quantity      float64 `json:"quantity"` // In the user's quantity unit, instead of quantity_grams
 }
 
 // RecipeWithNutrition represents a recipe with calculated nutrition information
 type RecipeWithNutrition struct {
 	Recipe
-	TotalWeight       float64 `json:"total_weight"`        // Total weight in grams
+	TotalWeight       float64 `json:"total_weight"`        // Total weight in the user's quantity unit
 	TotalCalories     float64 `json:"total_calories"`      // Total nutrition for entire recipe
 	TotalProtein      float64 `json:"total_protein"`
 	TotalCarbs        float64 `json:"total_carbs"`
@@ -81,6 +92,7 @@ type IngredientWithDetails struct {
 	FoodID        uint    `json:"food_id"`
 	FoodName      string  `json:"food_name"`
 	QuantityGrams float64 `json:"quantity_grams"`
+	Quantity      float64 `json:"quantity"` // In the user's quantity unit
 	Calories      float64 `json:"calories"`
 	Protein       float64 `json:"protein"`
 	Carbs         float64 `json:"carbs"`
@@ -108,4 +120,5 @@ type RecipeListResponse struct {
 	FatPer100g      float64                 `json:"fat_per_100g"`
 	FiberPer100g    float64                 `json:"fiber_per_100g"`
 	Ingredients     []IngredientWithDetails `json:"ingredients"`
+	Units           *units.Preferences      `json:"units,omitempty"` // Units of the response values
 }
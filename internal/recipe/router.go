@@ -6,6 +6,7 @@ import (
 
 	"ultra-bis/internal/auth"
 	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/user"
 )
 
 // RegisterRoutes registers all recipe routes with improved middleware chaining
@@ -69,6 +70,30 @@ func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
 			http.NotFound(w, r)
 		}
 	})
+
+	// Global recipe curation: /admin/recipes (admin only)
+	// Updates, deletions and ingredient changes on global recipes go through
+	// the regular /recipes/{id} endpoints, which allow admins to modify them
+	mux.HandleFunc("/admin/recipes", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			httputil.ChainMiddleware(
+				handler.ListGlobalRecipes,
+				auth.JWTMiddleware,
+				httputil.RequireRole(string(user.RoleAdmin)),
+			)(w, r)
+
+		case http.MethodPost:
+			httputil.ChainMiddleware(
+				handler.CreateGlobalRecipe,
+				auth.JWTMiddleware,
+				httputil.RequireRole(string(user.RoleAdmin)),
+			)(w, r)
+
+		default:
+			httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	})
 }
 
 // handleRecipeDetail handles /recipes/{id} and /recipes/{filter}
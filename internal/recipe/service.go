@@ -6,6 +6,9 @@ import (
 	"fmt"
 
 	"gorm.io/gorm"
+
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/user"
 )
 
 var (
@@ -46,6 +49,30 @@ func NewService(repo *Repository, foodProvider FoodProvider, db *gorm.DB) *Servi
 
 // CreateRecipe creates a new recipe with ingredients in a single transaction
 func (s *Service) CreateRecipe(ctx context.Context, userID uint, req CreateRecipeRequest) (*Recipe, error) {
+	return s.createRecipe(ctx, &userID, req)
+}
+
+// CreateGlobalRecipe creates a global recipe (UserID == nil) visible to every user
+// Only administrators may create global recipes
+func (s *Service) CreateGlobalRecipe(ctx context.Context, req CreateRecipeRequest) (*Recipe, error) {
+	if !isAdmin(ctx) {
+		return nil, ErrForbidden
+	}
+	return s.createRecipe(ctx, nil, req)
+}
+
+// ListGlobalRecipes retrieves all global recipes with nutrition calculated
+func (s *Service) ListGlobalRecipes(ctx context.Context) ([]RecipeListResponse, error) {
+	recipes, err := s.repo.GetGlobal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global recipes: %w", err)
+	}
+
+	return s.enrichRecipesWithNutrition(recipes)
+}
+
+// createRecipe creates a recipe owned by ownerID, or a global recipe when ownerID is nil
+func (s *Service) createRecipe(ctx context.Context, ownerID *uint, req CreateRecipeRequest) (*Recipe, error) {
 	// Validation
 	if req.Name == "" {
 		return nil, fmt.Errorf("%w: name is required", ErrInvalidInput)
@@ -101,7 +128,7 @@ func (s *Service) CreateRecipe(ctx context.Context, userID uint, req CreateRecip
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		recipe = &Recipe{
 			Name:   req.Name,
-			UserID: &userID,
+			UserID: ownerID,
 			Tag:    tag,
 		}
 
@@ -186,7 +213,7 @@ func (s *Service) UpdateRecipe(ctx context.Context, userID uint, recipeID int, r
 	}
 
 	// Check ownership
-	if recipe.UserID == nil || *recipe.UserID != userID {
+	if !canModify(ctx, recipe, userID) {
 		return nil, ErrForbidden
 	}
 
@@ -221,7 +248,7 @@ func (s *Service) DeleteRecipe(ctx context.Context, userID uint, recipeID int) e
 	}
 
 	// Check ownership
-	if recipe.UserID == nil || *recipe.UserID != userID {
+	if !canModify(ctx, recipe, userID) {
 		return ErrForbidden
 	}
 
@@ -240,7 +267,7 @@ func (s *Service) AddIngredient(ctx context.Context, userID uint, recipeID int,
 	}
 
 	// Check ownership
-	if recipe.UserID == nil || *recipe.UserID != userID {
+	if !canModify(ctx, recipe, userID) {
 		return nil, ErrForbidden
 	}
 
@@ -280,7 +307,7 @@ func (s *Service) UpdateIngredient(ctx context.Context, userID uint, recipeID, i
 	}
 
 	// Check ownership
-	if recipe.UserID == nil || *recipe.UserID != userID {
+	if !canModify(ctx, recipe, userID) {
 		return nil, ErrForbidden
 	}
 
@@ -320,7 +347,7 @@ func (s *Service) DeleteIngredient(ctx context.Context, userID uint, recipeID, i
 	}
 
 	// Check ownership
-	if recipe.UserID == nil || *recipe.UserID != userID {
+	if !canModify(ctx, recipe, userID) {
 		return ErrForbidden
 	}
 
@@ -341,6 +368,21 @@ func (s *Service) DeleteIngredient(ctx context.Context, userID uint, recipeID, i
 	return nil
 }
 
+// canModify reports whether the user may modify the recipe
+// Users may modify their own recipes; administrators may also modify global recipes
+func canModify(ctx context.Context, recipe *Recipe, userID uint) bool {
+	if recipe.UserID == nil {
+		return isAdmin(ctx)
+	}
+	return *recipe.UserID == userID
+}
+
+// isAdmin reports whether the request context belongs to an administrator
+func isAdmin(ctx context.Context) bool {
+	role, _ := httputil.UserRoleFromContext(ctx)
+	return role == string(user.RoleAdmin)
+}
+
 // calculateNutrition calculates nutrition for a single recipe
 // This method now returns an error if any food is missing (no silent failures)
 func (s *Service) calculateNutrition(recipe *Recipe) (*RecipeWithNutrition, error) {
@@ -0,0 +1,73 @@
+package recipe
+
+import "ultra-bis/internal/units"
+
+// Quantities are stored in grams and energies in kcal. Requests may give ingredient
+// quantities in the user's unit with "quantity", and responses are converted to the
+// user's units and state them in "units".
+
+// NormalizeUnits converts the ingredient quantities of a request to grams
+func (req *CreateRecipeRequest) NormalizeUnits(prefs units.Preferences) {
+	for i := range req.Ingredients {
+		req.Ingredients[i].QuantityGrams = quantityGrams(req.Ingredients[i].QuantityGrams, req.Ingredients[i].Quantity, prefs)
+	}
+}
+
+// NormalizeUnits converts the quantity of a request to grams
+func (req *AddIngredientRequest) NormalizeUnits(prefs units.Preferences) {
+	req.QuantityGrams = quantityGrams(req.QuantityGrams, req.Quantity, prefs)
+}
+
+// NormalizeUnits converts the quantity of a request to grams
+func (req *UpdateIngredientRequest) NormalizeUnits(prefs units.Preferences) {
+	req.QuantityGrams = quantityGrams(req.QuantityGrams, req.Quantity, prefs)
+}
+
+// quantityGrams returns grams when given, otherwise quantity converted from the user's unit
+func quantityGrams(grams, quantity float64, prefs units.Preferences) float64 {
+	if grams == 0 && quantity > 0 {
+		return prefs.QuantityToGrams(quantity)
+	}
+	return grams
+}
+
+// ConvertUnits converts the ingredient quantity to prefs and states its units
+func (i *RecipeIngredient) ConvertUnits(prefs units.Preferences) {
+	i.Quantity = prefs.QuantityFromGrams(i.QuantityGrams)
+	i.Units = &prefs
+}
+
+// ConvertUnits converts the recipe's ingredient quantities to prefs and states its units
+func (r *Recipe) ConvertUnits(prefs units.Preferences) {
+	for i := range r.Ingredients {
+		r.Ingredients[i].Quantity = prefs.QuantityFromGrams(r.Ingredients[i].QuantityGrams)
+	}
+	r.Units = &prefs
+}
+
+// ConvertUnits converts the recipe's weight, quantities and energies to prefs and states its units
+func (r *RecipeWithNutrition) ConvertUnits(prefs units.Preferences) {
+	r.Recipe.ConvertUnits(prefs)
+	r.TotalWeight = prefs.QuantityFromGrams(r.TotalWeight)
+	r.TotalCalories = prefs.EnergyFromKcal(r.TotalCalories)
+	r.CaloriesPer100g = prefs.EnergyFromKcal(r.CaloriesPer100g)
+}
+
+// ConvertUnits converts the recipe's weight, quantities and energies to prefs and states its units
+func (r *RecipeListResponse) ConvertUnits(prefs units.Preferences) {
+	r.TotalWeight = prefs.QuantityFromGrams(r.TotalWeight)
+	r.TotalCalories = prefs.EnergyFromKcal(r.TotalCalories)
+	r.CaloriesPer100g = prefs.EnergyFromKcal(r.CaloriesPer100g)
+	for i := range r.Ingredients {
+		r.Ingredients[i].Quantity = prefs.QuantityFromGrams(r.Ingredients[i].QuantityGrams)
+		r.Ingredients[i].Calories = prefs.EnergyFromKcal(r.Ingredients[i].Calories)
+	}
+	r.Units = &prefs
+}
+
+// ConvertRecipeUnits converts a list of recipes to prefs
+func ConvertRecipeUnits(recipes []RecipeListResponse, prefs units.Preferences) {
+	for i := range recipes {
+		recipes[i].ConvertUnits(prefs)
+	}
+}
@@ -0,0 +1,84 @@
+package streaks
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"ultra-bis/internal/webhook"
+)
+
+// A day is reported as missed once it is over in every time zone, and only within the lookback
+// so that a reporter started on an existing database does not publish the whole history
+const (
+	AdherenceDelay    = 36 * time.Hour
+	AdherenceLookback = 7
+)
+
+// AdherenceReporter publishes a goal.adherence.missed event for each logged day that missed its calorie goal
+type AdherenceReporter struct {
+	repo      *Repository
+	publisher *webhook.Publisher
+}
+
+// NewAdherenceReporter creates a new adherence reporter
+func NewAdherenceReporter(repo *Repository, publisher *webhook.Publisher) *AdherenceReporter {
+	return &AdherenceReporter{repo: repo, publisher: publisher}
+}
+
+// MissedAdherence builds the goal.adherence.missed event data of a day
+func MissedAdherence(status DayStatus) webhook.AdherenceMissed {
+	return webhook.AdherenceMissed{
+		Date:        status.Date.Format("2006-01-02"),
+		Calories:    status.Calories,
+		CalorieGoal: status.CalorieGoal,
+		Protein:     status.Protein,
+		ProteinGoal: status.ProteinGoal,
+		ProteinHit:  status.ProteinHit,
+	}
+}
+
+// AdherenceDedupeKey identifies the event of a user's day, so that each day is reported at most once
+func AdherenceDedupeKey(userID uint, date time.Time) string {
+	return fmt.Sprintf("%s:%d:%s", webhook.EventGoalAdherenceMissed, userID, date.Format("2006-01-02"))
+}
+
+// ReportMissed publishes the missed days that are over at now and were not reported yet
+// Returns how many events were published
+func (a *AdherenceReporter) ReportMissed(now time.Time) (int, error) {
+	to := dateOnly(now.Add(-AdherenceDelay))
+	from := to.AddDate(0, 0, -AdherenceLookback)
+
+	statuses, err := a.repo.ListMissedCalorieDays(from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, status := range statuses {
+		enqueued, err := a.publisher.PublishOnce(status.UserID, webhook.EventGoalAdherenceMissed,
+			AdherenceDedupeKey(status.UserID, status.Date), MissedAdherence(status))
+		if err != nil {
+			return published, err
+		}
+		if enqueued {
+			published++
+		}
+	}
+	return published, nil
+}
+
+// Run calls ReportMissed every interval; it blocks and is meant to run in its own goroutine
+func (a *AdherenceReporter) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if published, err := a.ReportMissed(time.Now()); err != nil {
+			log.Printf("Adherence report failed: %v", err)
+		} else if published > 0 {
+			log.Printf("Adherence: %d missed days reported", published)
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,96 @@
+package streaks
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"ultra-bis/internal/i18n"
+)
+
+// MaxBadgeDays bounds the streak length a badge may require
+const MaxBadgeDays = 3650
+
+// badgeCodePattern restricts badge codes to the snake_case identifiers used by the built-in badges
+var badgeCodePattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+//go:embed badges.json
+var builtinBadgesJSON []byte
+
+// builtinBadges are the badges every user can earn, unless replaced by a custom badge
+var builtinBadges = loadBuiltinBadges()
+
+func loadBuiltinBadges() []Badge {
+	var badges []Badge
+	if err := json.Unmarshal(builtinBadgesJSON, &badges); err != nil {
+		panic(fmt.Sprintf("invalid built-in badges: %v", err))
+	}
+	for _, badge := range badges {
+		if err := ValidateBadge(&badge); err != nil {
+			panic(fmt.Sprintf("invalid built-in badge %s: %v", badge.Code, err))
+		}
+	}
+	return badges
+}
+
+// BuiltinBadges returns a copy of the built-in badges
+func BuiltinBadges() []Badge {
+	return append([]Badge(nil), builtinBadges...)
+}
+
+// IsBuiltinBadge reports whether code is a built-in badge
+func IsBuiltinBadge(code string) bool {
+	for _, badge := range builtinBadges {
+		if badge.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeBadges returns the built-in badges with the custom ones applied: a custom badge replaces
+// the built-in badge with the same code (a disabled one withdraws it) and others are added
+func MergeBadges(custom []CustomBadge) []Badge {
+	byCode := make(map[string]Badge)
+	for _, badge := range builtinBadges {
+		byCode[badge.Code] = badge
+	}
+	for _, badge := range custom {
+		byCode[badge.Code] = badge.Badge
+	}
+
+	badges := make([]Badge, 0, len(byCode))
+	for _, badge := range byCode {
+		badges = append(badges, badge)
+	}
+	sort.Slice(badges, func(i, j int) bool {
+		if badges[i].Days != badges[j].Days {
+			return badges[i].Days < badges[j].Days
+		}
+		return badges[i].Code < badges[j].Code
+	})
+	return badges
+}
+
+// ValidateBadge checks a badge definition
+func ValidateBadge(badge *Badge) error {
+	if !badgeCodePattern.MatchString(badge.Code) {
+		return errors.New("code must be 1 to 50 lowercase letters, digits or underscores")
+	}
+	if badge.Disabled {
+		return nil
+	}
+	if !ValidKind(badge.Kind) {
+		return errors.New("kind must be 'logging', 'calorie_goal' or 'protein_goal'")
+	}
+	if badge.Days < 1 || badge.Days > MaxBadgeDays {
+		return errors.New("days must be between 1 and 3650")
+	}
+	if badge.Name.Get(i18n.DefaultLocale) == "" {
+		return errors.New("An English name is required")
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package streaks
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/i18n"
+)
+
+// DefaultCalorieTolerance is the percentage of the calorie goal a day may deviate by and still count
+const DefaultCalorieTolerance = 10.0
+
+// Evaluate builds the status of a day from its totals and the goal in effect that day (nil without a goal)
+// The goal is first adjusted for the calories burned exercising that day, as in the diary summary;
+// tolerance is the percentage of the calorie goal the day may deviate by
+func Evaluate(date time.Time, entries int, calories, protein, caloriesBurned float64, dayGoal *goal.NutritionGoal, tolerance float64) DayStatus {
+	status := DayStatus{
+		Date:     dateOnly(date),
+		Entries:  entries,
+		Calories: calories,
+		Protein:  protein,
+		Logged:   entries > 0,
+	}
+	if dayGoal == nil || !status.Logged {
+		return status
+	}
+	dayGoal = dayGoal.AdjustForExercise(caloriesBurned)
+
+	status.CalorieGoal = dayGoal.Calories
+	status.ProteinGoal = dayGoal.Protein
+	if dayGoal.Calories > 0 {
+		status.WithinCalories = math.Abs(calories-dayGoal.Calories) <= dayGoal.Calories*tolerance/100
+	}
+	if dayGoal.Protein > 0 {
+		status.ProteinHit = protein >= dayGoal.Protein
+	}
+	return status
+}
+
+// Counts reports whether the day counts towards streaks of kind
+func (d DayStatus) Counts(kind string) bool {
+	switch kind {
+	case KindLogging:
+		return d.Logged
+	case KindCalories:
+		return d.WithinCalories
+	case KindProtein:
+		return d.ProteinHit
+	default:
+		return false
+	}
+}
+
+// Apply updates the streak after date started or stopped counting. It handles in place the
+// changes that only touch the most recent run, such as logging today, and returns false when
+// the change may join or split earlier runs: the streak must then be rebuilt with Rebuild.
+func (s *Streak) Apply(date time.Time, counts bool) bool {
+	date = dateOnly(date)
+
+	if s.LastDate == nil {
+		if !counts {
+			return true
+		}
+		s.setCurrent(date, date, 1)
+		return true
+	}
+
+	start, last := *s.StartDate, *s.LastDate
+	switch {
+	case counts && !date.Before(start) && !date.After(last):
+		// Already part of the most recent run
+		return true
+	case counts && date.Equal(last.AddDate(0, 0, 1)):
+		s.setCurrent(start, date, s.Current+1)
+		return true
+	case counts && date.After(last):
+		// The days in between do not count, otherwise they would end the run
+		s.setCurrent(date, date, 1)
+		return true
+	case !counts && date.After(last):
+		return true
+	case !counts && date.Before(start) && !s.inLongest(date):
+		// Shortens a run that is neither the most recent nor the longest
+		return true
+	default:
+		return false
+	}
+}
+
+// Rebuild recomputes the streak from every counting day, in ascending order
+func (s *Streak) Rebuild(dates []time.Time) {
+	s.Current, s.StartDate, s.LastDate = 0, nil, nil
+	s.Longest, s.LongestStart, s.LongestEnd = 0, nil, nil
+
+	for _, date := range dates {
+		date = dateOnly(date)
+		if s.LastDate != nil && date.Equal(s.LastDate.AddDate(0, 0, 1)) {
+			s.setCurrent(*s.StartDate, date, s.Current+1)
+			continue
+		}
+		s.setCurrent(date, date, 1)
+	}
+}
+
+// setCurrent sets the most recent run and carries it over to the longest one when it is at least as long
+func (s *Streak) setCurrent(start, last time.Time, length int) {
+	s.StartDate, s.LastDate, s.Current = &start, &last, length
+	if length >= s.Longest {
+		s.Longest, s.LongestStart, s.LongestEnd = length, &start, &last
+	}
+}
+
+// inLongest reports whether date is within the longest run
+func (s *Streak) inLongest(date time.Time) bool {
+	return s.LongestStart != nil && !date.Before(*s.LongestStart) && !date.After(*s.LongestEnd)
+}
+
+// Status returns the streak as seen on today: the current length drops to 0 once a day was missed
+func (s *Streak) Status(today time.Time) StreakStatus {
+	status := StreakStatus{Kind: s.Kind, Longest: s.Longest}
+	if s.LongestStart != nil {
+		status.LongestStart = s.LongestStart.Format("2006-01-02")
+		status.LongestEnd = s.LongestEnd.Format("2006-01-02")
+	}
+	if s.LastDate == nil {
+		return status
+	}
+
+	status.StartDate = s.StartDate.Format("2006-01-02")
+	status.LastDate = s.LastDate.Format("2006-01-02")
+	if !s.LastDate.Before(dateOnly(today).AddDate(0, 0, -1)) {
+		status.Active = true
+		status.Current = s.Current
+	}
+	return status
+}
+
+// NewlyEarned returns the enabled badges reached by the longest run of a streak that are not yet in earned
+// (keyed by badge code), along with the day each was reached
+func NewlyEarned(badges []Badge, streak *Streak, earned map[string]bool) []Achievement {
+	var achievements []Achievement
+	for _, badge := range badges {
+		if badge.Disabled || badge.Kind != streak.Kind || earned[badge.Code] || streak.Longest < badge.Days {
+			continue
+		}
+		achievements = append(achievements, Achievement{
+			UserID:    streak.UserID,
+			BadgeCode: badge.Code,
+			EarnedOn:  streak.LongestStart.AddDate(0, 0, badge.Days-1),
+		})
+	}
+	return achievements
+}
+
+// BadgeStatuses lists the enabled badges with the user's progress, in the locale, earned badges first
+func BadgeStatuses(badges []Badge, streaks map[string]StreakStatus, achievements []Achievement, locale string) AchievementsResponse {
+	earnedOn := make(map[string]time.Time, len(achievements))
+	for _, achievement := range achievements {
+		earnedOn[achievement.BadgeCode] = achievement.EarnedOn
+	}
+
+	response := AchievementsResponse{Badges: []BadgeStatus{}}
+	for _, badge := range badges {
+		if badge.Disabled {
+			continue
+		}
+		status := BadgeStatus{
+			Code:        badge.Code,
+			Kind:        badge.Kind,
+			Days:        badge.Days,
+			Name:        localized(badge.Name, locale),
+			Description: localized(badge.Description, locale),
+			Progress:    min(streaks[badge.Kind].Current, badge.Days),
+		}
+		if date, ok := earnedOn[badge.Code]; ok {
+			status.Earned = true
+			status.EarnedOn = date.Format("2006-01-02")
+			status.Progress = badge.Days
+			response.Earned++
+		}
+		response.Badges = append(response.Badges, status)
+	}
+	response.Total = len(response.Badges)
+
+	kindOrder := make(map[string]int, len(Kinds))
+	for i, kind := range Kinds {
+		kindOrder[kind] = i
+	}
+	sort.SliceStable(response.Badges, func(i, j int) bool {
+		a, b := response.Badges[i], response.Badges[j]
+		if a.Earned != b.Earned {
+			return a.Earned
+		}
+		if a.Kind != b.Kind {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		return a.Days < b.Days
+	})
+	return response
+}
+
+// localized returns the text in locale, falling back to the default locale
+func localized(texts i18n.Texts, locale string) string {
+	if text := texts.Get(locale); text != "" {
+		return text
+	}
+	return texts.Get(i18n.DefaultLocale)
+}
+
+// dateOnly drops the time of day, keeping the calendar date
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
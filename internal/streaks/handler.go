@@ -0,0 +1,146 @@
+package streaks
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/i18n"
+)
+
+// Handler handles streak and achievement requests
+type Handler struct {
+	repo    *Repository
+	tracker *Tracker
+}
+
+// NewHandler creates a new streaks handler
+func NewHandler(repo *Repository, tracker *Tracker) *Handler {
+	return &Handler{
+		repo:    repo,
+		tracker: tracker,
+	}
+}
+
+// GetStreaks handles GET /streaks
+// Returns the current and longest logging, calorie goal and protein goal streaks
+func (h *Handler) GetStreaks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	streaks, err := h.tracker.Streaks(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	today := calendar.FromRequest(r).Today()
+	response := StreaksResponse{
+		Streaks:          make([]StreakStatus, 0, len(Kinds)),
+		CalorieTolerance: h.tracker.CalorieTolerance(),
+	}
+	for _, kind := range Kinds {
+		response.Streaks = append(response.Streaks, streaks[kind].Status(today))
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, response)
+}
+
+// GetAchievements handles GET /achievements
+// Returns every badge in the request locale with the user's progress, earned badges first
+func (h *Handler) GetAchievements(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	streaks, err := h.tracker.Streaks(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	achievements, err := h.tracker.Achievements(userID, streaks)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	badges, err := h.tracker.Badges()
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	today := calendar.FromRequest(r).Today()
+	statuses := make(map[string]StreakStatus, len(streaks))
+	for kind, streak := range streaks {
+		statuses[kind] = streak.Status(today)
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, BadgeStatuses(badges, statuses, achievements, i18n.FromRequest(r)))
+}
+
+// ListCustomBadges handles GET /admin/badges
+// Returns the admin-defined badges; GET /achievements shows them merged with the built-in ones
+func (h *Handler) ListCustomBadges(w http.ResponseWriter, r *http.Request) {
+	badges, err := h.repo.ListCustomBadges()
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.WriteJSON(w, http.StatusOK, badges)
+}
+
+// SaveCustomBadge handles POST /admin/badges
+// Adds a badge, or replaces the badge with the same code; {"code": ..., "disabled": true} withdraws a built-in badge
+func (h *Handler) SaveCustomBadge(w http.ResponseWriter, r *http.Request) {
+	var badge Badge
+	if err := json.NewDecoder(r.Body).Decode(&badge); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := ValidateBadge(&badge); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	custom := &CustomBadge{Badge: badge}
+	if err := h.repo.SaveCustomBadge(custom); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, custom)
+}
+
+// DeleteCustomBadge handles DELETE /admin/badges/{code}
+// Removes a custom badge; a built-in badge it replaced becomes available again
+func (h *Handler) DeleteCustomBadge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	code := strings.TrimPrefix(r.URL.Path, "/admin/badges/")
+	if err := h.repo.DeleteCustomBadge(code); err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Custom badge not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,121 @@
+package streaks
+
+import (
+	"time"
+
+	"ultra-bis/internal/i18n"
+)
+
+// Kinds of streaks, by what makes a day count
+const (
+	KindLogging  = "logging"      // At least one diary entry
+	KindCalories = "calorie_goal" // Calories within the goal ± the tolerance
+	KindProtein  = "protein_goal" // Protein at or above the goal
+)
+
+// Kinds lists every streak kind, in response order
+var Kinds = []string{KindLogging, KindCalories, KindProtein}
+
+// ValidKind checks if kind is a streak kind
+func ValidKind(kind string) bool {
+	for _, known := range Kinds {
+		if kind == known {
+			return true
+		}
+	}
+	return false
+}
+
+// DayStatus records which streaks a day counts towards; it is updated whenever the day's entries change
+type DayStatus struct {
+	ID             uint      `json:"-" gorm:"primarykey"`
+	UpdatedAt      time.Time `json:"-"`
+	UserID         uint      `json:"-" gorm:"not null;uniqueIndex:idx_day_status_user_date"`
+	Date           time.Time `json:"date" gorm:"type:date;not null;uniqueIndex:idx_day_status_user_date"`
+	Entries        int       `json:"entries"`
+	Calories       float64   `json:"calories"`
+	Protein        float64   `json:"protein"`
+	CalorieGoal    float64   `json:"calorie_goal"` // Goal in effect that day, 0 without a goal
+	ProteinGoal    float64   `json:"protein_goal"`
+	Logged         bool      `json:"logged"`
+	WithinCalories bool      `json:"within_calories"`
+	ProteinHit     bool      `json:"protein_hit"`
+}
+
+// Streak holds a user's most recent and longest runs of consecutive counting days for one kind
+type Streak struct {
+	ID           uint       `json:"-" gorm:"primarykey"`
+	UpdatedAt    time.Time  `json:"-"`
+	UserID       uint       `json:"-" gorm:"not null;uniqueIndex:idx_streak_user_kind"`
+	Kind         string     `json:"kind" gorm:"type:varchar(20);not null;uniqueIndex:idx_streak_user_kind"`
+	Current      int        `json:"current"` // Length of the most recent run
+	StartDate    *time.Time `json:"start_date,omitempty" gorm:"type:date"`
+	LastDate     *time.Time `json:"last_date,omitempty" gorm:"type:date"` // Latest counting day
+	Longest      int        `json:"longest"`
+	LongestStart *time.Time `json:"longest_start,omitempty" gorm:"type:date"`
+	LongestEnd   *time.Time `json:"longest_end,omitempty" gorm:"type:date"`
+}
+
+// Badge is awarded once a streak of Kind reaches Days consecutive days
+type Badge struct {
+	Code        string     `json:"code" gorm:"type:varchar(50);not null;uniqueIndex"`
+	Kind        string     `json:"kind" gorm:"type:varchar(20);not null"`
+	Days        int        `json:"days" gorm:"not null"`
+	Name        i18n.Texts `json:"name" gorm:"type:jsonb"`
+	Description i18n.Texts `json:"description,omitempty" gorm:"type:jsonb"`
+	Disabled    bool       `json:"disabled,omitempty"` // Set on a custom badge to withdraw the built-in badge with the same code
+}
+
+// CustomBadge is a badge added by an admin; it replaces the built-in badge with the same code
+type CustomBadge struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Badge     `gorm:"embedded"`
+}
+
+// Achievement records a badge earned by a user; badges stay earned if the streak is later broken
+type Achievement struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_achievement_user_badge"`
+	BadgeCode string    `json:"badge_code" gorm:"type:varchar(50);not null;uniqueIndex:idx_achievement_user_badge"`
+	EarnedOn  time.Time `json:"earned_on" gorm:"type:date;not null"` // Day the streak reached the badge's length
+}
+
+// StreakStatus is a streak as returned by GET /streaks
+type StreakStatus struct {
+	Kind         string `json:"kind"`
+	Current      int    `json:"current"` // 0 once a day was missed
+	Active       bool   `json:"active"`  // The run reaches today or yesterday, so logging today extends it
+	StartDate    string `json:"start_date,omitempty"`
+	LastDate     string `json:"last_date,omitempty"`
+	Longest      int    `json:"longest"`
+	LongestStart string `json:"longest_start,omitempty"`
+	LongestEnd   string `json:"longest_end,omitempty"`
+}
+
+// StreaksResponse is the response of GET /streaks
+type StreaksResponse struct {
+	Streaks          []StreakStatus `json:"streaks"`
+	CalorieTolerance float64        `json:"calorie_tolerance"` // Percentage of the calorie goal a day may deviate by
+}
+
+// BadgeStatus is a badge with the user's progress, as returned by GET /achievements
+type BadgeStatus struct {
+	Code        string `json:"code"`
+	Kind        string `json:"kind"`
+	Days        int    `json:"days"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Earned      bool   `json:"earned"`
+	EarnedOn    string `json:"earned_on,omitempty"`
+	Progress    int    `json:"progress"` // Current streak length, capped at Days
+}
+
+// AchievementsResponse is the response of GET /achievements
+type AchievementsResponse struct {
+	Earned int           `json:"earned"`
+	Total  int           `json:"total"`
+	Badges []BadgeStatus `json:"badges"` // Earned badges first, then by kind and length
+}
@@ -0,0 +1,184 @@
+package streaks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// kindColumns maps each streak kind to the day status column telling whether a day counts
+var kindColumns = map[string]string{
+	KindLogging:  "logged",
+	KindCalories: "within_calories",
+	KindProtein:  "protein_hit",
+}
+
+// Repository handles database operations for streaks and achievements
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new streaks repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// GetDayStatuses retrieves the statuses of the days in [from, to] that were evaluated, keyed by YYYY-MM-DD
+func (r *Repository) GetDayStatuses(userID uint, from, to time.Time) (map[string]DayStatus, error) {
+	var statuses []DayStatus
+	err := r.db.Where("user_id = ? AND date >= ? AND date <= ?", userID, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Find(&statuses).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get day statuses: %w", err)
+	}
+
+	byDate := make(map[string]DayStatus, len(statuses))
+	for _, status := range statuses {
+		byDate[status.Date.Format("2006-01-02")] = status
+	}
+	return byDate, nil
+}
+
+// SaveDayStatus creates or replaces the status of a day
+func (r *Repository) SaveDayStatus(status *DayStatus) error {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"entries", "calories", "protein", "calorie_goal", "protein_goal",
+			"logged", "within_calories", "protein_hit", "updated_at",
+		}),
+	}).Create(status)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save day status: %w", result.Error)
+	}
+	return nil
+}
+
+// CreateDayStatuses stores the statuses of a user's history in batches, keeping days already stored
+func (r *Repository) CreateDayStatuses(statuses []DayStatus) error {
+	if len(statuses) == 0 {
+		return nil
+	}
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(statuses, 500).Error; err != nil {
+		return fmt.Errorf("failed to create day statuses: %w", err)
+	}
+	return nil
+}
+
+// GetCountingDates retrieves the days counting towards a streak kind, oldest first
+func (r *Repository) GetCountingDates(userID uint, kind string) ([]time.Time, error) {
+	column, ok := kindColumns[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown streak kind: %s", kind)
+	}
+
+	var dates []time.Time
+	result := r.db.Model(&DayStatus{}).
+		Where("user_id = ? AND "+column+" = ?", userID, true).
+		Order("date").
+		Pluck("date", &dates)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get %s days: %w", kind, result.Error)
+	}
+	return dates, nil
+}
+
+// ListMissedCalorieDays retrieves every user's logged days from from to to (inclusive) that had a
+// calorie goal and missed it, oldest first
+func (r *Repository) ListMissedCalorieDays(from, to time.Time) ([]DayStatus, error) {
+	var statuses []DayStatus
+	result := r.db.
+		Where("date BETWEEN ? AND ? AND logged = ? AND calorie_goal > 0 AND within_calories = ?",
+			from.Format("2006-01-02"), to.Format("2006-01-02"), true, false).
+		Order("date, user_id").
+		Find(&statuses)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list missed calorie days: %w", result.Error)
+	}
+	return statuses, nil
+}
+
+// GetStreaks retrieves a user's streaks, keyed by kind; it is empty until the history was evaluated
+func (r *Repository) GetStreaks(userID uint) (map[string]*Streak, error) {
+	var list []Streak
+	if err := r.db.Where("user_id = ?", userID).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("failed to get streaks: %w", err)
+	}
+
+	streaks := make(map[string]*Streak, len(list))
+	for i := range list {
+		streaks[list[i].Kind] = &list[i]
+	}
+	return streaks, nil
+}
+
+// SaveStreak creates or updates a streak; a new streak replaces any stored for the same user and kind
+func (r *Repository) SaveStreak(streak *Streak) error {
+	query := r.db
+	if streak.ID == 0 {
+		query = query.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "kind"}},
+			UpdateAll: true,
+		})
+	}
+	if err := query.Save(streak).Error; err != nil {
+		return fmt.Errorf("failed to save streak: %w", err)
+	}
+	return nil
+}
+
+// GetAchievements retrieves the badges a user earned, oldest first
+func (r *Repository) GetAchievements(userID uint) ([]Achievement, error) {
+	var achievements []Achievement
+	if err := r.db.Where("user_id = ?", userID).Order("earned_on, id").Find(&achievements).Error; err != nil {
+		return nil, fmt.Errorf("failed to get achievements: %w", err)
+	}
+	return achievements, nil
+}
+
+// CreateAchievements records earned badges, ignoring badges the user already has
+func (r *Repository) CreateAchievements(achievements []Achievement) error {
+	if len(achievements) == 0 {
+		return nil
+	}
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&achievements).Error; err != nil {
+		return fmt.Errorf("failed to create achievements: %w", err)
+	}
+	return nil
+}
+
+// ListCustomBadges retrieves every admin-defined badge, oldest first
+func (r *Repository) ListCustomBadges() ([]CustomBadge, error) {
+	var badges []CustomBadge
+	if err := r.db.Order("id").Find(&badges).Error; err != nil {
+		return nil, fmt.Errorf("failed to list custom badges: %w", err)
+	}
+	return badges, nil
+}
+
+// SaveCustomBadge creates a custom badge or replaces the one with the same code
+func (r *Repository) SaveCustomBadge(badge *CustomBadge) error {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"kind", "days", "name", "description", "disabled", "updated_at"}),
+	}).Create(badge)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save custom badge: %w", result.Error)
+	}
+	return nil
+}
+
+// DeleteCustomBadge removes an admin-defined badge by code
+func (r *Repository) DeleteCustomBadge(code string) error {
+	result := r.db.Where("code = ?", code).Delete(&CustomBadge{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete custom badge: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("custom badge not found")
+	}
+	return nil
+}
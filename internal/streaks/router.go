@@ -0,0 +1,37 @@
+package streaks
+
+import (
+	"net/http"
+
+	"ultra-bis/internal/auth"
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/user"
+)
+
+// RegisterRoutes registers all streak and achievement routes to the provided mux
+func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("/streaks", auth.JWTMiddleware(handler.GetStreaks))
+	mux.HandleFunc("/achievements", auth.JWTMiddleware(handler.GetAchievements))
+
+	// Badge configuration (admin only)
+	requireAdmin := httputil.RequireRole(string(user.RoleAdmin))
+	mux.HandleFunc("/admin/badges", httputil.ChainMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				handler.ListCustomBadges(w, r)
+			case http.MethodPost:
+				handler.SaveCustomBadge(w, r)
+			default:
+				httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			}
+		},
+		auth.JWTMiddleware,
+		requireAdmin,
+	))
+	mux.HandleFunc("/admin/badges/", httputil.ChainMiddleware(
+		handler.DeleteCustomBadge,
+		auth.JWTMiddleware,
+		requireAdmin,
+	))
+}
@@ -0,0 +1,225 @@
+package tests
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"ultra-bis/internal/goal"
+	"ultra-bis/internal/i18n"
+	"ultra-bis/internal/streaks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// day returns the date n days after 2025-01-01
+func day(n int) time.Time {
+	return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+}
+
+func TestEvaluate(t *testing.T) {
+	dayGoal := &goal.NutritionGoal{Calories: 2000, Protein: 150}
+
+	status := streaks.Evaluate(day(0), 3, 2150, 160, 0, dayGoal, 10)
+	assert.True(t, status.Logged)
+	assert.True(t, status.WithinCalories)
+	assert.True(t, status.ProteinHit)
+	assert.Equal(t, 2000.0, status.CalorieGoal)
+
+	status = streaks.Evaluate(day(0), 3, 2250, 149, 0, dayGoal, 10)
+	assert.True(t, status.Counts(streaks.KindLogging))
+	assert.False(t, status.Counts(streaks.KindCalories))
+	assert.False(t, status.Counts(streaks.KindProtein))
+
+	// Without a goal only logging counts, and an empty day counts for nothing
+	status = streaks.Evaluate(day(0), 2, 2000, 150, 0, nil, 10)
+	assert.True(t, status.Logged)
+	assert.False(t, status.WithinCalories)
+	status = streaks.Evaluate(day(0), 0, 0, 0, 0, dayGoal, 10)
+	assert.False(t, status.Logged)
+	assert.False(t, status.WithinCalories)
+}
+
+func TestEvaluate_AdjustsTheGoalForExercise(t *testing.T) {
+	dayGoal := &goal.NutritionGoal{Calories: 2000, Protein: 150, ExercisePolicy: goal.ExercisePolicyFull}
+
+	// 2500 kcal misses the plain target but is on target once the 500 kcal burned are added
+	status := streaks.Evaluate(day(0), 3, 2500, 160, 500, dayGoal, 10)
+	assert.True(t, status.WithinCalories)
+	assert.Equal(t, 2500.0, status.CalorieGoal)
+	assert.Equal(t, 187.5, status.ProteinGoal)
+	assert.False(t, status.ProteinHit)
+
+	// The goal's policy decides how much of the exercise counts
+	dayGoal.ExercisePolicy = goal.ExercisePolicyIgnore
+	status = streaks.Evaluate(day(0), 3, 2500, 160, 500, dayGoal, 10)
+	assert.False(t, status.WithinCalories)
+	assert.Equal(t, 2000.0, status.CalorieGoal)
+}
+
+func TestStreak_ApplyExtendsInPlace(t *testing.T) {
+	streak := &streaks.Streak{Kind: streaks.KindLogging}
+
+	for i := 0; i < 5; i++ {
+		assert.True(t, streak.Apply(day(i), true))
+	}
+	assert.Equal(t, 5, streak.Current)
+	assert.Equal(t, 5, streak.Longest)
+
+	// A gap starts a new run and keeps the longest one
+	assert.True(t, streak.Apply(day(7), true))
+	assert.Equal(t, 1, streak.Current)
+	assert.Equal(t, 5, streak.Longest)
+	assert.Equal(t, day(4), *streak.LongestEnd)
+
+	// Missing a day inside the longest run needs a rebuild
+	assert.False(t, streak.Apply(day(2), false))
+	streak.Rebuild([]time.Time{day(0), day(1), day(3), day(4), day(7)})
+	assert.Equal(t, 1, streak.Current)
+	assert.Equal(t, 2, streak.Longest)
+	assert.Equal(t, day(4), *streak.LongestEnd)
+
+	// Filling the gap joins the runs
+	assert.False(t, streak.Apply(day(6), true))
+	streak.Rebuild([]time.Time{day(0), day(1), day(3), day(4), day(6), day(7)})
+	assert.Equal(t, 2, streak.Current)
+	assert.Equal(t, day(6), *streak.StartDate)
+	assert.Equal(t, 2, streak.Longest)
+	assert.Equal(t, day(7), *streak.LongestEnd)
+}
+
+func TestStreak_ApplyMatchesRebuild(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	counting := make(map[int]bool)
+	streak := &streaks.Streak{Kind: streaks.KindLogging}
+
+	for step := 0; step < 2000; step++ {
+		n := random.Intn(40)
+		counts := random.Intn(3) > 0
+		if counting[n] == counts {
+			continue
+		}
+		counting[n] = counts
+
+		var dates []time.Time
+		for d, ok := range counting {
+			if ok {
+				dates = append(dates, day(d))
+			}
+		}
+		sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+		if !streak.Apply(day(n), counts) {
+			streak.Rebuild(dates)
+		}
+
+		expected := &streaks.Streak{Kind: streaks.KindLogging}
+		expected.Rebuild(dates)
+		require.Equal(t, expected.Current, streak.Current, "step %d", step)
+		require.Equal(t, expected.Longest, streak.Longest, "step %d", step)
+		require.Equal(t, expected.LastDate, streak.LastDate, "step %d", step)
+	}
+}
+
+func TestStreak_Status(t *testing.T) {
+	streak := &streaks.Streak{Kind: streaks.KindProtein}
+	streak.Rebuild([]time.Time{day(0), day(1), day(2)})
+
+	// Not logging today yet keeps the streak alive
+	status := streak.Status(day(3))
+	assert.True(t, status.Active)
+	assert.Equal(t, 3, status.Current)
+	assert.Equal(t, "2025-01-01", status.StartDate)
+
+	status = streak.Status(day(4))
+	assert.False(t, status.Active)
+	assert.Equal(t, 0, status.Current)
+	assert.Equal(t, 3, status.Longest)
+
+	empty := &streaks.Streak{Kind: streaks.KindLogging}
+	assert.Equal(t, streaks.StreakStatus{Kind: streaks.KindLogging}, empty.Status(day(0)))
+}
+
+func TestNewlyEarned(t *testing.T) {
+	streak := &streaks.Streak{UserID: 7, Kind: streaks.KindLogging}
+	var dates []time.Time
+	for i := 0; i < 8; i++ {
+		dates = append(dates, day(i))
+	}
+	streak.Rebuild(dates)
+
+	achievements := streaks.NewlyEarned(streaks.BuiltinBadges(), streak, map[string]bool{"first_log": true})
+	require.Len(t, achievements, 1)
+	assert.Equal(t, "logging_7", achievements[0].BadgeCode)
+	assert.Equal(t, uint(7), achievements[0].UserID)
+	assert.Equal(t, day(6), achievements[0].EarnedOn)
+}
+
+func TestBadgeStatuses(t *testing.T) {
+	badges := streaks.MergeBadges([]streaks.CustomBadge{
+		{Badge: streaks.Badge{Code: "logging_100", Disabled: true}},
+		{Badge: streaks.Badge{Code: "protein_3", Kind: streaks.KindProtein, Days: 3, Name: i18n.Texts{"en": "Triple"}}},
+	})
+	current := map[string]streaks.StreakStatus{streaks.KindLogging: {Current: 4}, streaks.KindProtein: {Current: 1}}
+	achievements := []streaks.Achievement{{BadgeCode: "first_log", EarnedOn: day(0)}}
+
+	response := streaks.BadgeStatuses(badges, current, achievements, "fr")
+	assert.Equal(t, 1, response.Earned)
+	assert.Equal(t, len(streaks.BuiltinBadges()), response.Total)
+
+	first := response.Badges[0]
+	assert.Equal(t, "first_log", first.Code)
+	assert.True(t, first.Earned)
+	assert.Equal(t, "Première bouchée", first.Name)
+	assert.Equal(t, "2025-01-01", first.EarnedOn)
+
+	byCode := make(map[string]streaks.BadgeStatus)
+	for _, badge := range response.Badges {
+		byCode[badge.Code] = badge
+	}
+	assert.NotContains(t, byCode, "logging_100")
+	assert.Equal(t, 4, byCode["logging_7"].Progress)
+	assert.Equal(t, "Triple", byCode["protein_3"].Name)
+	assert.Equal(t, 1, byCode["protein_3"].Progress)
+}
+
+func TestValidateBadge(t *testing.T) {
+	valid := streaks.Badge{Code: "calories_14", Kind: streaks.KindCalories, Days: 14, Name: i18n.Texts{"en": "Fortnight"}}
+	assert.NoError(t, streaks.ValidateBadge(&valid))
+
+	invalid := valid
+	invalid.Code = "Calories 14"
+	assert.Error(t, streaks.ValidateBadge(&invalid))
+
+	invalid = valid
+	invalid.Kind = "weight"
+	assert.Error(t, streaks.ValidateBadge(&invalid))
+
+	invalid = valid
+	invalid.Days = 0
+	assert.Error(t, streaks.ValidateBadge(&invalid))
+
+	invalid = valid
+	invalid.Name = i18n.Texts{"fr": "Quinzaine"}
+	assert.EqualError(t, streaks.ValidateBadge(&invalid), "An English name is required")
+
+	// Withdrawing a badge only needs its code
+	assert.NoError(t, streaks.ValidateBadge(&streaks.Badge{Code: "logging_365", Disabled: true}))
+	assert.True(t, streaks.IsBuiltinBadge("logging_365"))
+}
+
+func TestMissedAdherence(t *testing.T) {
+	status := streaks.Evaluate(day(5), 3, 2600, 90, 0, &goal.NutritionGoal{Calories: 2000, Protein: 120}, streaks.DefaultCalorieTolerance)
+	require.False(t, status.WithinCalories)
+
+	missed := streaks.MissedAdherence(status)
+	assert.Equal(t, "2025-01-06", missed.Date)
+	assert.Equal(t, 2600.0, missed.Calories)
+	assert.Equal(t, 2000.0, missed.CalorieGoal)
+	assert.Equal(t, 120.0, missed.ProteinGoal)
+	assert.False(t, missed.ProteinHit)
+
+	assert.Equal(t, "goal.adherence.missed:4:2025-01-06", streaks.AdherenceDedupeKey(4, day(5)))
+}
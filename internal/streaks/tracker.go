@@ -0,0 +1,298 @@
+package streaks
+
+import (
+	"sort"
+	"time"
+
+	"ultra-bis/internal/diary"
+	"ultra-bis/internal/exercise"
+	"ultra-bis/internal/goal"
+)
+
+// Tracker keeps day statuses, streaks and achievements up to date as diary entries, exercises and goals change
+// Only the changed days are evaluated again; a user's history is evaluated once, on first use.
+type Tracker struct {
+	repo             *Repository
+	diaryRepo        *diary.Repository
+	goalRepo         *goal.Repository
+	exerciseRepo     *exercise.Repository
+	calorieTolerance float64
+}
+
+// NewTracker creates a tracker; calorieTolerance is the percentage of the calorie goal a day may deviate by
+func NewTracker(repo *Repository, diaryRepo *diary.Repository, goalRepo *goal.Repository, calorieTolerance float64) *Tracker {
+	return &Tracker{
+		repo:             repo,
+		diaryRepo:        diaryRepo,
+		goalRepo:         goalRepo,
+		calorieTolerance: calorieTolerance,
+	}
+}
+
+// SetExerciseRepo sets the exercise repository, so calorie goals include the calories burned
+// exercising as in the diary summary
+func (t *Tracker) SetExerciseRepo(repo *exercise.Repository) {
+	t.exerciseRepo = repo
+}
+
+// CalorieTolerance returns the percentage of the calorie goal a day may deviate by
+func (t *Tracker) CalorieTolerance() float64 {
+	return t.calorieTolerance
+}
+
+// DayChanged implements diary.DayObserver, goal.DayObserver and exercise.DayObserver: it evaluates the
+// given days of a user again and updates the streaks whose days started or stopped counting, then awards
+// any new badge. Days that were never evaluated and have no entries are skipped.
+func (t *Tracker) DayChanged(userID uint, dates ...time.Time) error {
+	streaks, initialized, err := t.load(userID)
+	if err != nil || initialized {
+		// A history evaluated just now already includes the change
+		return err
+	}
+
+	dates = uniqueDates(dates)
+	if len(dates) == 0 {
+		return nil
+	}
+	from, to := dates[0], dates[len(dates)-1]
+
+	days, err := t.loadDays(userID, from, to)
+	if err != nil {
+		return err
+	}
+	previous, err := t.repo.GetDayStatuses(userID, from, to)
+	if err != nil {
+		return err
+	}
+
+	changed := make(map[string]bool)
+	for _, date := range dates {
+		before, evaluated := previous[date.Format("2006-01-02")]
+		if !evaluated && days.totals[date].Entries == 0 {
+			continue
+		}
+
+		status := days.evaluate(userID, date, t.calorieTolerance)
+		if err := t.repo.SaveDayStatus(&status); err != nil {
+			return err
+		}
+
+		for _, kind := range Kinds {
+			counts := status.Counts(kind)
+			if evaluated && before.Counts(kind) == counts {
+				continue
+			}
+			streak := streaks[kind]
+			if !streak.Apply(date, counts) {
+				countingDates, err := t.repo.GetCountingDates(userID, kind)
+				if err != nil {
+					return err
+				}
+				streak.Rebuild(countingDates)
+			}
+			changed[kind] = true
+		}
+	}
+
+	for kind := range changed {
+		if err := t.repo.SaveStreak(streaks[kind]); err != nil {
+			return err
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return t.award(userID, streaks)
+}
+
+// Streaks returns a user's streaks keyed by kind, evaluating their history on first use
+func (t *Tracker) Streaks(userID uint) (map[string]*Streak, error) {
+	streaks, _, err := t.load(userID)
+	return streaks, err
+}
+
+// Achievements awards any badge the user's streaks reached since the badges changed, then returns
+// the earned badges, oldest first
+func (t *Tracker) Achievements(userID uint, streaks map[string]*Streak) ([]Achievement, error) {
+	if err := t.award(userID, streaks); err != nil {
+		return nil, err
+	}
+	return t.repo.GetAchievements(userID)
+}
+
+// Badges returns the badges users can earn: the built-in ones with the custom ones applied
+func (t *Tracker) Badges() ([]Badge, error) {
+	custom, err := t.repo.ListCustomBadges()
+	if err != nil {
+		return nil, err
+	}
+	return MergeBadges(custom), nil
+}
+
+// load returns a user's streaks; initialized is true when their history had to be evaluated first
+func (t *Tracker) load(userID uint) (streaks map[string]*Streak, initialized bool, err error) {
+	streaks, err = t.repo.GetStreaks(userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(streaks) == len(Kinds) {
+		return streaks, false, nil
+	}
+
+	streaks, err = t.evaluateHistory(userID)
+	return streaks, true, err
+}
+
+// evaluateHistory evaluates every logged day of a user and builds their streaks from scratch
+func (t *Tracker) evaluateHistory(userID uint) (map[string]*Streak, error) {
+	days, err := t.loadDays(userID, time.Time{}, time.Now().AddDate(1, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make([]time.Time, 0, len(days.totals))
+	for date := range days.totals {
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	statuses := make([]DayStatus, 0, len(dates))
+	for _, date := range dates {
+		statuses = append(statuses, days.evaluate(userID, date, t.calorieTolerance))
+	}
+	if err := t.repo.CreateDayStatuses(statuses); err != nil {
+		return nil, err
+	}
+
+	streaks := make(map[string]*Streak, len(Kinds))
+	for _, kind := range Kinds {
+		var countingDates []time.Time
+		for _, status := range statuses {
+			if status.Counts(kind) {
+				countingDates = append(countingDates, status.Date)
+			}
+		}
+
+		streak := &Streak{UserID: userID, Kind: kind}
+		streak.Rebuild(countingDates)
+		if err := t.repo.SaveStreak(streak); err != nil {
+			return nil, err
+		}
+		streaks[kind] = streak
+	}
+
+	if err := t.award(userID, streaks); err != nil {
+		return nil, err
+	}
+	return streaks, nil
+}
+
+// dayInputs holds what the days of a range are evaluated from, loaded in one query each
+type dayInputs struct {
+	totals   map[time.Time]diary.MealDayTotals
+	goals    []goal.NutritionGoal
+	dayTypes map[string]string
+	burned   map[string]exercise.Totals
+}
+
+// loadDays loads the meal totals, goals, day types and exercise of a user's days in [from, to]
+func (t *Tracker) loadDays(userID uint, from, to time.Time) (*dayInputs, error) {
+	totals, err := t.diaryRepo.GetMealTotalsByDay(userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	days := &dayInputs{totals: groupTotals(totals), dayTypes: map[string]string{}, burned: map[string]exercise.Totals{}}
+	if len(days.totals) == 0 {
+		return days, nil
+	}
+
+	// Only days with entries can count, so the range shrinks to the logged ones
+	first, last := to, from
+	for date := range days.totals {
+		if date.Before(first) {
+			first = date
+		}
+		if date.After(last) {
+			last = date
+		}
+	}
+
+	if days.goals, err = t.goalRepo.GetAll(userID); err != nil {
+		return nil, err
+	}
+	if days.dayTypes, err = t.goalRepo.GetDayTypes(userID, first, last); err != nil {
+		return nil, err
+	}
+	if t.exerciseRepo != nil {
+		if days.burned, err = t.exerciseRepo.GetTotalsByDay(userID, first, last); err != nil {
+			return nil, err
+		}
+	}
+	return days, nil
+}
+
+// evaluate builds the current status of one day from its entries, exercise and goal
+func (d *dayInputs) evaluate(userID uint, date time.Time, tolerance float64) DayStatus {
+	day := d.totals[date]
+	burned := d.burned[date.Format("2006-01-02")]
+	dayGoal := diary.EffectiveGoal(d.goals, d.dayTypes, date)
+
+	status := Evaluate(date, day.Entries, day.Calories, day.Protein, burned.Calories, dayGoal, tolerance)
+	status.UserID = userID
+	return status
+}
+
+// award records the badges the streaks reached that the user does not have yet
+func (t *Tracker) award(userID uint, streaks map[string]*Streak) error {
+	badges, err := t.Badges()
+	if err != nil {
+		return err
+	}
+	achievements, err := t.repo.GetAchievements(userID)
+	if err != nil {
+		return err
+	}
+
+	earned := make(map[string]bool, len(achievements))
+	for _, achievement := range achievements {
+		earned[achievement.BadgeCode] = true
+	}
+
+	var awarded []Achievement
+	for _, kind := range Kinds {
+		if streak, ok := streaks[kind]; ok {
+			awarded = append(awarded, NewlyEarned(badges, streak, earned)...)
+		}
+	}
+	return t.repo.CreateAchievements(awarded)
+}
+
+// groupTotals adds up the meal totals of each day, keyed by date
+func groupTotals(totals []diary.MealDayTotals) map[time.Time]diary.MealDayTotals {
+	days := make(map[time.Time]diary.MealDayTotals)
+	for _, row := range totals {
+		date := dateOnly(row.Day)
+		day := days[date]
+		day.Day = date
+		day.Entries += row.Entries
+		day.Calories += row.Calories
+		day.Protein += row.Protein
+		days[date] = day
+	}
+	return days
+}
+
+// uniqueDates returns the distinct days of dates, oldest first
+func uniqueDates(dates []time.Time) []time.Time {
+	seen := make(map[time.Time]bool, len(dates))
+	unique := make([]time.Time, 0, len(dates))
+	for _, date := range dates {
+		date = dateOnly(date)
+		if !seen[date] {
+			seen[date] = true
+			unique = append(unique, date)
+		}
+	}
+	sort.Slice(unique, func(i, j int) bool { return unique[i].Before(unique[j]) })
+	return unique
+}
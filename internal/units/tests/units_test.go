@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"ultra-bis/internal/diary"
+	"ultra-bis/internal/units"
+	"ultra-bis/internal/user"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	assert.Equal(t, units.Metric, units.Resolve("", "", "", ""))
+	assert.Equal(t, units.Imperial, units.Resolve("imperial", "", "", ""))
+
+	// Individual units override the system's defaults, unknown ones are ignored
+	prefs := units.Resolve("imperial", "st", "g", "kJ")
+	assert.Equal(t, units.Preferences{System: "imperial", Weight: "st", Height: "in", Quantity: "g", Energy: "kJ"}, prefs)
+	assert.Equal(t, units.Metric, units.Resolve("metric", "tons", "cups", "cal"))
+}
+
+func TestConversions(t *testing.T) {
+	imperial := units.Resolve("imperial", "", "", "kJ")
+
+	assert.Equal(t, 176.37, imperial.WeightFromKg(80))
+	assert.InDelta(t, 80, imperial.WeightToKg(176.37), 0.01)
+	assert.Equal(t, 12.6, units.Resolve("imperial", "st", "", "").WeightFromKg(80.0136))
+	assert.Equal(t, 70.87, imperial.HeightFromCm(180))
+	assert.InDelta(t, 180, imperial.HeightToCm(70.87), 0.01)
+	assert.Equal(t, 3.53, imperial.QuantityFromGrams(100))
+	assert.InDelta(t, 28.35, imperial.QuantityToGrams(1), 0.01)
+	assert.Equal(t, 8368.0, imperial.EnergyFromKcal(2000))
+	assert.InDelta(t, 2000, imperial.EnergyToKcal(8368), 0.001)
+
+	// Metric values are returned unchanged
+	assert.Equal(t, 80.123, units.Metric.WeightFromKg(80.123))
+	assert.Equal(t, 150.0, units.Metric.QuantityToGrams(150))
+}
+
+func TestFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	assert.Equal(t, units.Metric, units.FromRequest(r))
+
+	prefs := units.Resolve("metric", "", "", "kJ")
+	r = r.WithContext(units.WithPreferences(r.Context(), prefs))
+	assert.Equal(t, prefs, units.FromRequest(r))
+
+	// The units query parameter switches to a system's defaults
+	r = httptest.NewRequest("GET", "/metrics?units=imperial", nil)
+	r = r.WithContext(units.WithPreferences(r.Context(), prefs))
+	assert.Equal(t, units.Imperial, units.FromRequest(r))
+}
+
+func TestUserProfile_ConvertsHeightAndWeight(t *testing.T) {
+	u := user.User{UnitSystem: "imperial", Height: 180, Weight: 80}
+
+	profile := u.Profile(u.Units())
+	assert.Equal(t, 70.87, profile.Height)
+	assert.Equal(t, 176.37, profile.Weight)
+	assert.Equal(t, units.Imperial, profile.Units)
+
+	// The user itself keeps its canonical values
+	assert.Equal(t, 80.0, u.Weight)
+}
+
+func TestDiaryEntry_ConvertUnits(t *testing.T) {
+	inline := 250.0
+	entry := diary.DiaryEntry{
+		QuantityGrams:      200,
+		Calories:           500,
+		InlineFoodCalories: &inline,
+		CustomIngredients:  diary.CustomIngredients{{QuantityGrams: 50, Calories: 100}},
+	}
+
+	prefs := units.Resolve("imperial", "", "", "kJ")
+	entry.ConvertUnits(prefs)
+
+	assert.Equal(t, 200.0, entry.QuantityGrams, "quantity_grams stays in grams")
+	assert.Equal(t, 7.05, entry.Quantity)
+	assert.Equal(t, 2092.0, entry.Calories)
+	assert.Equal(t, 1046.0, *entry.InlineFoodCalories)
+	assert.Equal(t, 250.0, inline, "the request value is not modified")
+	assert.Equal(t, 1.76, entry.CustomIngredients[0].Quantity)
+	assert.Equal(t, 418.4, entry.CustomIngredients[0].Calories)
+	assert.Equal(t, &prefs, entry.Units)
+}
+
+func TestCreateDiaryEntryRequest_NormalizeUnits(t *testing.T) {
+	req := diary.CreateDiaryEntryRequest{
+		Quantity:           2,
+		InlineFoodCalories: 418.4,
+		CustomIngredients:  []diary.CustomIngredientRequest{{FoodID: 1, Quantity: 1}, {FoodID: 2, QuantityGrams: 30}},
+	}
+
+	req.NormalizeUnits(units.Resolve("imperial", "", "", "kJ"))
+
+	assert.InDelta(t, 56.7, req.QuantityGrams, 0.01)
+	assert.InDelta(t, 100, req.InlineFoodCalories, 0.001)
+	assert.InDelta(t, 28.35, req.CustomIngredients[0].QuantityGrams, 0.01)
+	assert.Equal(t, 30.0, req.CustomIngredients[1].QuantityGrams, "quantity_grams takes precedence")
+}
@@ -0,0 +1,203 @@
+package units
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// Unit systems
+const (
+	SystemMetric   = "metric"
+	SystemImperial = "imperial"
+)
+
+// Units of each measure; storage always uses the metric one
+const (
+	Kilogram    = "kg"
+	Pound       = "lb"
+	Stone       = "st"
+	Centimeter  = "cm"
+	Inch        = "in"
+	Gram        = "g"
+	Ounce       = "oz"
+	Kilocalorie = "kcal"
+	Kilojoule   = "kJ"
+)
+
+// Conversion factors to the canonical units
+const (
+	kgPerPound    = 0.45359237
+	kgPerStone    = 14 * kgPerPound
+	cmPerInch     = 2.54
+	gramsPerOunce = 28.349523125
+	kJPerKcal     = 4.184
+)
+
+// Preferences are the units a user reads and writes values in
+// It is also returned in payloads to state the units of their values
+type Preferences struct {
+	System   string `json:"system"`
+	Weight   string `json:"weight"`   // Body weight: kg, lb or st
+	Height   string `json:"height"`   // Follows the system: cm or in
+	Quantity string `json:"quantity"` // Food quantities: g or oz
+	Energy   string `json:"energy"`   // kcal or kJ
+}
+
+// Metric is the canonical unit set, used for storage and by default
+var Metric = Preferences{System: SystemMetric, Weight: Kilogram, Height: Centimeter, Quantity: Gram, Energy: Kilocalorie}
+
+// Imperial is the default unit set of the imperial system
+var Imperial = Preferences{System: SystemImperial, Weight: Pound, Height: Inch, Quantity: Ounce, Energy: Kilocalorie}
+
+// ForSystem returns the default units of a system, Metric when unknown
+func ForSystem(system string) Preferences {
+	if system == SystemImperial {
+		return Imperial
+	}
+	return Metric
+}
+
+// Resolve returns the units of a system with the given overrides; empty overrides follow the system
+func Resolve(system, weight, quantity, energy string) Preferences {
+	prefs := ForSystem(system)
+	if ValidWeight(weight) {
+		prefs.Weight = weight
+	}
+	if ValidQuantity(quantity) {
+		prefs.Quantity = quantity
+	}
+	if ValidEnergy(energy) {
+		prefs.Energy = energy
+	}
+	return prefs
+}
+
+// ValidSystem checks if system is a known unit system
+func ValidSystem(system string) bool {
+	return system == SystemMetric || system == SystemImperial
+}
+
+// ValidWeight checks if unit is a known body weight unit
+func ValidWeight(unit string) bool {
+	return unit == Kilogram || unit == Pound || unit == Stone
+}
+
+// ValidQuantity checks if unit is a known food quantity unit
+func ValidQuantity(unit string) bool {
+	return unit == Gram || unit == Ounce
+}
+
+// ValidEnergy checks if unit is a known energy unit
+func ValidEnergy(unit string) bool {
+	return unit == Kilocalorie || unit == Kilojoule
+}
+
+// WeightFromKg converts a stored weight to the preferred unit
+func (p Preferences) WeightFromKg(kg float64) float64 {
+	switch p.Weight {
+	case Pound:
+		return round(kg / kgPerPound)
+	case Stone:
+		return round(kg / kgPerStone)
+	}
+	return kg
+}
+
+// WeightToKg converts a weight given in the preferred unit for storage
+func (p Preferences) WeightToKg(weight float64) float64 {
+	switch p.Weight {
+	case Pound:
+		return weight * kgPerPound
+	case Stone:
+		return weight * kgPerStone
+	}
+	return weight
+}
+
+// HeightFromCm converts a stored height to the preferred unit
+func (p Preferences) HeightFromCm(cm float64) float64 {
+	if p.Height == Inch {
+		return round(cm / cmPerInch)
+	}
+	return cm
+}
+
+// HeightToCm converts a height given in the preferred unit for storage
+func (p Preferences) HeightToCm(height float64) float64 {
+	if p.Height == Inch {
+		return height * cmPerInch
+	}
+	return height
+}
+
+// QuantityFromGrams converts a stored food quantity to the preferred unit
+func (p Preferences) QuantityFromGrams(grams float64) float64 {
+	if p.Quantity == Ounce {
+		return round(grams / gramsPerOunce)
+	}
+	return grams
+}
+
+// QuantityToGrams converts a food quantity given in the preferred unit for storage
+func (p Preferences) QuantityToGrams(quantity float64) float64 {
+	if p.Quantity == Ounce {
+		return quantity * gramsPerOunce
+	}
+	return quantity
+}
+
+// EnergyFromKcal converts stored calories to the preferred unit
+func (p Preferences) EnergyFromKcal(kcal float64) float64 {
+	if p.Energy == Kilojoule {
+		return round(kcal * kJPerKcal)
+	}
+	return kcal
+}
+
+// EnergyToKcal converts an energy given in the preferred unit for storage
+func (p Preferences) EnergyToKcal(energy float64) float64 {
+	if p.Energy == Kilojoule {
+		return energy / kJPerKcal
+	}
+	return energy
+}
+
+// round rounds a converted value to 2 decimal places
+func round(val float64) float64 {
+	return math.Round(val*100) / 100
+}
+
+// contextKey is a custom type for context keys to avoid collisions
+type contextKey string
+
+// preferencesKey is the context key for the authenticated user's units
+const preferencesKey contextKey = "units"
+
+// WithPreferences returns a context carrying the user's units
+func WithPreferences(ctx context.Context, prefs Preferences) context.Context {
+	return context.WithValue(ctx, preferencesKey, prefs)
+}
+
+// FromContext returns the units carried by a context, or Metric
+func FromContext(ctx context.Context) Preferences {
+	if prefs, ok := ctx.Value(preferencesKey).(Preferences); ok {
+		return prefs
+	}
+	return Metric
+}
+
+// FromRequest returns the units of a request: the user's preferences, unless
+// the units query parameter asks for a system's defaults (?units=imperial)
+func FromRequest(r *http.Request) Preferences {
+	return Override(r, FromContext(r.Context()))
+}
+
+// Override applies the units query parameter of a request to prefs
+func Override(r *http.Request, prefs Preferences) Preferences {
+	if system := strings.ToLower(r.URL.Query().Get("units")); ValidSystem(system) {
+		return ForSystem(system)
+	}
+	return prefs
+}
@@ -5,6 +5,9 @@ import (
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+
+	"ultra-bis/internal/calendar"
+	"ultra-bis/internal/units"
 )
 
 // ActivityLevel represents the user's physical activity level
@@ -27,6 +30,20 @@ const (
 	Gain     GoalType = "gain"      // Gain weight/muscle
 )
 
+// Role represents the access level of a user
+type Role string
+
+const (
+	RoleUser  Role = "user"  // Regular account, owns its own data
+	RoleCoach Role = "coach" // Nutrition coach
+	RoleAdmin Role = "admin" // Curates global recipes, general foods and users
+)
+
+// ValidateRole checks if role is a known role
+func ValidateRole(role Role) bool {
+	return role == RoleUser || role == RoleCoach || role == RoleAdmin
+}
+
 // User represents a user in the system
 type User struct {
 	ID            uint          `json:"id" gorm:"primarykey"`
@@ -43,6 +60,17 @@ type User struct {
 	BodyFat       float64       `json:"body_fat" gorm:"type:decimal(5,2)"` // body fat percentage
 	ActivityLevel ActivityLevel `json:"activity_level" gorm:"type:varchar(20);default:'moderate'"`
 	GoalType      GoalType      `json:"goal_type" gorm:"type:varchar(20);default:'maintain'"`
+	Role          Role          `json:"role" gorm:"type:varchar(20);not null;default:'user';index"`
+	Locale        string        `json:"locale" gorm:"type:varchar(10)"` // Preferred language for API messages, empty to use Accept-Language
+	UnitSystem    string        `json:"unit_system" gorm:"type:varchar(10);not null;default:'metric'"` // "metric" or "imperial"
+	WeightUnit    string        `json:"weight_unit" gorm:"type:varchar(5)"`   // "kg", "lb" or "st", empty to follow the unit system
+	QuantityUnit  string        `json:"quantity_unit" gorm:"type:varchar(5)"` // "g" or "oz", empty to follow the unit system
+	EnergyUnit    string        `json:"energy_unit" gorm:"type:varchar(5)"`   // "kcal" or "kJ", empty to follow the unit system
+	TimeZone      string        `json:"time_zone" gorm:"type:varchar(64)"`                            // IANA time zone for "today" and day boundaries, empty for UTC
+	WeekStart     string        `json:"week_start" gorm:"type:varchar(10);not null;default:'monday'"` // First day of the week, e.g. "monday" or "sunday"
+	FastingProtocol string      `json:"fasting_protocol" gorm:"type:varchar(10)"` // "16:8", "5:2" or "omad", empty when not fasting
+	Disabled      bool          `json:"disabled" gorm:"not null;default:false"`
+	DisabledAt    *time.Time    `json:"disabled_at,omitempty"`
 }
 
 // RegisterRequest represents the registration request
@@ -74,6 +102,61 @@ type UpdateProfileRequest struct {
 	BodyFat       float64       `json:"body_fat"`
 	ActivityLevel ActivityLevel `json:"activity_level"`
 	GoalType      GoalType      `json:"goal_type"`
+	Locale        string        `json:"locale"`
+	UnitSystem    string        `json:"unit_system"`
+	WeightUnit    string        `json:"weight_unit"`
+	QuantityUnit  string        `json:"quantity_unit"`
+	EnergyUnit    string        `json:"energy_unit"`
+	TimeZone      string        `json:"time_zone"`
+	WeekStart     string        `json:"week_start"`
+}
+
+// ProfileResponse represents a user's profile with height and weight in their preferred units
+type ProfileResponse struct {
+	User
+	Units units.Preferences `json:"units"`
+}
+
+// UpdateRoleRequest represents an admin request to change a user's role
+type UpdateRoleRequest struct {
+	Role Role `json:"role"`
+}
+
+// UserListResponse represents a paginated list of users for admin endpoints
+type UserListResponse struct {
+	Count    int64  `json:"count"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+	Users    []User `json:"users"`
+}
+
+// Preferences are the profile settings applied to each of a user's requests
+type Preferences struct {
+	Locale   string // "" to use Accept-Language
+	Units    units.Preferences
+	Calendar calendar.Settings
+}
+
+// Preferences returns the user's locale, units and calendar settings
+func (u *User) Preferences() Preferences {
+	return Preferences{Locale: u.Locale, Units: u.Units(), Calendar: u.Calendar()}
+}
+
+// Calendar returns the user's time zone and first day of the week
+func (u *User) Calendar() calendar.Settings {
+	return calendar.New(u.TimeZone, u.WeekStart)
+}
+
+// Units returns the units the user reads and writes values in
+func (u *User) Units() units.Preferences {
+	return units.Resolve(u.UnitSystem, u.WeightUnit, u.QuantityUnit, u.EnergyUnit)
+}
+
+// Profile returns the user's profile converted to prefs
+func (u User) Profile(prefs units.Preferences) ProfileResponse {
+	u.Height = prefs.HeightFromCm(u.Height)
+	u.Weight = prefs.WeightFromKg(u.Weight)
+	return ProfileResponse{User: u, Units: prefs}
 }
 
 // HashPassword hashes the user's password
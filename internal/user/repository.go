@@ -3,6 +3,7 @@ package user
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -74,3 +75,99 @@ func (r *Repository) EmailExists(email string) (bool, error) {
 	}
 	return count > 0, nil
 }
+
+// List retrieves users with pagination, optionally filtered by email or name
+func (r *Repository) List(query string, page, pageSize int) ([]User, int64, error) {
+	var users []User
+	var count int64
+
+	db := r.db.Model(&User{})
+	if query != "" {
+		db = db.Where("LOWER(email) LIKE LOWER(?) OR LOWER(name) LIKE LOWER(?)", "%"+query+"%", "%"+query+"%")
+	}
+
+	if err := db.Count(&count).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := db.Order("id ASC").Limit(pageSize).Offset(offset).Find(&users).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return users, count, nil
+}
+
+// SetRole changes the role of a user
+func (r *Repository) SetRole(id uint, role Role) error {
+	result := r.db.Model(&User{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update role: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// SetDisabled enables or disables a user account
+func (r *Repository) SetDisabled(id uint, disabled bool) error {
+	var disabledAt *time.Time
+	if disabled {
+		now := time.Now()
+		disabledAt = &now
+	}
+
+	result := r.db.Model(&User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"disabled":    disabled,
+		"disabled_at": disabledAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update account status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// Preferences returns the locale, units and calendar a user chose in their profile
+func (r *Repository) Preferences(id uint) (Preferences, error) {
+	var u User
+	result := r.db.Select("id", "locale", "unit_system", "weight_unit", "quantity_unit", "energy_unit", "time_zone", "week_start").
+		Limit(1).Find(&u, id)
+	if result.Error != nil {
+		return Preferences{}, fmt.Errorf("failed to get preferences: %w", result.Error)
+	}
+	return u.Preferences(), nil
+}
+
+// AccountStatus reports whether a user account has been disabled, and its current role
+// Unknown users are reported as disabled so their tokens stop working
+func (r *Repository) AccountStatus(id uint) (disabled bool, role string, err error) {
+	var u User
+	result := r.db.Select("id", "disabled", "role").First(&u, id)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return true, "", nil
+	}
+	if result.Error != nil {
+		return false, "", fmt.Errorf("failed to get account status: %w", result.Error)
+	}
+
+	return u.Disabled, string(u.Role), nil
+}
+
+// PromoteAdmins grants the admin role to the users with the given emails
+// Used at startup to bootstrap administrators from configuration
+func (r *Repository) PromoteAdmins(emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+
+	result := r.db.Model(&User{}).Where("email IN ?", emails).Update("role", RoleAdmin)
+	if result.Error != nil {
+		return fmt.Errorf("failed to promote admins: %w", result.Error)
+	}
+	return nil
+}
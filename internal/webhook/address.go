@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+// errBlockedAddress is returned for endpoints on loopback, link-local, private, reserved or unspecified addresses
+var errBlockedAddress = errors.New("url must not point to a local or private address")
+
+// blockedPrefixes are the ranges deliveries are refused to: the API must not be used to reach
+// itself, the cloud metadata service or the private network it runs in
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This" network, unspecified
+	netip.MustParsePrefix("10.0.0.0/8"),      // Private
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT
+	netip.MustParsePrefix("127.0.0.0/8"),     // Loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // Link-local, cloud metadata
+	netip.MustParsePrefix("172.16.0.0/12"),   // Private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // Documentation
+	netip.MustParsePrefix("192.168.0.0/16"),  // Private
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // Documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // Documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // Multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved, broadcast
+	netip.MustParsePrefix("::/96"),           // Unspecified, loopback, IPv4-compatible
+	netip.MustParsePrefix("100::/64"),        // Discard
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+	netip.MustParsePrefix("fc00::/7"),        // Unique local
+	netip.MustParsePrefix("fe80::/10"),       // Link-local
+	netip.MustParsePrefix("ff00::/8"),        // Multicast
+}
+
+// IPv6 ranges embedding an IPv4 address, which is checked in turn
+var (
+	nat64Prefix = netip.MustParsePrefix("64:ff9b::/96") // IPv4 in the last 4 bytes
+	sixToFour   = netip.MustParsePrefix("2002::/16")    // IPv4 in bytes 2 to 5
+)
+
+// BlockedIP reports whether deliveries to ip are refused; IPv4-mapped and other IPv6 forms
+// of an IPv4 address are blocked like the address itself
+func BlockedIP(ip net.IP) bool {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return true
+	}
+	return blockedAddr(addr.Unmap())
+}
+
+// blockedAddr reports whether addr, unmapped, is in a blocked range
+func blockedAddr(addr netip.Addr) bool {
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	bytes := addr.As16()
+	switch {
+	case nat64Prefix.Contains(addr):
+		return blockedAddr(netip.AddrFrom4([4]byte(bytes[12:16])))
+	case sixToFour.Contains(addr):
+		return blockedAddr(netip.AddrFrom4([4]byte(bytes[2:6])))
+	}
+	return false
+}
+
+// validateHost rejects hosts that are local names or blocked IP literals
+// Other names are checked once resolved, when the dispatcher connects
+func validateHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errBlockedAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && BlockedIP(ip) {
+		return errBlockedAddress
+	}
+	return nil
+}
+
+// dialControl refuses connections to blocked addresses, checked on the resolved IP so that
+// DNS rebinding and redirects cannot reach them either
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || BlockedIP(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", host)
+	}
+	return nil
+}
+
+// NewDeliveryClient returns the HTTP client deliveries are sent with: it times out after
+// RequestTimeout, ignores proxy settings and only connects to public addresses
+func NewDeliveryClient() *http.Client {
+	dialer := &net.Dialer{Timeout: RequestTimeout, Control: dialControl}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: RequestTimeout, Transport: transport}
+}
@@ -0,0 +1,190 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Delivery retry policy
+const (
+	MaxAttempts    = 10               // Attempts before a delivery is marked failed
+	BaseRetryDelay = time.Minute      // Delay after the first failed attempt, doubled after each one
+	MaxRetryDelay  = 6 * time.Hour    // Longest delay between two attempts
+	RequestTimeout = 10 * time.Second // Time an endpoint has to answer
+
+	// Retention of finished deliveries and of events; longer than the adherence lookback,
+	// so that a pruned dedupe key is never published again
+	Retention = 30 * 24 * time.Hour
+
+	claimBatch    = 50
+	deliveryLease = 10 * time.Minute // Long enough to send a whole batch
+)
+
+// Backoff returns the delay before the next attempt of a delivery that failed attempts times
+func Backoff(attempts int) time.Duration {
+	delay := BaseRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= MaxRetryDelay {
+			return MaxRetryDelay
+		}
+	}
+	return delay
+}
+
+// RecordAttempt records the outcome of an attempt: delivered on a 2xx status, otherwise
+// retried after Backoff until MaxAttempts, then failed
+func (d *Delivery) RecordAttempt(status int, err error, now time.Time) {
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.ResponseStatus = status
+
+	if err == nil && status >= 200 && status < 300 {
+		d.Status = StatusDelivered
+		d.DeliveredAt = &now
+		d.LastError = ""
+		return
+	}
+
+	switch {
+	case err != nil:
+		d.LastError = err.Error()
+	default:
+		d.LastError = "endpoint answered " + strconv.Itoa(status)
+	}
+	if d.Attempts >= MaxAttempts {
+		d.Status = StatusFailed
+		return
+	}
+	d.NextAttemptAt = now.Add(Backoff(d.Attempts))
+}
+
+// Send POSTs the event to the endpoint, signed with its secret, and returns the response status
+func Send(client *http.Client, endpoint *Endpoint, event *Event, delivery *Delivery, now time.Time) (int, error) {
+	body, err := json.Marshal(Envelope{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		UserID:    event.UserID,
+		Data:      event.Data,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid endpoint URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Ultra-Bis-Webhooks/1.0")
+	req.Header.Set(EventHeader, event.Type)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, now, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	return resp.StatusCode, nil
+}
+
+// Dispatcher delivers the pending deliveries of the outbox, with retries
+type Dispatcher struct {
+	repo   *Repository
+	client *http.Client
+}
+
+// NewDispatcher creates a new dispatcher
+func NewDispatcher(repo *Repository) *Dispatcher {
+	return &Dispatcher{repo: repo, client: NewDeliveryClient()}
+}
+
+// DeliverDue sends the deliveries due at now, batch after batch
+// Returns how many were delivered and how many attempts failed
+func (d *Dispatcher) DeliverDue(now time.Time) (delivered, failed int, err error) {
+	for {
+		deliveries, err := d.repo.ClaimDue(now, now.Add(deliveryLease), claimBatch)
+		if err != nil {
+			return delivered, failed, err
+		}
+
+		for i := range deliveries {
+			if err := d.deliver(&deliveries[i]); err != nil {
+				return delivered, failed, err
+			}
+			if deliveries[i].Status == StatusDelivered {
+				delivered++
+			} else {
+				failed++
+			}
+		}
+
+		if len(deliveries) < claimBatch {
+			return delivered, failed, nil
+		}
+	}
+}
+
+// deliver makes one attempt of a delivery and records its outcome
+func (d *Dispatcher) deliver(delivery *Delivery) error {
+	now := time.Now()
+
+	endpoint, err := d.repo.GetEndpointByID(delivery.EndpointID)
+	if err != nil || !endpoint.Active {
+		delivery.Status = StatusFailed
+		delivery.LastError = "endpoint is inactive or was deleted"
+		return d.repo.SaveDelivery(delivery)
+	}
+	event, err := d.repo.GetEvent(delivery.EventID)
+	if err != nil {
+		delivery.Status = StatusFailed
+		delivery.LastError = "event was deleted"
+		return d.repo.SaveDelivery(delivery)
+	}
+
+	status, err := Send(d.client, endpoint, event, delivery, now)
+	delivery.RecordAttempt(status, err, now)
+	return d.repo.SaveDelivery(delivery)
+}
+
+// Run calls DeliverDue every interval; it blocks and is meant to run in its own goroutine
+func (d *Dispatcher) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if delivered, failed, err := d.DeliverDue(time.Now()); err != nil {
+			log.Printf("Webhook delivery failed: %v", err)
+		} else if delivered > 0 || failed > 0 {
+			log.Printf("Webhooks: %d delivered, %d failed attempts", delivered, failed)
+		}
+		<-ticker.C
+	}
+}
+
+// RunRetention prunes the deliveries and events older than Retention every interval; it blocks
+// and is meant to run in its own goroutine
+func (d *Dispatcher) RunRetention(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if deliveries, events, err := d.repo.Prune(time.Now().Add(-Retention)); err != nil {
+			log.Printf("Webhook retention failed: %v", err)
+		} else if deliveries > 0 || events > 0 {
+			log.Printf("Webhooks: pruned %d deliveries and %d events", deliveries, events)
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ultra-bis/internal/httputil"
+	"ultra-bis/internal/user"
+)
+
+// Delivery log page sizes
+const (
+	DefaultDeliveryLimit = 50
+	MaxDeliveryLimit     = 200
+)
+
+// Handler handles webhook endpoint and delivery requests
+type Handler struct {
+	repo *Repository
+}
+
+// NewHandler creates a new webhook handler
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// ListEventTypes handles GET /webhooks/events
+func (h *Handler) ListEventTypes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, EventTypes)
+}
+
+// CreateEndpoint handles POST /webhooks
+// The endpoint's signing secret is only returned in this response
+func (h *Handler) CreateEndpoint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req EndpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !h.validate(w, r, &req) {
+		return
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	endpoint := &Endpoint{UserID: userID, Secret: secret, Active: true}
+	endpoint.apply(&req)
+	if err := h.repo.CreateEndpoint(endpoint); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusCreated, EndpointResponse{Endpoint: *endpoint, Secret: secret})
+}
+
+// ListEndpoints handles GET /webhooks
+func (h *Handler) ListEndpoints(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	endpoints, err := h.repo.ListEndpoints(userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, endpoints)
+}
+
+// GetEndpoint handles GET /webhooks/{id}
+func (h *Handler) GetEndpoint(w http.ResponseWriter, r *http.Request, id uint) {
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	endpoint, err := h.repo.GetEndpoint(id, userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Webhook endpoint not found")
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, endpoint)
+}
+
+// UpdateEndpoint handles PUT /webhooks/{id}
+// Replaces the URL, description, events and scope; active pauses or resumes deliveries
+func (h *Handler) UpdateEndpoint(w http.ResponseWriter, r *http.Request, id uint) {
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req EndpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	endpoint, err := h.repo.GetEndpoint(id, userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Webhook endpoint not found")
+		return
+	}
+	if !h.validate(w, r, &req) {
+		return
+	}
+
+	endpoint.apply(&req)
+	if err := h.repo.UpdateEndpoint(endpoint); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, endpoint)
+}
+
+// DeleteEndpoint handles DELETE /webhooks/{id}
+func (h *Handler) DeleteEndpoint(w http.ResponseWriter, r *http.Request, id uint) {
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.repo.DeleteEndpoint(id, userID); err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Webhook endpoint not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries handles GET /webhooks/{id}/deliveries?status=pending|delivered|failed&limit=N
+// Returns the endpoint's delivery log, newest first
+func (h *Handler) ListDeliveries(w http.ResponseWriter, r *http.Request, id uint) {
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if _, err := h.repo.GetEndpoint(id, userID); err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Webhook endpoint not found")
+		return
+	}
+
+	query := r.URL.Query()
+	status := query.Get("status")
+	if status != "" && status != StatusPending && status != StatusDelivered && status != StatusFailed {
+		httputil.WriteError(w, http.StatusBadRequest, "status must be 'pending', 'delivered' or 'failed'")
+		return
+	}
+	limit := DefaultDeliveryLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > MaxDeliveryLimit {
+			httputil.WriteError(w, http.StatusBadRequest, "limit must be between 1 and 200")
+			return
+		}
+	}
+
+	deliveries, err := h.repo.ListDeliveries(id, status, limit)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, deliveries)
+}
+
+// ReplayDelivery handles POST /webhooks/{id}/deliveries/{deliveryID}/replay
+// Schedules the delivery's event to be sent again now, as a new delivery
+func (h *Handler) ReplayDelivery(w http.ResponseWriter, r *http.Request, id, deliveryID uint) {
+	userID, ok := httputil.GetUserID(r)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	endpoint, err := h.repo.GetEndpoint(id, userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Webhook endpoint not found")
+		return
+	}
+	original, err := h.repo.GetDelivery(deliveryID, endpoint.ID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Webhook delivery not found")
+		return
+	}
+	if original.Status == StatusPending {
+		httputil.WriteError(w, http.StatusConflict, "Delivery is still pending")
+		return
+	}
+
+	replay := &Delivery{
+		UserID:        endpoint.UserID,
+		EndpointID:    endpoint.ID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Status:        StatusPending,
+		NextAttemptAt: time.Now(),
+		ReplayOf:      &original.ID,
+	}
+	if err := h.repo.CreateDelivery(replay); err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusAccepted, replay)
+}
+
+// validate checks an endpoint request and that only admins receive every user's events,
+// writing the error response when it fails
+func (h *Handler) validate(w http.ResponseWriter, r *http.Request, req *EndpointRequest) bool {
+	if err := ValidateEndpointRequest(req); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	if role, _ := httputil.GetUserRole(r); req.AllUsers && role != string(user.RoleAdmin) {
+		httputil.WriteError(w, http.StatusForbidden, "Only admins can receive the events of all users")
+		return false
+	}
+	return true
+}
+
+// apply copies the request's values to the endpoint
+func (e *Endpoint) apply(req *EndpointRequest) {
+	e.URL = req.URL
+	e.Description = req.Description
+	e.Events = EventList(req.Events)
+	e.AllUsers = req.AllUsers
+	if req.Active != nil {
+		e.Active = *req.Active
+	}
+}
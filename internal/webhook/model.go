@@ -0,0 +1,184 @@
+package webhook
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Event types
+const (
+	EventDiaryEntryCreated   = "diary.entry.created"
+	EventGoalActivated       = "goal.activated"
+	EventMetricRecorded      = "metric.recorded"
+	EventGoalAdherenceMissed = "goal.adherence.missed"
+)
+
+// EventTypes lists the event types an endpoint can subscribe to
+var EventTypes = []string{EventDiaryEntryCreated, EventGoalActivated, EventMetricRecorded, EventGoalAdherenceMissed}
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed" // Gave up after MaxAttempts, or the endpoint is gone
+)
+
+// EventList is a list of event types stored as JSONB
+type EventList []string
+
+// Value implements the driver.Valuer interface for JSONB serialization
+func (e EventList) Value() (driver.Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return json.Marshal(e)
+}
+
+// Scan implements the sql.Scanner interface for JSONB deserialization
+func (e *EventList) Scan(value interface{}) error {
+	if value == nil {
+		*e = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to scan EventList: not a byte slice")
+	}
+
+	return json.Unmarshal(bytes, e)
+}
+
+// Payload is an event's data, stored and sent as raw JSON
+type Payload json.RawMessage
+
+// Value implements the driver.Valuer interface for JSONB serialization
+func (p Payload) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	return []byte(p), nil
+}
+
+// Scan implements the sql.Scanner interface for JSONB deserialization
+func (p *Payload) Scan(value interface{}) error {
+	if value == nil {
+		*p = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to scan Payload: not a byte slice")
+	}
+
+	*p = append(Payload(nil), bytes...)
+	return nil
+}
+
+// MarshalJSON writes the payload as is
+func (p Payload) MarshalJSON() ([]byte, error) {
+	if len(p) == 0 {
+		return []byte("null"), nil
+	}
+	return p, nil
+}
+
+// UnmarshalJSON keeps a copy of the raw JSON
+func (p *Payload) UnmarshalJSON(data []byte) error {
+	*p = append(Payload(nil), data...)
+	return nil
+}
+
+// Endpoint is a URL registered to receive a user's events, signed with its secret
+// Endpoints of admins with AllUsers receive the events of every user
+type Endpoint struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	URL         string    `json:"url" gorm:"type:varchar(2048);not null"`
+	Description string    `json:"description,omitempty" gorm:"type:varchar(255)"`
+	Events      EventList `json:"events" gorm:"type:jsonb"` // Subscribed event types, empty for all
+	AllUsers    bool      `json:"all_users" gorm:"not null;default:false"`
+	Active      bool      `json:"active" gorm:"not null;default:true"`
+	Secret      string    `json:"-" gorm:"type:varchar(80);not null"` // HMAC key, shown once at creation
+}
+
+// Subscribed reports whether the endpoint receives events of eventType
+func (e *Endpoint) Subscribed(eventType string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range e.Events {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is an entry of the outbox: something that happened to a user, to be delivered to endpoints
+type Event struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Type      string    `json:"type" gorm:"type:varchar(50);not null"`
+	DedupeKey *string   `json:"-" gorm:"type:varchar(100);uniqueIndex"` // Set for events that must be published only once
+	Data      Payload   `json:"data" gorm:"type:jsonb"`
+}
+
+// Delivery is one event sent to one endpoint, with its attempts; deliveries form the delivery log
+type Delivery struct {
+	ID             uint       `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	UserID         uint       `json:"-" gorm:"not null;index"` // Owner of the endpoint
+	EndpointID     uint       `json:"endpoint_id" gorm:"not null;index"`
+	EventID        uint       `json:"event_id" gorm:"not null;index"`
+	EventType      string     `json:"event_type" gorm:"type:varchar(50);not null"`
+	Status         string     `json:"status" gorm:"type:varchar(10);not null;index:idx_webhook_delivery_due"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"not null;index:idx_webhook_delivery_due"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty"` // HTTP status of the last attempt
+	LastError      string     `json:"last_error,omitempty" gorm:"type:text"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	ReplayOf       *uint      `json:"replay_of,omitempty"` // Delivery this one replays
+}
+
+// Envelope is the JSON body POSTed to endpoints
+type Envelope struct {
+	ID        uint      `json:"id"` // Event ID, the same across retries and replays
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint      `json:"user_id"`
+	Data      Payload   `json:"data"`
+}
+
+// EndpointRequest represents the request to register or update an endpoint
+type EndpointRequest struct {
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	Events      []string `json:"events"`    // Empty for all event types
+	AllUsers    bool     `json:"all_users"` // Admins only
+	Active      *bool    `json:"active"`    // Defaults to true
+}
+
+// EndpointResponse represents an endpoint with its secret, only returned on creation
+type EndpointResponse struct {
+	Endpoint
+	Secret string `json:"secret"`
+}
+
+// AdherenceMissed is the data of a goal.adherence.missed event
+type AdherenceMissed struct {
+	Date        string  `json:"date"`
+	Calories    float64 `json:"calories"`
+	CalorieGoal float64 `json:"calorie_goal"`
+	Protein     float64 `json:"protein"`
+	ProteinGoal float64 `json:"protein_goal"`
+	ProteinHit  bool    `json:"protein_hit"`
+}
@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Publisher writes events to the outbox; the dispatcher delivers them
+type Publisher struct {
+	repo *Repository
+}
+
+// NewPublisher creates a new publisher
+func NewPublisher(repo *Repository) *Publisher {
+	return &Publisher{repo: repo}
+}
+
+// Publish records an event of a user with data as its JSON payload
+func (p *Publisher) Publish(userID uint, eventType string, data interface{}) error {
+	_, err := p.publish(userID, eventType, nil, data)
+	return err
+}
+
+// PublishOnce records an event unless one with the same dedupe key was already published
+// Returns false when the event was a duplicate
+func (p *Publisher) PublishOnce(userID uint, eventType, dedupeKey string, data interface{}) (bool, error) {
+	return p.publish(userID, eventType, &dedupeKey, data)
+}
+
+// Notify publishes an event and logs a failure rather than returning it, for callers
+// whose change was already saved and must not fail because of a webhook
+// A nil publisher does nothing
+func (p *Publisher) Notify(userID uint, eventType string, data interface{}) {
+	if p == nil {
+		return
+	}
+	if err := p.Publish(userID, eventType, data); err != nil {
+		log.Printf("Failed to publish %s for user %d: %v", eventType, userID, err)
+	}
+}
+
+// publish marshals data and enqueues the event
+func (p *Publisher) publish(userID uint, eventType string, dedupeKey *string, data interface{}) (bool, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	event := &Event{UserID: userID, Type: eventType, DedupeKey: dedupeKey, Data: Payload(payload)}
+	return p.repo.Enqueue(event, time.Now())
+}
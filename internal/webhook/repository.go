@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ultra-bis/internal/user"
+)
+
+// Repository handles database operations for webhook endpoints, the event outbox and deliveries
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new webhook repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateEndpoint registers an endpoint
+func (r *Repository) CreateEndpoint(endpoint *Endpoint) error {
+	if err := r.db.Create(endpoint).Error; err != nil {
+		return fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+// ListEndpoints retrieves a user's endpoints, oldest first
+func (r *Repository) ListEndpoints(userID uint) ([]Endpoint, error) {
+	var endpoints []Endpoint
+	if err := r.db.Where("user_id = ?", userID).Order("id").Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// GetEndpoint retrieves one of a user's endpoints
+func (r *Repository) GetEndpoint(id, userID uint) (*Endpoint, error) {
+	var endpoint Endpoint
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&endpoint).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook endpoint not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+	return &endpoint, nil
+}
+
+// UpdateEndpoint saves an endpoint
+func (r *Repository) UpdateEndpoint(endpoint *Endpoint) error {
+	if err := r.db.Save(endpoint).Error; err != nil {
+		return fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+// DeleteEndpoint removes one of a user's endpoints and its delivery log
+func (r *Repository) DeleteEndpoint(id, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&Endpoint{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete webhook endpoint: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("webhook endpoint not found")
+		}
+		if err := tx.Where("endpoint_id = ?", id).Delete(&Delivery{}).Error; err != nil {
+			return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+		}
+		return nil
+	})
+}
+
+// Enqueue writes an event to the outbox with a pending delivery for every active endpoint
+// subscribed to it, in one transaction. An event no endpoint subscribes to is not written,
+// unless it has a dedupe key, which must be remembered. An event whose dedupe key was already
+// published is skipped; enqueued reports whether the event was new
+func (r *Repository) Enqueue(event *Event, now time.Time) (enqueued bool, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		// An endpoint receives every user's events only while its owner is still an admin
+		var endpoints []Endpoint
+		err := tx.Select("webhook_endpoints.*").
+			Joins("JOIN users ON users.id = webhook_endpoints.user_id").
+			Where("webhook_endpoints.active = ? AND (webhook_endpoints.user_id = ? OR (webhook_endpoints.all_users = ? AND users.role = ?))",
+				true, event.UserID, true, string(user.RoleAdmin)).
+			Find(&endpoints).Error
+		if err != nil {
+			return fmt.Errorf("failed to find webhook endpoints: %w", err)
+		}
+
+		var subscribed []Endpoint
+		for i := range endpoints {
+			if endpoints[i].Subscribed(event.Type) {
+				subscribed = append(subscribed, endpoints[i])
+			}
+		}
+		if len(subscribed) == 0 && event.DedupeKey == nil {
+			return nil
+		}
+
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "dedupe_key"}},
+			DoNothing: true,
+		}).Create(event)
+		if result.Error != nil {
+			return fmt.Errorf("failed to create webhook event: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		enqueued = true
+
+		if len(subscribed) == 0 {
+			return nil
+		}
+		deliveries := make([]Delivery, len(subscribed))
+		for i := range subscribed {
+			deliveries[i] = Delivery{
+				UserID:        subscribed[i].UserID,
+				EndpointID:    subscribed[i].ID,
+				EventID:       event.ID,
+				EventType:     event.Type,
+				Status:        StatusPending,
+				NextAttemptAt: now,
+			}
+		}
+		if err := tx.Create(&deliveries).Error; err != nil {
+			return fmt.Errorf("failed to create webhook deliveries: %w", err)
+		}
+		return nil
+	})
+	return enqueued, err
+}
+
+// Prune deletes the finished deliveries last updated before cutoff, then the events created
+// before cutoff that no delivery refers to anymore
+func (r *Repository) Prune(cutoff time.Time) (deliveries, events int64, err error) {
+	result := r.db.Where("status IN ? AND updated_at < ?", []string{StatusDelivered, StatusFailed}, cutoff).Delete(&Delivery{})
+	if result.Error != nil {
+		return 0, 0, fmt.Errorf("failed to prune webhook deliveries: %w", result.Error)
+	}
+	deliveries = result.RowsAffected
+
+	result = r.db.Where("created_at < ? AND NOT EXISTS (SELECT 1 FROM webhook_deliveries WHERE webhook_deliveries.event_id = webhook_events.id)", cutoff).
+		Delete(&Event{})
+	if result.Error != nil {
+		return deliveries, 0, fmt.Errorf("failed to prune webhook events: %w", result.Error)
+	}
+	return deliveries, result.RowsAffected, nil
+}
+
+// ClaimDue locks up to limit pending deliveries due at now and pushes their next attempt to
+// leaseUntil, so that another dispatcher does not send them while they are being delivered
+func (r *Repository) ClaimDue(now, leaseUntil time.Time, limit int) ([]Delivery, error) {
+	var deliveries []Delivery
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", StatusPending, now).
+			Order("next_attempt_at, id").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+		}
+		return tx.Model(&Delivery{}).Where("id IN ?", ids).Update("next_attempt_at", leaseUntil).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// GetEndpointByID retrieves an endpoint of any user, for delivery
+func (r *Repository) GetEndpointByID(id uint) (*Endpoint, error) {
+	var endpoint Endpoint
+	if err := r.db.First(&endpoint, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+	return &endpoint, nil
+}
+
+// GetEvent retrieves an event of the outbox
+func (r *Repository) GetEvent(id uint) (*Event, error) {
+	var event Event
+	if err := r.db.First(&event, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhook event: %w", err)
+	}
+	return &event, nil
+}
+
+// SaveDelivery records the outcome of a delivery attempt
+func (r *Repository) SaveDelivery(delivery *Delivery) error {
+	if err := r.db.Save(delivery).Error; err != nil {
+		return fmt.Errorf("failed to save webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries retrieves the delivery log of an endpoint, newest first, optionally filtered by status
+func (r *Repository) ListDeliveries(endpointID uint, status string, limit int) ([]Delivery, error) {
+	var deliveries []Delivery
+	query := r.db.Where("endpoint_id = ?", endpointID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// GetDelivery retrieves one delivery of an endpoint
+func (r *Repository) GetDelivery(id, endpointID uint) (*Delivery, error) {
+	var delivery Delivery
+	if err := r.db.Where("id = ? AND endpoint_id = ?", id, endpointID).First(&delivery).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook delivery not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// CreateDelivery schedules a delivery, e.g. the replay of an earlier one
+func (r *Repository) CreateDelivery(delivery *Delivery) error {
+	if err := r.db.Create(delivery).Error; err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ultra-bis/internal/auth"
+)
+
+// RegisterRoutes registers all webhook-related routes to the provided mux
+func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
+	// All webhook routes are protected with JWT
+
+	mux.HandleFunc("/webhooks", auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handler.ListEndpoints(w, r)
+		case http.MethodPost:
+			handler.CreateEndpoint(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/webhooks/events", auth.JWTMiddleware(handler.ListEventTypes))
+
+	// /webhooks/{id}, /webhooks/{id}/deliveries and /webhooks/{id}/deliveries/{deliveryID}/replay
+	mux.HandleFunc("/webhooks/", auth.JWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/webhooks/"), "/")
+		id, err := strconv.ParseUint(parts[0], 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+
+		switch {
+		case len(parts) == 1:
+			switch r.Method {
+			case http.MethodGet:
+				handler.GetEndpoint(w, r, uint(id))
+			case http.MethodPut:
+				handler.UpdateEndpoint(w, r, uint(id))
+			case http.MethodDelete:
+				handler.DeleteEndpoint(w, r, uint(id))
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		case len(parts) == 2 && parts[1] == "deliveries":
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			handler.ListDeliveries(w, r, uint(id))
+		case len(parts) == 4 && parts[1] == "deliveries" && parts[3] == "replay":
+			deliveryID, err := strconv.ParseUint(parts[2], 10, 32)
+			if err != nil {
+				http.Error(w, "Invalid ID", http.StatusBadRequest)
+				return
+			}
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			handler.ReplayDelivery(w, r, uint(id), uint(deliveryID))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-Ultra-Signature" // t=<unix seconds>,v1=<hex HMAC-SHA256>
+	EventHeader     = "X-Ultra-Event"
+	DeliveryHeader  = "X-Ultra-Delivery"
+)
+
+// SecretPrefix starts every endpoint secret
+const SecretPrefix = "whsec_"
+
+// GenerateSecret returns a new random endpoint secret
+func GenerateSecret() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return SecretPrefix + hex.EncodeToString(bytes), nil
+}
+
+// Sign returns the signature header of body sent at timestamp: the HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the endpoint secret
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + computeSignature(secret, unix, body)
+}
+
+// Verify checks a signature header against body, rejecting timestamps older than tolerance
+// Receivers can use it (or its equivalent) to authenticate deliveries
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var unix, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || signature == "" {
+		return errors.New("malformed signature header")
+	}
+	if now.Sub(time.Unix(seconds, 0)) > tolerance {
+		return errors.New("signature timestamp is too old")
+	}
+	if !hmac.Equal([]byte(signature), []byte(computeSignature(secret, unix, body))) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// computeSignature returns the hex HMAC-SHA256 of "<unix>.<body>"
+func computeSignature(secret, unix string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
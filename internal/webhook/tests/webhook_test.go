@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ultra-bis/internal/webhook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := "whsec_test"
+	body := []byte(`{"type":"goal.activated"}`)
+	sentAt := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+
+	header := webhook.Sign(secret, sentAt, body)
+	assert.Regexp(t, `^t=1736164800,v1=[0-9a-f]{64}$`, header)
+
+	assert.NoError(t, webhook.Verify(secret, header, body, 5*time.Minute, sentAt.Add(time.Minute)))
+	assert.EqualError(t, webhook.Verify(secret, header, []byte(`{"type":"metric.recorded"}`), 5*time.Minute, sentAt), "signature does not match")
+	assert.EqualError(t, webhook.Verify("whsec_other", header, body, 5*time.Minute, sentAt), "signature does not match")
+	assert.EqualError(t, webhook.Verify(secret, header, body, 5*time.Minute, sentAt.Add(time.Hour)), "signature timestamp is too old")
+	assert.EqualError(t, webhook.Verify(secret, "v1=abc", body, 5*time.Minute, sentAt), "malformed signature header")
+}
+
+func TestGenerateSecret(t *testing.T) {
+	first, err := webhook.GenerateSecret()
+	require.NoError(t, err)
+	second, err := webhook.GenerateSecret()
+	require.NoError(t, err)
+
+	assert.Regexp(t, `^whsec_[0-9a-f]{64}$`, first)
+	assert.NotEqual(t, first, second)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, webhook.Backoff(1))
+	assert.Equal(t, 2*time.Minute, webhook.Backoff(2))
+	assert.Equal(t, 8*time.Minute, webhook.Backoff(4))
+	assert.Equal(t, 256*time.Minute, webhook.Backoff(9))
+	assert.Equal(t, webhook.MaxRetryDelay, webhook.Backoff(10))
+	assert.Equal(t, webhook.MaxRetryDelay, webhook.Backoff(50))
+}
+
+func TestRecordAttempt(t *testing.T) {
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+
+	delivery := &webhook.Delivery{Status: webhook.StatusPending}
+	delivery.RecordAttempt(503, nil, now)
+	assert.Equal(t, webhook.StatusPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, "endpoint answered 503", delivery.LastError)
+	assert.Equal(t, now.Add(time.Minute), delivery.NextAttemptAt)
+
+	delivery.RecordAttempt(0, errors.New("connection refused"), now)
+	assert.Equal(t, "connection refused", delivery.LastError)
+	assert.Equal(t, now.Add(2*time.Minute), delivery.NextAttemptAt)
+
+	delivery.RecordAttempt(204, nil, now)
+	assert.Equal(t, webhook.StatusDelivered, delivery.Status)
+	assert.Empty(t, delivery.LastError)
+	require.NotNil(t, delivery.DeliveredAt)
+
+	// The last allowed attempt gives up
+	failing := &webhook.Delivery{Status: webhook.StatusPending, Attempts: webhook.MaxAttempts - 1}
+	failing.RecordAttempt(500, nil, now)
+	assert.Equal(t, webhook.StatusFailed, failing.Status)
+	assert.Equal(t, webhook.MaxAttempts, failing.Attempts)
+}
+
+func TestSubscribed(t *testing.T) {
+	all := webhook.Endpoint{}
+	assert.True(t, all.Subscribed(webhook.EventMetricRecorded))
+
+	some := webhook.Endpoint{Events: webhook.EventList{webhook.EventGoalActivated}}
+	assert.True(t, some.Subscribed(webhook.EventGoalActivated))
+	assert.False(t, some.Subscribed(webhook.EventDiaryEntryCreated))
+}
+
+func TestValidateEndpointRequest(t *testing.T) {
+	req := &webhook.EndpointRequest{URL: " https://example.com/hooks ", Events: []string{webhook.EventGoalActivated}}
+	require.NoError(t, webhook.ValidateEndpointRequest(req))
+	assert.Equal(t, "https://example.com/hooks", req.URL)
+
+	for _, url := range []string{"", "example.com/hooks", "ftp://example.com", "https://"} {
+		assert.EqualError(t, webhook.ValidateEndpointRequest(&webhook.EndpointRequest{URL: url}), "url must be an absolute http or https URL", url)
+	}
+
+	for _, url := range []string{"http://127.0.0.1:8080/hooks", "http://localhost/hooks", "http://169.254.169.254/latest/meta-data", "https://10.0.0.5", "http://192.168.1.10", "http://[::1]/hooks", "http://0.0.0.0", "http://100.64.1.1", "http://[::ffff:127.0.0.1]/hooks"} {
+		assert.EqualError(t, webhook.ValidateEndpointRequest(&webhook.EndpointRequest{URL: url}), "url must not point to a local or private address", url)
+	}
+
+	err := webhook.ValidateEndpointRequest(&webhook.EndpointRequest{URL: "https://example.com", Events: []string{"diary.entry.deleted"}})
+	assert.ErrorContains(t, err, "unknown event type: diary.entry.deleted")
+}
+
+func TestSend(t *testing.T) {
+	secret := "whsec_test"
+	now := time.Now()
+
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	endpoint := &webhook.Endpoint{URL: server.URL, Secret: secret}
+	event := &webhook.Event{ID: 7, UserID: 3, Type: webhook.EventMetricRecorded, Data: webhook.Payload(`{"weight":80.4}`)}
+	delivery := &webhook.Delivery{ID: 42}
+
+	status, err := webhook.Send(server.Client(), endpoint, event, delivery, now)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+
+	require.NotNil(t, received)
+	assert.Equal(t, webhook.EventMetricRecorded, received.Header.Get(webhook.EventHeader))
+	assert.Equal(t, "42", received.Header.Get(webhook.DeliveryHeader))
+	assert.NoError(t, webhook.Verify(secret, received.Header.Get(webhook.SignatureHeader), body, time.Minute, now))
+
+	var envelope webhook.Envelope
+	require.NoError(t, json.Unmarshal(body, &envelope))
+	assert.Equal(t, uint(7), envelope.ID)
+	assert.Equal(t, uint(3), envelope.UserID)
+	assert.JSONEq(t, `{"weight":80.4}`, string(envelope.Data))
+}
+
+func TestBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.4.2", true},
+		{"192.168.1.10", true},
+		{"169.254.169.254", true},
+		{"0.0.0.0", true},
+		{"100.64.0.1", true}, // Carrier-grade NAT
+		{"100.127.255.254", true},
+		{"198.18.0.1", true}, // Benchmarking
+		{"198.19.255.254", true},
+		{"255.255.255.255", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"::", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"::ffff:127.0.0.1", true}, // IPv4-mapped
+		{"::ffff:169.254.169.254", true},
+		{"::ffff:100.64.0.1", true},
+		{"64:ff9b::a9fe:a9fe", true}, // NAT64 of 169.254.169.254
+		{"2002:0a00:0001::1", true},  // 6to4 of 10.0.0.1
+		{"93.184.216.34", false},
+		{"100.128.0.1", false},
+		{"198.20.0.1", false},
+		{"::ffff:93.184.216.34", false},
+		{"64:ff9b::5db8:d822", false}, // NAT64 of 93.184.216.34
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.blocked, webhook.BlockedIP(net.ParseIP(tt.ip)), tt.ip)
+	}
+}
+
+func TestDeliveryClient_RefusesLocalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	endpoint := &webhook.Endpoint{URL: server.URL, Secret: "whsec_test"}
+	event := &webhook.Event{ID: 1, Type: webhook.EventGoalActivated, Data: webhook.Payload(`{}`)}
+
+	_, err := webhook.Send(webhook.NewDeliveryClient(), endpoint, event, &webhook.Delivery{ID: 1}, time.Now())
+	assert.ErrorContains(t, err, "is not allowed")
+}
@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ValidateEndpointRequest checks an endpoint's URL, description and event types
+func ValidateEndpointRequest(req *EndpointRequest) error {
+	req.URL = strings.TrimSpace(req.URL)
+	req.Description = strings.TrimSpace(req.Description)
+
+	parsed, err := url.Parse(req.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(req.URL) > 2048 {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if err := validateHost(parsed.Hostname()); err != nil {
+		return err
+	}
+	if len(req.Description) > 255 {
+		return errors.New("description must be at most 255 characters")
+	}
+	for _, eventType := range req.Events {
+		if !validEventType(eventType) {
+			return fmt.Errorf("unknown event type: %s (available: %s)", eventType, strings.Join(EventTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// validEventType reports whether eventType is one of EventTypes
+func validEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}